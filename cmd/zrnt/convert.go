@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+)
+
+func cmdConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt convert [flags] <input> <output>\n\n"+
+			"Converts a state or signed block between the ssz, ssz_snappy, json and yaml formats.\n"+
+			"JSON and YAML are versioned: {\"version\": \"deneb\", \"data\": ...}\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	kind := fs.String("type", kindState, "Type of the object: state or block")
+	fork := fs.String("fork", "", "Fork of the object, detected if empty")
+	inFormat := fs.String("in-format", "", "Format of the input, detected from the file extension if empty")
+	outFormat := fs.String("out-format", "", "Format of the output, detected from the file extension if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected an input and output path")
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return err
+	}
+	obj, err := loadObject(sp, *kind, beacon.ForkName(*fork), fs.Arg(0), *inFormat)
+	if err != nil {
+		return err
+	}
+	format, err := formatOf(fs.Arg(1), *outFormat)
+	if err != nil {
+		return err
+	}
+	data, err := obj.encode(sp, format)
+	if err != nil {
+		return err
+	}
+	return writeFile(fs.Arg(1), data)
+}
+
+func cmdHTR(args []string) error {
+	fs := flag.NewFlagSet("htr", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt htr [flags] <files...>\n\n"+
+			"Prints the hash tree root of each state, or the block root of each signed block.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	kind := fs.String("type", kindState, "Type of the objects: state or block")
+	fork := fs.String("fork", "", "Fork of the objects, detected if empty")
+	format := fs.String("format", "", "Format of the files, detected from the file extension if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args() {
+		obj, err := loadObject(sp, *kind, beacon.ForkName(*fork), path, *format)
+		if err != nil {
+			return fmt.Errorf("failed to load %q: %w", path, err)
+		}
+		var root tree.Root
+		if block, ok := obj.raw.(beacon.OpaqueBlock); ok {
+			root = block.Envelope(sp, [4]byte{}).BlockRoot
+		} else {
+			root = obj.raw.HashTreeRoot(sp, tree.GetHashFn())
+		}
+		fmt.Printf("%s %s %s\n", root, obj.fork, path)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+// loadSecretKeys reads hex encoded secret keys, one per line.
+func loadSecretKeys(path string) ([]*blsu.SecretKey, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []*blsu.SecretKey
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "0x")
+		if line == "" {
+			continue
+		}
+		var b [32]byte
+		if n, err := hex.Decode(b[:], []byte(line)); err != nil || n != 32 {
+			return nil, fmt.Errorf("line %d: expected 32 hex encoded bytes", i+1)
+		}
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&b); err != nil {
+			return nil, fmt.Errorf("line %d: invalid secret key: %w", i+1, err)
+		}
+		out = append(out, &sk)
+	}
+	return out, nil
+}
+
+func cmdDepositData(args []string) error {
+	fs := flag.NewFlagSet("deposit-data", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt deposit-data [flags]\n\n"+
+			"Creates signed deposit data in the deposit_data-*.json format of the deposit CLI,\n"+
+			"from a file of hex encoded secret keys or from interop keys. Or verifies such a file, with --verify.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	verifyPath := fs.String("verify", "", "Path to a deposit data JSON file to verify, instead of creating deposit data")
+	keysPath := fs.String("secret-keys", "", "Path to a file with hex encoded secret keys, one per line")
+	interopStart := fs.Uint64("interop-start", 0, "Index of the first interop key, if no secret keys are given")
+	interopCount := fs.Uint64("interop-count", 0, "Number of interop keys, if no secret keys are given")
+	credsStr := fs.String("withdrawal-credentials", "", "Withdrawal credentials of all deposits, hex encoded")
+	amount := fs.Uint64("amount", 0, "Deposit amount in Gwei, the max effective balance of the spec if 0")
+	networkName := fs.String("network-name", "", "Network name of the deposit data, the config name of the spec if empty")
+	out := fs.String("out", "-", "Path to write the deposit data JSON to, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return err
+	}
+	if *verifyPath != "" {
+		data, err := readFile(*verifyPath)
+		if err != nil {
+			return err
+		}
+		var entries []common.DepositCLIData
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("failed to decode deposit data: %w", err)
+		}
+		if _, err := common.VerifyDepositCLIData(sp, entries); err != nil {
+			return err
+		}
+		fmt.Printf("%d deposits are valid\n", len(entries))
+		return nil
+	}
+
+	var keys []*blsu.SecretKey
+	if *keysPath != "" {
+		keys, err = loadSecretKeys(*keysPath)
+		if err != nil {
+			return fmt.Errorf("failed to load secret keys: %w", err)
+		}
+	} else {
+		for i := *interopStart; i < *interopStart+*interopCount; i++ {
+			sk, err := interop.SecretKey(i)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, sk)
+		}
+	}
+	if len(keys) == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected --secret-keys or --interop-count")
+	}
+	if *credsStr == "" {
+		fs.Usage()
+		return fmt.Errorf("--withdrawal-credentials is required, e.g. 0x01 followed by 11 zero bytes and the execution address to withdraw to")
+	}
+	var creds common.Root
+	if err := creds.UnmarshalText([]byte(*credsStr)); err != nil {
+		return fmt.Errorf("invalid withdrawal credentials: %w", err)
+	}
+	depositAmount := common.Gwei(*amount)
+	if depositAmount == 0 {
+		depositAmount = sp.MAX_EFFECTIVE_BALANCE
+	}
+	name := *networkName
+	if name == "" {
+		name = sp.CONFIG_NAME
+	}
+	entries := make([]*common.DepositCLIData, 0, len(keys))
+	for _, sk := range keys {
+		d, _, err := common.NewDepositData(sp, sk, creds, depositAmount)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, d.DepositCLIData(sp, name))
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(*out, append(data, '\n'))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+const (
+	formatSSZ       = "ssz"
+	formatSSZSnappy = "ssz_snappy"
+	formatJSON      = "json"
+	formatYAML      = "yaml"
+)
+
+const (
+	kindState = "state"
+	kindBlock = "block"
+)
+
+// formatOf returns the format override if not empty, or the format based on the file extension otherwise.
+func formatOf(path string, override string) (string, error) {
+	if override != "" {
+		switch override {
+		case formatSSZ, formatSSZSnappy, formatJSON, formatYAML:
+			return override, nil
+		default:
+			return "", fmt.Errorf("unknown format: %q", override)
+		}
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ssz":
+		return formatSSZ, nil
+	case ".ssz_snappy":
+		return formatSSZSnappy, nil
+	case ".json":
+		return formatJSON, nil
+	case ".yaml", ".yml":
+		return formatYAML, nil
+	default:
+		return "", fmt.Errorf("cannot determine format of %q, specify it explicitly", path)
+	}
+}
+
+func readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func writeFile(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// object is a state or signed block of any fork, in its flat native Go structure.
+type object struct {
+	kind string
+	fork beacon.ForkName
+	raw  common.SpecObj
+}
+
+func newRawObject(kind string, fork beacon.ForkName) (common.SpecObj, error) {
+	switch kind {
+	case kindState:
+		return beacon.NewRawBeaconStateOf(fork)
+	case kindBlock:
+		return beacon.NewSignedBeaconBlockOf(fork)
+	default:
+		return nil, fmt.Errorf("unknown object type: %q", kind)
+	}
+}
+
+// decodeObject decodes a state or block. The fork is detected if empty.
+// JSON and YAML inputs are versioned, like the responses of the beacon API: {"version": "deneb", "data": ...}.
+// Versioned JSON is decoded with the beacon package, inputs without the version wrapper require the fork to be specified.
+func decodeObject(spec *common.Spec, kind string, fork beacon.ForkName, format string, data []byte) (*object, error) {
+	switch format {
+	case formatSSZSnappy:
+		var err error
+		data, err = snappy.Decode(nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snappy data: %w", err)
+		}
+		fallthrough
+	case formatSSZ:
+		if fork == "" {
+			var err error
+			switch kind {
+			case kindState:
+				fork, err = beacon.DetectBeaconStateFork(spec, data)
+			case kindBlock:
+				fork, err = beacon.DetectSignedBeaconBlockFork(spec, data)
+			default:
+				err = fmt.Errorf("unknown object type: %q", kind)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		raw, err := newRawObject(kind, fork)
+		if err != nil {
+			return nil, err
+		}
+		if err := raw.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %s: %w", fork, kind, err)
+		}
+		return &object{kind: kind, fork: fork, raw: raw}, nil
+	case formatJSON:
+		var v struct {
+			Version beacon.ForkName `json:"version"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v.Version != "" {
+			switch kind {
+			case kindState:
+				state, err := beacon.UnmarshalVersionedBeaconState(spec, data)
+				if err != nil {
+					return nil, err
+				}
+				return stateObject(spec, state)
+			case kindBlock:
+				block, _, err := beacon.UnmarshalVersionedSignedBeaconBlock(spec, data)
+				if err != nil {
+					return nil, err
+				}
+				return &object{kind: kind, fork: v.Version, raw: block}, nil
+			default:
+				return nil, fmt.Errorf("unknown object type: %q", kind)
+			}
+		}
+		if fork == "" {
+			return nil, fmt.Errorf("JSON %s has no version, the fork must be specified", kind)
+		}
+		raw, err := newRawObject(kind, fork)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, raw); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %s: %w", fork, kind, err)
+		}
+		return &object{kind: kind, fork: fork, raw: raw}, nil
+	case formatYAML:
+		var v struct {
+			Version beacon.ForkName `yaml:"version"`
+			Data    yaml.Node       `yaml:"data"`
+		}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v.Version == "" {
+			v.Version = fork
+			v.Data = yaml.Node{}
+			if err := yaml.Unmarshal(data, &v.Data); err != nil {
+				return nil, err
+			}
+		}
+		if v.Version == "" {
+			return nil, fmt.Errorf("YAML %s has no version, the fork must be specified", kind)
+		}
+		raw, err := newRawObject(kind, v.Version)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.Data.Decode(raw); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %s: %w", v.Version, kind, err)
+		}
+		return &object{kind: kind, fork: v.Version, raw: raw}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %q", format)
+	}
+}
+
+func (o *object) encode(spec *common.Spec, format string) ([]byte, error) {
+	switch format {
+	case formatSSZ, formatSSZSnappy:
+		var buf bytes.Buffer
+		if err := o.raw.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+			return nil, err
+		}
+		if format == formatSSZSnappy {
+			return snappy.Encode(nil, buf.Bytes()), nil
+		}
+		return buf.Bytes(), nil
+	case formatJSON:
+		var out []byte
+		switch o.kind {
+		case kindState:
+			state, err := o.state(spec)
+			if err != nil {
+				return nil, err
+			}
+			if out, err = beacon.MarshalVersionedBeaconState(spec, state); err != nil {
+				return nil, err
+			}
+		case kindBlock:
+			block, ok := o.raw.(beacon.OpaqueBlock)
+			if !ok {
+				return nil, fmt.Errorf("unexpected block type %T", o.raw)
+			}
+			var err error
+			if out, err = beacon.MarshalVersionedSignedBeaconBlock(block); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown object type: %q", o.kind)
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, out, "", "  "); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	case formatYAML:
+		// the beacon package only has versioned JSON, YAML wraps the object the same way.
+		return yaml.Marshal(&struct {
+			Version beacon.ForkName `yaml:"version"`
+			Data    common.SpecObj  `yaml:"data"`
+		}{Version: o.fork, Data: o.raw})
+	default:
+		return nil, fmt.Errorf("unknown format: %q", format)
+	}
+}
+
+// state converts the object into a tree-backed state.
+func (o *object) state(spec *common.Spec) (common.BeaconState, error) {
+	if o.kind != kindState {
+		return nil, fmt.Errorf("expected a state, got a %s", o.kind)
+	}
+	data, err := o.encode(spec, formatSSZ)
+	if err != nil {
+		return nil, err
+	}
+	return beacon.DecodeBeaconStateOf(spec, o.fork, data)
+}
+
+func stateObject(spec *common.Spec, state common.BeaconState) (*object, error) {
+	fork, err := beacon.StateForkName(state)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, err
+	}
+	return decodeObject(spec, kindState, fork, formatSSZ, buf.Bytes())
+}
+
+func loadObject(spec *common.Spec, kind string, fork beacon.ForkName, path string, format string) (*object, error) {
+	format, err := formatOf(path, format)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(spec, kind, fork, format, data)
+}
+
+func loadState(spec *common.Spec, path string, format string) (common.BeaconState, error) {
+	obj, err := loadObject(spec, kindState, "", path, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state %q: %w", path, err)
+	}
+	return obj.state(spec)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+func TestObjectFormats(t *testing.T) {
+	spec := configs.Minimal
+	validators, err := interop.KickstartValidators(uint64(spec.SLOTS_PER_EPOCH), common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, spec.MAX_EFFECTIVE_BALANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := deneb.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := stateObject(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.fork != beacon.Deneb {
+		t.Fatalf("expected a deneb state, got %s", obj.fork)
+	}
+	root := state.HashTreeRoot(tree.GetHashFn())
+
+	// versioned JSON is the same as that of the beacon package, and decodes with it
+	data, err := obj.encode(spec, formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := beacon.MarshalVersionedBeaconState(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(compact.Bytes(), expected) {
+		t.Fatal("versioned JSON does not match the beacon package encoding")
+	}
+	decodedState, err := beacon.UnmarshalVersionedBeaconState(spec, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedState.HashTreeRoot(tree.GetHashFn()) != root {
+		t.Fatal("beacon package decoded a different state")
+	}
+
+	for _, format := range []string{formatSSZ, formatSSZSnappy, formatJSON, formatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := obj.encode(spec, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeObject(spec, kindState, "", format, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.fork != beacon.Deneb {
+				t.Fatalf("expected a deneb state, got %s", decoded.fork)
+			}
+			if got := decoded.raw.HashTreeRoot(spec, tree.GetHashFn()); got != root {
+				t.Fatalf("decoded state root %s does not match %s", got, root)
+			}
+		})
+	}
+
+	// JSON without the version wrapper requires the fork
+	raw, err := json.Marshal(obj.raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeObject(spec, kindState, "", formatJSON, raw); err == nil {
+		t.Fatal("expected an error for JSON without version")
+	}
+	decoded, err := decodeObject(spec, kindState, beacon.Deneb, formatJSON, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.raw.HashTreeRoot(spec, tree.GetHashFn()); got != root {
+		t.Fatalf("decoded state root %s does not match %s", got, root)
+	}
+
+	// blocks use the versioned block JSON of the beacon package
+	block := &deneb.SignedBeaconBlock{Message: deneb.BeaconBlock{Slot: 3, ProposerIndex: 2}}
+	blockObj := &object{kind: kindBlock, fork: beacon.Deneb, raw: block}
+	data, err = blockObj.encode(spec, formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedBlock, _, err := beacon.UnmarshalVersionedSignedBeaconBlock(spec, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedBlock.HashTreeRoot(spec, tree.GetHashFn()) != block.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("beacon package decoded a different block")
+	}
+	decoded, err = decodeObject(spec, kindBlock, "", formatJSON, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.fork != beacon.Deneb || decoded.raw.HashTreeRoot(spec, tree.GetHashFn()) != block.HashTreeRoot(spec, tree.GetHashFn()) {
+		t.Fatal("decoded a different block")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// validatorEntry is the JSON and YAML encoding of phase0.KickstartValidatorData
+type validatorEntry struct {
+	Pubkey                common.BLSPubkey `json:"pubkey" yaml:"pubkey"`
+	WithdrawalCredentials common.Root      `json:"withdrawal_credentials" yaml:"withdrawal_credentials"`
+	Balance               common.Gwei      `json:"balance" yaml:"balance"`
+}
+
+// decodeFile decodes a JSON or YAML file, based on the file extension.
+func decodeFile(path string, dst interface{}) error {
+	format, err := formatOf(path, "")
+	if err != nil {
+		return err
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case formatJSON:
+		return json.Unmarshal(data, dst)
+	case formatYAML:
+		return yaml.Unmarshal(data, dst)
+	default:
+		return fmt.Errorf("expected a JSON or YAML file, got %s", format)
+	}
+}
+
+// loadGenesisDeposits loads a JSON or YAML list of deposits with proofs,
+// or the deposit_data-*.json file of the deposit CLI (and the deposit-data command), which has no proofs.
+// The proofs of deposit CLI entries are built from the deposit tree of the listed deposits, in order.
+// Unless ignoreSigs is set, the fork version and signatures of deposit CLI entries are verified.
+func loadGenesisDeposits(spec *common.Spec, path string, ignoreSigs bool) ([]common.Deposit, error) {
+	format, err := formatOf(path, "")
+	if err != nil {
+		return nil, err
+	}
+	if format == formatJSON {
+		data, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// deposit CLI entries are recognized by their deposit data root
+		var probe []struct {
+			DepositDataRoot *string `json:"deposit_data_root"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, err
+		}
+		if len(probe) > 0 && probe[0].DepositDataRoot != nil {
+			var entries []common.DepositCLIData
+			if err := json.Unmarshal(data, &entries); err != nil {
+				return nil, err
+			}
+			var deps []common.DepositData
+			if ignoreSigs {
+				deps = make([]common.DepositData, len(entries))
+				for i := range entries {
+					d, err := entries[i].DepositData()
+					if err != nil {
+						return nil, fmt.Errorf("entry %d: %w", i, err)
+					}
+					deps[i] = *d
+				}
+			} else if deps, err = common.VerifyDepositCLIData(spec, entries); err != nil {
+				return nil, err
+			}
+			return phase0.GenesisDeposits(deps)
+		}
+	}
+	var deposits []common.Deposit
+	if err := decodeFile(path, &deposits); err != nil {
+		return nil, err
+	}
+	return deposits, nil
+}
+
+// genesisFork returns the fork that the spec schedules at the genesis epoch, like spec.ForkVersion does:
+// a fork is only scheduled if all the forks before it are.
+func genesisFork(spec *common.Spec) beacon.ForkName {
+	epoch := common.GENESIS_EPOCH
+	switch {
+	case epoch < spec.ALTAIR_FORK_EPOCH:
+		return beacon.Phase0
+	case epoch < spec.BELLATRIX_FORK_EPOCH:
+		return beacon.Altair
+	case epoch < spec.CAPELLA_FORK_EPOCH:
+		return beacon.Bellatrix
+	case epoch < spec.DENEB_FORK_EPOCH:
+		return beacon.Capella
+	case epoch < spec.ALPACA_FORK_EPOCH:
+		return beacon.Deneb
+	default:
+		return beacon.Electra
+	}
+}
+
+// genesisFromEth1 builds a genesis state of the given fork with the genesis builder of the fork.
+// The execution payload header of post-merge forks is loaded from headerPath, or is empty if the path is empty.
+func genesisFromEth1(spec *common.Spec, fork beacon.ForkName, eth1BlockHash common.Root, time common.Timestamp,
+	deposits []common.Deposit, headerPath string, ignoreSigs bool) (common.BeaconState, error) {
+	loadHeader := func(header interface{}) error {
+		if headerPath == "" {
+			return nil
+		}
+		if err := decodeFile(headerPath, header); err != nil {
+			return fmt.Errorf("failed to load execution payload header: %w", err)
+		}
+		return nil
+	}
+	if headerPath != "" && (fork == beacon.Phase0 || fork == beacon.Altair) {
+		return nil, fmt.Errorf("%s genesis states have no execution payload header", fork)
+	}
+	switch fork {
+	case beacon.Phase0:
+		state, _, err := phase0.GenesisFromEth1(spec, eth1BlockHash, time, deposits, ignoreSigs)
+		return state, err
+	case beacon.Altair:
+		state, _, err := altair.GenesisFromEth1(spec, eth1BlockHash, time, deposits, ignoreSigs)
+		return state, err
+	case beacon.Bellatrix:
+		var header bellatrix.ExecutionPayloadHeader
+		if err := loadHeader(&header); err != nil {
+			return nil, err
+		}
+		state, _, err := bellatrix.GenesisFromEth1(spec, eth1BlockHash, time, deposits, &header, ignoreSigs)
+		return state, err
+	case beacon.Capella:
+		var header capella.ExecutionPayloadHeader
+		if err := loadHeader(&header); err != nil {
+			return nil, err
+		}
+		state, _, err := capella.GenesisFromEth1(spec, eth1BlockHash, time, deposits, &header, ignoreSigs)
+		return state, err
+	case beacon.Deneb:
+		var header deneb.ExecutionPayloadHeader
+		if err := loadHeader(&header); err != nil {
+			return nil, err
+		}
+		state, _, err := deneb.GenesisFromEth1(spec, eth1BlockHash, time, deposits, &header, ignoreSigs)
+		return state, err
+	case beacon.Electra:
+		var header deneb.ExecutionPayloadHeader
+		if err := loadHeader(&header); err != nil {
+			return nil, err
+		}
+		state, _, err := electra.GenesisFromEth1(spec, eth1BlockHash, time, deposits, &header, ignoreSigs)
+		return state, err
+	default:
+		return nil, fmt.Errorf("unknown fork: %q", fork)
+	}
+}
+
+func cmdGenesis(args []string) error {
+	fs := flag.NewFlagSet("genesis", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt genesis [flags]\n\n"+
+			"Builds a genesis state of any fork, from a JSON or YAML list of validators (pubkey, withdrawal_credentials, balance),\n"+
+			"or from a JSON or YAML list of deposits (proof, data), or from a deposit_data-*.json file of the deposit CLI.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	validatorsPath := fs.String("validators", "", "Path to the list of validators")
+	depositsPath := fs.String("deposits", "", "Path to the list of deposits")
+	ignoreSigs := fs.Bool("ignore-signatures", false, "Ignore the signatures and proofs of the deposits")
+	eth1BlockHashStr := fs.String("eth1-block-hash", "0x4242424242424242424242424242424242424242424242424242424242424242", "Eth1 block hash to start with")
+	eth1Time := fs.Uint64("eth1-time", 0, "Timestamp of the eth1 block. With deposits the genesis delay is added to this, with validators it is the genesis time itself.")
+	fork := fs.String("fork", "", "Fork of the genesis state, the latest fork scheduled at the genesis epoch by the config if empty")
+	headerPath := fs.String("execution-payload-header", "", "Path to the JSON or YAML execution payload header of the execution genesis block, for Bellatrix and later. Empty if not set.")
+	out := fs.String("out", "", "Path to write the genesis state to, or - for stdout")
+	outFormat := fs.String("out-format", "", "Format of the output, detected from the file extension if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*validatorsPath == "") == (*depositsPath == "") {
+		fs.Usage()
+		return fmt.Errorf("expected either --validators or --deposits")
+	}
+	if *out == "" {
+		return fmt.Errorf("--out is required")
+	}
+	var eth1BlockHash common.Root
+	if err := eth1BlockHash.UnmarshalText([]byte(*eth1BlockHashStr)); err != nil {
+		return fmt.Errorf("invalid eth1 block hash: %w", err)
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return err
+	}
+	genesisForkName := beacon.ForkName(*fork)
+	if genesisForkName == "" {
+		genesisForkName = genesisFork(sp)
+	}
+	var state common.BeaconState
+	if *validatorsPath != "" {
+		var entries []validatorEntry
+		if err := decodeFile(*validatorsPath, &entries); err != nil {
+			return fmt.Errorf("failed to load validators: %w", err)
+		}
+		validators := make([]phase0.KickstartValidatorData, len(entries))
+		for i, e := range entries {
+			validators[i] = phase0.KickstartValidatorData{
+				Pubkey:                e.Pubkey,
+				WithdrawalCredentials: e.WithdrawalCredentials,
+				Balance:               e.Balance,
+			}
+		}
+		// like phase0.KickStartState, the deposits are not verified, and the eth1 time is the genesis time
+		state, err = genesisFromEth1(sp, genesisForkName, eth1BlockHash, 0, phase0.KickstartDeposits(validators), *headerPath, true)
+		if err == nil {
+			err = state.SetGenesisTime(common.Timestamp(*eth1Time))
+		}
+	} else {
+		var deposits []common.Deposit
+		deposits, err = loadGenesisDeposits(sp, *depositsPath, *ignoreSigs)
+		if err != nil {
+			return fmt.Errorf("failed to load deposits: %w", err)
+		}
+		state, err = genesisFromEth1(sp, genesisForkName, eth1BlockHash, common.Timestamp(*eth1Time), deposits, *headerPath, *ignoreSigs)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build genesis state: %w", err)
+	}
+	if valid, err := phase0.IsValidGenesisState(sp, state); err != nil {
+		return err
+	} else if !valid {
+		fmt.Fprintln(os.Stderr, "warning: genesis state does not meet the minimum genesis time and active validator count")
+	}
+	obj, err := stateObject(sp, state)
+	if err != nil {
+		return err
+	}
+	format, err := formatOf(*out, *outFormat)
+	if err != nil {
+		return err
+	}
+	data, err := obj.encode(sp, format)
+	if err != nil {
+		return err
+	}
+	return writeFile(*out, data)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
+)
+
+func TestGenesisFromDepositData(t *testing.T) {
+	dir := t.TempDir()
+	depositsPath := filepath.Join(dir, "deposit_data-0.json")
+	creds := "0x01" + strings.Repeat("00", 11) + strings.Repeat("ee", 20)
+	if err := cmdDepositData([]string{"--interop-count", "32", "--out", depositsPath}); err == nil ||
+		!strings.Contains(err.Error(), "--withdrawal-credentials is required") {
+		t.Fatalf("expected missing withdrawal credentials error, got %v", err)
+	}
+	if err := cmdDepositData([]string{"--interop-count", "32", "--withdrawal-credentials", creds, "--out", depositsPath}); err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(dir, "genesis.json")
+	if err := cmdGenesis([]string{"--deposits", depositsPath, "--out", statePath}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(configs.Mainnet, statePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := vals.ValidatorCount(); err != nil || count != 32 {
+		t.Fatalf("expected 32 validators, got %d", count)
+	}
+	if index, err := state.Eth1DepositIndex(); err != nil || index != 32 {
+		t.Fatalf("expected all 32 deposits to be processed, got deposit index %d", index)
+	}
+}
+
+func TestGenesisForks(t *testing.T) {
+	spec := configs.Minimal
+	dir := t.TempDir()
+	validators, err := interop.KickstartValidators(uint64(spec.SLOTS_PER_EPOCH), common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, spec.MAX_EFFECTIVE_BALANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]validatorEntry, len(validators))
+	for i, v := range validators {
+		entries[i] = validatorEntry{Pubkey: v.Pubkey, WithdrawalCredentials: v.WithdrawalCredentials, Balance: v.Balance}
+	}
+	validatorsPath := filepath.Join(dir, "validators.json")
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(validatorsPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	headerPath := filepath.Join(dir, "header.json")
+	data, err = json.Marshal(&deneb.ExecutionPayloadHeader{BlockHash: common.Root{0xab}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(headerPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the config of the minimal spec does not schedule any fork at genesis
+	if fork := genesisFork(spec); fork != beacon.Phase0 {
+		t.Fatalf("expected a phase0 genesis by default, got %s", fork)
+	}
+	for _, fork := range []beacon.ForkName{beacon.Phase0, beacon.Altair, beacon.Bellatrix, beacon.Capella, beacon.Deneb, beacon.Electra} {
+		t.Run(string(fork), func(t *testing.T) {
+			statePath := filepath.Join(dir, string(fork)+".ssz")
+			args := append(minimalSpecArgs(), "--validators", validatorsPath, "--fork", string(fork),
+				"--eth1-time", "1000000", "--out", statePath)
+			if fork == beacon.Deneb || fork == beacon.Electra {
+				args = append(args, "--execution-payload-header", headerPath)
+			}
+			if err := cmdGenesis(args); err != nil {
+				t.Fatal(err)
+			}
+			state, err := loadState(spec, statePath, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := beacon.StateForkName(state); err != nil || got != fork {
+				t.Fatalf("expected a %s state, got %s (%v)", fork, got, err)
+			}
+			if genesisTime, err := state.GenesisTime(); err != nil || genesisTime != 1000000 {
+				t.Fatalf("unexpected genesis time %d", genesisTime)
+			}
+			vals, err := state.Validators()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count, err := vals.ValidatorCount(); err != nil || count != uint64(len(validators)) {
+				t.Fatalf("expected %d validators, got %d", len(validators), count)
+			}
+			if s, ok := state.(interface {
+				LatestExecutionPayloadHeader() (*deneb.ExecutionPayloadHeaderView, error)
+			}); ok {
+				h, err := s.LatestExecutionPayloadHeader()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if blockHash, err := h.BlockHash(); err != nil || blockHash != (common.Root{0xab}) {
+					t.Fatalf("unexpected execution block hash %s", blockHash)
+				}
+			}
+		})
+	}
+	if err := cmdGenesis(append(minimalSpecArgs(), "--validators", validatorsPath, "--fork", "altair",
+		"--execution-payload-header", headerPath, "--out", filepath.Join(dir, "invalid.ssz"))); err == nil {
+		t.Fatal("expected an error for an execution payload header of a pre-merge genesis")
+	}
+}
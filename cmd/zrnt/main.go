@@ -0,0 +1,63 @@
+// Command zrnt is a tool to run state transitions, convert, and inspect eth2 beacon states and blocks,
+// offline, on files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	help string
+	run  func(args []string) error
+}
+
+var commands = map[string]command{
+	"transition":   {"Apply slots and blocks to a pre-state", cmdTransition},
+	"convert":      {"Convert a state or block between ssz, ssz_snappy, json and yaml", cmdConvert},
+	"htr":          {"Print the hash tree root of a state or block", cmdHTR},
+	"committees":   {"Print the beacon committees of an epoch", cmdCommittees},
+	"proposers":    {"Print the proposers of the current epoch of a state", cmdProposers},
+	"genesis":      {"Build a genesis state from validators or deposits", cmdGenesis},
+	"deposit-data": {"Create or verify signed deposit data in the deposit CLI format", cmdDepositData},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: zrnt <command> [flags] [args]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'zrnt <command> -h' for the flags of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
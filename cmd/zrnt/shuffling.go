@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+type committeeJSON struct {
+	Slot       common.Slot             `json:"slot"`
+	Index      common.CommitteeIndex   `json:"index"`
+	Validators []common.ValidatorIndex `json:"validators"`
+}
+
+type proposerJSON struct {
+	Slot           common.Slot           `json:"slot"`
+	ValidatorIndex common.ValidatorIndex `json:"validator_index"`
+}
+
+func loadShufflingState(fs *flag.FlagSet, spec *specFlags, path string, format string) (*common.Spec, common.BeaconState, *common.EpochsContext, error) {
+	if path == "" {
+		fs.Usage()
+		return nil, nil, nil, fmt.Errorf("--state is required")
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	state, err := loadState(sp, path, format)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	epc, err := common.NewEpochsContext(sp, state)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create epochs context: %w", err)
+	}
+	return sp, state, epc, nil
+}
+
+func cmdCommittees(args []string) error {
+	fs := flag.NewFlagSet("committees", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt committees [flags]\n\n"+
+			"Prints the beacon committees of the previous, current or next epoch of the state.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	statePath := fs.String("state", "", "Path to the state, or - for stdin")
+	format := fs.String("format", "", "Format of the state, detected from the file extension if empty")
+	epochFlag := fs.Int64("epoch", -1, "Epoch to print the committees of, defaults to the current epoch of the state")
+	asJSON := fs.Bool("json", false, "Print the committees as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sp, state, epc, err := loadShufflingState(fs, spec, *statePath, *format)
+	if err != nil {
+		return err
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+	epoch := sp.SlotToEpoch(slot)
+	if *epochFlag >= 0 {
+		epoch = common.Epoch(*epochFlag)
+	}
+	count, err := epc.GetCommitteeCountPerSlot(epoch)
+	if err != nil {
+		return err
+	}
+	start, err := sp.EpochStartSlot(epoch)
+	if err != nil {
+		return err
+	}
+	var out []committeeJSON
+	for s := start; s < start+sp.SLOTS_PER_EPOCH; s++ {
+		for i := uint64(0); i < count; i++ {
+			committee, err := epc.GetBeaconCommittee(s, common.CommitteeIndex(i))
+			if err != nil {
+				return err
+			}
+			if *asJSON {
+				out = append(out, committeeJSON{Slot: s, Index: common.CommitteeIndex(i), Validators: committee})
+			} else {
+				fmt.Printf("slot %d, committee %d: %v\n", s, i, committee)
+			}
+		}
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+	return nil
+}
+
+func cmdProposers(args []string) error {
+	fs := flag.NewFlagSet("proposers", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt proposers [flags]\n\n"+
+			"Prints the proposers of the current epoch of the state.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	statePath := fs.String("state", "", "Path to the state, or - for stdin")
+	format := fs.String("format", "", "Format of the state, detected from the file extension if empty")
+	asJSON := fs.Bool("json", false, "Print the proposers as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sp, _, epc, err := loadShufflingState(fs, spec, *statePath, *format)
+	if err != nil {
+		return err
+	}
+	start, err := sp.EpochStartSlot(epc.CurrentEpoch.Epoch)
+	if err != nil {
+		return err
+	}
+	var out []proposerJSON
+	for s := start; s < start+sp.SLOTS_PER_EPOCH; s++ {
+		proposer, err := epc.GetBeaconProposer(s)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			out = append(out, proposerJSON{Slot: s, ValidatorIndex: proposer})
+		} else {
+			fmt.Printf("slot %d: %d\n", s, proposer)
+		}
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// specFlags registers the configs.SpecOptions as flags, with the same names as the "ask" tags of the options.
+// Options with a "changed" tag are set to whether the flag of the tag was given.
+type specFlags struct {
+	opts configs.SpecOptions
+	fs   *flag.FlagSet
+}
+
+func addSpecFlags(fs *flag.FlagSet) *specFlags {
+	s := &specFlags{fs: fs}
+	s.opts.Default()
+	v := reflect.ValueOf(&s.opts).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("ask")
+		if !ok || !strings.HasPrefix(name, "--") || field.Type.Kind() != reflect.String {
+			continue
+		}
+		ptr := v.Field(i).Addr().Interface().(*string)
+		fs.StringVar(ptr, strings.TrimPrefix(name, "--"), *ptr, field.Tag.Get("help"))
+	}
+	return s
+}
+
+// Spec loads the spec, after the flags have been parsed.
+func (s *specFlags) Spec() (*common.Spec, error) {
+	visited := make(map[string]bool)
+	s.fs.Visit(func(f *flag.Flag) {
+		visited[f.Name] = true
+	})
+	v := reflect.ValueOf(&s.opts).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup("changed")
+		if !ok || t.Field(i).Type.Kind() != reflect.Bool {
+			continue
+		}
+		v.Field(i).SetBool(visited[name])
+	}
+	return s.opts.Spec()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// minimalSpecArgs sets the config and all presets to minimal.
+func minimalSpecArgs() (out []string) {
+	t := reflect.TypeOf(configs.SpecOptions{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("ask")
+		if name == "--config" || strings.HasPrefix(name, "--preset-") {
+			out = append(out, name, "minimal")
+		}
+	}
+	return out
+}
+
+func TestSpecFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	spec := addSpecFlags(fs)
+	t.Run("all options", func(t *testing.T) {
+		typ := reflect.TypeOf(configs.SpecOptions{})
+		for i := 0; i < typ.NumField(); i++ {
+			name, ok := typ.Field(i).Tag.Lookup("ask")
+			if !ok {
+				continue
+			}
+			f := fs.Lookup(strings.TrimPrefix(name, "--"))
+			if f == nil {
+				t.Fatalf("missing flag %s", name)
+			}
+			if f.Usage != typ.Field(i).Tag.Get("help") {
+				t.Fatalf("flag %s has usage %q", name, f.Usage)
+			}
+		}
+		if fs.Lookup("preset-eip7594") == nil {
+			t.Fatal("missing EIP-7594 preset flag")
+		}
+	})
+	if err := fs.Parse(minimalSpecArgs()); err != nil {
+		t.Fatal(err)
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.SLOTS_PER_EPOCH != configs.Minimal.SLOTS_PER_EPOCH || sp.CONFIG_NAME != configs.Minimal.CONFIG_NAME {
+		t.Fatalf("expected the minimal spec, got %d slots per epoch of config %q", sp.SLOTS_PER_EPOCH, sp.CONFIG_NAME)
+	}
+	if sp.EIP7594Preset != configs.Minimal.EIP7594Preset {
+		t.Fatal("expected the minimal EIP-7594 preset")
+	}
+	if spec.opts.LegacyConfigChanged {
+		t.Fatal("legacy config was not set")
+	}
+	if err := fs.Parse([]string{"--legacy-config", "minimal"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spec.Spec(); err != nil {
+		t.Fatal(err)
+	}
+	if !spec.opts.LegacyConfigChanged {
+		t.Fatal("expected the legacy config to be marked as changed")
+	}
+}
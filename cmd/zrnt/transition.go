@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+func cmdTransition(args []string) error {
+	fs := flag.NewFlagSet("transition", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: zrnt transition [flags] [block files...]\n\n"+
+			"Applies the blocks, in order, to the pre-state, and then processes empty slots up to --to-slot.\n\n")
+		fs.PrintDefaults()
+	}
+	spec := addSpecFlags(fs)
+	pre := fs.String("pre", "", "Path to the pre-state, or - for stdin")
+	preFormat := fs.String("pre-format", "", "Format of the pre-state, detected from the file extension if empty")
+	post := fs.String("post", "", "Path to write the post-state to, or - for stdout")
+	postFormat := fs.String("post-format", "", "Format of the post-state, detected from the file extension if empty")
+	blockFormat := fs.String("block-format", "", "Format of the blocks, detected from the file extensions if empty")
+	toSlot := fs.Uint64("to-slot", 0, "Process empty slots up to this slot, after applying the blocks. Ignored if not past the last block.")
+	slots := fs.Uint64("slots", 0, "Process this many empty slots, after applying the blocks and --to-slot")
+	verify := fs.Bool("verify", true, "Verify the block signatures and state roots")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *pre == "" || *post == "" {
+		return fmt.Errorf("both --pre and --post are required")
+	}
+	sp, err := spec.Spec()
+	if err != nil {
+		return err
+	}
+	state, err := loadState(sp, *pre, *preFormat)
+	if err != nil {
+		return err
+	}
+	epc, err := common.NewEpochsContext(sp, state)
+	if err != nil {
+		return fmt.Errorf("failed to create epochs context: %w", err)
+	}
+	genesisValRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return err
+	}
+	up := &beacon.StandardUpgradeableBeaconState{BeaconState: state}
+	ctx := context.Background()
+	for _, path := range fs.Args() {
+		obj, err := loadObject(sp, kindBlock, "", path, *blockFormat)
+		if err != nil {
+			return fmt.Errorf("failed to load block %q: %w", path, err)
+		}
+		block := obj.raw.(beacon.OpaqueBlock)
+		benv := block.Envelope(sp, common.ForkDigest{})
+		benv.ForkDigest = common.ComputeForkDigest(sp.ForkVersion(benv.Slot), genesisValRoot)
+		if _, err := common.StateTransition(ctx, sp, epc, up, benv, *verify); err != nil {
+			return fmt.Errorf("failed to process block %q (slot %d, root %s): %w", path, benv.Slot, benv.BlockRoot, err)
+		}
+		fmt.Fprintf(os.Stderr, "processed block %s at slot %d\n", benv.BlockRoot, benv.Slot)
+	}
+	slot, err := up.Slot()
+	if err != nil {
+		return err
+	}
+	target := slot
+	if common.Slot(*toSlot) > target {
+		target = common.Slot(*toSlot)
+	}
+	target += common.Slot(*slots)
+	if target > slot {
+		if err := common.ProcessSlots(ctx, sp, epc, up, target); err != nil {
+			return fmt.Errorf("failed to process slots %d to %d: %w", slot, target, err)
+		}
+	}
+	obj, err := stateObject(sp, up.BeaconState)
+	if err != nil {
+		return err
+	}
+	format, err := formatOf(*post, *postFormat)
+	if err != nil {
+		return err
+	}
+	data, err := obj.encode(sp, format)
+	if err != nil {
+		return err
+	}
+	return writeFile(*post, data)
+}
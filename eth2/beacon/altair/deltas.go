@@ -151,3 +151,57 @@ func ProcessEpochRewardsAndPenalties(ctx context.Context, spec *common.Spec, epc
 	}
 	return state.SetBalances(balances)
 }
+
+// ValidatorRewards computes the rewards and penalties of one validator at a time,
+// equal to the sum of the deltas of AttestationRewardsAndPenalties.
+// This avoids the full validator lists of deltas, for processing the epoch in a single pass.
+type ValidatorRewards struct {
+	spec                   *common.Spec
+	isInactivityLeak       bool
+	baseRewardPerIncrement common.Gwei
+	activeIncrements       common.Gwei
+	sourceIncrements       common.Gwei
+	targetIncrements       common.Gwei
+	headIncrements         common.Gwei
+	inactivityPenaltyDenom common.Gwei
+}
+
+func NewValidatorRewards(spec *common.Spec, epc *common.EpochsContext, attesterData *EpochAttesterData,
+	isInactivityLeak bool, inactivityPenaltyQuotient uint64) *ValidatorRewards {
+	// the unslashed participating stakes are already at least 1 increment, like get_total_balance
+	return &ValidatorRewards{
+		spec:                   spec,
+		isInactivityLeak:       isInactivityLeak,
+		baseRewardPerIncrement: (spec.EFFECTIVE_BALANCE_INCREMENT * common.Gwei(spec.BASE_REWARD_FACTOR)) / epc.TotalActiveStakeSqRoot,
+		activeIncrements:       epc.TotalActiveStake / spec.EFFECTIVE_BALANCE_INCREMENT,
+		sourceIncrements:       attesterData.PrevEpochUnslashedStake.SourceStake / spec.EFFECTIVE_BALANCE_INCREMENT,
+		targetIncrements:       attesterData.PrevEpochUnslashedStake.TargetStake / spec.EFFECTIVE_BALANCE_INCREMENT,
+		headIncrements:         attesterData.PrevEpochUnslashedStake.HeadStake / spec.EFFECTIVE_BALANCE_INCREMENT,
+		inactivityPenaltyDenom: common.Gwei(uint64(spec.INACTIVITY_SCORE_BIAS) * inactivityPenaltyQuotient),
+	}
+}
+
+// Deltas returns the total reward and penalty of an eligible validator,
+// given its participation in the previous epoch and its updated inactivity score.
+func (r *ValidatorRewards) Deltas(flat *common.FlatValidator, participation ParticipationFlags, inactivityScore uint64) (reward common.Gwei, penalty common.Gwei) {
+	increments := flat.EffectiveBalance / r.spec.EFFECTIVE_BALANCE_INCREMENT
+	baseReward := increments * r.baseRewardPerIncrement
+	flagDeltas := func(flag ParticipationFlags, weight common.Gwei, participatingIncrements common.Gwei) {
+		if !flat.Slashed && participation&flag != 0 {
+			if !r.isInactivityLeak {
+				rewardNumerator := (baseReward * weight) * participatingIncrements
+				rewardDenominator := r.activeIncrements * WEIGHT_DENOMINATOR
+				reward += rewardNumerator / rewardDenominator
+			}
+		} else if flag != TIMELY_HEAD_FLAG {
+			penalty += (baseReward * weight) / WEIGHT_DENOMINATOR
+		}
+	}
+	flagDeltas(TIMELY_SOURCE_FLAG, TIMELY_SOURCE_WEIGHT, r.sourceIncrements)
+	flagDeltas(TIMELY_TARGET_FLAG, TIMELY_TARGET_WEIGHT, r.targetIncrements)
+	flagDeltas(TIMELY_HEAD_FLAG, TIMELY_HEAD_WEIGHT, r.headIncrements)
+	if !(!flat.Slashed && (participation&TIMELY_TARGET_FLAG != 0)) {
+		penalty += (flat.EffectiveBalance * common.Gwei(inactivityScore)) / r.inactivityPenaltyDenom
+	}
+	return reward, penalty
+}
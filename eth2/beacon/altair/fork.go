@@ -80,6 +80,10 @@ func UpgradeToAltair(spec *common.Spec, epc *common.EpochsContext, pre *phase0.B
 	if err != nil {
 		return nil, err
 	}
+	rewardAdjustmentFactor, err := pre.RewardAdjustmentFactor()
+	if err != nil {
+		return nil, err
+	}
 	eth1Data, err := pre.Eth1Data()
 	if err != nil {
 		return nil, err
@@ -100,6 +104,10 @@ func UpgradeToAltair(spec *common.Spec, epc *common.EpochsContext, pre *phase0.B
 	if err != nil {
 		return nil, err
 	}
+	reserves, err := pre.Reserves()
+	if err != nil {
+		return nil, err
+	}
 	randaoMixes, err := pre.RandaoMixes()
 	if err != nil {
 		return nil, err
@@ -160,11 +168,13 @@ func UpgradeToAltair(spec *common.Spec, epc *common.EpochsContext, pre *phase0.B
 		latestBlockHeader.View(),
 		blockRoots.(view.View),
 		stateRoots.(view.View),
+		(*view.Uint64View)(&rewardAdjustmentFactor),
 		eth1Data.View(),
 		eth1DataVotes.(view.View),
 		(*view.Uint64View)(&eth1DepositIndex),
 		validators.(view.View),
 		balances.(view.View),
+		(*view.Uint64View)(&reserves),
 		randaoMixes.(view.View),
 		previousEpochParticipation,
 		currentEpochParticipation,
@@ -0,0 +1,49 @@
+package altair
+
+import (
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Altair, like phase0.GenesisFromEth1.
+// Both the previous and current fork version are the Altair fork version.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit,
+	ignoreSignaturesAndProofs bool) (*BeaconStateView, *common.EpochsContext, error) {
+	pre, epc, err := phase0.GenesisFromEth1(spec, eth1BlockHash, time, deps, ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The upgrade initializes the participation registries and inactivity scores of all genesis validators.
+	state, err := UpgradeToAltair(spec, epc, pre)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetFork(common.Fork{
+		PreviousVersion: spec.ALTAIR_FORK_VERSION,
+		CurrentVersion:  spec.ALTAIR_FORK_VERSION,
+		Epoch:           common.GENESIS_EPOCH,
+	}); err != nil {
+		return nil, nil, err
+	}
+	emptyBody := BeaconBlockBody{}
+	if err := state.SetLatestBlockHeader(&common.BeaconBlockHeader{
+		BodyRoot: emptyBody.HashTreeRoot(spec, tree.GetHashFn()),
+	}); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// KickStartState builds a genesis state that starts at Altair without Eth 1.0 deposits, like phase0.KickStartState.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
@@ -98,25 +98,7 @@ func ProcessInactivityUpdates(ctx context.Context, spec *common.Spec, attesterDa
 		if err != nil {
 			return err
 		}
-		newScore := score
-
-		// Increase the inactivity score of inactive validators
-		if !attesterData.Flats[vi].Slashed && (attesterData.PrevParticipation[vi]&TIMELY_TARGET_FLAG != 0) {
-			if newScore > 0 {
-				newScore -= 1
-			}
-		} else {
-			newScore += uint64(spec.INACTIVITY_SCORE_BIAS)
-		}
-
-		// Decrease the inactivity score of all eligible validators during a leak-free epoch
-		if !isInactivityLeak {
-			if newScore < uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE) {
-				newScore = 0
-			} else {
-				newScore -= uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE)
-			}
-		}
+		newScore := UpdateInactivityScore(spec, score, attesterData.Flats[vi].Slashed, attesterData.PrevParticipation[vi], isInactivityLeak)
 
 		// if there was any change, update the state.
 		if newScore != score {
@@ -127,3 +109,58 @@ func ProcessInactivityUpdates(ctx context.Context, spec *common.Spec, attesterDa
 	}
 	return nil
 }
+
+// AllScores reads all the inactivity scores, in one iteration over the list.
+func (v *InactivityScoresView) AllScores() ([]uint64, error) {
+	length, err := v.Length()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint64, 0, length)
+	iter := v.ReadonlyIter()
+	for {
+		el, ok, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		score, err := AsUint64(el, nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, uint64(score))
+	}
+	return out, nil
+}
+
+// NewInactivityScoresView creates a view of the scores, to replace the inactivity scores of a state with at once.
+func NewInactivityScoresView(scores []uint64, limit uint64) (*InactivityScoresView, error) {
+	tmp := make([]BasicView, len(scores), len(scores))
+	for i, score := range scores {
+		tmp[i] = Uint64View(score)
+	}
+	return AsInactivityScores(BasicListType(Uint64Type, limit).FromElements(tmp...))
+}
+
+// UpdateInactivityScore returns the new inactivity score of an eligible validator, like ProcessInactivityUpdates.
+func UpdateInactivityScore(spec *common.Spec, score uint64, slashed bool, prevParticipation ParticipationFlags, isInactivityLeak bool) uint64 {
+	// Increase the inactivity score of inactive validators
+	if !slashed && (prevParticipation&TIMELY_TARGET_FLAG != 0) {
+		if score > 0 {
+			score -= 1
+		}
+	} else {
+		score += uint64(spec.INACTIVITY_SCORE_BIAS)
+	}
+	// Decrease the inactivity score of all eligible validators during a leak-free epoch
+	if !isInactivityLeak {
+		if score < uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE) {
+			score = 0
+		} else {
+			score -= uint64(spec.INACTIVITY_SCORE_RECOVERY_RATE)
+		}
+	}
+	return score
+}
@@ -0,0 +1,251 @@
+package altair
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type SinglePassEpochBeaconState interface {
+	AltairLikeBeaconState
+	SetInactivityScores(scores []uint64) error
+}
+
+var _ SinglePassEpochBeaconState = (*BeaconStateView)(nil)
+
+// ProcessEpochSinglePass processes the justification, inactivity updates, rewards and penalties,
+// registry updates, eth1 data reset and effective balance updates of the Altair-like forks up to Deneb,
+// like the stepwise ProcessEpoch of these forks does, but in a single iteration over the validators,
+// after a single iteration over the eligible validators for the inactivity scores, rewards and penalties.
+// The balances and inactivity scores are kept in memory, and written back to the state once.
+// The remaining epoch steps differ per fork, and are left to the caller.
+//
+// The activation churn limit is computed from the churn limit with activationChurnLimit,
+// or is the churn limit itself if activationChurnLimit is nil.
+func ProcessEpochSinglePass(ctx context.Context, spec *common.Spec, epc *common.EpochsContext,
+	state SinglePassEpochBeaconState, activationChurnLimit func(churnLimit uint64) uint64) error {
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return err
+	}
+	attesterData, err := ComputeEpochAttesterData(ctx, spec, epc, flats, state)
+	if err != nil {
+		return err
+	}
+	just := phase0.JustificationStakeData{
+		CurrentEpoch:                  epc.CurrentEpoch.Epoch,
+		TotalActiveStake:              epc.TotalActiveStake,
+		PrevEpochUnslashedTargetStake: attesterData.PrevEpochUnslashedStake.TargetStake,
+		CurrEpochUnslashedTargetStake: attesterData.CurrEpochUnslashedTargetStake,
+	}
+	if err := phase0.ProcessEpochJustification(ctx, spec, &just, state); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	prevEpoch := epc.PreviousEpoch.Epoch
+	currentEpoch := epc.CurrentEpoch.Epoch
+	// Skip the genesis epoch as score updates and rewards are based on the previous epoch participation
+	isGenesis := currentEpoch == common.GENESIS_EPOCH
+	finalized, err := state.FinalizedCheckpoint()
+	if err != nil {
+		return err
+	}
+	finalityDelay := prevEpoch - finalized.Epoch
+	isInactivityLeak := finalityDelay > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
+
+	balsView, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	allBals, err := balsView.AllBalances()
+	if err != nil {
+		return err
+	}
+	if len(allBals) != len(flats) {
+		return fmt.Errorf("balances count %d does not match validators count %d", len(allBals), len(flats))
+	}
+	bals := phase0.Balances(allBals)
+	var scores []uint64
+	if !isGenesis {
+		scoresView, err := state.InactivityScores()
+		if err != nil {
+			return err
+		}
+		scores, err = scoresView.AllScores()
+		if err != nil {
+			return err
+		}
+		if len(scores) != len(flats) {
+			return fmt.Errorf("inactivity scores count %d does not match validators count %d", len(scores), len(flats))
+		}
+		rewards := NewValidatorRewards(spec, epc, attesterData, isInactivityLeak,
+			state.ForkSettings(spec).InactivityPenaltyQuotient)
+		for _, vi := range attesterData.EligibleIndices {
+			flat := &flats[vi]
+			participation := attesterData.PrevParticipation[vi]
+			scores[vi] = UpdateInactivityScore(spec, scores[vi], flat.Slashed, participation, isInactivityLeak)
+			reward, penalty := rewards.Deltas(flat, participation, scores[vi])
+			bal := bals[vi] + reward
+			if bal >= penalty {
+				bal -= penalty
+			} else {
+				bal = 0
+			}
+			bals[vi] = bal
+		}
+	}
+
+	HYSTERESIS_INCREMENT := spec.EFFECTIVE_BALANCE_INCREMENT / common.Gwei(spec.HYSTERESIS_QUOTIENT)
+	DOWNWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_DOWNWARD_MULTIPLIER)
+	UPWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_UPWARD_MULTIPLIER)
+
+	var toEject, toSetEligibility, toMaybeActivate, toUpdateEffectiveBalance []common.ValidatorIndex
+	activeCount := uint64(0)
+	exitQueueEnd := spec.ComputeActivationExitEpoch(currentEpoch)
+	exitQueueEndChurn := uint64(0)
+	for i := range flats {
+		flat := &flats[i]
+		vi := common.ValidatorIndex(i)
+
+		// Registry updates, based on the effective balances from before this epoch transition
+		active := flat.IsActive(currentEpoch)
+		if active {
+			activeCount++
+		}
+		if active && flat.EffectiveBalance <= spec.EJECTION_BALANCE && flat.ExitEpoch == common.FAR_FUTURE_EPOCH {
+			toEject = append(toEject, vi)
+		}
+		if flat.ActivationEligibilityEpoch == common.FAR_FUTURE_EPOCH && flat.EffectiveBalance == spec.MAX_EFFECTIVE_BALANCE {
+			toSetEligibility = append(toSetEligibility, vi)
+		}
+		if flat.ActivationEpoch == common.FAR_FUTURE_EPOCH && flat.ActivationEligibilityEpoch <= currentEpoch {
+			toMaybeActivate = append(toMaybeActivate, vi)
+		}
+		if exit := flat.ExitEpoch; exit != common.FAR_FUTURE_EPOCH {
+			if exit > exitQueueEnd {
+				exitQueueEnd = exit
+				exitQueueEndChurn = 0
+			}
+			if exit == exitQueueEnd {
+				exitQueueEndChurn++
+			}
+		}
+
+		// Effective balance updates, based on the balances after the rewards and penalties
+		balance, effBalance := bals[i], flat.EffectiveBalance
+		if balance+DOWNWARD_THRESHOLD < effBalance || effBalance+UPWARD_THRESHOLD < balance {
+			toUpdateEffectiveBalance = append(toUpdateEffectiveBalance, vi)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	churnLimit := spec.GetChurnLimit(activeCount)
+	if exitQueueEndChurn >= churnLimit {
+		if exitQueueEnd == ^common.Epoch(0) { // practically impossible, but here for spec test introduced in consensus-specs#2887
+			return fmt.Errorf("exitQueueEnd overflowing: %d", exitQueueEnd)
+		}
+		exitQueueEnd++
+		exitQueueEndChurn = 0
+	}
+	// process ejections
+	for _, index := range toEject {
+		val, err := vals.Validator(index)
+		if err != nil {
+			return err
+		}
+		if err := val.SetExitEpoch(exitQueueEnd); err != nil {
+			return err
+		}
+		withdrawEpoch := exitQueueEnd + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+		if withdrawEpoch < exitQueueEnd { // practically impossible, but here for spec test introduced in consensus-specs#2887
+			return fmt.Errorf("exit epoch overflow: %d + %d = %d", exitQueueEnd, spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY, withdrawEpoch)
+		}
+		exitQueueEndChurn += 1
+		if exitQueueEndChurn >= churnLimit {
+			exitQueueEndChurn = 0
+			exitQueueEnd += 1
+		}
+	}
+	// Process activation eligibility
+	eligibilityEpoch := currentEpoch + 1
+	for _, index := range toSetEligibility {
+		val, err := vals.Validator(index)
+		if err != nil {
+			return err
+		}
+		if err := val.SetActivationEligibilityEpoch(eligibilityEpoch); err != nil {
+			return err
+		}
+	}
+	// Process activations
+	{
+		// Order by the sequence of activation_eligibility_epoch setting and then index
+		sort.Slice(toMaybeActivate, func(i int, j int) bool {
+			a := flats[toMaybeActivate[i]].ActivationEligibilityEpoch
+			b := flats[toMaybeActivate[j]].ActivationEligibilityEpoch
+			if a == b {
+				return toMaybeActivate[i] < toMaybeActivate[j]
+			}
+			return a < b
+		})
+		dequeueLimit := churnLimit
+		if activationChurnLimit != nil {
+			dequeueLimit = activationChurnLimit(churnLimit)
+		}
+		if uint64(len(toMaybeActivate)) > dequeueLimit {
+			toMaybeActivate = toMaybeActivate[:dequeueLimit]
+		}
+		activationEpoch := spec.ComputeActivationExitEpoch(currentEpoch)
+		for _, index := range toMaybeActivate {
+			if flats[index].ActivationEligibilityEpoch > finalized.Epoch {
+				break
+			}
+			val, err := vals.Validator(index)
+			if err != nil {
+				return err
+			}
+			if err := val.SetActivationEpoch(activationEpoch); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := phase0.ProcessEth1DataReset(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	for _, index := range toUpdateEffectiveBalance {
+		balance := bals[index]
+		effBalance := balance - (balance % spec.EFFECTIVE_BALANCE_INCREMENT)
+		if spec.MAX_EFFECTIVE_BALANCE < effBalance {
+			effBalance = spec.MAX_EFFECTIVE_BALANCE
+		}
+		val, err := vals.Validator(index)
+		if err != nil {
+			return err
+		}
+		if err := val.SetEffectiveBalance(effBalance); err != nil {
+			return err
+		}
+	}
+	if err := state.SetBalances(bals); err != nil {
+		return err
+	}
+	if !isGenesis {
+		if err := state.SetInactivityScores(scores); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -449,6 +449,15 @@ func (state *BeaconStateView) InactivityScores() (*InactivityScoresView, error)
 	return AsInactivityScores(state.Get(_inactivityScores))
 }
 
+func (state *BeaconStateView) SetInactivityScores(scores []uint64) error {
+	typ := state.Fields[_inactivityScores].Type.(*BasicListTypeDef)
+	scoresView, err := NewInactivityScoresView(scores, typ.ListLimit)
+	if err != nil {
+		return err
+	}
+	return state.Set(_inactivityScores, scoresView)
+}
+
 func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettings {
 	return &common.ForkSettings{
 		MinSlashingPenaltyQuotient: uint64(spec.MIN_SLASHING_PENALTY_QUOTIENT_ALTAIR),
@@ -456,6 +465,7 @@ func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettin
 		CalcProposerShare: func(whistleblowerReward common.Gwei) common.Gwei {
 			return whistleblowerReward * PROPOSER_WEIGHT / WEIGHT_DENOMINATOR
 		},
+		MaxEffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
 	}
 }
 
@@ -9,6 +9,21 @@ import (
 )
 
 func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	if err := ProcessEpochSinglePass(ctx, spec, epc, state, nil); err != nil {
+		return err
+	}
+	if err := phase0.ProcessRandaoMixesReset(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := ProcessParticipationFlagUpdates(ctx, spec, state); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ProcessEpochStepwise processes the epoch one step at a time, like the spec does.
+// It produces the same post-state as ProcessEpoch, and serves as reference to the single pass processing.
+func (state *BeaconStateView) ProcessEpochStepwise(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
 	vals, err := state.Validators()
 	if err != nil {
 		return err
@@ -54,44 +69,44 @@ func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spe
 	return nil
 }
 
-func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) error {
+func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) (common.PayloadStatus, error) {
 	body, ok := benv.Body.(*BeaconBlockBody)
 	if !ok {
-		return fmt.Errorf("unexpected block type %T in Altair ProcessBlock", benv.Body)
+		return common.PayloadUnknown, fmt.Errorf("unexpected block type %T in Altair ProcessBlock", benv.Body)
 	}
 	expectedProposer, err := epc.GetBeaconProposer(benv.Slot)
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := common.ProcessHeader(ctx, spec, state, &benv.BeaconBlockHeader, expectedProposer); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessRandaoReveal(ctx, spec, epc, state, body.RandaoReveal); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessEth1Vote(ctx, spec, epc, state, body.Eth1Data); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Safety checks, in case the user of the function provided too many operations
 	if err := body.CheckLimits(spec); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 
 	if err := phase0.ProcessProposerSlashings(ctx, spec, epc, state, body.ProposerSlashings); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessAttesterSlashings(ctx, spec, epc, state, body.AttesterSlashings); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := ProcessAttestations(ctx, spec, epc, state, body.Attestations); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Note: state.AddValidator changed in Altair, but the deposit processing itself stayed the same.
 	if err := phase0.ProcessDeposits(ctx, spec, epc, state, body.Deposits); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessVoluntaryExits(ctx, spec, epc, state, body.VoluntaryExits); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
-	return nil
+	return common.PayloadValid, nil
 }
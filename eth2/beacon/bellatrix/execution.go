@@ -321,17 +321,18 @@ type NewPayloadRequest struct {
 }
 
 type ExecutionEngine interface {
-	BellatrixNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload) (valid bool, err error)
+	BellatrixNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload) (status common.PayloadStatus, err error)
 	BellatrixIsValidBlockHash(ctx context.Context, payload *ExecutionPayload) (bool, error)
 }
 
-func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (bool, error) {
+// VerifyAndNotifyNewPayload returns the status of the payload. SYNCING and ACCEPTED payloads may be imported optimistically.
+func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (common.PayloadStatus, error) {
 	executionPayload := newPayloadRequest.ExecutionPayload
 
 	if ok, err := eng.BellatrixIsValidBlockHash(ctx, executionPayload); err != nil {
-		return false, fmt.Errorf("failed to check block hash: %w", err)
+		return common.PayloadInvalid, fmt.Errorf("failed to check block hash: %w", err)
 	} else if !ok {
-		return false, nil
+		return common.PayloadInvalidBlockHash, nil
 	}
 
 	return eng.BellatrixNotifyNewPayload(ctx, executionPayload)
@@ -8,17 +8,20 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 )
 
-func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state ExecutionTrackingBeaconState, executionPayload *ExecutionPayload, engine ExecutionEngine) error {
+// ProcessExecutionPayload verifies the payload against the state, and notifies the execution engine of it.
+// It returns the status of the payload: VALID, or SYNCING and ACCEPTED for payloads that are not validated yet.
+// The latter are not rejected, the block is imported optimistically and the forkchoice has to track the status.
+func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state ExecutionTrackingBeaconState, executionPayload *ExecutionPayload, engine ExecutionEngine) (common.PayloadStatus, error) {
 	if err := ctx.Err(); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if engine == nil {
-		return errors.New("nil execution engine")
+		return common.PayloadUnknown, errors.New("nil execution engine")
 	}
 
 	slot, err := state.Slot()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 
 	completed := true
@@ -26,20 +29,20 @@ func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state Execu
 		var err error
 		completed, err = s.IsTransitionCompleted()
 		if err != nil {
-			return err
+			return common.PayloadUnknown, err
 		}
 	}
 	if completed {
 		latestExecHeader, err := state.LatestExecutionPayloadHeader()
 		if err != nil {
-			return err
+			return common.PayloadUnknown, err
 		}
 		parent, err := latestExecHeader.Raw()
 		if err != nil {
-			return fmt.Errorf("failed to read previous header: %v", err)
+			return common.PayloadUnknown, fmt.Errorf("failed to read previous header: %v", err)
 		}
 		if executionPayload.ParentHash != parent.BlockHash {
-			return fmt.Errorf("expected parent hash %s in execution payload, but got %s",
+			return common.PayloadUnknown, fmt.Errorf("expected parent hash %s in execution payload, but got %s",
 				parent.BlockHash, executionPayload.ParentHash)
 		}
 	}
@@ -47,35 +50,42 @@ func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state Execu
 	// verify random
 	mixes, err := state.RandaoMixes()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	expectedMix, err := mixes.GetRandomMix(spec.SlotToEpoch(slot))
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if executionPayload.PrevRandao != expectedMix {
-		return fmt.Errorf("invalid random data %s, expected %s", executionPayload.PrevRandao, expectedMix)
+		return common.PayloadUnknown, fmt.Errorf("invalid random data %s, expected %s", executionPayload.PrevRandao, expectedMix)
 	}
 
 	// verify timestamp
 	genesisTime, err := state.GenesisTime()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if expectedTime, err := spec.TimeAtSlot(slot, genesisTime); err != nil {
-		return fmt.Errorf("slot or genesis time in state is corrupt, cannot compute time: %v", err)
+		return common.PayloadUnknown, fmt.Errorf("slot or genesis time in state is corrupt, cannot compute time: %v", err)
 	} else if executionPayload.Timestamp != expectedTime {
-		return fmt.Errorf("state at slot %d, genesis time %d, expected execution payload time %d, but got %d",
+		return common.PayloadUnknown, fmt.Errorf("state at slot %d, genesis time %d, expected execution payload time %d, but got %d",
 			slot, genesisTime, expectedTime, executionPayload.Timestamp)
 	}
 
-	if valid, err := VerifyAndNotifyNewPayload(ctx, engine, &NewPayloadRequest{ExecutionPayload: executionPayload}); err != nil {
-		return fmt.Errorf("unexpected problem in execution engine when inserting block %s (height %d), err: %v",
+	status, err := VerifyAndNotifyNewPayload(ctx, engine, &NewPayloadRequest{ExecutionPayload: executionPayload})
+	if err != nil {
+		return common.PayloadUnknown, fmt.Errorf("unexpected problem in execution engine when inserting block %s (height %d), err: %v",
 			executionPayload.BlockHash, executionPayload.BlockNumber, err)
-	} else if !valid {
-		return fmt.Errorf("execution engine says payload is invalid: %s (height %d)",
+	} else if status.IsInvalid() {
+		return status, fmt.Errorf("execution engine says payload is invalid (%s): %s (height %d)", status,
 			executionPayload.BlockHash, executionPayload.BlockNumber)
+	} else if status == common.PayloadUnknown {
+		return common.PayloadUnknown, fmt.Errorf("execution engine returned no status for payload %s (height %d)", executionPayload.BlockHash, executionPayload.BlockNumber)
 	}
 
-	return state.SetLatestExecutionPayloadHeader(executionPayload.Header(spec))
+	// SYNCING and ACCEPTED payloads are not validated yet: the block is imported optimistically
+	if err := state.SetLatestExecutionPayloadHeader(executionPayload.Header(spec)); err != nil {
+		return common.PayloadUnknown, err
+	}
+	return status, nil
 }
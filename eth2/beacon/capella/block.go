@@ -130,7 +130,7 @@ func (b *BeaconBlockBody) Deserialize(spec *common.Spec, dr *codec.DecodingReade
 		&b.RandaoReveal, &b.Eth1Data,
 		&b.Graffiti, spec.Wrap(&b.ProposerSlashings),
 		spec.Wrap(&b.AttesterSlashings), spec.Wrap(&b.Attestations),
-		spec.Wrap(&b.VoluntaryExits),
+		spec.Wrap(&b.Deposits), spec.Wrap(&b.VoluntaryExits),
 		spec.Wrap(&b.ExecutionPayload),
 	)
 }
@@ -350,18 +350,19 @@ type NewPayloadRequest struct {
 }
 
 type ExecutionEngine interface {
-	CapellaNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload) (valid bool, err error)
+	CapellaNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload) (status common.PayloadStatus, err error)
 	CapellaIsValidBlockHash(ctx context.Context, payload *ExecutionPayload) (bool, error)
 }
 
-func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (bool, error) {
+// VerifyAndNotifyNewPayload returns the status of the payload. SYNCING and ACCEPTED payloads may be imported optimistically.
+func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (common.PayloadStatus, error) {
 	executionPayload := newPayloadRequest.ExecutionPayload
 
 	// Modified in Capella
 	if ok, err := eng.CapellaIsValidBlockHash(ctx, executionPayload); err != nil {
-		return false, fmt.Errorf("failed to check block hash: %w", err)
+		return common.PayloadInvalid, fmt.Errorf("failed to check block hash: %w", err)
 	} else if !ok {
-		return false, nil
+		return common.PayloadInvalidBlockHash, nil
 	}
 
 	return eng.CapellaNotifyNewPayload(ctx, executionPayload)
@@ -43,6 +43,10 @@ func UpgradeToCapella(spec *common.Spec, epc *common.EpochsContext, pre *bellatr
 	if err != nil {
 		return nil, err
 	}
+	rewardAdjustmentFactor, err := pre.RewardAdjustmentFactor()
+	if err != nil {
+		return nil, err
+	}
 	eth1Data, err := pre.Eth1Data()
 	if err != nil {
 		return nil, err
@@ -63,6 +67,10 @@ func UpgradeToCapella(spec *common.Spec, epc *common.EpochsContext, pre *bellatr
 	if err != nil {
 		return nil, err
 	}
+	reserves, err := pre.Reserves()
+	if err != nil {
+		return nil, err
+	}
 	randaoMixes, err := pre.RandaoMixes()
 	if err != nil {
 		return nil, err
@@ -131,11 +139,13 @@ func UpgradeToCapella(spec *common.Spec, epc *common.EpochsContext, pre *bellatr
 		latestBlockHeader.View(),
 		blockRoots.(view.View),
 		stateRoots.(view.View),
+		(*view.Uint64View)(&rewardAdjustmentFactor),
 		eth1Data.View(),
 		eth1DataVotes.(view.View),
 		(*view.Uint64View)(&eth1DepositIndex),
 		validators.(view.View),
 		balances.(view.View),
+		(*view.Uint64View)(&reserves),
 		randaoMixes.(view.View),
 		previousEpochParticipation,
 		currentEpochParticipation,
@@ -0,0 +1,55 @@
+package capella
+
+import (
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Capella, like phase0.GenesisFromEth1.
+// The execution payload header is the header of the execution genesis block.
+// Both the previous and current fork version are the Capella fork version,
+// and the withdrawal sweep starts at the first withdrawal and validator.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit,
+	executionHeader *ExecutionPayloadHeader, ignoreSignaturesAndProofs bool) (*BeaconStateView, *common.EpochsContext, error) {
+	pre, epc, err := bellatrix.GenesisFromEth1(spec, eth1BlockHash, time, deps, new(bellatrix.ExecutionPayloadHeader), ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := UpgradeToCapella(spec, epc, pre)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetFork(common.Fork{
+		PreviousVersion: spec.CAPELLA_FORK_VERSION,
+		CurrentVersion:  spec.CAPELLA_FORK_VERSION,
+		Epoch:           common.GENESIS_EPOCH,
+	}); err != nil {
+		return nil, nil, err
+	}
+	emptyBody := BeaconBlockBody{}
+	if err := state.SetLatestBlockHeader(&common.BeaconBlockHeader{
+		BodyRoot: emptyBody.HashTreeRoot(spec, tree.GetHashFn()),
+	}); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetLatestExecutionPayloadHeader(executionHeader); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// KickStartState builds a genesis state that starts at Capella without Eth 1.0 deposits, like phase0.KickStartState.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData,
+	executionHeader *ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), executionHeader, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
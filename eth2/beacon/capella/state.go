@@ -474,6 +474,15 @@ func (state *BeaconStateView) InactivityScores() (*altair.InactivityScoresView,
 	return altair.AsInactivityScores(state.Get(_inactivityScores))
 }
 
+func (state *BeaconStateView) SetInactivityScores(scores []uint64) error {
+	typ := state.Fields[_inactivityScores].Type.(*BasicListTypeDef)
+	scoresView, err := altair.NewInactivityScoresView(scores, typ.ListLimit)
+	if err != nil {
+		return err
+	}
+	return state.Set(_inactivityScores, scoresView)
+}
+
 func (state *BeaconStateView) LatestExecutionPayloadHeader() (*ExecutionPayloadHeaderView, error) {
 	return AsExecutionPayloadHeader(state.Get(_latestExecutionPayloadHeader))
 }
@@ -524,6 +533,7 @@ func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettin
 		CalcProposerShare: func(whistleblowerReward common.Gwei) common.Gwei {
 			return whistleblowerReward * altair.PROPOSER_WEIGHT / altair.WEIGHT_DENOMINATOR
 		},
+		MaxEffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
 	}
 }
 
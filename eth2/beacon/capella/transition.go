@@ -13,6 +13,24 @@ import (
 )
 
 func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	if err := altair.ProcessEpochSinglePass(ctx, spec, epc, state, nil); err != nil {
+		return err
+	}
+	if err := phase0.ProcessRandaoMixesReset(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := ProcessHistoricalSummariesUpdate(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := altair.ProcessParticipationFlagUpdates(ctx, spec, state); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ProcessEpochStepwise processes the epoch one step at a time, like the spec does.
+// It produces the same post-state as ProcessEpoch, and serves as reference to the single pass processing.
+func (state *BeaconStateView) ProcessEpochStepwise(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
 	vals, err := state.Validators()
 	if err != nil {
 		return err
@@ -61,58 +79,59 @@ func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spe
 	return nil
 }
 
-func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) error {
+func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) (common.PayloadStatus, error) {
 	body, ok := benv.Body.(*BeaconBlockBody)
 	if !ok {
-		return fmt.Errorf("unexpected block type %T in Bellatrix ProcessBlock", benv.Body)
+		return common.PayloadUnknown, fmt.Errorf("unexpected block type %T in Bellatrix ProcessBlock", benv.Body)
 	}
 	expectedProposer, err := epc.GetBeaconProposer(benv.Slot)
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := common.ProcessHeader(ctx, spec, state, &benv.BeaconBlockHeader, expectedProposer); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// [Modified in Capella] Removed `is_execution_enabled` check in Capella
 	if err := ProcessWithdrawals(ctx, spec, state, &body.ExecutionPayload); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Modified in Capella
 	eng, ok := spec.ExecutionEngine.(ExecutionEngine)
 	if !ok {
-		return fmt.Errorf("provided execution-engine interface does not support Capella: %T", spec.ExecutionEngine)
+		return common.PayloadUnknown, fmt.Errorf("provided execution-engine interface does not support Capella: %T", spec.ExecutionEngine)
 	}
-	if err := ProcessExecutionPayload(ctx, spec, state, &body.ExecutionPayload, eng); err != nil {
-		return err
+	status, err := ProcessExecutionPayload(ctx, spec, state, &body.ExecutionPayload, eng)
+	if err != nil {
+		return status, err
 	}
 	if err := phase0.ProcessRandaoReveal(ctx, spec, epc, state, body.RandaoReveal); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessEth1Vote(ctx, spec, epc, state, body.Eth1Data); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Safety checks, in case the user of the function provided too many operations
 	if err := body.CheckLimits(spec); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 
 	if err := phase0.ProcessProposerSlashings(ctx, spec, epc, state, body.ProposerSlashings); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessAttesterSlashings(ctx, spec, epc, state, body.AttesterSlashings); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := altair.ProcessAttestations(ctx, spec, epc, state, body.Attestations); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Note: state.AddValidator changed in Altair, but the deposit processing itself stayed the same.
 	if err := phase0.ProcessDeposits(ctx, spec, epc, state, body.Deposits); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessVoluntaryExits(ctx, spec, epc, state, body.VoluntaryExits); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
-	return nil
+	return status, nil
 }
 
 func HasEth1WithdrawalCredential(validator common.Validator) bool {
@@ -133,14 +152,17 @@ func Eth1WithdrawalCredential(validator common.Validator) common.Eth1Address {
 	return address
 }
 
-func IsFullyWithdrawableValidator(validator common.Validator, balance common.Gwei, epoch common.Epoch) bool {
-	// TODO fix it
-	return true
-	//withdrawableEpoch, err := validator.WithdrawableEpoch()
-	//if err != nil {
-	//	panic(err)
-	//}
-	//return HasEth1WithdrawalCredential(validator) && withdrawableEpoch <= epoch && balance > 0
+func IsFullyWithdrawableValidator(spec *common.Spec, validator common.Validator, balance common.Gwei, epoch common.Epoch) bool {
+	exitEpoch, err := validator.ExitEpoch()
+	if err != nil {
+		panic(err)
+	}
+	// Validators have no withdrawable epoch in this fork, it is derived from the exit epoch.
+	withdrawableEpoch := exitEpoch + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	if withdrawableEpoch < exitEpoch { // not exiting, or exiting too far in the future to ever be withdrawable
+		return false
+	}
+	return HasEth1WithdrawalCredential(validator) && withdrawableEpoch <= epoch && balance > 0
 }
 
 func IsPartiallyWithdrawableValidator(spec *common.Spec, validator common.Validator, balance common.Gwei, epoch common.Epoch) bool {
@@ -201,7 +223,7 @@ func GetExpectedWithdrawals(state BeaconStateWithWithdrawals, spec *common.Spec)
 		if i >= validatorCount || i >= uint64(spec.MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP) {
 			break
 		}
-		if IsFullyWithdrawableValidator(validator, balance, epoch) {
+		if IsFullyWithdrawableValidator(spec, validator, balance, epoch) {
 			withdrawals = append(withdrawals, common.Withdrawal{
 				Index:          withdrawalIndex,
 				ValidatorIndex: validatorIndex,
@@ -0,0 +1,186 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forkchoice/proto"
+)
+
+// CheckpointAnchor is a verified finalized state and block pair, to start a chain from, instead of genesis.
+type CheckpointAnchor struct {
+	// State at the start slot of the checkpoint epoch. May be past the slot of the block if there were gap slots.
+	State common.BeaconState
+	// Block is the latest block included in State.
+	Block *common.BeaconBlockEnvelope
+	// Checkpoint that is anchored: the block root, and the epoch of the state.
+	Checkpoint common.Checkpoint
+	// The context of the anchor state (shuffling, proposers, etc.)
+	Epc *common.EpochsContext
+}
+
+// VerifyCheckpointAnchor checks that the downloaded state and block are consistent with each other and with the spec,
+// and optionally match the trusted checkpoint (if not nil).
+// The state must be at the start of an epoch, and may be processed past the slot of the block (gap slots).
+func VerifyCheckpointAnchor(spec *common.Spec, state common.BeaconState, block *common.BeaconBlockEnvelope, trusted *common.Checkpoint) (*CheckpointAnchor, error) {
+	hFn := tree.GetHashFn()
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	// slot alignment
+	if slot%spec.SLOTS_PER_EPOCH != 0 {
+		return nil, fmt.Errorf("anchor state slot %d is not at the start of an epoch", slot)
+	}
+	if block.Slot > slot {
+		return nil, fmt.Errorf("anchor block slot %d is after anchor state slot %d", block.Slot, slot)
+	}
+	epoch := spec.SlotToEpoch(slot)
+
+	// fork version
+	fork, err := state.Fork()
+	if err != nil {
+		return nil, err
+	}
+	if expected := spec.ForkVersion(slot); fork.CurrentVersion != expected {
+		return nil, fmt.Errorf("anchor state has fork version %s, but expected %s at slot %d", fork.CurrentVersion, expected, slot)
+	}
+	genesisValRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	if block.ForkDigest != (common.ForkDigest{}) {
+		if expected := common.ComputeForkDigest(spec.ForkVersion(block.Slot), genesisValRoot); block.ForkDigest != expected {
+			return nil, fmt.Errorf("anchor block has fork digest %s, but expected %s at slot %d", block.ForkDigest, expected, block.Slot)
+		}
+	}
+
+	// block root
+	if bodyRoot := block.Body.HashTreeRoot(spec, hFn); bodyRoot != block.BodyRoot {
+		return nil, fmt.Errorf("anchor block body root %s does not match header body root %s", bodyRoot, block.BodyRoot)
+	}
+	blockRoot := block.BeaconBlockHeader.HashTreeRoot(hFn)
+	if block.BlockRoot != (common.Root{}) && block.BlockRoot != blockRoot {
+		return nil, fmt.Errorf("anchor block has cached root %s, but computed %s", block.BlockRoot, blockRoot)
+	}
+	latestRoot, err := phase0.LatestBlockRoot(state)
+	if err != nil {
+		return nil, err
+	}
+	if latestRoot != blockRoot {
+		return nil, fmt.Errorf("anchor state latest block root %s does not match anchor block root %s", latestRoot, blockRoot)
+	}
+
+	// state root
+	header, err := state.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header.StateRoot == (common.Root{}) {
+		// the state was not processed past the block slot yet, the block state-root must match the state itself.
+		if stateRoot := state.HashTreeRoot(hFn); stateRoot != block.StateRoot {
+			return nil, fmt.Errorf("anchor block state root %s does not match anchor state root %s", block.StateRoot, stateRoot)
+		}
+	} else if header.StateRoot != block.StateRoot {
+		return nil, fmt.Errorf("anchor block state root %s does not match latest header state root %s", block.StateRoot, header.StateRoot)
+	}
+
+	checkpoint := common.Checkpoint{Epoch: epoch, Root: blockRoot}
+	if trusted != nil && *trusted != checkpoint {
+		return nil, fmt.Errorf("anchor %s does not match trusted checkpoint %s", checkpoint, *trusted)
+	}
+
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create epochs context for anchor state: %w", err)
+	}
+	return &CheckpointAnchor{
+		State:      state,
+		Block:      block,
+		Checkpoint: checkpoint,
+		Epc:        epc,
+	}, nil
+}
+
+// Balances returns the effective balances of the active validators of the anchor state,
+// to initialize the forkchoice vote weights with.
+func (a *CheckpointAnchor) Balances() ([]common.Gwei, error) {
+	vals, err := a.State.Validators()
+	if err != nil {
+		return nil, err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]common.Gwei, len(flats))
+	for i := range flats {
+		if flats[i].IsActive(a.Checkpoint.Epoch) {
+			out[i] = flats[i].EffectiveBalance
+		}
+	}
+	return out, nil
+}
+
+// ForkChoice creates a new forkchoice, with the anchor as justified and finalized checkpoint.
+func (a *CheckpointAnchor) ForkChoice(sink proto.NodeSink) (forkchoice.Forkchoice, error) {
+	balances, err := a.Balances()
+	if err != nil {
+		return nil, err
+	}
+	spec := a.Epc.Spec
+	graph := proto.NewProtoArray(a.Block.ParentRoot, a.Checkpoint.Root, a.Block.Slot,
+		a.Checkpoint.Epoch, a.Checkpoint.Epoch, sink)
+	// Gap slots between the block and the start of the epoch are represented as empty nodes.
+	stateSlot, _ := spec.EpochStartSlot(a.Checkpoint.Epoch)
+	if stateSlot > a.Block.Slot {
+		graph.ProcessSlot(a.Checkpoint.Root, stateSlot, a.Checkpoint.Epoch, a.Checkpoint.Epoch)
+	}
+	return forkchoice.NewForkChoice(spec, a.Checkpoint, a.Checkpoint,
+		a.Checkpoint.Root, stateSlot, graph, proto.NewProtoVoteStore(spec), balances)
+}
+
+// Entry returns the anchor as chain entry, to seed a chain with.
+func (a *CheckpointAnchor) Entry() ChainEntry {
+	return &anchorEntry{a}
+}
+
+type anchorEntry struct {
+	anchor *CheckpointAnchor
+}
+
+var _ ChainEntry = (*anchorEntry)(nil)
+
+func (e *anchorEntry) Step() common.Step {
+	stateSlot, _ := e.anchor.Epc.Spec.EpochStartSlot(e.anchor.Checkpoint.Epoch)
+	return common.AsStep(stateSlot, stateSlot == e.anchor.Block.Slot)
+}
+
+func (e *anchorEntry) BlockRoot() (common.Root, error) {
+	return e.anchor.Checkpoint.Root, nil
+}
+
+func (e *anchorEntry) ParentRoot() (common.Root, error) {
+	if e.Step().Block() {
+		return e.anchor.Block.ParentRoot, nil
+	}
+	// empty slot: the parent is the previous block
+	return e.anchor.Checkpoint.Root, nil
+}
+
+func (e *anchorEntry) StateRoot() (common.Root, error) {
+	return e.anchor.State.HashTreeRoot(tree.GetHashFn()), nil
+}
+
+func (e *anchorEntry) EpochsContext(ctx context.Context) (*common.EpochsContext, error) {
+	return e.anchor.Epc.Clone(), nil
+}
+
+func (e *anchorEntry) State(ctx context.Context) (common.BeaconState, error) {
+	return e.anchor.State.CopyState()
+}
@@ -1,6 +1,9 @@
 package common
 
 import (
+	"fmt"
+
+	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 
@@ -56,6 +59,81 @@ func (d *DepositData) MessageRoot() Root {
 	return d.ToMessage().HashTreeRoot(tree.GetHashFn())
 }
 
+// DepositDomain is the domain of deposit signatures.
+// Deposits are valid across forks, so it uses the genesis fork version, and no genesis validators root.
+func DepositDomain(spec *Spec) BLSDomain {
+	return ComputeDomain(DOMAIN_DEPOSIT, spec.GENESIS_FORK_VERSION, Root{})
+}
+
+// NewDepositData creates the deposit data of the key, signed over the deposit message.
+// It also returns the deposit_data_root, the leaf of the deposit in the deposit contract tree.
+func NewDepositData(spec *Spec, sk *blsu.SecretKey, withdrawalCredentials Root, amount Gwei) (*DepositData, Root, error) {
+	pub, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, Root{}, err
+	}
+	d := &DepositData{
+		Pubkey:                pub.Serialize(),
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                amount,
+	}
+	signingRoot := ComputeSigningRoot(d.MessageRoot(), DepositDomain(spec))
+	d.Signature = blsu.Sign(sk, signingRoot[:]).Serialize()
+	return d, d.HashTreeRoot(tree.GetHashFn()), nil
+}
+
+// VerifySignature checks the signature over the deposit message.
+// Note that an invalid signature does not make a deposit invalid: deposit processing skips it instead.
+func (d *DepositData) VerifySignature(spec *Spec) bool {
+	pub, err := d.Pubkey.Pubkey()
+	if err != nil {
+		return false
+	}
+	sig, err := d.Signature.Signature()
+	if err != nil {
+		return false
+	}
+	signingRoot := ComputeSigningRoot(d.MessageRoot(), DepositDomain(spec))
+	return blsu.Verify(pub, signingRoot[:], sig)
+}
+
+// VerifyDepositDataBatch verifies the signatures of all the deposit data at once,
+// and returns an error with the index of the first invalid deposit data, if any.
+func VerifyDepositDataBatch(spec *Spec, deps []DepositData) error {
+	if len(deps) == 0 {
+		return nil
+	}
+	dom := DepositDomain(spec)
+	pubs := make([]*blsu.Pubkey, len(deps))
+	msgs := make([][]byte, len(deps))
+	sigs := make([]*blsu.Signature, len(deps))
+	for i := range deps {
+		d := &deps[i]
+		pub, err := d.Pubkey.Pubkey()
+		if err != nil {
+			return fmt.Errorf("deposit data %d has invalid pubkey: %w", i, err)
+		}
+		sig, err := d.Signature.Signature()
+		if err != nil {
+			return fmt.Errorf("deposit data %d has invalid signature: %w", i, err)
+		}
+		signingRoot := ComputeSigningRoot(d.MessageRoot(), dom)
+		pubs[i] = pub
+		msgs[i] = signingRoot[:]
+		sigs[i] = sig
+	}
+	if ok, err := blsu.SignatureSetVerify(pubs, msgs, sigs); err == nil && ok {
+		return nil
+	}
+	// Find the culprit
+	for i := range deps {
+		if !blsu.Verify(pubs[i], msgs[i], sigs[i]) {
+			return fmt.Errorf("deposit data %d has invalid signature", i)
+		}
+	}
+	return fmt.Errorf("deposit data batch failed to verify")
+}
+
 var DepositMessageType = ContainerType("DepositMessage", []FieldDef{
 	{"pubkey", BLSPubkeyType},
 	{"withdrawal_credentials", Bytes32Type},
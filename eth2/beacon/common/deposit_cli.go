@@ -0,0 +1,108 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+)
+
+// DEPOSIT_CLI_VERSION is the deposit CLI version that DepositCLIData entries are compatible with.
+// Deposit UIs such as the launchpad check it, the format has not changed since.
+const DEPOSIT_CLI_VERSION = "2.7.0"
+
+// DepositCLIData is an entry of the deposit_data-*.json file of the standard deposit CLI.
+// Byte fields are hex encoded without 0x prefix, and the amount is a plain JSON number.
+type DepositCLIData struct {
+	Pubkey                string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                uint64 `json:"amount"`
+	Signature             string `json:"signature"`
+	DepositMessageRoot    string `json:"deposit_message_root"`
+	DepositDataRoot       string `json:"deposit_data_root"`
+	ForkVersion           string `json:"fork_version"`
+	NetworkName           string `json:"network_name"`
+	DepositCLIVersion     string `json:"deposit_cli_version"`
+}
+
+// DepositCLIData converts the deposit data to a deposit CLI entry, for the given network name (e.g. "mainnet").
+func (d *DepositData) DepositCLIData(spec *Spec, networkName string) *DepositCLIData {
+	msgRoot := d.MessageRoot()
+	dataRoot := d.HashTreeRoot(tree.GetHashFn())
+	return &DepositCLIData{
+		Pubkey:                hex.EncodeToString(d.Pubkey[:]),
+		WithdrawalCredentials: hex.EncodeToString(d.WithdrawalCredentials[:]),
+		Amount:                uint64(d.Amount),
+		Signature:             hex.EncodeToString(d.Signature[:]),
+		DepositMessageRoot:    hex.EncodeToString(msgRoot[:]),
+		DepositDataRoot:       hex.EncodeToString(dataRoot[:]),
+		ForkVersion:           hex.EncodeToString(spec.GENESIS_FORK_VERSION[:]),
+		NetworkName:           networkName,
+		DepositCLIVersion:     DEPOSIT_CLI_VERSION,
+	}
+}
+
+// DepositData decodes the deposit data of the entry, and checks that the roots in the entry match it.
+// The signature is not verified, see VerifyDepositCLIData.
+func (c *DepositCLIData) DepositData() (*DepositData, error) {
+	var d DepositData
+	if err := decodeHexField("pubkey", c.Pubkey, d.Pubkey[:]); err != nil {
+		return nil, err
+	}
+	if err := decodeHexField("withdrawal_credentials", c.WithdrawalCredentials, d.WithdrawalCredentials[:]); err != nil {
+		return nil, err
+	}
+	d.Amount = Gwei(c.Amount)
+	if err := decodeHexField("signature", c.Signature, d.Signature[:]); err != nil {
+		return nil, err
+	}
+	var msgRoot, dataRoot Root
+	if err := decodeHexField("deposit_message_root", c.DepositMessageRoot, msgRoot[:]); err != nil {
+		return nil, err
+	}
+	if err := decodeHexField("deposit_data_root", c.DepositDataRoot, dataRoot[:]); err != nil {
+		return nil, err
+	}
+	if root := d.MessageRoot(); root != msgRoot {
+		return nil, fmt.Errorf("deposit_message_root %s does not match the deposit message root %s", msgRoot, root)
+	}
+	if root := d.HashTreeRoot(tree.GetHashFn()); root != dataRoot {
+		return nil, fmt.Errorf("deposit_data_root %s does not match the deposit data root %s", dataRoot, root)
+	}
+	return &d, nil
+}
+
+// VerifyDepositCLIData checks all the entries: their roots, their fork version against the spec,
+// and their signatures, verified as one batch.
+func VerifyDepositCLIData(spec *Spec, entries []DepositCLIData) ([]DepositData, error) {
+	forkVersion := hex.EncodeToString(spec.GENESIS_FORK_VERSION[:])
+	deps := make([]DepositData, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		if e.ForkVersion != forkVersion {
+			return nil, fmt.Errorf("entry %d has fork version %s, expected %s", i, e.ForkVersion, forkVersion)
+		}
+		d, err := e.DepositData()
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		deps[i] = *d
+	}
+	if err := VerifyDepositDataBatch(spec, deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+func decodeHexField(name string, v string, dst []byte) error {
+	if len(v) >= 2 && v[0] == '0' && (v[1] == 'x' || v[1] == 'X') {
+		v = v[2:]
+	}
+	if len(v) != len(dst)*2 {
+		return fmt.Errorf("%s must be %d hex encoded bytes, got %d characters", name, len(dst), len(v))
+	}
+	if _, err := hex.Decode(dst, []byte(v)); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func testDepositSpec() *Spec {
+	return &Spec{Config: Config{GENESIS_FORK_VERSION: Version{0x00, 0x00, 0x10, 0x20}}}
+}
+
+func testDepositData(t *testing.T, spec *Spec, count int) []DepositData {
+	out := make([]DepositData, count)
+	for i := range out {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		creds := Root{ETH1_ADDRESS_WITHDRAWAL_PREFIX, 12: byte(i)}
+		d, root, err := NewDepositData(spec, &sk, creds, 32_000_000_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != d.HashTreeRoot(tree.GetHashFn()) {
+			t.Fatal("unexpected deposit data root")
+		}
+		if !d.VerifySignature(spec) {
+			t.Fatalf("deposit data %d has invalid signature", i)
+		}
+		out[i] = *d
+	}
+	return out
+}
+
+func TestVerifyDepositDataBatch(t *testing.T) {
+	spec := testDepositSpec()
+	deps := testDepositData(t, spec, 4)
+	if err := VerifyDepositDataBatch(spec, deps); err != nil {
+		t.Fatal(err)
+	}
+	deps[2].Amount += 1
+	if err := VerifyDepositDataBatch(spec, deps); err == nil || !strings.Contains(err.Error(), "deposit data 2") {
+		t.Fatalf("expected deposit data 2 to be invalid, got %v", err)
+	}
+	// other networks use another fork version in the domain
+	other := testDepositSpec()
+	other.GENESIS_FORK_VERSION = Version{0x01}
+	if deps[0].VerifySignature(other) {
+		t.Fatal("expected signature to be invalid for another genesis fork version")
+	}
+}
+
+func TestDepositCLIData(t *testing.T) {
+	spec := testDepositSpec()
+	deps := testDepositData(t, spec, 2)
+	entries := make([]*DepositCLIData, len(deps))
+	for i := range deps {
+		entries[i] = deps[i].DepositCLIData(spec, "testnet")
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"amount":32000000000,`) || !strings.Contains(string(data), `"fork_version":"00001020"`) {
+		t.Fatalf("unexpected deposit CLI format: %s", data)
+	}
+	var decoded []DepositCLIData
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	out, err := VerifyDepositCLIData(spec, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range out {
+		if out[i] != deps[i] {
+			t.Fatalf("deposit data %d does not round-trip", i)
+		}
+	}
+
+	decoded[1].Amount += 1
+	if _, err := VerifyDepositCLIData(spec, decoded); err == nil || !strings.Contains(err.Error(), "deposit_message_root") {
+		t.Fatalf("expected root mismatch, got %v", err)
+	}
+	decoded[1].Amount -= 1
+	decoded[0].ForkVersion = "00000000"
+	if _, err := VerifyDepositCLIData(spec, decoded); err == nil || !strings.Contains(err.Error(), "fork version") {
+		t.Fatalf("expected fork version mismatch, got %v", err)
+	}
+}
@@ -25,15 +25,38 @@ type EpochsContext struct {
 	TotalActiveStake Gwei
 	// cached integer square root of TotalActiveStake
 	TotalActiveStakeSqRoot Gwei
+
+	// ShufflingCache is optional, to share shufflings and proposers between contexts of different branches.
+	// Clones of the context share the cache.
+	ShufflingCache *ShufflingCache
 }
 
 // NewEpochsContext constructs a new context for the processing of the current epoch.
 func NewEpochsContext(spec *Spec, state BeaconState) (*EpochsContext, error) {
+	return NewEpochsContextWithCaches(spec, state, nil, nil)
+}
+
+// NewEpochsContextWithCache constructs a new context for the processing of the current epoch,
+// consulting the cache for the shufflings and proposers. The cache may be nil.
+func NewEpochsContextWithCache(spec *Spec, state BeaconState, cache *ShufflingCache) (*EpochsContext, error) {
+	return NewEpochsContextWithCaches(spec, state, nil, cache)
+}
+
+// NewEpochsContextWithCaches constructs a new context for the processing of the current epoch,
+// sharing the caches with other contexts. Both caches are optional.
+// The pubkey cache is synced with the validators of the state, see PubkeyCache.Sync.
+// The shuffling cache is consulted for the shufflings and proposers.
+func NewEpochsContextWithCaches(spec *Spec, state BeaconState, pubkeys *PubkeyCache, shufflings *ShufflingCache) (*EpochsContext, error) {
 	vals, err := state.Validators()
 	if err != nil {
 		return nil, err
 	}
-	pc, err := NewPubkeyCache(vals)
+	var pc *PubkeyCache
+	if pubkeys != nil {
+		pc, err = pubkeys.Sync(vals)
+	} else {
+		pc, err = NewPubkeyCache(vals)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +64,7 @@ func NewEpochsContext(spec *Spec, state BeaconState) (*EpochsContext, error) {
 	epc := &EpochsContext{
 		Spec:                 spec,
 		ValidatorPubkeyCache: pc,
+		ShufflingCache:       shufflings,
 	}
 	if err := epc.LoadShuffling(state); err != nil {
 		return nil, err
@@ -65,7 +89,7 @@ func (epc *EpochsContext) LoadShuffling(state BeaconState) error {
 		return err
 	}
 	currentEpoch := epc.Spec.SlotToEpoch(slot)
-	epc.CurrentEpoch, err = ComputeShufflingEpoch(epc.Spec, state, indicesBounded, currentEpoch)
+	epc.CurrentEpoch, err = epc.shufflingEpoch(state, indicesBounded, currentEpoch)
 	if err != nil {
 		return err
 	}
@@ -77,12 +101,12 @@ func (epc *EpochsContext) LoadShuffling(state BeaconState) error {
 	if prevEpoch == currentEpoch { // in case of genesis
 		epc.PreviousEpoch = epc.CurrentEpoch
 	} else {
-		epc.PreviousEpoch, err = ComputeShufflingEpoch(epc.Spec, state, indicesBounded, prevEpoch)
+		epc.PreviousEpoch, err = epc.shufflingEpoch(state, indicesBounded, prevEpoch)
 		if err != nil {
 			return err
 		}
 	}
-	epc.NextEpoch, err = ComputeShufflingEpoch(epc.Spec, state, indicesBounded, currentEpoch+1)
+	epc.NextEpoch, err = epc.shufflingEpoch(state, indicesBounded, currentEpoch+1)
 	if err != nil {
 		return err
 	}
@@ -126,7 +150,7 @@ func (epc *EpochsContext) LoadProposers(state BeaconState) error {
 			return err
 		}
 	}
-	props, err := ComputeProposers(epc.Spec, state, epc.CurrentEpoch.Epoch, epc.CurrentEpoch.ActiveIndices)
+	props, err := epc.proposersEpoch(state, epc.CurrentEpoch)
 	if err != nil {
 		return err
 	}
@@ -134,8 +158,45 @@ func (epc *EpochsContext) LoadProposers(state BeaconState) error {
 	return nil
 }
 
+// shufflingEpoch computes the shuffling of the epoch, or retrieves it from the shuffling cache.
+func (epc *EpochsContext) shufflingEpoch(state BeaconState, indicesBounded []BoundedIndex, epoch Epoch) (*ShufflingEpoch, error) {
+	compute := func() (*ShufflingEpoch, error) {
+		return ComputeShufflingEpoch(epc.Spec, state, indicesBounded, epoch)
+	}
+	if epc.ShufflingCache == nil {
+		return compute()
+	}
+	root, ok, err := AttesterDecisionRoot(epc.Spec, state, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return compute()
+	}
+	return epc.ShufflingCache.Shuffling(ShufflingCacheKey{Epoch: epoch, DecisionRoot: root}, compute)
+}
+
+// proposersEpoch computes the proposers of the epoch of the shuffling, or retrieves them from the shuffling cache.
+func (epc *EpochsContext) proposersEpoch(state BeaconState, shuf *ShufflingEpoch) (*ProposersEpoch, error) {
+	compute := func() (*ProposersEpoch, error) {
+		return ComputeProposers(epc.Spec, state, shuf.Epoch, shuf.ActiveIndices)
+	}
+	if epc.ShufflingCache == nil {
+		return compute()
+	}
+	root, ok, err := ProposerDecisionRoot(epc.Spec, state, shuf.Epoch)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return compute()
+	}
+	return epc.ShufflingCache.Proposers(ShufflingCacheKey{Epoch: shuf.Epoch, DecisionRoot: root}, compute)
+}
+
 func (epc *EpochsContext) Clone() *EpochsContext {
-	// All fields can be reused, just need a fresh shallow copy of the outer container
+	// All fields can be reused, just need a fresh shallow copy of the outer container.
+	// The shuffling cache is shared with the clone.
 	epcClone := *epc
 	return &epcClone
 }
@@ -153,7 +214,7 @@ func (epc *EpochsContext) RotateEpochs(state BeaconState) error {
 	if err != nil {
 		return err
 	}
-	epc.NextEpoch, err = ComputeShufflingEpoch(epc.Spec, state, indicesBounded, nextEpoch)
+	epc.NextEpoch, err = epc.shufflingEpoch(state, indicesBounded, nextEpoch)
 	if err != nil {
 		return err
 	}
@@ -87,3 +87,70 @@ func (v *ExtraDataView) Raw() (ExtraData, error) {
 // bellatrix.ExecutionEngine, capella.ExecutionEngine, deneb.ExecutionEngine
 type ExecutionEngine interface {
 }
+
+// PayloadStatus is the status of an execution payload, as reported by the execution engine.
+type PayloadStatus uint8
+
+const (
+	// PayloadUnknown: no status was reported. This is the zero value, to not mistake a missing status for a valid one.
+	PayloadUnknown PayloadStatus = iota
+	// PayloadValid: the payload and its ancestors are fully validated.
+	PayloadValid
+	// PayloadInvalid: the payload, or one of its ancestors, is invalid.
+	PayloadInvalid
+	// PayloadSyncing: the engine is syncing, and cannot validate the payload yet.
+	PayloadSyncing
+	// PayloadAccepted: the payload is not validated, but its block hash is valid and it extends a side chain.
+	PayloadAccepted
+	// PayloadInvalidBlockHash: the block hash of the payload does not match its contents.
+	PayloadInvalidBlockHash
+)
+
+func (s PayloadStatus) String() string {
+	switch s {
+	case PayloadUnknown:
+		return "UNKNOWN"
+	case PayloadValid:
+		return "VALID"
+	case PayloadInvalid:
+		return "INVALID"
+	case PayloadSyncing:
+		return "SYNCING"
+	case PayloadAccepted:
+		return "ACCEPTED"
+	case PayloadInvalidBlockHash:
+		return "INVALID_BLOCK_HASH"
+	default:
+		return fmt.Sprintf("PayloadStatus(%d)", uint8(s))
+	}
+}
+
+// MarshalText encodes the status as in the engine API. Unknown statuses cannot be encoded.
+func (s PayloadStatus) MarshalText() ([]byte, error) {
+	if s == PayloadUnknown || s > PayloadInvalidBlockHash {
+		return nil, fmt.Errorf("cannot encode unknown payload status %d", uint8(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status of the engine API, e.g. "VALID" or "SYNCING".
+func (s *PayloadStatus) UnmarshalText(text []byte) error {
+	for st := PayloadValid; st <= PayloadInvalidBlockHash; st++ {
+		if string(text) == st.String() {
+			*s = st
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized payload status: %q", text)
+}
+
+// IsInvalid returns true if the payload must be rejected.
+func (s PayloadStatus) IsInvalid() bool {
+	return s == PayloadInvalid || s == PayloadInvalidBlockHash
+}
+
+// IsOptimistic returns true if the payload is not rejected, but not validated yet either,
+// i.e. the block may be imported optimistically.
+func (s PayloadStatus) IsOptimistic() bool {
+	return s == PayloadSyncing || s == PayloadAccepted
+}
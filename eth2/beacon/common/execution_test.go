@@ -0,0 +1,38 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPayloadStatusText(t *testing.T) {
+	var zero PayloadStatus
+	if zero != PayloadUnknown || zero.IsInvalid() || zero.IsOptimistic() {
+		t.Fatalf("expected the zero value to be unknown, got %s", zero)
+	}
+	if _, err := zero.MarshalText(); err == nil {
+		t.Fatal("expected error for encoding an unknown status")
+	}
+	for _, status := range []PayloadStatus{PayloadValid, PayloadInvalid, PayloadSyncing, PayloadAccepted, PayloadInvalidBlockHash} {
+		data, err := json.Marshal(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded PayloadStatus
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded != status {
+			t.Fatalf("expected %s, got %s", status, decoded)
+		}
+	}
+	var decoded PayloadStatus
+	if err := decoded.UnmarshalText([]byte("SYNCING")); err != nil || decoded != PayloadSyncing {
+		t.Fatalf("expected SYNCING, got %s, err: %v", decoded, err)
+	}
+	for _, text := range []string{"UNKNOWN", "valid", ""} {
+		if err := decoded.UnmarshalText([]byte(text)); err == nil {
+			t.Fatalf("expected error for status %q", text)
+		}
+	}
+}
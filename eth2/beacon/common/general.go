@@ -186,6 +186,26 @@ func (n NodeRef) String() string {
 	return n.Root.String() + ":" + n.Slot.String()
 }
 
+func (n *NodeRef) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&n.Slot, &n.Root)
+}
+
+func (n *NodeRef) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(n.Slot, &n.Root)
+}
+
+func (n *NodeRef) ByteLength() uint64 {
+	return 8 + 32
+}
+
+func (n *NodeRef) FixedLength() uint64 {
+	return 8 + 32
+}
+
+func (n *NodeRef) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(n.Slot, n.Root)
+}
+
 type ExtendedNodeRef struct {
 	NodeRef
 	ParentRoot Root
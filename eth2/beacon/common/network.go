@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/protolambda/ztyp/bitfields"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
@@ -36,6 +37,13 @@ func (d *Eth2Data) HashTreeRoot(hFn tree.HashFn) Root {
 	return hFn.HashTreeRoot(&d.ForkDigest, &d.NextForkVersion, &d.NextForkEpoch)
 }
 
+// NodeID is the discv5 node identity: a uint256, in big-endian byte order.
+type NodeID [32]byte
+
+func (id NodeID) String() string {
+	return "0x" + hex.EncodeToString(id[:])
+}
+
 const ATTESTATION_SUBNET_COUNT = 64
 
 const attnetByteLen = (ATTESTATION_SUBNET_COUNT + 7) / 8
@@ -45,6 +53,14 @@ type AttnetBits [attnetByteLen]byte
 func (ab *AttnetBits) BitLen() uint64 {
 	return ATTESTATION_SUBNET_COUNT
 }
+
+func (ab *AttnetBits) GetBit(i uint64) bool {
+	return bitfields.GetBit(ab[:], i)
+}
+
+func (ab *AttnetBits) SetBit(i uint64, v bool) {
+	bitfields.SetBit(ab[:], i, v)
+}
 func (p *AttnetBits) Deserialize(dr *codec.DecodingReader) error {
 	if p == nil {
 		return errors.New("nil attnet bits")
@@ -42,6 +42,7 @@ func ComputeProposers(spec *Spec, state BeaconState, epoch Epoch, active []Valid
 	if err != nil {
 		return nil, err
 	}
+	maxEffectiveBalance := state.ForkSettings(spec).MaxEffectiveBalance
 
 	hFn := hashing.GetHashFn()
 	// compute beacon proposers
@@ -55,7 +56,7 @@ func ComputeProposers(spec *Spec, state BeaconState, epoch Epoch, active []Valid
 		for i := Slot(0); i < spec.SLOTS_PER_EPOCH; i++ {
 			binary.LittleEndian.PutUint64(buf[32:], uint64(startSlot+i))
 			seed := hFn(buf[:])
-			proposer, err := ComputeProposerIndex(spec, vals, active, seed)
+			proposer, err := ComputeProposerIndex(spec, vals, active, seed, maxEffectiveBalance)
 			if err != nil {
 				return nil, err
 			}
@@ -74,7 +75,9 @@ func ComputeProposers(spec *Spec, state BeaconState, epoch Epoch, active []Valid
 	}, nil
 }
 
-func ComputeProposerIndex(spec *Spec, registry ValidatorRegistry, active []ValidatorIndex, seed Root) (ValidatorIndex, error) {
+// ComputeProposerIndex samples a proposer from the active validators, weighted by effective balance:
+// a candidate is accepted with the probability of its effective balance relative to maxEffectiveBalance.
+func ComputeProposerIndex(spec *Spec, registry ValidatorRegistry, active []ValidatorIndex, seed Root, maxEffectiveBalance Gwei) (ValidatorIndex, error) {
 	if len(active) == 0 {
 		return 0, errors.New("no active validators available to compute proposer")
 	}
@@ -98,7 +101,7 @@ func ComputeProposerIndex(spec *Spec, registry ValidatorRegistry, active []Valid
 			if err != nil {
 				return 0, err
 			}
-			if effectiveBalance*0xff >= spec.MAX_EFFECTIVE_BALANCE*Gwei(randomByte) {
+			if effectiveBalance*0xff >= maxEffectiveBalance*Gwei(randomByte) {
 				return candidateIndex, nil
 			}
 		}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ShufflingCacheKey identifies a shuffling or proposer computation:
+// the epoch, and the block root that the RANDAO seed and validator set of that epoch were decided by.
+// Branches that share the decision block share the result.
+type ShufflingCacheKey struct {
+	Epoch        Epoch
+	DecisionRoot Root
+}
+
+type shufflingCacheKind uint8
+
+const (
+	attesterShufflingKind shufflingCacheKind = iota
+	proposersKind
+)
+
+type shufflingCacheEntryKey struct {
+	kind shufflingCacheKind
+	ShufflingCacheKey
+}
+
+type shufflingCacheEntry struct {
+	key shufflingCacheEntryKey
+	// closed when the computation completes
+	done  chan struct{}
+	value interface{}
+	err   error
+	elem  *list.Element
+}
+
+// ShufflingCache is a LRU cache of attester shufflings and proposers, safe for concurrent use.
+// Concurrent requests for the same key wait for a single computation.
+// A cache must not be shared between different specs or chains.
+type ShufflingCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[shufflingCacheEntryKey]*shufflingCacheEntry
+	// front is the most recently used
+	lru *list.List
+}
+
+// NewShufflingCache creates a cache that keeps up to capacity shufflings and proposer epochs (at least 1).
+func NewShufflingCache(capacity int) *ShufflingCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ShufflingCache{
+		capacity: capacity,
+		entries:  make(map[shufflingCacheEntryKey]*shufflingCacheEntry, capacity),
+		lru:      list.New(),
+	}
+}
+
+// Len returns the number of cached and in-flight entries.
+func (c *ShufflingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Shuffling returns the cached attester shuffling of the key, or computes and caches it.
+func (c *ShufflingCache) Shuffling(key ShufflingCacheKey, compute func() (*ShufflingEpoch, error)) (*ShufflingEpoch, error) {
+	v, err := c.get(shufflingCacheEntryKey{attesterShufflingKind, key}, func() (interface{}, error) {
+		return compute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ShufflingEpoch), nil
+}
+
+// Proposers returns the cached proposers of the key, or computes and caches them.
+func (c *ShufflingCache) Proposers(key ShufflingCacheKey, compute func() (*ProposersEpoch, error)) (*ProposersEpoch, error) {
+	v, err := c.get(shufflingCacheEntryKey{proposersKind, key}, func() (interface{}, error) {
+		return compute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ProposersEpoch), nil
+}
+
+func (c *ShufflingCache) get(key shufflingCacheEntryKey, compute func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e.elem)
+		c.mu.Unlock()
+		<-e.done
+		return e.value, e.err
+	}
+	e := &shufflingCacheEntry{key: key, done: make(chan struct{})}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	for c.lru.Len() > c.capacity {
+		// evicting an in-flight entry is fine, the waiters keep a reference to it
+		c.remove(c.lru.Back().Value.(*shufflingCacheEntry))
+	}
+	c.mu.Unlock()
+
+	e.value, e.err = compute()
+	if e.err != nil {
+		// don't cache failures, later requests can retry
+		c.mu.Lock()
+		if c.entries[key] == e {
+			c.remove(e)
+		}
+		c.mu.Unlock()
+	}
+	close(e.done)
+	return e.value, e.err
+}
+
+func (c *ShufflingCache) remove(e *shufflingCacheEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+}
+
+// decisionRoot returns the block root at the last slot before the given epoch,
+// if it is still available in the state history. ok is false if there is no such slot,
+// e.g. around genesis, or if the state is not past it.
+func decisionRoot(spec *Spec, state BeaconState, epoch Epoch) (root Root, ok bool, err error) {
+	if epoch == 0 {
+		return Root{}, false, nil
+	}
+	start, err := spec.EpochStartSlot(epoch)
+	if err != nil {
+		return Root{}, false, err
+	}
+	slot := start - 1
+	current, err := state.Slot()
+	if err != nil {
+		return Root{}, false, err
+	}
+	if slot >= current || slot+spec.SLOTS_PER_HISTORICAL_ROOT < current {
+		return Root{}, false, nil
+	}
+	root, err = GetBlockRootAtSlot(spec, state, slot)
+	if err != nil {
+		return Root{}, false, err
+	}
+	return root, true, nil
+}
+
+// AttesterDecisionRoot returns the block root that the attester shuffling of the epoch depends on:
+// the seed and active validators are known at the end of the epoch before the previous epoch.
+func AttesterDecisionRoot(spec *Spec, state BeaconState, epoch Epoch) (root Root, ok bool, err error) {
+	if epoch == 0 {
+		return Root{}, false, nil
+	}
+	return decisionRoot(spec, state, epoch-1)
+}
+
+// ProposerDecisionRoot returns the block root that the proposers of the epoch depend on:
+// proposers are weighted by the effective balances, which are updated in the transition into the epoch.
+func ProposerDecisionRoot(spec *Spec, state BeaconState, epoch Epoch) (root Root, ok bool, err error) {
+	return decisionRoot(spec, state, epoch)
+}
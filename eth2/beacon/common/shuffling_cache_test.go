@@ -0,0 +1,101 @@
+package common
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShufflingCacheDedup(t *testing.T) {
+	cache := NewShufflingCache(4)
+	key := ShufflingCacheKey{Epoch: 3, DecisionRoot: Root{0x01}}
+	var calls int32
+	release := make(chan struct{})
+	compute := func() (*ShufflingEpoch, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &ShufflingEpoch{Epoch: 3}, nil
+	}
+	var wg sync.WaitGroup
+	results := make([]*ShufflingEpoch, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := cache.Shuffling(key, compute)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = res
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("expected a single computation, got %d", calls)
+	}
+	for _, res := range results {
+		if res != results[0] {
+			t.Fatal("expected all requests to get the same shuffling")
+		}
+	}
+	// proposers of the same key are cached separately
+	props, err := cache.Proposers(key, func() (*ProposersEpoch, error) {
+		return &ProposersEpoch{Epoch: 3}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props.Epoch != 3 || cache.Len() != 2 {
+		t.Fatalf("unexpected proposers %v, cache size %d", props, cache.Len())
+	}
+}
+
+func TestShufflingCacheEviction(t *testing.T) {
+	cache := NewShufflingCache(2)
+	computed := 0
+	get := func(epoch Epoch) {
+		_, err := cache.Shuffling(ShufflingCacheKey{Epoch: epoch}, func() (*ShufflingEpoch, error) {
+			computed++
+			return &ShufflingEpoch{Epoch: epoch}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	get(1)
+	get(2)
+	get(1) // 1 is now the most recently used
+	get(3) // evicts 2
+	if computed != 3 {
+		t.Fatalf("expected 3 computations, got %d", computed)
+	}
+	get(1)
+	if computed != 3 {
+		t.Fatal("expected epoch 1 to stay cached")
+	}
+	get(2)
+	if computed != 4 || cache.Len() != 2 {
+		t.Fatalf("expected epoch 2 to be recomputed, computations: %d, cache size: %d", computed, cache.Len())
+	}
+}
+
+func TestShufflingCacheError(t *testing.T) {
+	cache := NewShufflingCache(2)
+	key := ShufflingCacheKey{Epoch: 1}
+	if _, err := cache.Shuffling(key, func() (*ShufflingEpoch, error) {
+		return nil, errors.New("fail")
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+	if cache.Len() != 0 {
+		t.Fatal("expected failed computation not to be cached")
+	}
+	res, err := cache.Shuffling(key, func() (*ShufflingEpoch, error) {
+		return &ShufflingEpoch{Epoch: 1}, nil
+	})
+	if err != nil || res.Epoch != 1 {
+		t.Fatalf("expected retry to succeed, got %v, %v", res, err)
+	}
+}
@@ -122,14 +122,25 @@ type DenebPreset struct {
 }
 
 type ElectraPreset struct {
-	MAX_PENDING_DEPOSITS                 Uint64View `yaml:"MAX_PENDING_DEPOSITS" json:"MAX_PENDING_DEPOSITS"`
-	MAX_PENDING_PARTIAL_WITHDRAWALS      Uint64View `yaml:"MAX_PENDING_PARTIAL_WITHDRAWALS" json:"MAX_PENDING_PARTIAL_WITHDRAWALS"`
-	MAX_DEPOSIT_REQUESTS_PER_PAYLOAD     Uint64View `yaml:"MAX_DEPOSIT_REQUESTS_PER_PAYLOAD" json:"MAX_DEPOSIT_REQUESTS_PER_PAYLOAD"`
-	MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD  Uint64View `yaml:"MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD" json:"MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD"`
-	MAX_VALIDATORS_PER_COMMITTEE_ELECTRA Uint64View `yaml:"MAX_VALIDATORS_PER_COMMITTEE_ELECTRA" json:"MAX_VALIDATORS_PER_COMMITTEE_ELECTRA"`
-	MAX_ATTESTATIONS_ALPACA              Uint64View `yaml:"MAX_ATTESTATIONS_ALPACA" json:"MAX_ATTESTATIONS_ALPACA"`
-	MAX_ATTESTING_INDICES                Uint64View `yaml:"MAX_ATTESTING_INDICES" json:"MAX_ATTESTING_INDICES"`
-	COMMITTEE_BITS                       Uint64View `yaml:"COMMITTEE_BITS" json:"COMMITTEE_BITS"`
+	MIN_ACTIVATION_BALANCE                     Gwei       `yaml:"MIN_ACTIVATION_BALANCE" json:"MIN_ACTIVATION_BALANCE"`
+	MAX_EFFECTIVE_BALANCE_ELECTRA              Gwei       `yaml:"MAX_EFFECTIVE_BALANCE_ELECTRA" json:"MAX_EFFECTIVE_BALANCE_ELECTRA"`
+	MAX_PENDING_DEPOSITS                       Uint64View `yaml:"MAX_PENDING_DEPOSITS" json:"MAX_PENDING_DEPOSITS"`
+	MAX_PENDING_PARTIAL_WITHDRAWALS            Uint64View `yaml:"MAX_PENDING_PARTIAL_WITHDRAWALS" json:"MAX_PENDING_PARTIAL_WITHDRAWALS"`
+	PENDING_CONSOLIDATIONS_LIMIT               Uint64View `yaml:"PENDING_CONSOLIDATIONS_LIMIT" json:"PENDING_CONSOLIDATIONS_LIMIT"`
+	MAX_DEPOSIT_REQUESTS_PER_PAYLOAD           Uint64View `yaml:"MAX_DEPOSIT_REQUESTS_PER_PAYLOAD" json:"MAX_DEPOSIT_REQUESTS_PER_PAYLOAD"`
+	MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD        Uint64View `yaml:"MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD" json:"MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD"`
+	MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD     Uint64View `yaml:"MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD" json:"MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD"`
+	MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP Uint64View `yaml:"MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP" json:"MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP"`
+	MAX_VALIDATORS_PER_COMMITTEE_ELECTRA       Uint64View `yaml:"MAX_VALIDATORS_PER_COMMITTEE_ELECTRA" json:"MAX_VALIDATORS_PER_COMMITTEE_ELECTRA"`
+	MAX_ATTESTATIONS_ALPACA                    Uint64View `yaml:"MAX_ATTESTATIONS_ALPACA" json:"MAX_ATTESTATIONS_ALPACA"`
+	MAX_ATTESTING_INDICES                      Uint64View `yaml:"MAX_ATTESTING_INDICES" json:"MAX_ATTESTING_INDICES"`
+	COMMITTEE_BITS                             Uint64View `yaml:"COMMITTEE_BITS" json:"COMMITTEE_BITS"`
+}
+
+type EIP7594Preset struct {
+	FIELD_ELEMENTS_PER_CELL               Uint64View `yaml:"FIELD_ELEMENTS_PER_CELL" json:"FIELD_ELEMENTS_PER_CELL"`
+	FIELD_ELEMENTS_PER_EXT_BLOB           Uint64View `yaml:"FIELD_ELEMENTS_PER_EXT_BLOB" json:"FIELD_ELEMENTS_PER_EXT_BLOB"`
+	KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH Uint64View `yaml:"KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH" json:"KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH"`
 }
 
 type Config struct {
@@ -195,6 +206,9 @@ type Config struct {
 	CHURN_LIMIT_QUOTIENT           Uint64View `yaml:"CHURN_LIMIT_QUOTIENT" json:"CHURN_LIMIT_QUOTIENT"`
 	// New in Deneb:EIP7514
 	MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT Uint64View `yaml:"MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT" json:"MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT"`
+	// New in Electra:EIP7251
+	MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA         Gwei `yaml:"MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA" json:"MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA"`
+	MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT Gwei `yaml:"MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT" json:"MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT"`
 
 	// Fork choice
 	PROPOSER_SCORE_BOOST                Uint64View `yaml:"PROPOSER_SCORE_BOOST" json:"PROPOSER_SCORE_BOOST"`
@@ -237,6 +251,15 @@ type Config struct {
 	// EIP7594
 	EIP7594_FORK_VERSION Version `yaml:"EIP7594_FORK_VERSION" json:"EIP7594_FORK_VERSION"`
 	EIP7594_FORK_EPOCH   Epoch   `yaml:"EIP7594_FORK_EPOCH" json:"EIP7594_FORK_EPOCH"`
+
+	// EIP7594 data availability sampling
+	NUMBER_OF_COLUMNS                            Uint64View `yaml:"NUMBER_OF_COLUMNS" json:"NUMBER_OF_COLUMNS"`
+	NUMBER_OF_CUSTODY_GROUPS                     Uint64View `yaml:"NUMBER_OF_CUSTODY_GROUPS" json:"NUMBER_OF_CUSTODY_GROUPS"`
+	DATA_COLUMN_SIDECAR_SUBNET_COUNT             Uint64View `yaml:"DATA_COLUMN_SIDECAR_SUBNET_COUNT" json:"DATA_COLUMN_SIDECAR_SUBNET_COUNT"`
+	MAX_REQUEST_DATA_COLUMN_SIDECARS             Uint64View `yaml:"MAX_REQUEST_DATA_COLUMN_SIDECARS" json:"MAX_REQUEST_DATA_COLUMN_SIDECARS"`
+	SAMPLES_PER_SLOT                             Uint64View `yaml:"SAMPLES_PER_SLOT" json:"SAMPLES_PER_SLOT"`
+	CUSTODY_REQUIREMENT                          Uint64View `yaml:"CUSTODY_REQUIREMENT" json:"CUSTODY_REQUIREMENT"`
+	MIN_EPOCHS_FOR_DATA_COLUMN_SIDECARS_REQUESTS Uint64View `yaml:"MIN_EPOCHS_FOR_DATA_COLUMN_SIDECARS_REQUESTS" json:"MIN_EPOCHS_FOR_DATA_COLUMN_SIDECARS_REQUESTS"`
 }
 
 type SpecObj interface {
@@ -311,6 +334,7 @@ type Spec struct {
 	CapellaPreset   `json:",inline" yaml:",inline"`
 	DenebPreset     `json:",inline" yaml:",inline"`
 	ElectraPreset   `json:",inline" yaml:",inline"`
+	EIP7594Preset   `json:",inline" yaml:",inline"`
 	Config          `json:",inline" yaml:",inline"`
 
 	ExecutionEngine `json:"-" yaml:"-"`
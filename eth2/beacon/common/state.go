@@ -17,6 +17,7 @@ type Eth1DataVotes interface {
 	Reset() error
 	Length() (uint64, error)
 	Count(dat Eth1Data) (uint64, error)
+	Votes() ([]Eth1Data, error)
 	Append(dat Eth1Data) error
 }
 
@@ -71,6 +72,9 @@ type ForkSettings struct {
 	MinSlashingPenaltyQuotient uint64
 	InactivityPenaltyQuotient  uint64
 	CalcProposerShare          func(whistleblowerReward Gwei) Gwei
+	// MaxEffectiveBalance is the highest effective balance of a validator,
+	// the balance-weighted proposer selection samples effective balances against it.
+	MaxEffectiveBalance Gwei
 }
 
 type BeaconState interface {
@@ -129,7 +133,8 @@ type BeaconState interface {
 	ProcessEpoch(ctx context.Context, spec *Spec, epc *EpochsContext) error
 	// ProcessBlock applies a block to the state.
 	// Excludes slot processing and signature validation. Just applies the block as-is. Error if mismatching slot.
-	ProcessBlock(ctx context.Context, spec *Spec, epc *EpochsContext, benv *BeaconBlockEnvelope) error
+	// Returns the status of the execution payload, to track in the forkchoice. Blocks without payload are valid.
+	ProcessBlock(ctx context.Context, spec *Spec, epc *EpochsContext, benv *BeaconBlockEnvelope) (PayloadStatus, error)
 }
 
 type UpgradeableBeaconState interface {
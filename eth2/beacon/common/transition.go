@@ -101,27 +101,28 @@ func ProcessSlots(ctx context.Context, spec *Spec, epc *EpochsContext, state Upg
 // StateTransition to the slot of the given block, then process the block.
 // Returns an error if the slot is older or equal to what the state is already at.
 // Mutates the state, does not copy.
-func StateTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state UpgradeableBeaconState, benv *BeaconBlockEnvelope, validateResult bool) error {
+// Returns the status of the execution payload of the block, see BeaconState.ProcessBlock.
+func StateTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state UpgradeableBeaconState, benv *BeaconBlockEnvelope, validateResult bool) (PayloadStatus, error) {
 	if err := ProcessSlots(ctx, spec, epc, state, benv.Slot); err != nil {
-		return err
+		return PayloadUnknown, err
 	}
 	return PostSlotTransition(ctx, spec, epc, state, benv, validateResult)
 }
 
 // PostSlotTransition finishes a state transition after applying ProcessSlots(..., block.Slot).
-func PostSlotTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state BeaconState, benv *BeaconBlockEnvelope, validateResult bool) error {
+func PostSlotTransition(ctx context.Context, spec *Spec, epc *EpochsContext, state BeaconState, benv *BeaconBlockEnvelope, validateResult bool) (PayloadStatus, error) {
 	slot, err := state.Slot()
 	if err != nil {
-		return err
+		return PayloadUnknown, err
 	}
 	if slot != benv.Slot {
-		return fmt.Errorf("transition of block, post-slot-processing, must run on state with same slot")
+		return PayloadUnknown, fmt.Errorf("transition of block, post-slot-processing, must run on state with same slot")
 	}
 	if validateResult {
 		// TODO: tests have invalid fork version in state
 		fork, err := state.Fork()
 		if err != nil {
-			return err
+			return PayloadUnknown, err
 		}
 		//version := spec.ForkVersion(benv.Slot)
 		//if fork.CurrentVersion != version {
@@ -130,27 +131,28 @@ func PostSlotTransition(ctx context.Context, spec *Spec, epc *EpochsContext, sta
 		//}
 		proposer, err := epc.GetBeaconProposer(benv.Slot)
 		if err != nil {
-			return err
+			return PayloadUnknown, err
 		}
 		genValRoot, err := state.GenesisValidatorsRoot()
 		if err != nil {
-			return err
+			return PayloadUnknown, err
 		}
 		pub, ok := epc.ValidatorPubkeyCache.Pubkey(proposer)
 		if !ok {
-			return fmt.Errorf("unknown pubkey for proposer %d", proposer)
+			return PayloadUnknown, fmt.Errorf("unknown pubkey for proposer %d", proposer)
 		}
 		if !benv.VerifySignatureVersioned(spec, fork.CurrentVersion, genValRoot, proposer, pub) {
-			return errors.New("block has invalid signature")
+			return PayloadUnknown, errors.New("block has invalid signature")
 		}
 	}
-	if err := state.ProcessBlock(ctx, spec, epc, benv); err != nil {
-		return err
+	status, err := state.ProcessBlock(ctx, spec, epc, benv)
+	if err != nil {
+		return status, err
 	}
 
 	// State root verification
 	if validateResult && benv.StateRoot != state.HashTreeRoot(tree.GetHashFn()) {
-		return errors.New("block has invalid state root")
+		return PayloadUnknown, errors.New("block has invalid state root")
 	}
-	return nil
+	return status, nil
 }
@@ -1,157 +1,248 @@
 package common
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"sync"
 )
 
-// PubkeyCache is shared between any state. However, if .Append(index, pubkey) conflicts, a new cache will be forked out.
-type PubkeyCache struct {
-	parent *PubkeyCache
-	// The count up until the conflicting validator index (cause of the pubkey cache fork).
+const pubkeyChunkSize = 1 << 10
+
+// Entries are allocated in chunks, so they never move once added, and can be shared without copying.
+type pubkeyChunk [pubkeyChunkSize]CachedPubkey
+
+// pubkeyStore is an append-only list of pubkeys, shared by all the caches derived from it.
+// If a cache appends a pubkey that conflicts with the pubkey at the same index in the store,
+// a new store is forked out, with the conflicting store as parent.
+type pubkeyStore struct {
+	parent *pubkeyStore
+	// The count up until the conflicting validator index (cause of the pubkey store fork).
 	trustedParentCount ValidatorIndex
 	pub2idx            map[BLSPubkey]ValidatorIndex
 	// starting at trustedParentCount
-	idx2pub []CachedPubkey
+	chunks []*pubkeyChunk
+	count  ValidatorIndex
 	// Can have many reads concurrently, but only 1 write.
 	rwLock sync.RWMutex
+
+	// When the store is backed by a file, new entries are appended to the file.
+	file    *os.File
+	w       *bufio.Writer
+	fileErr error
+}
+
+func newPubkeyStore(parent *pubkeyStore, trustedParentCount ValidatorIndex) *pubkeyStore {
+	return &pubkeyStore{
+		parent:             parent,
+		trustedParentCount: trustedParentCount,
+		pub2idx:            make(map[BLSPubkey]ValidatorIndex),
+	}
+}
+
+// end returns the index after the last entry. The caller must hold the lock.
+func (s *pubkeyStore) end() ValidatorIndex {
+	return s.trustedParentCount + s.count
 }
 
+// entry returns the entry at the index, which must be in the range of the store itself. The caller must hold the lock.
+func (s *pubkeyStore) entry(index ValidatorIndex) *CachedPubkey {
+	i := uint64(index - s.trustedParentCount)
+	return &s.chunks[i/pubkeyChunkSize][i%pubkeyChunkSize]
+}
+
+// slot returns the entry after the last entry, to be filled and then added with push.
+// The caller must hold the write lock.
+func (s *pubkeyStore) slot() *CachedPubkey {
+	i := uint64(s.count)
+	if i/pubkeyChunkSize >= uint64(len(s.chunks)) {
+		s.chunks = append(s.chunks, new(pubkeyChunk))
+	}
+	return &s.chunks[i/pubkeyChunkSize][i%pubkeyChunkSize]
+}
+
+// push adds the entry returned by slot. The caller must hold the write lock.
+func (s *pubkeyStore) push(c *CachedPubkey) {
+	s.pub2idx[c.Compressed] = s.end()
+	s.count += 1
+}
+
+// add appends the pubkey, and persists it if the store is backed by a file. The caller must hold the write lock.
+func (s *pubkeyStore) add(pub BLSPubkey) {
+	c := s.slot()
+	c.Compressed = pub
+	c.decompressed.Store(nil)
+	if s.w != nil && s.fileErr == nil {
+		var rec [pubkeyRecordSize]byte
+		encodePubkeyRecord(&rec, c)
+		if _, err := s.w.Write(rec[:]); err != nil {
+			s.fileErr = err
+		}
+	}
+	s.push(c)
+}
+
+func (s *pubkeyStore) pubkey(index ValidatorIndex) (pub *CachedPubkey, ok bool) {
+	s.rwLock.RLock()
+	defer s.rwLock.RUnlock()
+	if index >= s.trustedParentCount {
+		if index >= s.end() {
+			return nil, false
+		}
+		return s.entry(index), true
+	} else if s.parent != nil {
+		return s.parent.pubkey(index)
+	} else {
+		return nil, false
+	}
+}
+
+// validatorIndex looks up the index of the pubkey, only considering indices below the limit.
+func (s *pubkeyStore) validatorIndex(pubkey BLSPubkey, limit ValidatorIndex) (index ValidatorIndex, ok bool) {
+	s.rwLock.RLock()
+	defer s.rwLock.RUnlock()
+	index, ok = s.pub2idx[pubkey]
+	if ok && index < limit {
+		return index, true
+	}
+	if s.parent != nil {
+		if limit > s.trustedParentCount {
+			limit = s.trustedParentCount
+		}
+		return s.parent.validatorIndex(pubkey, limit)
+	}
+	return 0, false
+}
+
+// PubkeyCache is a copy-on-write view of the first validators of a shared append-only pubkey store.
+// A cache is never modified after creation: AddValidator returns a new cache,
+// and can be called on the same cache by different branches safely.
+// The store is only forked out if a branch adds a different pubkey at the same index.
+type PubkeyCache struct {
+	store *pubkeyStore
+	count ValidatorIndex
+}
+
+// NewPubkeyCache creates a cache of the pubkeys of the registry. The pubkeys are decompressed lazily.
+// See OpenPubkeyCache to persist the decompressed pubkeys instead.
 func NewPubkeyCache(vals ValidatorRegistry) (*PubkeyCache, error) {
 	valCount, err := vals.ValidatorCount()
 	if err != nil {
 		return nil, err
 	}
-	pc := &PubkeyCache{
-		parent:             nil,
-		trustedParentCount: 0,
-		pub2idx:            make(map[BLSPubkey]ValidatorIndex),
-		idx2pub:            make([]CachedPubkey, 0),
-	}
-	currentCount := uint64(len(pc.idx2pub))
-	for i := currentCount; i < valCount; i++ {
-		idx := ValidatorIndex(i)
-		v, err := vals.Validator(idx)
+	s := newPubkeyStore(nil, 0)
+	for i := uint64(0); i < valCount; i++ {
+		pub, err := registryPubkey(vals, ValidatorIndex(i))
 		if err != nil {
 			return nil, err
 		}
-		pub, err := v.Pubkey()
-		if err != nil {
-			return nil, err
-		}
-		pc.pub2idx[pub] = idx
-		pc.idx2pub = append(pc.idx2pub, CachedPubkey{Compressed: pub})
+		s.add(pub)
 	}
-	return pc, nil
+	return &PubkeyCache{store: s, count: s.end()}, nil
 }
 
 func EmptyPubkeyCache() *PubkeyCache {
-	return &PubkeyCache{
-		parent:             nil,
-		trustedParentCount: 0,
-		pub2idx:            make(map[BLSPubkey]ValidatorIndex),
-		idx2pub:            make([]CachedPubkey, 0),
+	return &PubkeyCache{store: newPubkeyStore(nil, 0), count: 0}
+}
+
+func registryPubkey(vals ValidatorRegistry, index ValidatorIndex) (BLSPubkey, error) {
+	v, err := vals.Validator(index)
+	if err != nil {
+		return BLSPubkey{}, err
 	}
+	return v.Pubkey()
 }
 
-// Get the pubkey of a validator index.
-// Note: this does not mean the validator is part of the current state.
-// It merely means that this is a known pubkey for that particular validator
-// (could be in a later part of a forked version of the state).
-func (pc *PubkeyCache) Pubkey(index ValidatorIndex) (pub *CachedPubkey, ok bool) {
-	pc.rwLock.RLock()
-	defer pc.rwLock.RUnlock()
-	return pc.unsafePubkey(index)
+// Count returns the number of validators in the cache.
+func (pc *PubkeyCache) Count() ValidatorIndex {
+	return pc.count
 }
 
-func (pc *PubkeyCache) unsafePubkey(index ValidatorIndex) (pub *CachedPubkey, ok bool) {
-	if index >= pc.trustedParentCount {
-		if index >= pc.trustedParentCount+ValidatorIndex(len(pc.idx2pub)) {
-			return nil, false
-		}
-		return &pc.idx2pub[index-pc.trustedParentCount], true
-	} else if pc.parent != nil {
-		return pc.parent.Pubkey(index)
-	} else {
+// Get the pubkey of a validator index.
+// Note: this does not mean the validator is part of the current state,
+// only that the cache has it for the validators it was created or synced with.
+func (pc *PubkeyCache) Pubkey(index ValidatorIndex) (pub *CachedPubkey, ok bool) {
+	if index >= pc.count {
 		return nil, false
 	}
+	return pc.store.pubkey(index)
 }
 
 // Get the validator index of a pubkey.
-// Note: this does not mean the validator is part of the current state.
-// It merely means that this is a known pubkey for that particular validator
-// (could be in a later part of a forked version of the state).
+// Note: this does not mean the validator is part of the current state,
+// only that the cache has it for the validators it was created or synced with.
 func (pc *PubkeyCache) ValidatorIndex(pubkey BLSPubkey) (index ValidatorIndex, ok bool) {
-	pc.rwLock.RLock()
-	defer pc.rwLock.RUnlock()
-	return pc.unsafeValidatorIndex(pubkey)
+	return pc.store.validatorIndex(pubkey, pc.count)
 }
 
-func (pc *PubkeyCache) unsafeValidatorIndex(pubkey BLSPubkey) (index ValidatorIndex, ok bool) {
-	index, ok = pc.pub2idx[pubkey]
-	if !ok && pc.parent != nil {
-		return pc.parent.ValidatorIndex(pubkey)
+// AddValidator returns a cache with the (index, pubkey) pair appended, the index must be the count of the cache.
+// The cache itself is not modified. The new cache shares the store, unless the store already has
+// a different pubkey at the index: then the part before the index is inherited, and a forked store is used.
+func (pc *PubkeyCache) AddValidator(index ValidatorIndex, pub BLSPubkey) (*PubkeyCache, error) {
+	if index != pc.count {
+		return nil, fmt.Errorf("AddValidator is incorrect, expecting index %d next, but got: (%d, %x)", pc.count, index, pub)
+	}
+	if existingIndex, ok := pc.ValidatorIndex(pub); ok {
+		return nil, fmt.Errorf("AddValidator is incorrect, pubkey %x is already known at index %d, cannot add it at index %d", pub, existingIndex, index)
+	}
+	// A cache synced back to before the fork point of its store continues in the parent store that has the index.
+	// The parent and fork point of a store never change, reading them needs no lock.
+	s := pc.store
+	for index < s.trustedParentCount {
+		s = s.parent
 	}
-	return index, ok
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if index == s.end() {
+		s.add(pub)
+		if s.w != nil && s.fileErr == nil {
+			s.fileErr = s.w.Flush()
+		}
+		return &PubkeyCache{store: s, count: index + 1}, nil
+	}
+	if s.entry(index).Compressed == pub {
+		// already added by another branch
+		return &PubkeyCache{store: s, count: index + 1}, nil
+	}
+	// conflict detected! Deposit log fork!
+	// Fork out the existing index, only trust the history. Forked stores are not persisted.
+	forked := newPubkeyStore(s, index)
+	forked.add(pub)
+	return &PubkeyCache{store: forked, count: index + 1}, nil
 }
 
-// AddValidator appends the (index, pubkey) pair to the pubkey cache. It returns the same cache if the added entry is not conflicting.
-// If it conflicts, the part is inherited, and a forked pubkey cache is returned.
-func (pc *PubkeyCache) AddValidator(index ValidatorIndex, pub BLSPubkey) (*PubkeyCache, error) {
-	existingIndex, indexExists := pc.ValidatorIndex(pub)
-	existingPubkey, pubkeyExists := pc.Pubkey(index)
-
-	if indexExists {
-		if existingIndex != index {
-			// conflict detected! Deposit log fork!
-			forkedPc := &PubkeyCache{
-				parent: pc,
-				// fork out the existing index, only trust the history
-				trustedParentCount: existingIndex,
-				pub2idx:            make(map[BLSPubkey]ValidatorIndex),
-				idx2pub:            make([]CachedPubkey, 0),
-			}
-			// Do not have to unlock this cache (parent of forkedPc) early, as the forkedPc is guaranteed to handle it.
-			return forkedPc.AddValidator(index, pub)
-		}
-		if pubkeyExists {
-			if existingPubkey.Compressed != pub {
-				// conflict detected! Deposit log fork!
-				forkedPc := &PubkeyCache{
-					parent: pc,
-					// fork out the existing index, only trust the history
-					trustedParentCount: index,
-					pub2idx:            make(map[BLSPubkey]ValidatorIndex),
-					idx2pub:            make([]CachedPubkey, 0),
-				}
-				// Do not have to unlock this cache (parent of forkedPc) early, as the forkedPc is guaranteed to handle it.
-				return forkedPc.AddValidator(index, pub)
-			}
+// Sync returns a cache with exactly the validators of the registry.
+// Known validators are checked against the registry, without decompressing them again,
+// and new validators are appended like with AddValidator.
+func (pc *PubkeyCache) Sync(vals ValidatorRegistry) (*PubkeyCache, error) {
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	out := &PubkeyCache{store: pc.store, count: pc.count}
+	if ValidatorIndex(valCount) < out.count {
+		out.count = ValidatorIndex(valCount)
+	}
+	for i := ValidatorIndex(0); i < out.count; i++ {
+		pub, err := registryPubkey(vals, i)
+		if err != nil {
+			return nil, err
 		}
-		// append is no-op, validator already exists
-		return pc, nil
-	}
-	if pubkeyExists {
-		if existingPubkey.Compressed != pub {
-			// conflict detected! Deposit log fork!
-			forkedPc := &PubkeyCache{
-				parent: pc,
-				// fork out the existing index, only trust the history
-				trustedParentCount: index,
-				pub2idx:            make(map[BLSPubkey]ValidatorIndex),
-				idx2pub:            make([]CachedPubkey, 0),
-			}
-			// Do not have to unlock this cache (parent of forkedPc) early, as the forkedPc is guaranteed to handle it.
-			return forkedPc.AddValidator(index, pub)
+		if known, _ := out.Pubkey(i); known.Compressed != pub {
+			// the registry is from a different deposit history, continue from there.
+			out.count = i
+			break
 		}
 	}
-	pc.rwLock.Lock()
-	defer pc.rwLock.Unlock()
-	if expected := pc.trustedParentCount + ValidatorIndex(len(pc.idx2pub)); index != expected {
-		// index is unknown, but too far ahead of cache; in between indices are missing.
-		return nil, fmt.Errorf("AddValidator is incorrect, missing earlier index. got: (%d, %x), but currently expecting %d next", index, pub, expected)
+	for i := out.count; i < ValidatorIndex(valCount); i++ {
+		pub, err := registryPubkey(vals, i)
+		if err != nil {
+			return nil, err
+		}
+		out, err = out.AddValidator(i, pub)
+		if err != nil {
+			return nil, err
+		}
 	}
-	pc.idx2pub = append(pc.idx2pub, CachedPubkey{Compressed: pub})
-	pc.pub2idx[pub] = index
-	return pc, nil
+	return out, nil
 }
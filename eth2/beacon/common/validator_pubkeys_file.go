@@ -0,0 +1,169 @@
+package common
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	kbls "github.com/kilic/bls12-381"
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+// The pubkey cache file starts with the magic bytes, followed by a record per validator, in order of validator index.
+// A record is the compressed pubkey, a flag byte that is 1 if the pubkey is a valid point, and the uncompressed point.
+// Loading an uncompressed point only needs an on-curve check, not the square root of decompression.
+const (
+	pubkeyFileMagic  = "zrntpk01"
+	pubkeyRecordSize = 48 + 1 + 96
+)
+
+func encodePubkeyRecord(rec *[pubkeyRecordSize]byte, c *CachedPubkey) {
+	copy(rec[:48], c.Compressed[:])
+	pub, err := c.Pubkey()
+	if err != nil {
+		// invalid pubkeys are recorded too, to not try to decompress them again when loading
+		return
+	}
+	rec[48] = 1
+	// copy, ToBytes converts the point to affine coordinates in-place
+	p := *(*kbls.PointG1)(pub)
+	copy(rec[49:], kbls.NewG1().ToBytes(&p))
+}
+
+// decodePubkeyRecord fills the entry with the record, and returns false if the record is not consistent.
+func decodePubkeyRecord(rec *[pubkeyRecordSize]byte, c *CachedPubkey) bool {
+	copy(c.Compressed[:], rec[:48])
+	c.decompressed.Store(nil)
+	switch rec[48] {
+	case 0:
+		_, err := c.Compressed.Pubkey()
+		return err != nil
+	case 1:
+		g1 := kbls.NewG1()
+		p, err := g1.FromBytes(rec[49:])
+		if err != nil {
+			return false
+		}
+		if BLSPubkey(g1.ToCompressed(p)) != c.Compressed {
+			return false
+		}
+		c.decompressed.Store((*blsu.Pubkey)(p))
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenPubkeyCache opens the pubkey cache file at the given path, or creates it, and returns a cache of the registry.
+//
+// Records of the file are checked against the registry, and the file is truncated at the first record that
+// does not match, e.g. when the file was written for a different deposit history.
+// Records past the registry, of validators that were added later, are kept, to share with the caches of new states.
+// The remaining validators of the registry are decompressed and appended to the file.
+//
+// Validators added to the cache, and to caches derived from it, are appended to the file as well,
+// unless they conflict with existing entries. The file stays open until Close.
+func OpenPubkeyCache(path string, vals ValidatorRegistry) (*PubkeyCache, error) {
+	valCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := newPubkeyStore(nil, 0)
+	if err := s.load(f, vals, ValidatorIndex(valCount)); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to load pubkey cache file %q: %w", path, err)
+	}
+	return &PubkeyCache{store: s, count: ValidatorIndex(valCount)}, nil
+}
+
+func (s *pubkeyStore) load(f *os.File, vals ValidatorRegistry, valCount ValidatorIndex) error {
+	r := bufio.NewReader(f)
+	var magic [len(pubkeyFileMagic)]byte
+	if _, err := io.ReadFull(r, magic[:]); err == io.EOF {
+		// new file
+		if _, err := f.Write([]byte(pubkeyFileMagic)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if string(magic[:]) != pubkeyFileMagic {
+		return errors.New("not a pubkey cache file")
+	}
+
+	var rec [pubkeyRecordSize]byte
+	for {
+		if _, err := io.ReadFull(r, rec[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			// a partial record may be left after an interrupted write
+			break
+		} else if err != nil {
+			return err
+		}
+		index := s.end()
+		if index < valCount {
+			pub, err := registryPubkey(vals, index)
+			if err != nil {
+				return err
+			}
+			if BLSPubkey(rec[:48]) != pub {
+				break
+			}
+		} else if _, dup := s.pub2idx[BLSPubkey(rec[:48])]; dup {
+			break
+		}
+		c := s.slot()
+		if !decodePubkeyRecord(&rec, c) {
+			break
+		}
+		s.push(c)
+	}
+	size := int64(len(pubkeyFileMagic)) + int64(s.count)*pubkeyRecordSize
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	s.file = f
+	s.w = bufio.NewWriter(f)
+	for i := s.end(); i < valCount; i++ {
+		pub, err := registryPubkey(vals, i)
+		if err != nil {
+			return err
+		}
+		s.add(pub)
+		if s.fileErr != nil {
+			return s.fileErr
+		}
+	}
+	return s.w.Flush()
+}
+
+// Close flushes and closes the file of the cache opened with OpenPubkeyCache,
+// shared by all caches derived from it. Validators added after closing are only kept in memory.
+// It returns the first error of persisting validators, if any. Close is a no-op for caches without file.
+func (pc *PubkeyCache) Close() error {
+	s := pc.store
+	for s.parent != nil {
+		s = s.parent
+	}
+	s.rwLock.Lock()
+	defer s.rwLock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.fileErr
+	if err == nil {
+		err = s.w.Flush()
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file, s.w = nil, nil
+	return err
+}
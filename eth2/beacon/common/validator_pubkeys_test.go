@@ -0,0 +1,111 @@
+package common
+
+import (
+	"sync"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+)
+
+func testPubkeys(t *testing.T, count int) []BLSPubkey {
+	out := make([]BLSPubkey, count)
+	for i := range out {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = pub.Serialize()
+	}
+	return out
+}
+
+func TestPubkeyCacheCopyOnWrite(t *testing.T) {
+	pubs := testPubkeys(t, 5)
+	pc := EmptyPubkeyCache()
+	var err error
+	for i := 0; i < 3; i++ {
+		pc, err = pc.AddValidator(ValidatorIndex(i), pubs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// two branches with different deposits at index 3
+	a, err := pc.AddValidator(3, pubs[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pc.AddValidator(3, pubs[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a third branch with the same deposit as the first
+	c, err := pc.AddValidator(3, pubs[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pc.Pubkey(3); ok {
+		t.Fatal("the original cache must not be modified")
+	}
+	if _, ok := pc.ValidatorIndex(pubs[3]); ok {
+		t.Fatal("the original cache must not see pubkeys of branches")
+	}
+	if a.store != pc.store || c.store != pc.store || b.store == pc.store {
+		t.Fatal("expected only the conflicting branch to fork out the store")
+	}
+	for cache, expected := range map[*PubkeyCache]BLSPubkey{a: pubs[3], b: pubs[4], c: pubs[3]} {
+		got, ok := cache.Pubkey(3)
+		if !ok || got.Compressed != expected {
+			t.Fatalf("unexpected pubkey at index 3: %v", got)
+		}
+		if index, ok := cache.ValidatorIndex(expected); !ok || index != 3 {
+			t.Fatalf("unexpected index of pubkey: %d", index)
+		}
+		if index, ok := cache.ValidatorIndex(pubs[1]); !ok || index != 1 {
+			t.Fatalf("expected inherited pubkey at index 1, got %d", index)
+		}
+	}
+	if _, ok := b.ValidatorIndex(pubs[3]); ok {
+		t.Fatal("the forked branch must not see the pubkey of the other branch")
+	}
+	if _, err := a.AddValidator(5, pubs[4]); err == nil {
+		t.Fatal("expected error for missing index")
+	}
+	if _, err := a.AddValidator(4, pubs[0]); err == nil {
+		t.Fatal("expected error for known pubkey")
+	}
+}
+
+func TestPubkeyCacheConcurrentPubkey(t *testing.T) {
+	pubs := testPubkeys(t, 1)
+	pc, err := EmptyPubkeyCache().AddValidator(0, pubs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, _ := pc.Pubkey(0)
+	// concurrent lazy decompression
+	results := make([]*blsu.Pubkey, 4)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pub, err := cached.Pubkey()
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = pub
+		}(i)
+	}
+	wg.Wait()
+	for _, pub := range results {
+		if pub == nil || pub.Serialize() != pubs[0] {
+			t.Fatal("unexpected decompressed pubkey")
+		}
+	}
+}
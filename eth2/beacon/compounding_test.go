@@ -0,0 +1,300 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func compoundingCredentials(i int) (out common.Root) {
+	out = eth1Credentials(i)
+	out[0] = common.COMPOUNDING_WITHDRAWAL_PREFIX
+	return
+}
+
+func TestCompoundingEffectiveBalance(t *testing.T) {
+	spec := configs.Minimal
+	state := electra.NewBeaconStateView(spec)
+	// 0x01 validators stay capped at MIN_ACTIVATION_BALANCE, 0x02 validators grow up to MAX_EFFECTIVE_BALANCE_ELECTRA
+	if err := state.AddValidator(spec, common.BLSPubkey{0}, eth1Credentials(0), 100_000_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddValidator(spec, common.BLSPubkey{1}, compoundingCredentials(1), 100_000_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.AddValidator(spec, common.BLSPubkey{2}, compoundingCredentials(2), 3000_000_000_000); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the balance of validator 1 grows after the deposit, the effective balance must follow
+	if err := bals.SetBalance(1, 200_000_000_000); err != nil {
+		t.Fatal(err)
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc := &common.EpochsContext{Spec: spec}
+	if err := electra.ProcessEffectiveBalanceUpdates(context.Background(), spec, epc, flats, state); err != nil {
+		t.Fatal(err)
+	}
+	vals, err = state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []common.Gwei{spec.MIN_ACTIVATION_BALANCE, 200_000_000_000, spec.MAX_EFFECTIVE_BALANCE_ELECTRA} {
+		v, err := vals.Validator(common.ValidatorIndex(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eff, err := v.EffectiveBalance(); err != nil || eff != expected {
+			t.Fatalf("validator %d: expected effective balance %d, got %d", i, expected, eff)
+		}
+		// only the balance above the cap of the validator is withdrawn by the sweep
+		bal, err := bals.GetBalance(common.ValidatorIndex(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		partially, err := electra.IsPartiallyWithdrawableValidator(spec, v, bal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if partially != (i != 1) {
+			t.Fatalf("validator %d: unexpected partial withdrawability %v", i, partially)
+		}
+	}
+}
+
+func TestCompoundingWithdrawalSweep(t *testing.T) {
+	spec := configs.Minimal
+	state := electra.NewBeaconStateView(spec)
+	var blsCredentials common.Root
+	for i, v := range []struct {
+		creds   common.Root
+		balance common.Gwei
+	}{
+		// skims the balance above MAX_EFFECTIVE_BALANCE_ELECTRA
+		{compoundingCredentials(0), spec.MAX_EFFECTIVE_BALANCE_ELECTRA + 7},
+		// below its own cap, nothing is withdrawn
+		{compoundingCredentials(1), 100_000_000_000},
+		// skims the balance above MIN_ACTIVATION_BALANCE
+		{eth1Credentials(2), spec.MIN_ACTIVATION_BALANCE + 5},
+		// withdrawable, the full balance is withdrawn
+		{compoundingCredentials(3), 100_000_000_000},
+		// exited, but not withdrawable yet
+		{compoundingCredentials(4), 100_000_000_000},
+		// no execution address to withdraw to
+		{blsCredentials, spec.MIN_ACTIVATION_BALANCE + 5},
+	} {
+		if err := state.AddValidator(spec, common.BLSPubkey{byte(i)}, v.creds, v.balance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	epoch := spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY + 1
+	slot, err := spec.EpochStartSlot(epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetSlot(slot); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exitEpoch := range map[common.ValidatorIndex]common.Epoch{3: 1, 4: epoch} {
+		v, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.SetExitEpoch(exitEpoch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withdrawals, _, err := electra.GetExpectedWithdrawals(state, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		index  common.ValidatorIndex
+		amount common.Gwei
+	}{{0, 7}, {2, 5}, {3, 100_000_000_000}}
+	if len(withdrawals) != len(expected) {
+		t.Fatalf("expected %d withdrawals, got %v", len(expected), withdrawals)
+	}
+	for i, e := range expected {
+		if w := withdrawals[i]; w.ValidatorIndex != e.index || w.Amount != e.amount {
+			t.Fatalf("withdrawal %d: expected %d gwei of validator %d, got %s", i, e.amount, e.index, w)
+		}
+	}
+}
+
+func TestBalanceWeightedExitChurn(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, testValidators(t, &spec),
+		&deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perEpoch := electra.GetActivationExitChurnLimit(&spec, epc.TotalActiveStake)
+	if perEpoch != 2*spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("expected an exit churn of two validators per epoch, got %d", perEpoch)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a compounding validator with a large effective balance takes the churn of multiple epochs
+	v3, err := vals.Validator(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v3.SetEffectiveBalance(8 * spec.MIN_ACTIVATION_BALANCE); err != nil {
+		t.Fatal(err)
+	}
+	first := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+	for i, expected := range []common.Epoch{first, first, first + 1, first + 5, first + 5, first + 6} {
+		index := common.ValidatorIndex(i)
+		if err := electra.InitiateValidatorExit(&spec, epc, state, index); err != nil {
+			t.Fatal(err)
+		}
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := vals.Validator(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exitEpoch, err := v.ExitEpoch(); err != nil || exitEpoch != expected {
+			t.Fatalf("validator %d: expected exit epoch %d, got %d", i, expected, exitEpoch)
+		}
+	}
+	if earliest, err := state.EarliestExitEpoch(); err != nil || earliest != first+6 {
+		t.Fatalf("unexpected earliest exit epoch %d", earliest)
+	}
+	if toConsume, err := state.ExitBalanceToConsume(); err != nil || toConsume != spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("unexpected exit balance to consume %d", toConsume)
+	}
+}
+
+func TestEpochRegistryUpdatesEjection(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, testValidators(t, &spec),
+		&deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new validator becomes eligible for activation in the same registry update as the ejection
+	if err := state.AddValidator(&spec, common.BLSPubkey{0xaa}, compoundingCredentials(0xaa), spec.MIN_ACTIVATION_BALANCE); err != nil {
+		t.Fatal(err)
+	}
+	newIndex := common.ValidatorIndex(spec.SLOTS_PER_EPOCH)
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := vals.Validator(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetEffectiveBalance(spec.EJECTION_BALANCE); err != nil {
+		t.Fatal(err)
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := electra.ProcessEpochRegistryUpdates(context.Background(), &spec, epc, flats, state); err != nil {
+		t.Fatal(err)
+	}
+	vals, err = state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the eligibility update must not undo the exit of the ejected validator
+	v, err = vals.Validator(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitEpoch, err := v.ExitEpoch(); err != nil || exitEpoch != spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch) {
+		t.Fatalf("expected the ejected validator to exit, got exit epoch %d", exitEpoch)
+	}
+	v, err = vals.Validator(newIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eligibility, err := v.ActivationEligibilityEpoch(); err != nil || eligibility != epc.CurrentEpoch.Epoch+1 {
+		t.Fatalf("expected the new validator to become eligible, got eligibility epoch %d", eligibility)
+	}
+}
+
+func TestCompoundingProposerWeight(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	validators := testValidators(t, &spec)
+	validators[0].WithdrawalCredentials = compoundingCredentials(0)
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the genesis effective balances are capped like before Electra, raise the compounding validator to the max
+	v0, err := vals.Validator(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v0.SetEffectiveBalance(spec.MAX_EFFECTIVE_BALANCE_ELECTRA); err != nil {
+		t.Fatal(err)
+	}
+	maxEffectiveBalance := state.ForkSettings(&spec).MaxEffectiveBalance
+	if maxEffectiveBalance != spec.MAX_EFFECTIVE_BALANCE_ELECTRA {
+		t.Fatalf("expected proposers to be sampled against %d, got %d", spec.MAX_EFFECTIVE_BALANCE_ELECTRA, maxEffectiveBalance)
+	}
+	// a validator with 64 times the effective balance of the others is accepted 64 times as often:
+	// it proposes most of the blocks, instead of one in every len(validators).
+	active := epc.CurrentEpoch.ActiveIndices
+	selected := 0
+	const samples = 256
+	for i := 0; i < samples; i++ {
+		seed := common.Root{byte(i), byte(i >> 8), 0xaa}
+		proposer, err := common.ComputeProposerIndex(&spec, vals, active, seed, maxEffectiveBalance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proposer == 0 {
+			selected++
+		}
+	}
+	if selected < samples/2 {
+		t.Fatalf("expected the compounding validator to propose most of the blocks, got %d of %d", selected, samples)
+	}
+}
@@ -0,0 +1,187 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestConsolidationRequests(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	spec.SHARD_COMMITTEE_PERIOD = 0
+	// leave churn for consolidations: 128 ETH of balance churn, of which 32 ETH goes to activations and exits
+	spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA = 4 * spec.MIN_ACTIVATION_BALANCE
+	spec.MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT = spec.MIN_ACTIVATION_BALANCE
+	validators := testValidators(t, &spec)
+	for i := range validators {
+		validators[i].WithdrawalCredentials = eth1Credentials(i)
+	}
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if churn := electra.GetConsolidationChurnLimit(&spec, epc.TotalActiveStake); churn != 3*spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("unexpected consolidation churn %d", churn)
+	}
+	address := func(i int) (out common.Eth1Address) {
+		creds := eth1Credentials(i)
+		copy(out[:], creds[12:])
+		return
+	}
+	validator := func(i common.ValidatorIndex) common.Validator {
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	requests := []electra.ConsolidationRequest{
+		// not authorized by the withdrawal address of validator 0, ignored
+		{SourceAddress: address(1), SourcePubkey: validators[0].Pubkey, TargetPubkey: validators[0].Pubkey},
+		// switch of validator 0 to compounding credentials
+		{SourceAddress: address(0), SourcePubkey: validators[0].Pubkey, TargetPubkey: validators[0].Pubkey},
+		// consolidation of validator 2 into validator 0
+		{SourceAddress: address(2), SourcePubkey: validators[2].Pubkey, TargetPubkey: validators[0].Pubkey},
+		// target validator 1 does not have compounding credentials, ignored
+		{SourceAddress: address(3), SourcePubkey: validators[3].Pubkey, TargetPubkey: validators[1].Pubkey},
+	}
+	if err := electra.ProcessConsolidationRequests(context.Background(), &spec, epc, state, requests); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := electra.HasCompoundingWithdrawalCredential(validator(0)); err != nil || !ok {
+		t.Fatal("expected validator 0 to switch to compounding credentials")
+	}
+	first := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+	for i, expected := range []common.Epoch{common.FAR_FUTURE_EPOCH, common.FAR_FUTURE_EPOCH, first, common.FAR_FUTURE_EPOCH} {
+		if exitEpoch, err := validator(common.ValidatorIndex(i)).ExitEpoch(); err != nil || exitEpoch != expected {
+			t.Fatalf("validator %d: expected exit epoch %d, got %d", i, expected, exitEpoch)
+		}
+	}
+	if toConsume, err := state.ConsolidationBalanceToConsume(); err != nil || toConsume != 2*spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("unexpected consolidation balance to consume %d", toConsume)
+	}
+	pending, err := state.PendingConsolidations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	consolidations, err := pending.Consolidations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consolidations) != 1 || consolidations[0] != (electra.PendingConsolidation{SourceIndex: 2, TargetIndex: 0}) {
+		t.Fatalf("unexpected pending consolidations %v", consolidations)
+	}
+
+	// the balance only moves once the source is withdrawable
+	if err := electra.ProcessPendingConsolidations(context.Background(), &spec, epc, state); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = state.PendingConsolidations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consolidations, err := pending.Consolidations(); err != nil || len(consolidations) != 1 {
+		t.Fatalf("expected the consolidation to stay pending, got %v", consolidations)
+	}
+	later := *epc
+	nextEpoch := *epc.NextEpoch
+	nextEpoch.Epoch = first + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	later.NextEpoch = &nextEpoch
+	if err := electra.ProcessPendingConsolidations(context.Background(), &spec, &later, state); err != nil {
+		t.Fatal(err)
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []common.Gwei{2 * spec.MIN_ACTIVATION_BALANCE, spec.MIN_ACTIVATION_BALANCE, 0} {
+		if bal, err := bals.GetBalance(common.ValidatorIndex(i)); err != nil || bal != expected {
+			t.Fatalf("validator %d: expected balance %d, got %d", i, expected, bal)
+		}
+	}
+	pending, err = state.PendingConsolidations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consolidations, err := pending.Consolidations(); err != nil || len(consolidations) != 0 {
+		t.Fatalf("expected no pending consolidations, got %v", consolidations)
+	}
+}
+
+func TestSwitchToCompoundingChargesChurn(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	spec.SHARD_COMMITTEE_PERIOD = 0
+	validators := testValidators(t, &spec)
+	for i := range validators {
+		validators[i].WithdrawalCredentials = eth1Credentials(i)
+	}
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perEpoch := electra.GetActivationExitChurnLimit(&spec, epc.TotalActiveStake)
+	if perEpoch != 2*spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("expected an exit churn of two validators per epoch, got %d", perEpoch)
+	}
+	// validator 0 has a balance far above MIN_ACTIVATION_BALANCE, validator 1 has none in excess
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bals.SetBalance(0, 8*spec.MIN_ACTIVATION_BALANCE); err != nil {
+		t.Fatal(err)
+	}
+	address := func(i int) (out common.Eth1Address) {
+		creds := eth1Credentials(i)
+		copy(out[:], creds[12:])
+		return
+	}
+	requests := []electra.ConsolidationRequest{
+		{SourceAddress: address(1), SourcePubkey: validators[1].Pubkey, TargetPubkey: validators[1].Pubkey},
+		{SourceAddress: address(0), SourcePubkey: validators[0].Pubkey, TargetPubkey: validators[0].Pubkey},
+	}
+	if err := electra.ProcessConsolidationRequests(context.Background(), &spec, epc, state, requests); err != nil {
+		t.Fatal(err)
+	}
+	// the excess of 7 validators worth of balance takes the churn of 3.5 epochs
+	first := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+	if earliest, err := state.EarliestExitEpoch(); err != nil || earliest != first+3 {
+		t.Fatalf("unexpected earliest exit epoch %d", earliest)
+	}
+	if toConsume, err := state.ExitBalanceToConsume(); err != nil || toConsume != spec.MIN_ACTIVATION_BALANCE {
+		t.Fatalf("unexpected exit balance to consume %d", toConsume)
+	}
+	// exits queue up behind the excess balance
+	if err := electra.InitiateValidatorExit(&spec, epc, state, 2); err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := vals.Validator(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitEpoch, err := v.ExitEpoch(); err != nil || exitEpoch != first+3 {
+		t.Fatalf("expected exit epoch %d, got %d", first+3, exitEpoch)
+	}
+}
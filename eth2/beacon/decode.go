@@ -0,0 +1,343 @@
+package beacon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// ForkName is the lower-case name of a fork, as used in the "version" field of versioned API responses.
+type ForkName string
+
+const (
+	Phase0    ForkName = "phase0"
+	Altair    ForkName = "altair"
+	Bellatrix ForkName = "bellatrix"
+	Capella   ForkName = "capella"
+	Deneb     ForkName = "deneb"
+	Electra   ForkName = "electra"
+)
+
+// ForkNameFromVersion finds the fork of the given fork version in the spec.
+func ForkNameFromVersion(spec *common.Spec, version common.Version) (ForkName, error) {
+	// newest first, in case a config reuses a version of an unscheduled fork.
+	switch version {
+	case spec.ALPACA_FORK_VERSION:
+		return Electra, nil
+	case spec.DENEB_FORK_VERSION:
+		return Deneb, nil
+	case spec.CAPELLA_FORK_VERSION:
+		return Capella, nil
+	case spec.BELLATRIX_FORK_VERSION:
+		return Bellatrix, nil
+	case spec.ALTAIR_FORK_VERSION:
+		return Altair, nil
+	case spec.GENESIS_FORK_VERSION:
+		return Phase0, nil
+	default:
+		return "", fmt.Errorf("unrecognized fork version: %s", version)
+	}
+}
+
+// ForkNameAtSlot returns the name of the fork that is active at the given slot.
+func ForkNameAtSlot(spec *common.Spec, slot common.Slot) ForkName {
+	// the spec only returns versions of known forks.
+	name, _ := ForkNameFromVersion(spec, spec.ForkVersion(slot))
+	return name
+}
+
+// NewBeaconStateOf allocates an empty (default) state of the given fork.
+func NewBeaconStateOf(spec *common.Spec, fork ForkName) (common.BeaconState, error) {
+	switch fork {
+	case Phase0:
+		return phase0.NewBeaconStateView(spec), nil
+	case Altair:
+		return altair.NewBeaconStateView(spec), nil
+	case Bellatrix:
+		return bellatrix.NewBeaconStateView(spec), nil
+	case Capella:
+		return capella.NewBeaconStateView(spec), nil
+	case Deneb:
+		return deneb.NewBeaconStateView(spec), nil
+	case Electra:
+		return electra.NewBeaconStateView(spec), nil
+	default:
+		return nil, fmt.Errorf("unrecognized fork: %q", fork)
+	}
+}
+
+// NewRawBeaconStateOf allocates an empty state of the given fork, in the flat native Go structure (not tree-backed).
+func NewRawBeaconStateOf(fork ForkName) (common.SpecObj, error) {
+	switch fork {
+	case Phase0:
+		return new(phase0.BeaconState), nil
+	case Altair:
+		return new(altair.BeaconState), nil
+	case Bellatrix:
+		return new(bellatrix.BeaconState), nil
+	case Capella:
+		return new(capella.BeaconState), nil
+	case Deneb:
+		return new(deneb.BeaconState), nil
+	case Electra:
+		return new(electra.BeaconState), nil
+	default:
+		return nil, fmt.Errorf("unrecognized fork: %q", fork)
+	}
+}
+
+// NewSignedBeaconBlockOf allocates an empty signed block of the given fork.
+func NewSignedBeaconBlockOf(fork ForkName) (OpaqueBlock, error) {
+	switch fork {
+	case Phase0:
+		return new(phase0.SignedBeaconBlock), nil
+	case Altair:
+		return new(altair.SignedBeaconBlock), nil
+	case Bellatrix:
+		return new(bellatrix.SignedBeaconBlock), nil
+	case Capella:
+		return new(capella.SignedBeaconBlock), nil
+	case Deneb:
+		return new(deneb.SignedBeaconBlock), nil
+	case Electra:
+		return new(electra.SignedBeaconBlock), nil
+	default:
+		return nil, fmt.Errorf("unrecognized fork: %q", fork)
+	}
+}
+
+// StateForkName returns the fork of the given state, based on its type.
+func StateForkName(state common.BeaconState) (ForkName, error) {
+	switch state.(type) {
+	case *phase0.BeaconStateView:
+		return Phase0, nil
+	case *altair.BeaconStateView:
+		return Altair, nil
+	case *bellatrix.BeaconStateView:
+		return Bellatrix, nil
+	case *capella.BeaconStateView:
+		return Capella, nil
+	case *deneb.BeaconStateView:
+		return Deneb, nil
+	case *electra.BeaconStateView:
+		return Electra, nil
+	case *StandardUpgradeableBeaconState:
+		return StateForkName(state.(*StandardUpgradeableBeaconState).BeaconState)
+	default:
+		return "", fmt.Errorf("unrecognized state type: %T", state)
+	}
+}
+
+// BlockForkName returns the fork of the given signed block, based on its type.
+func BlockForkName(block OpaqueBlock) (ForkName, error) {
+	switch block.(type) {
+	case *phase0.SignedBeaconBlock:
+		return Phase0, nil
+	case *altair.SignedBeaconBlock:
+		return Altair, nil
+	case *bellatrix.SignedBeaconBlock:
+		return Bellatrix, nil
+	case *capella.SignedBeaconBlock:
+		return Capella, nil
+	case *deneb.SignedBeaconBlock:
+		return Deneb, nil
+	case *electra.SignedBeaconBlock:
+		return Electra, nil
+	default:
+		return "", fmt.Errorf("unrecognized block type: %T", block)
+	}
+}
+
+const (
+	// genesis_time (8) + genesis_validators_root (32) + slot (8) + fork.previous_version (4)
+	stateCurrentVersionOffset = 8 + 32 + 8 + 4
+	// offset of the message (4) + signature (96)
+	signedBlockFixedLength = 4 + 96
+)
+
+// DecodeBeaconState decodes a SSZ encoded state of any fork.
+// The fork is determined by the fork.current_version of the state.
+func DecodeBeaconState(spec *common.Spec, data []byte) (common.BeaconState, error) {
+	fork, err := DetectBeaconStateFork(spec, data)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBeaconStateOf(spec, fork, data)
+}
+
+// DetectBeaconStateFork determines the fork of a SSZ encoded state, by reading the fork.current_version.
+func DetectBeaconStateFork(spec *common.Spec, data []byte) (ForkName, error) {
+	if len(data) < stateCurrentVersionOffset+4 {
+		return "", fmt.Errorf("state of %d bytes is too short to read fork version", len(data))
+	}
+	var version common.Version
+	copy(version[:], data[stateCurrentVersionOffset:stateCurrentVersionOffset+4])
+	return ForkNameFromVersion(spec, version)
+}
+
+// DecodeBeaconStateOf decodes a SSZ encoded state of the given fork.
+func DecodeBeaconStateOf(spec *common.Spec, fork ForkName, data []byte) (common.BeaconState, error) {
+	dr := codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))
+	var (
+		state common.BeaconState
+		err   error
+	)
+	switch fork {
+	case Phase0:
+		state, err = phase0.AsBeaconStateView(phase0.BeaconStateType(spec).Deserialize(dr))
+	case Altair:
+		state, err = altair.AsBeaconStateView(altair.BeaconStateType(spec).Deserialize(dr))
+	case Bellatrix:
+		state, err = bellatrix.AsBeaconStateView(bellatrix.BeaconStateType(spec).Deserialize(dr))
+	case Capella:
+		state, err = capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(dr))
+	case Deneb:
+		state, err = deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(dr))
+	case Electra:
+		state, err = electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(dr))
+	default:
+		return nil, fmt.Errorf("unrecognized fork: %q", fork)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s state: %w", fork, err)
+	}
+	return state, nil
+}
+
+// DecodeSignedBeaconBlock decodes a SSZ encoded signed block of any fork.
+// The fork is determined by the slot of the block.
+// The fork digest of the returned envelope is left zero, since the genesis validators root is not known.
+func DecodeSignedBeaconBlock(spec *common.Spec, data []byte) (OpaqueBlock, *common.BeaconBlockEnvelope, error) {
+	fork, err := DetectSignedBeaconBlockFork(spec, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DecodeSignedBeaconBlockOf(spec, fork, data)
+}
+
+// DetectSignedBeaconBlockFork determines the fork of a SSZ encoded signed block, by reading the slot of the block.
+func DetectSignedBeaconBlockFork(spec *common.Spec, data []byte) (ForkName, error) {
+	if len(data) < signedBlockFixedLength {
+		return "", fmt.Errorf("signed block of %d bytes is too short", len(data))
+	}
+	msgOffset := uint64(binary.LittleEndian.Uint32(data[:4]))
+	if msgOffset != signedBlockFixedLength || uint64(len(data)) < msgOffset+8 {
+		return "", fmt.Errorf("signed block has invalid message offset %d", msgOffset)
+	}
+	slot := common.Slot(binary.LittleEndian.Uint64(data[msgOffset : msgOffset+8]))
+	return ForkNameAtSlot(spec, slot), nil
+}
+
+// DecodeSignedBeaconBlockOf decodes a SSZ encoded signed block of the given fork.
+func DecodeSignedBeaconBlockOf(spec *common.Spec, fork ForkName, data []byte) (OpaqueBlock, *common.BeaconBlockEnvelope, error) {
+	block, err := NewSignedBeaconBlockOf(fork)
+	if err != nil {
+		return nil, nil, err
+	}
+	dr := codec.NewDecodingReader(bytes.NewReader(data), uint64(len(data)))
+	if err := block.Deserialize(spec, dr); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s block: %w", fork, err)
+	}
+	return block, block.Envelope(spec, common.ForkDigest{}), nil
+}
+
+type versionedJSON struct {
+	Version ForkName        `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// MarshalVersionedBeaconState encodes the state as versioned JSON: {"version": "deneb", "data": {...}}
+func MarshalVersionedBeaconState(spec *common.Spec, state common.BeaconState) ([]byte, error) {
+	if s, ok := state.(*StandardUpgradeableBeaconState); ok {
+		state = s.BeaconState
+	}
+	fork, err := StateForkName(state)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	switch x := state.(type) {
+	case *phase0.BeaconStateView:
+		raw, err = x.Raw(spec)
+	case *altair.BeaconStateView:
+		raw, err = x.Raw(spec)
+	case *bellatrix.BeaconStateView:
+		raw, err = x.Raw(spec)
+	case *capella.BeaconStateView:
+		raw, err = x.Raw(spec)
+	case *deneb.BeaconStateView:
+		raw, err = x.Raw(spec)
+	case *electra.BeaconStateView:
+		raw, err = x.Raw(spec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s state to raw form: %w", fork, err)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&versionedJSON{Version: fork, Data: data})
+}
+
+// UnmarshalVersionedBeaconState decodes a state from versioned JSON: {"version": "deneb", "data": {...}}
+func UnmarshalVersionedBeaconState(spec *common.Spec, data []byte) (common.BeaconState, error) {
+	var v versionedJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	raw, err := NewRawBeaconStateOf(v.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(v.Data, raw); err != nil {
+		return nil, fmt.Errorf("failed to decode %s state JSON: %w", v.Version, err)
+	}
+	// The tree-backed state is created by SSZ-encoding the raw state.
+	var buf bytes.Buffer
+	if err := raw.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		return nil, fmt.Errorf("failed to encode %s state: %w", v.Version, err)
+	}
+	return DecodeBeaconStateOf(spec, v.Version, buf.Bytes())
+}
+
+// MarshalVersionedSignedBeaconBlock encodes the block as versioned JSON: {"version": "deneb", "data": {...}}
+func MarshalVersionedSignedBeaconBlock(block OpaqueBlock) ([]byte, error) {
+	fork, err := BlockForkName(block)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&versionedJSON{Version: fork, Data: data})
+}
+
+// UnmarshalVersionedSignedBeaconBlock decodes a signed block from versioned JSON: {"version": "deneb", "data": {...}}
+// The fork digest of the returned envelope is left zero, since the genesis validators root is not known.
+func UnmarshalVersionedSignedBeaconBlock(spec *common.Spec, data []byte) (OpaqueBlock, *common.BeaconBlockEnvelope, error) {
+	var v versionedJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, nil, err
+	}
+	block, err := NewSignedBeaconBlockOf(v.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(v.Data, block); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s block JSON: %w", v.Version, err)
+	}
+	return block, block.Envelope(spec, common.ForkDigest{}), nil
+}
@@ -0,0 +1,144 @@
+package beacon
+
+import (
+	"bytes"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func testGenesisState(t *testing.T, spec *common.Spec) *phase0.BeaconStateView {
+	state, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, testValidators(t, spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func testValidators(t *testing.T, spec *common.Spec) []phase0.KickstartValidatorData {
+	validators := make([]phase0.KickstartValidatorData, spec.SLOTS_PER_EPOCH)
+	for i := range validators {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = phase0.KickstartValidatorData{
+			Pubkey:  pub.Serialize(),
+			Balance: spec.MAX_EFFECTIVE_BALANCE,
+		}
+	}
+	return validators
+}
+
+func TestDecodeBeaconState(t *testing.T) {
+	spec := configs.Minimal
+	state := testGenesisState(t, spec)
+	expected := state.HashTreeRoot(tree.GetHashFn())
+
+	var buf bytes.Buffer
+	if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeBeaconState(spec, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*phase0.BeaconStateView); !ok {
+		t.Fatalf("unexpected state type %T", decoded)
+	}
+	if root := decoded.HashTreeRoot(tree.GetHashFn()); root != expected {
+		t.Fatalf("decoded state root %s does not match %s", root, expected)
+	}
+
+	data, err := MarshalVersionedBeaconState(spec, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(`{"version":"phase0","data":{`)) {
+		t.Fatalf("unexpected versioned JSON prefix: %.40s", data)
+	}
+	fromJSON, err := UnmarshalVersionedBeaconState(spec, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := fromJSON.HashTreeRoot(tree.GetHashFn()); root != expected {
+		t.Fatalf("JSON decoded state root %s does not match %s", root, expected)
+	}
+}
+
+func TestDecodeSignedBeaconBlock(t *testing.T) {
+	spec := configs.Minimal
+	block := &phase0.SignedBeaconBlock{
+		Message: phase0.BeaconBlock{Slot: 42, ProposerIndex: 3, ParentRoot: common.Root{0xaa}},
+	}
+	var buf bytes.Buffer
+	if err := block.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	decoded, env, err := DecodeSignedBeaconBlock(spec, buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork, err := BlockForkName(decoded); err != nil || fork != ForkNameAtSlot(spec, 42) {
+		t.Fatalf("unexpected block fork %q (%v)", fork, err)
+	}
+	if env.Slot != 42 || env.ProposerIndex != 3 || env.ParentRoot != (common.Root{0xaa}) {
+		t.Fatalf("unexpected envelope header: %v", env.BeaconBlockHeader)
+	}
+
+	data, err := MarshalVersionedSignedBeaconBlock(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, envJSON, err := UnmarshalVersionedSignedBeaconBlock(spec, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envJSON.BlockRoot != env.BlockRoot {
+		t.Fatalf("JSON decoded block root %s does not match %s", envJSON.BlockRoot, env.BlockRoot)
+	}
+}
+
+func TestDecodeCapellaBlockBodyDeposits(t *testing.T) {
+	spec := configs.Minimal
+	body := &capella.BeaconBlockBody{
+		Graffiti: common.Root{0x42},
+		Deposits: phase0.Deposits{{
+			Data: common.DepositData{Pubkey: common.BLSPubkey{0x01}, Amount: spec.MAX_EFFECTIVE_BALANCE},
+		}},
+		VoluntaryExits: phase0.VoluntaryExits{{
+			Message: phase0.VoluntaryExit{Epoch: 3, ValidatorIndex: 5},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := body.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	var decoded capella.BeaconBlockBody
+	if err := decoded.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Deposits) != 1 || decoded.Deposits[0].Data.Amount != spec.MAX_EFFECTIVE_BALANCE {
+		t.Fatalf("expected the deposit to be decoded, got %d deposits", len(decoded.Deposits))
+	}
+	if len(decoded.VoluntaryExits) != 1 || decoded.VoluntaryExits[0].Message.ValidatorIndex != 5 {
+		t.Fatalf("expected the voluntary exit to be decoded, got %d exits", len(decoded.VoluntaryExits))
+	}
+	hFn := tree.GetHashFn()
+	if decoded.HashTreeRoot(spec, hFn) != body.HashTreeRoot(spec, hFn) {
+		t.Fatal("decoded body root does not match")
+	}
+}
@@ -76,6 +76,19 @@ func ProcessAttestation(spec *common.Spec, epc *common.EpochsContext, state alta
 		return fmt.Errorf("attestation could not be verified in its indexed form: %v", err)
 	}
 
+	return ProcessAttestationParticipation(spec, epc, state, data, applyFlags, indexedAtt.AttestingIndices)
+}
+
+// ProcessAttestationParticipation applies the participation flags of a verified attestation to the attesting validators,
+// and rewards the proposer for the newly set flags.
+func ProcessAttestationParticipation(spec *common.Spec, epc *common.EpochsContext, state altair.AltairLikeBeaconState,
+	data *phase0.AttestationData, applyFlags altair.ParticipationFlags, attestingIndices []common.ValidatorIndex) error {
+	currentSlot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+	currentEpoch := spec.SlotToEpoch(currentSlot)
+
 	var epochParticipation *altair.ParticipationRegistryView
 	// Check source
 	if data.Target.Epoch == currentEpoch {
@@ -93,7 +106,7 @@ func ProcessAttestation(spec *common.Spec, epc *common.EpochsContext, state alta
 	// TODO: probably better to batch flag changes, needs optimization, tree structure not good for this.
 	proposerRewardNumerator := common.Gwei(0)
 	baseRewardPerIncrement := spec.EFFECTIVE_BALANCE_INCREMENT * common.Gwei(spec.BASE_REWARD_FACTOR) / epc.TotalActiveStakeSqRoot
-	for _, vi := range indexedAtt.AttestingIndices {
+	for _, vi := range attestingIndices {
 		if applyFlags == 0 { // no work to do, just skip ahead
 			continue
 		}
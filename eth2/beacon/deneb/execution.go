@@ -389,27 +389,28 @@ type NewPayloadRequest struct {
 }
 
 type ExecutionEngine interface {
-	DenebNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload, parentBeaconBlockRoot common.Root) (valid bool, err error)
+	DenebNotifyNewPayload(ctx context.Context, executionPayload *ExecutionPayload, parentBeaconBlockRoot common.Root) (status common.PayloadStatus, err error)
 	DenebIsValidVersionedHashes(ctx context.Context, payload *ExecutionPayload, versionedHashes []common.Hash32) (bool, error)
 	DenebIsValidBlockHash(ctx context.Context, payload *ExecutionPayload, parentBeaconBlockRoot common.Root) (bool, error)
 }
 
-func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (bool, error) {
+// VerifyAndNotifyNewPayload returns the status of the payload. SYNCING and ACCEPTED payloads may be imported optimistically.
+func VerifyAndNotifyNewPayload(ctx context.Context, eng ExecutionEngine, newPayloadRequest *NewPayloadRequest) (common.PayloadStatus, error) {
 	executionPayload := newPayloadRequest.ExecutionPayload
 	parentBeaconBlockRoot := newPayloadRequest.ParentBeaconBlockRoot
 
 	// Modified in Deneb
 	if ok, err := eng.DenebIsValidBlockHash(ctx, executionPayload, parentBeaconBlockRoot); err != nil {
-		return false, fmt.Errorf("failed to check block hash: %w", err)
+		return common.PayloadInvalid, fmt.Errorf("failed to check block hash: %w", err)
 	} else if !ok {
-		return false, nil
+		return common.PayloadInvalidBlockHash, nil
 	}
 
 	// New in Deneb
 	if ok, err := eng.DenebIsValidVersionedHashes(ctx, executionPayload, newPayloadRequest.VersionedHashes); err != nil {
-		return false, fmt.Errorf("failed to check blob versioned hashes: %w", err)
+		return common.PayloadInvalid, fmt.Errorf("failed to check blob versioned hashes: %w", err)
 	} else if !ok {
-		return false, nil
+		return common.PayloadInvalid, nil
 	}
 
 	return eng.DenebNotifyNewPayload(ctx, executionPayload, parentBeaconBlockRoot)
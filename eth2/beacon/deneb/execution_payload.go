@@ -8,62 +8,63 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 )
 
-func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state ExecutionTrackingBeaconState, body *BeaconBlockBody, engine ExecutionEngine) error {
+// ProcessExecutionPayload returns the status of the payload, like bellatrix.ProcessExecutionPayload.
+func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state ExecutionTrackingBeaconState, body *BeaconBlockBody, engine ExecutionEngine) (common.PayloadStatus, error) {
 	if err := ctx.Err(); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if engine == nil {
-		return errors.New("nil execution engine")
+		return common.PayloadUnknown, errors.New("nil execution engine")
 	}
 	payload := &body.ExecutionPayload
 
 	slot, err := state.Slot()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 
 	latestExecHeader, err := state.LatestExecutionPayloadHeader()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Verify consistency of the parent hash with respect to the previous execution payload header
 	parent, err := latestExecHeader.Raw()
 	if err != nil {
-		return fmt.Errorf("failed to read previous header: %v", err)
+		return common.PayloadUnknown, fmt.Errorf("failed to read previous header: %v", err)
 	}
 	if payload.ParentHash != parent.BlockHash {
-		return fmt.Errorf("expected parent hash %s in execution payload, but got %s",
+		return common.PayloadUnknown, fmt.Errorf("expected parent hash %s in execution payload, but got %s",
 			parent.BlockHash, payload.ParentHash)
 	}
 
 	// Verify prev_randao
 	mixes, err := state.RandaoMixes()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	expectedMix, err := mixes.GetRandomMix(spec.SlotToEpoch(slot))
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if payload.PrevRandao != expectedMix {
-		return fmt.Errorf("invalid random data %s, expected %s", payload.PrevRandao, expectedMix)
+		return common.PayloadUnknown, fmt.Errorf("invalid random data %s, expected %s", payload.PrevRandao, expectedMix)
 	}
 
 	// Verify timestamp
 	genesisTime, err := state.GenesisTime()
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if expectedTime, err := spec.TimeAtSlot(slot, genesisTime); err != nil {
-		return fmt.Errorf("slot or genesis time in state is corrupt, cannot compute time: %v", err)
+		return common.PayloadUnknown, fmt.Errorf("slot or genesis time in state is corrupt, cannot compute time: %v", err)
 	} else if payload.Timestamp != expectedTime {
-		return fmt.Errorf("state at slot %d, genesis time %d, expected execution payload time %d, but got %d",
+		return common.PayloadUnknown, fmt.Errorf("state at slot %d, genesis time %d, expected execution payload time %d, but got %d",
 			slot, genesisTime, expectedTime, payload.Timestamp)
 	}
 
 	// [New in Deneb:EIP4844] Verify commitments are under limit
 	if uint64(len(body.BlobKZGCommitments)) > uint64(spec.MAX_BLOBS_PER_BLOCK) {
-		return fmt.Errorf("too many blob KZG commitments: %d", len(body.BlobKZGCommitments))
+		return common.PayloadUnknown, fmt.Errorf("too many blob KZG commitments: %d", len(body.BlobKZGCommitments))
 	}
 
 	// Verify the execution payload is valid
@@ -75,19 +76,25 @@ func ProcessExecutionPayload(ctx context.Context, spec *common.Spec, state Execu
 	}
 	latestHeader, err := state.LatestBlockHeader()
 	if err != nil {
-		return fmt.Errorf("failed to get current in-progresss latest beacon-block-header from beacon state: %w", err)
+		return common.PayloadUnknown, fmt.Errorf("failed to get current in-progresss latest beacon-block-header from beacon state: %w", err)
 	}
-	if valid, err := VerifyAndNotifyNewPayload(ctx, engine, &NewPayloadRequest{
+	status, err := VerifyAndNotifyNewPayload(ctx, engine, &NewPayloadRequest{
 		ExecutionPayload:      payload,
 		VersionedHashes:       versionedHashes,
 		ParentBeaconBlockRoot: latestHeader.ParentRoot,
-	}); err != nil {
-		return fmt.Errorf("unexpected problem in execution engine when inserting block %s (height %d), err: %v",
+	})
+	if err != nil {
+		return common.PayloadUnknown, fmt.Errorf("unexpected problem in execution engine when inserting block %s (height %d), err: %v",
 			payload.BlockHash, payload.BlockNumber, err)
-	} else if !valid {
-		return fmt.Errorf("execution engine says payload is invalid: %s (height %d)",
+	} else if status.IsInvalid() {
+		return status, fmt.Errorf("execution engine says payload is invalid (%s): %s (height %d)", status,
 			payload.BlockHash, payload.BlockNumber)
+	} else if status == common.PayloadUnknown {
+		return common.PayloadUnknown, fmt.Errorf("execution engine returned no status for payload %s (height %d)", payload.BlockHash, payload.BlockNumber)
 	}
 
-	return state.SetLatestExecutionPayloadHeader(payload.Header(spec))
+	if err := state.SetLatestExecutionPayloadHeader(payload.Header(spec)); err != nil {
+		return common.PayloadUnknown, err
+	}
+	return status, nil
 }
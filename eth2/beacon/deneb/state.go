@@ -476,6 +476,15 @@ func (state *BeaconStateView) InactivityScores() (*altair.InactivityScoresView,
 	return altair.AsInactivityScores(state.Get(_inactivityScores))
 }
 
+func (state *BeaconStateView) SetInactivityScores(scores []uint64) error {
+	typ := state.Fields[_inactivityScores].Type.(*BasicListTypeDef)
+	scoresView, err := altair.NewInactivityScoresView(scores, typ.ListLimit)
+	if err != nil {
+		return err
+	}
+	return state.Set(_inactivityScores, scoresView)
+}
+
 func (state *BeaconStateView) LatestExecutionPayloadHeader() (*ExecutionPayloadHeaderView, error) {
 	return AsExecutionPayloadHeader(state.Get(_latestExecutionPayloadHeader))
 }
@@ -522,6 +531,7 @@ func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettin
 		CalcProposerShare: func(whistleblowerReward common.Gwei) common.Gwei {
 			return whistleblowerReward * altair.PROPOSER_WEIGHT / altair.WEIGHT_DENOMINATOR
 		},
+		MaxEffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
 	}
 }
 
@@ -540,7 +550,7 @@ func (state *BeaconStateView) Raw(spec *common.Spec) (*BeaconState, error) {
 }
 
 func (state *BeaconStateView) CopyState() (common.BeaconState, error) {
-	return altair.AsBeaconStateView(state.ContainerView.Copy())
+	return AsBeaconStateView(state.ContainerView.Copy())
 }
 
 type ExecutionTrackingBeaconState interface {
@@ -0,0 +1,93 @@
+package eip7594
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+)
+
+type CustodyIndex uint64
+
+// GetCustodyGroups returns the custody groups of the node, sorted, as derived from its node ID.
+func GetCustodyGroups(spec *common.Spec, nodeID common.NodeID, custodyGroupCount uint64) ([]CustodyIndex, error) {
+	groupCount := uint64(spec.NUMBER_OF_CUSTODY_GROUPS)
+	if custodyGroupCount > groupCount {
+		return nil, fmt.Errorf("custody group count %d exceeds the number of custody groups %d", custodyGroupCount, groupCount)
+	}
+	groups := make([]CustodyIndex, 0, custodyGroupCount)
+	// Skip computation if all groups are custodied
+	if custodyGroupCount == groupCount {
+		for i := uint64(0); i < groupCount; i++ {
+			groups = append(groups, CustodyIndex(i))
+		}
+		return groups, nil
+	}
+	seen := make(map[CustodyIndex]struct{}, custodyGroupCount)
+	current := nodeID
+	var input [32]byte
+	for uint64(len(groups)) < custodyGroupCount {
+		// the node ID is hashed as little-endian uint256
+		for i := 0; i < 32; i++ {
+			input[i] = current[31-i]
+		}
+		h := hashing.Hash(input[:])
+		group := CustodyIndex(binary.LittleEndian.Uint64(h[:8]) % groupCount)
+		if _, ok := seen[group]; !ok {
+			seen[group] = struct{}{}
+			groups = append(groups, group)
+		}
+		// increment, wrapping around to 0 after UINT256_MAX
+		for i := 31; i >= 0; i-- {
+			current[i] += 1
+			if current[i] != 0 {
+				break
+			}
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i] < groups[j]
+	})
+	return groups, nil
+}
+
+// ComputeColumnsForCustodyGroup returns the columns of the custody group, in ascending order.
+func ComputeColumnsForCustodyGroup(spec *common.Spec, custodyGroup CustodyIndex) ([]ColumnIndex, error) {
+	groupCount := uint64(spec.NUMBER_OF_CUSTODY_GROUPS)
+	if uint64(custodyGroup) >= groupCount {
+		return nil, fmt.Errorf("custody group %d out of range", custodyGroup)
+	}
+	columnsPerGroup := uint64(spec.NUMBER_OF_COLUMNS) / groupCount
+	columns := make([]ColumnIndex, 0, columnsPerGroup)
+	for i := uint64(0); i < columnsPerGroup; i++ {
+		columns = append(columns, ColumnIndex(groupCount*i+uint64(custodyGroup)))
+	}
+	return columns, nil
+}
+
+// GetCustodyColumns returns all the columns the node custodies, sorted.
+func GetCustodyColumns(spec *common.Spec, nodeID common.NodeID, custodyGroupCount uint64) ([]ColumnIndex, error) {
+	groups, err := GetCustodyGroups(spec, nodeID, custodyGroupCount)
+	if err != nil {
+		return nil, err
+	}
+	var columns []ColumnIndex
+	for _, g := range groups {
+		groupColumns, err := ComputeColumnsForCustodyGroup(spec, g)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, groupColumns...)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i] < columns[j]
+	})
+	return columns, nil
+}
+
+// ComputeSubnetForDataColumnSidecar returns the gossip subnet that the sidecar of the column is published on.
+func ComputeSubnetForDataColumnSidecar(spec *common.Spec, column ColumnIndex) uint64 {
+	return uint64(column) % uint64(spec.DATA_COLUMN_SIDECAR_SUBNET_COUNT)
+}
@@ -0,0 +1,80 @@
+package eip7594
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestCustodyGroups(t *testing.T) {
+	spec := *configs.Mainnet
+	max := common.NodeID{}
+	for i := range max {
+		max[i] = 0xff
+	}
+	for _, nodeID := range []common.NodeID{{}, {0x12, 0x34}, max} {
+		for _, count := range []uint64{0, 1, uint64(spec.CUSTODY_REQUIREMENT), 100, uint64(spec.NUMBER_OF_CUSTODY_GROUPS)} {
+			groups, err := GetCustodyGroups(&spec, nodeID, count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if uint64(len(groups)) != count {
+				t.Fatalf("expected %d groups, got %d", count, len(groups))
+			}
+			for i, g := range groups {
+				if uint64(g) >= uint64(spec.NUMBER_OF_CUSTODY_GROUPS) {
+					t.Fatalf("custody group %d out of range", g)
+				}
+				if i > 0 && groups[i-1] >= g {
+					t.Fatalf("custody groups not sorted and unique: %v", groups)
+				}
+			}
+			// a larger custody is a superset of a smaller one
+			if count > 0 {
+				smaller, err := GetCustodyGroups(&spec, nodeID, count-1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, g := range smaller {
+					if i := sort.Search(len(groups), func(i int) bool { return groups[i] >= g }); i == len(groups) || groups[i] != g {
+						t.Fatalf("custody group %d missing from larger custody", g)
+					}
+				}
+			}
+		}
+	}
+	if _, err := GetCustodyGroups(&spec, common.NodeID{}, uint64(spec.NUMBER_OF_CUSTODY_GROUPS)+1); err == nil {
+		t.Fatal("expected error for too many custody groups")
+	}
+}
+
+func TestCustodyColumns(t *testing.T) {
+	spec := *configs.Mainnet
+	// multiple columns per group
+	spec.NUMBER_OF_CUSTODY_GROUPS = 32
+	columns, err := ComputeColumnsForCustodyGroup(&spec, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 4 || columns[0] != 5 || columns[1] != 37 || columns[3] != 101 {
+		t.Fatalf("unexpected columns %v", columns)
+	}
+	all, err := GetCustodyColumns(&spec, common.NodeID{0x42}, uint64(spec.NUMBER_OF_CUSTODY_GROUPS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(all)) != uint64(spec.NUMBER_OF_COLUMNS) {
+		t.Fatalf("expected full custody of %d columns, got %d", spec.NUMBER_OF_COLUMNS, len(all))
+	}
+	for i, c := range all {
+		if c != ColumnIndex(i) {
+			t.Fatalf("unexpected column %d at %d", c, i)
+		}
+	}
+	spec.DATA_COLUMN_SIDECAR_SUBNET_COUNT = 32
+	if subnet := ComputeSubnetForDataColumnSidecar(&spec, 101); subnet != 5 {
+		t.Fatalf("unexpected subnet %d", subnet)
+	}
+}
@@ -0,0 +1,386 @@
+package eip7594
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/conv"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+const BYTES_PER_FIELD_ELEMENT = 32
+
+// BlobKZGCommitmentsIndex is the field index of blob_kzg_commitments in the block body,
+// the same in the Deneb and Electra block bodies.
+const BlobKZGCommitmentsIndex = 9
+
+type ColumnIndex Uint64View
+
+func (i *ColumnIndex) Deserialize(dr *codec.DecodingReader) error {
+	return (*Uint64View)(i).Deserialize(dr)
+}
+
+func (i ColumnIndex) Serialize(w *codec.EncodingWriter) error {
+	return w.WriteUint64(uint64(i))
+}
+
+func (ColumnIndex) ByteLength() uint64 {
+	return 8
+}
+
+func (ColumnIndex) FixedLength() uint64 {
+	return 8
+}
+
+func (i ColumnIndex) HashTreeRoot(hFn tree.HashFn) common.Root {
+	return Uint64View(i).HashTreeRoot(hFn)
+}
+
+func (i ColumnIndex) MarshalJSON() ([]byte, error) {
+	return Uint64View(i).MarshalJSON()
+}
+
+func (i *ColumnIndex) UnmarshalJSON(b []byte) error {
+	return ((*Uint64View)(i)).UnmarshalJSON(b)
+}
+
+func (i ColumnIndex) String() string {
+	return Uint64View(i).String()
+}
+
+const ColumnIndexType = Uint64Type
+
+// CellSize is the byte size of a cell: FIELD_ELEMENTS_PER_CELL field elements of an extended blob.
+func CellSize(spec *common.Spec) uint64 {
+	return BYTES_PER_FIELD_ELEMENT * uint64(spec.FIELD_ELEMENTS_PER_CELL)
+}
+
+// Cell is a ByteVector[BYTES_PER_FIELD_ELEMENT * FIELD_ELEMENTS_PER_CELL]
+type Cell []byte
+
+func (c *Cell) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	*c = make(Cell, CellSize(spec))
+	_, err := dr.Read(*c)
+	return err
+}
+
+func (c Cell) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	if uint64(len(c)) != CellSize(spec) {
+		return fmt.Errorf("invalid cell size: %d", len(c))
+	}
+	return w.Write(c)
+}
+
+func (c Cell) ByteLength(spec *common.Spec) uint64 {
+	return CellSize(spec)
+}
+
+func (c *Cell) FixedLength(spec *common.Spec) uint64 {
+	return CellSize(spec)
+}
+
+func (c Cell) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.ByteVectorHTR(c)
+}
+
+func (c Cell) MarshalText() ([]byte, error) {
+	return conv.BytesMarshalText(c[:])
+}
+
+func (c *Cell) UnmarshalText(text []byte) error {
+	if c == nil {
+		return errors.New("cannot decode into nil cell")
+	}
+	return conv.DynamicBytesUnmarshalText((*[]byte)(c), text[:])
+}
+
+func CellType(spec *common.Spec) *BasicVectorTypeDef {
+	return BasicVectorType(ByteType, CellSize(spec))
+}
+
+// DataColumn is the column of cells, one cell per blob of the block.
+type DataColumn []Cell
+
+func (li *DataColumn) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*li)
+		*li = append(*li, Cell{})
+		return spec.Wrap(&((*li)[i]))
+	}, CellSize(spec), uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+func (li DataColumn) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return spec.Wrap(&li[i])
+	}, CellSize(spec), uint64(len(li)))
+}
+
+func (li DataColumn) ByteLength(spec *common.Spec) (out uint64) {
+	return CellSize(spec) * uint64(len(li))
+}
+
+func (*DataColumn) FixedLength(*common.Spec) uint64 {
+	return 0
+}
+
+func (li DataColumn) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return spec.Wrap(&li[i])
+		}
+		return nil
+	}, length, uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+func DataColumnType(spec *common.Spec) *ComplexListTypeDef {
+	return ComplexListType(CellType(spec), uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+type KZGProofs []common.KZGProof
+
+func (li *KZGProofs) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*li)
+		*li = append(*li, common.KZGProof{})
+		return &((*li)[i])
+	}, common.KZGProofSize, uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+func (li KZGProofs) Serialize(_ *common.Spec, w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return &li[i]
+	}, common.KZGProofSize, uint64(len(li)))
+}
+
+func (li KZGProofs) ByteLength(_ *common.Spec) (out uint64) {
+	return common.KZGProofSize * uint64(len(li))
+}
+
+func (*KZGProofs) FixedLength(*common.Spec) uint64 {
+	return 0
+}
+
+func (li KZGProofs) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &li[i]
+		}
+		return nil
+	}, length, uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+func KZGProofsType(spec *common.Spec) *ComplexListTypeDef {
+	return ComplexListType(common.KZGProofType, uint64(spec.MAX_BLOB_COMMITMENTS_PER_BLOCK))
+}
+
+// KZGCommitmentsInclusionProof is the merkle branch of the blob_kzg_commitments in the block body.
+// It represents a Vector[Bytes32, KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH]
+type KZGCommitmentsInclusionProof []common.Root
+
+func (p *KZGCommitmentsInclusionProof) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return tree.ReadRoots(dr, (*[]common.Root)(p), uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH))
+}
+
+func (p KZGCommitmentsInclusionProof) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	if uint64(len(p)) != uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH) {
+		return fmt.Errorf("invalid inclusion proof length: %d", len(p))
+	}
+	return tree.WriteRoots(w, p)
+}
+
+func (p KZGCommitmentsInclusionProof) ByteLength(spec *common.Spec) uint64 {
+	return uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH) * 32
+}
+
+func (p *KZGCommitmentsInclusionProof) FixedLength(spec *common.Spec) uint64 {
+	return uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH) * 32
+}
+
+func (p KZGCommitmentsInclusionProof) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(p))
+	return hFn.ComplexVectorHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &p[i]
+		}
+		return nil
+	}, length)
+}
+
+func KZGCommitmentsInclusionProofType(spec *common.Spec) VectorTypeDef {
+	return VectorType(RootType, uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH))
+}
+
+// ComputeKZGCommitmentsInclusionProof builds the inclusion proof of the blob_kzg_commitments
+// from a view of the block body (Deneb or Electra).
+func ComputeKZGCommitmentsInclusionProof(spec *common.Spec, body View) (KZGCommitmentsInclusionProof, error) {
+	proof, err := merkle.ProveView(body, "blob_kzg_commitments")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove blob kzg commitments: %w", err)
+	}
+	if uint64(len(proof.Branch)) != uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH) {
+		return nil, fmt.Errorf("unexpected inclusion proof depth %d", len(proof.Branch))
+	}
+	return proof.Branch, nil
+}
+
+type DataColumnSidecar struct {
+	// Index of the column in the extended matrix
+	Index                        ColumnIndex                    `json:"index" yaml:"index"`
+	Column                       DataColumn                     `json:"column" yaml:"column"`
+	KZGCommitments               deneb.KZGCommitments           `json:"kzg_commitments" yaml:"kzg_commitments"`
+	KZGProofs                    KZGProofs                      `json:"kzg_proofs" yaml:"kzg_proofs"`
+	SignedBlockHeader            common.SignedBeaconBlockHeader `json:"signed_block_header" yaml:"signed_block_header"`
+	KZGCommitmentsInclusionProof KZGCommitmentsInclusionProof   `json:"kzg_commitments_inclusion_proof" yaml:"kzg_commitments_inclusion_proof"`
+}
+
+func (sc *DataColumnSidecar) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(
+		&sc.Index, spec.Wrap(&sc.Column),
+		spec.Wrap(&sc.KZGCommitments), spec.Wrap(&sc.KZGProofs),
+		&sc.SignedBlockHeader, spec.Wrap(&sc.KZGCommitmentsInclusionProof),
+	)
+}
+
+func (sc *DataColumnSidecar) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.Container(
+		&sc.Index, spec.Wrap(&sc.Column),
+		spec.Wrap(&sc.KZGCommitments), spec.Wrap(&sc.KZGProofs),
+		&sc.SignedBlockHeader, spec.Wrap(&sc.KZGCommitmentsInclusionProof),
+	)
+}
+
+func (sc *DataColumnSidecar) ByteLength(spec *common.Spec) uint64 {
+	return codec.ContainerLength(
+		&sc.Index, spec.Wrap(&sc.Column),
+		spec.Wrap(&sc.KZGCommitments), spec.Wrap(&sc.KZGProofs),
+		&sc.SignedBlockHeader, spec.Wrap(&sc.KZGCommitmentsInclusionProof),
+	)
+}
+
+func (sc *DataColumnSidecar) FixedLength(*common.Spec) uint64 {
+	return 0
+}
+
+func (sc *DataColumnSidecar) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(
+		sc.Index, spec.Wrap(&sc.Column),
+		spec.Wrap(&sc.KZGCommitments), spec.Wrap(&sc.KZGProofs),
+		&sc.SignedBlockHeader, spec.Wrap(&sc.KZGCommitmentsInclusionProof),
+	)
+}
+
+// Verify checks the sidecar is well-formed: the index is in range,
+// and there is a cell and a proof for every commitment.
+// The KZG cell proofs themselves are not verified here, this requires the KZG trusted setup.
+func (sc *DataColumnSidecar) Verify(spec *common.Spec) error {
+	if uint64(sc.Index) >= uint64(spec.NUMBER_OF_COLUMNS) {
+		return fmt.Errorf("column index %d out of range", sc.Index)
+	}
+	if len(sc.KZGCommitments) == 0 {
+		return errors.New("data column sidecar has no kzg commitments")
+	}
+	if len(sc.Column) != len(sc.KZGCommitments) || len(sc.KZGProofs) != len(sc.KZGCommitments) {
+		return fmt.Errorf("mismatching data column sidecar lengths: %d cells, %d commitments, %d proofs",
+			len(sc.Column), len(sc.KZGCommitments), len(sc.KZGProofs))
+	}
+	return nil
+}
+
+// VerifyInclusionProof checks the KZG commitments against the body root of the signed block header.
+func (sc *DataColumnSidecar) VerifyInclusionProof(spec *common.Spec) error {
+	depth := uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH)
+	if uint64(len(sc.KZGCommitmentsInclusionProof)) != depth {
+		return fmt.Errorf("invalid inclusion proof length: %d", len(sc.KZGCommitmentsInclusionProof))
+	}
+	leaf := sc.KZGCommitments.HashTreeRoot(spec, tree.GetHashFn())
+	if !merkle.VerifyMerkleBranch(leaf, sc.KZGCommitmentsInclusionProof, depth,
+		BlobKZGCommitmentsIndex, sc.SignedBlockHeader.Message.BodyRoot) {
+		return errors.New("invalid kzg commitments inclusion proof")
+	}
+	return nil
+}
+
+func DataColumnSidecarType(spec *common.Spec) *ContainerTypeDef {
+	return ContainerType("DataColumnSidecar", []FieldDef{
+		{"index", ColumnIndexType},
+		{"column", DataColumnType(spec)},
+		{"kzg_commitments", deneb.KZGCommitmentsType(spec)},
+		{"kzg_proofs", KZGProofsType(spec)},
+		{"signed_block_header", common.SignedBeaconBlockHeaderType},
+		{"kzg_commitments_inclusion_proof", KZGCommitmentsInclusionProofType(spec)},
+	})
+}
+
+// GetDataColumnSidecars transposes the cells and proofs of the blobs of a block into a sidecar per column.
+// The cells and proofs are ordered per blob, like the commitments, with NUMBER_OF_COLUMNS entries per blob.
+func GetDataColumnSidecars(spec *common.Spec, signedBlockHeader *common.SignedBeaconBlockHeader,
+	commitments deneb.KZGCommitments, inclusionProof KZGCommitmentsInclusionProof,
+	cells [][]Cell, proofs [][]common.KZGProof) ([]DataColumnSidecar, error) {
+	if len(cells) != len(commitments) || len(proofs) != len(commitments) {
+		return nil, fmt.Errorf("got cells of %d blobs and proofs of %d blobs, but %d commitments",
+			len(cells), len(proofs), len(commitments))
+	}
+	columnCount := uint64(spec.NUMBER_OF_COLUMNS)
+	for i := range cells {
+		if uint64(len(cells[i])) != columnCount || uint64(len(proofs[i])) != columnCount {
+			return nil, fmt.Errorf("blob %d: expected %d cells and proofs, got %d cells and %d proofs",
+				i, columnCount, len(cells[i]), len(proofs[i]))
+		}
+	}
+	sidecars := make([]DataColumnSidecar, 0, columnCount)
+	for c := uint64(0); c < columnCount; c++ {
+		column := make(DataColumn, 0, len(cells))
+		columnProofs := make(KZGProofs, 0, len(proofs))
+		for i := range cells {
+			column = append(column, cells[i][c])
+			columnProofs = append(columnProofs, proofs[i][c])
+		}
+		sidecars = append(sidecars, DataColumnSidecar{
+			Index:                        ColumnIndex(c),
+			Column:                       column,
+			KZGCommitments:               commitments,
+			KZGProofs:                    columnProofs,
+			SignedBlockHeader:            *signedBlockHeader,
+			KZGCommitmentsInclusionProof: inclusionProof,
+		})
+	}
+	return sidecars, nil
+}
+
+type DataColumnIdentifier struct {
+	BlockRoot common.Root `json:"block_root" yaml:"block_root"`
+	Index     ColumnIndex `json:"index" yaml:"index"`
+}
+
+func (d *DataColumnIdentifier) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&d.BlockRoot, &d.Index)
+}
+
+func (d *DataColumnIdentifier) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&d.BlockRoot, &d.Index)
+}
+
+func (d *DataColumnIdentifier) ByteLength() uint64 {
+	return 32 + 8
+}
+
+func (d *DataColumnIdentifier) FixedLength() uint64 {
+	return 32 + 8
+}
+
+func (d *DataColumnIdentifier) HashTreeRoot(hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(d.BlockRoot, d.Index)
+}
+
+var DataColumnIdentifierType = ContainerType("DataColumnIdentifier", []FieldDef{
+	{"block_root", RootType},
+	{"index", ColumnIndexType},
+})
@@ -0,0 +1,120 @@
+package eip7594
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+func TestBlobKZGCommitmentsGindex(t *testing.T) {
+	spec := configs.Mainnet
+	depth := uint64(spec.KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH)
+	for name, typ := range map[string]view.TypeDef{
+		"deneb":   deneb.BeaconBlockBodyType(spec),
+		"electra": electra.BeaconBlockBodyType(spec),
+	} {
+		gindex, _, err := merkle.PathGindex(typ, "blob_kzg_commitments")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := tree.Gindex64(1<<depth | BlobKZGCommitmentsIndex); gindex != expected {
+			t.Fatalf("%s: expected gindex %d, got %d", name, expected, gindex)
+		}
+	}
+}
+
+func TestDataColumnSidecars(t *testing.T) {
+	spec := configs.Minimal
+	body := &electra.BeaconBlockBody{
+		BlobKZGCommitments: deneb.KZGCommitments{{0xaa}, {0xbb}},
+	}
+	var buf bytes.Buffer
+	if err := body.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	bodyView, err := electra.BeaconBlockBodyType(spec).Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inclusionProof, err := ComputeKZGCommitmentsInclusionProof(spec, bodyView)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &common.SignedBeaconBlockHeader{
+		Message: common.BeaconBlockHeader{Slot: 3, BodyRoot: body.HashTreeRoot(spec, tree.GetHashFn())},
+	}
+	columnCount := int(spec.NUMBER_OF_COLUMNS)
+	cells := make([][]Cell, len(body.BlobKZGCommitments))
+	proofs := make([][]common.KZGProof, len(body.BlobKZGCommitments))
+	for i := range cells {
+		for c := 0; c < columnCount; c++ {
+			cell := make(Cell, CellSize(spec))
+			cell[0], cell[1] = byte(i), byte(c)
+			cells[i] = append(cells[i], cell)
+			proofs[i] = append(proofs[i], common.KZGProof{byte(i), byte(c)})
+		}
+	}
+	sidecars, err := GetDataColumnSidecars(spec, header, body.BlobKZGCommitments, inclusionProof, cells, proofs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecars) != columnCount {
+		t.Fatalf("expected %d sidecars, got %d", columnCount, len(sidecars))
+	}
+	for c := range sidecars {
+		sc := &sidecars[c]
+		if err := sc.Verify(spec); err != nil {
+			t.Fatalf("column %d: %v", c, err)
+		}
+		if err := sc.VerifyInclusionProof(spec); err != nil {
+			t.Fatalf("column %d: %v", c, err)
+		}
+		if sc.Column[1][0] != 1 || sc.Column[1][1] != byte(c) || sc.KZGProofs[0][1] != byte(c) {
+			t.Fatalf("column %d: cells and proofs are not transposed", c)
+		}
+	}
+
+	// SSZ round-trip, and the hash-tree-root matches the type definition
+	sc := &sidecars[5]
+	buf.Reset()
+	if err := sc.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if uint64(buf.Len()) != sc.ByteLength(spec) {
+		t.Fatalf("expected %d bytes, got %d", sc.ByteLength(spec), buf.Len())
+	}
+	var decoded DataColumnSidecar
+	if err := decoded.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, sc) {
+		t.Fatal("decoded sidecar does not match")
+	}
+	scView, err := DataColumnSidecarType(spec).Deserialize(codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := sc.HashTreeRoot(spec, tree.GetHashFn()); root != scView.HashTreeRoot(tree.GetHashFn()) {
+		t.Fatal("sidecar hash-tree-root does not match the type definition")
+	}
+
+	// the commitments must be the ones of the block
+	sc.KZGCommitments = deneb.KZGCommitments{{0xaa}, {0xcc}}
+	if err := sc.VerifyInclusionProof(spec); err == nil {
+		t.Fatal("expected inclusion proof of other commitments to fail")
+	}
+	sc.Index = ColumnIndex(spec.NUMBER_OF_COLUMNS)
+	if err := sc.Verify(spec); err == nil {
+		t.Fatal("expected out of range column index to fail")
+	}
+}
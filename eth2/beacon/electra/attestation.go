@@ -1,9 +1,15 @@
 package electra
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -93,3 +99,125 @@ func (li AttestationsElectra) MarshalJSON() ([]byte, error) {
 	}
 	return json.Marshal([]AttestationElectra(li))
 }
+
+// CommitteeIndices returns the indices of the committees that are set in the committee bits, in order.
+func (attestation *AttestationElectra) CommitteeIndices() []common.CommitteeIndex {
+	bitLen := attestation.CommitteeBits.BitLen()
+	out := make([]common.CommitteeIndex, 0, bitLen)
+	for i := uint64(0); i < bitLen; i++ {
+		if attestation.CommitteeBits.GetBit(i) {
+			out = append(out, common.CommitteeIndex(i))
+		}
+	}
+	return out
+}
+
+// ConvertToIndexed converts the attestation, with aggregation bits spanning the concatenated committees of the
+// committee bits (EIP-7549), to an indexed attestation.
+func (attestation *AttestationElectra) ConvertToIndexed(spec *common.Spec, epc *common.EpochsContext) (*IndexedAttestationElectra, error) {
+	data := &attestation.Data
+	commCount, err := epc.GetCommitteeCountPerSlot(data.Target.Epoch)
+	if err != nil {
+		return nil, err
+	}
+	committeeIndices := attestation.CommitteeIndices()
+	if len(committeeIndices) == 0 {
+		return nil, errors.New("attestation has no committee bits set")
+	}
+	bitLen := attestation.AggregationBits.BitLen()
+	var participants []common.ValidatorIndex
+	offset := uint64(0)
+	for _, index := range committeeIndices {
+		if uint64(index) >= commCount {
+			return nil, fmt.Errorf("committee index %d out of range, expected less than %d", index, commCount)
+		}
+		committee, err := epc.GetBeaconCommittee(data.Slot, index)
+		if err != nil {
+			return nil, err
+		}
+		if offset+uint64(len(committee)) > bitLen {
+			return nil, fmt.Errorf("aggregation bits too short for committee %d: %d bits", index, bitLen)
+		}
+		count := 0
+		for i, vi := range committee {
+			if attestation.AggregationBits.GetBit(offset + uint64(i)) {
+				participants = append(participants, vi)
+				count++
+			}
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("no attesters in committee %d", index)
+		}
+		offset += uint64(len(committee))
+	}
+	if offset != bitLen {
+		return nil, fmt.Errorf("committees size does not match bits size: %d <> %d", offset, bitLen)
+	}
+	sort.Slice(participants, func(i int, j int) bool {
+		return participants[i] < participants[j]
+	})
+	return &IndexedAttestationElectra{
+		AttestingIndices: participants,
+		Data:             attestation.Data,
+		Signature:        attestation.Signature,
+	}, nil
+}
+
+func ProcessAttestations(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state altair.AltairLikeBeaconState, ops []AttestationElectra) error {
+	for i := range ops {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ProcessAttestation(spec, epc, state, &ops[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ProcessAttestation(spec *common.Spec, epc *common.EpochsContext, state altair.AltairLikeBeaconState, attestation *AttestationElectra) error {
+	data := &attestation.Data
+
+	currentSlot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+
+	currentEpoch := spec.SlotToEpoch(currentSlot)
+	previousEpoch := currentEpoch.Previous()
+
+	// Check target
+	if data.Target.Epoch < previousEpoch {
+		return errors.New("attestation data is invalid, target is too far in past")
+	} else if data.Target.Epoch > currentEpoch {
+		return errors.New("attestation data is invalid, target is in future")
+	}
+	// And if it matches the slot
+	if data.Target.Epoch != spec.SlotToEpoch(data.Slot) {
+		return errors.New("attestation data is invalid, slot epoch does not match target epoch")
+	}
+	if !(data.Slot+spec.MIN_ATTESTATION_INCLUSION_DELAY <= currentSlot) {
+		return errors.New("attestation is too new")
+	}
+
+	// Modified in Electra: the committees are in the committee bits, the index in the data must be 0
+	if data.Index != 0 {
+		return fmt.Errorf("attestation data index must be 0, got %d", data.Index)
+	}
+
+	// Note: this checks the source checkpoint.
+	applyFlags, err := deneb.GetApplicableAttestationParticipationFlags(spec, state, data, currentSlot-data.Slot)
+	if err != nil {
+		return err
+	}
+
+	// Check signature and bitfields
+	indexedAtt, err := attestation.ConvertToIndexed(spec, epc)
+	if err != nil {
+		return fmt.Errorf("attestation could not be converted to an indexed attestation: %v", err)
+	} else if err := ValidateIndexedAttestation(spec, epc, state, indexedAtt); err != nil {
+		return fmt.Errorf("attestation could not be verified in its indexed form: %v", err)
+	}
+
+	return deneb.ProcessAttestationParticipation(spec, epc, state, data, applyFlags, indexedAtt.AttestingIndices)
+}
@@ -13,7 +13,7 @@ import (
 	. "github.com/protolambda/ztyp/view"
 )
 
-func ProcessAttesterSlashings(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, ops []AttesterSlashingElectra) error {
+func ProcessAttesterSlashings(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, ops []AttesterSlashingElectra) error {
 	for i := range ops {
 		if err := ctx.Err(); err != nil {
 			return err
@@ -105,7 +105,7 @@ func (li AttesterSlashingsElectra) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]AttesterSlashingElectra(li))
 }
 
-func ProcessAttesterSlashing(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, attesterSlashing *AttesterSlashingElectra) error {
+func ProcessAttesterSlashing(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, attesterSlashing *AttesterSlashingElectra) error {
 	sa1 := &attesterSlashing.Attestation1
 	sa2 := &attesterSlashing.Attestation2
 
@@ -144,7 +144,7 @@ func ProcessAttesterSlashing(spec *common.Spec, epc *common.EpochsContext, state
 		if slashable, err := phase0.IsSlashable(validator, currentEpoch); err != nil {
 			errorAny = err
 		} else if slashable {
-			if err := phase0.SlashValidator(spec, epc, state, i, nil); err != nil {
+			if err := SlashValidator(spec, epc, state, i, nil); err != nil {
 				errorAny = err
 			} else {
 				slashedAny = true
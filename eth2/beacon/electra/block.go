@@ -188,7 +188,7 @@ func (b *BeaconBlockBody) CheckLimits(spec *common.Spec) error {
 	if x := uint64(len(b.AttesterSlashings)); x > uint64(spec.MAX_ATTESTER_SLASHINGS) {
 		return fmt.Errorf("too many attester slashings: %d", x)
 	}
-	if x := uint64(len(b.Attestations)); x > uint64(spec.MAX_ATTESTATIONS) {
+	if x := uint64(len(b.Attestations)); x > uint64(spec.MAX_ATTESTATIONS_ALPACA) {
 		return fmt.Errorf("too many attestations: %d", x)
 	}
 	if x := uint64(len(b.Deposits)); x > uint64(spec.MAX_DEPOSITS) {
@@ -210,6 +210,9 @@ func (b *BeaconBlockBody) CheckLimits(spec *common.Spec) error {
 	if x := uint64(len(b.ExecutionRequests.Withdrawals)); x > uint64(spec.MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD) {
 		return fmt.Errorf("too many withdrawal requests: %d", x)
 	}
+	if x := uint64(len(b.ExecutionRequests.Consolidations)); x > uint64(spec.MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD) {
+		return fmt.Errorf("too many consolidation requests: %d", x)
+	}
 	return nil
 }
 
@@ -0,0 +1,237 @@
+package electra
+
+import (
+	"context"
+	"errors"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type ExitChurnBeaconState interface {
+	common.BeaconState
+	ExitBalanceToConsume() (common.Gwei, error)
+	SetExitBalanceToConsume(v common.Gwei) error
+	EarliestExitEpoch() (common.Epoch, error)
+	SetEarliestExitEpoch(v common.Epoch) error
+}
+
+// GetBalanceChurnLimit returns the churn per epoch, in Gwei, for the given total active balance.
+func GetBalanceChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	churn := totalActiveBalance / common.Gwei(spec.CHURN_LIMIT_QUOTIENT)
+	if churn < spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA {
+		churn = spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA
+	}
+	return churn - churn%spec.EFFECTIVE_BALANCE_INCREMENT
+}
+
+// GetActivationExitChurnLimit returns the churn per epoch, in Gwei, that is available to exits.
+func GetActivationExitChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	return min(spec.MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT, GetBalanceChurnLimit(spec, totalActiveBalance))
+}
+
+// ComputeExitEpochAndUpdateChurn consumes exitBalance of the exit churn,
+// and returns the epoch in which the exit of that balance can happen.
+// Balances larger than the remaining churn of an epoch spill over into the next epochs.
+func ComputeExitEpochAndUpdateChurn(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, exitBalance common.Gwei) (common.Epoch, error) {
+	stateEarliest, err := state.EarliestExitEpoch()
+	if err != nil {
+		return 0, err
+	}
+	earliestExitEpoch := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+	if stateEarliest > earliestExitEpoch {
+		earliestExitEpoch = stateEarliest
+	}
+	perEpochChurn := GetActivationExitChurnLimit(spec, epc.TotalActiveStake)
+	var exitBalanceToConsume common.Gwei
+	// New epoch for exits
+	if stateEarliest < earliestExitEpoch {
+		exitBalanceToConsume = perEpochChurn
+	} else {
+		exitBalanceToConsume, err = state.ExitBalanceToConsume()
+		if err != nil {
+			return 0, err
+		}
+	}
+	// Exit doesn't fit in the current earliest epoch
+	if exitBalance > exitBalanceToConsume {
+		balanceToProcess := exitBalance - exitBalanceToConsume
+		additionalEpochs := (balanceToProcess-1)/perEpochChurn + 1
+		earliestExitEpoch += common.Epoch(additionalEpochs)
+		exitBalanceToConsume += additionalEpochs * perEpochChurn
+	}
+	if err := state.SetExitBalanceToConsume(exitBalanceToConsume - exitBalance); err != nil {
+		return 0, err
+	}
+	if err := state.SetEarliestExitEpoch(earliestExitEpoch); err != nil {
+		return 0, err
+	}
+	return earliestExitEpoch, nil
+}
+
+// InitiateValidatorExit initiates the exit of the validator of the given index.
+// Unlike phase0, the exit queue is weighted by the effective balance of the exiting validator.
+func InitiateValidatorExit(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, index common.ValidatorIndex) error {
+	validators, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	v, err := validators.Validator(index)
+	if err != nil {
+		return err
+	}
+	exitEp, err := v.ExitEpoch()
+	if err != nil {
+		return err
+	}
+	// Return if validator already initiated exit
+	if exitEp != common.FAR_FUTURE_EPOCH {
+		return nil
+	}
+	effBalance, err := v.EffectiveBalance()
+	if err != nil {
+		return err
+	}
+	exitEp, err = ComputeExitEpochAndUpdateChurn(spec, epc, state, effBalance)
+	if err != nil {
+		return err
+	}
+	if err := v.SetExitEpoch(exitEp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SlashValidator slashes the validator with the given index, like phase0.SlashValidator,
+// but with the balance-weighted exit of Electra.
+func SlashValidator(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState,
+	slashedIndex common.ValidatorIndex, whistleblowerIndex *common.ValidatorIndex) error {
+
+	if err := InitiateValidatorExit(spec, epc, state, slashedIndex); err != nil {
+		return err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	v, err := vals.Validator(slashedIndex)
+	if err != nil {
+		return err
+	}
+	if err := v.MakeSlashed(); err != nil {
+		return err
+	}
+
+	effectiveBalance, err := v.EffectiveBalance()
+	if err != nil {
+		return err
+	}
+
+	settings := state.ForkSettings(spec)
+	bals, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	if err := common.DecreaseBalance(bals, slashedIndex, effectiveBalance/common.Gwei(settings.MinSlashingPenaltyQuotient)); err != nil {
+		return err
+	}
+
+	slot, err := state.Slot()
+	if err != nil {
+		return err
+	}
+	propIndex, err := epc.GetBeaconProposer(slot)
+	if err != nil {
+		return err
+	}
+	if whistleblowerIndex == nil {
+		whistleblowerIndex = &propIndex
+	}
+	whistleblowerReward := effectiveBalance / common.Gwei(spec.WHISTLEBLOWER_REWARD_QUOTIENT)
+	proposerReward := settings.CalcProposerShare(whistleblowerReward)
+	if err := common.IncreaseBalance(bals, propIndex, proposerReward); err != nil {
+		return err
+	}
+	if err := common.IncreaseBalance(bals, *whistleblowerIndex, whistleblowerReward-proposerReward); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ProcessProposerSlashings(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, ops []phase0.ProposerSlashing) error {
+	for i := range ops {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ProcessProposerSlashing(spec, epc, state, &ops[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ProcessProposerSlashing(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, ps *phase0.ProposerSlashing) error {
+	if err := phase0.ValidateProposerSlashing(spec, epc, state, ps); err != nil {
+		return err
+	}
+	return SlashValidator(spec, epc, state, ps.SignedHeader1.Message.ProposerIndex, nil)
+}
+
+// GetPendingBalanceToWithdraw returns the sum of the pending partial withdrawals of the validator.
+func GetPendingBalanceToWithdraw(state PendingPartialWithdrawalsBeaconState, index common.ValidatorIndex) (common.Gwei, error) {
+	pending, err := state.PendingPartialWithdrawals()
+	if err != nil {
+		return 0, err
+	}
+	withdrawals, err := pending.Withdrawals()
+	if err != nil {
+		return 0, err
+	}
+	var out common.Gwei
+	for _, w := range withdrawals {
+		if w.Index == index {
+			out += w.Amount
+		}
+	}
+	return out, nil
+}
+
+type VoluntaryExitBeaconState interface {
+	ExitChurnBeaconState
+	PendingPartialWithdrawalsBeaconState
+}
+
+func ProcessVoluntaryExits(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state VoluntaryExitBeaconState, ops []phase0.SignedVoluntaryExit) error {
+	for i := range ops {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ProcessVoluntaryExit(spec, epc, state, &ops[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ValidateVoluntaryExit(spec *common.Spec, epc *common.EpochsContext, state VoluntaryExitBeaconState, signedExit *phase0.SignedVoluntaryExit) error {
+	if err := deneb.ValidateVoluntaryExit(spec, epc, state, signedExit); err != nil {
+		return err
+	}
+	// [New in Electra:EIP7251] Only exit validator if it has no pending withdrawals in the queue
+	pending, err := GetPendingBalanceToWithdraw(state, signedExit.Message.ValidatorIndex)
+	if err != nil {
+		return err
+	}
+	if pending != 0 {
+		return errors.New("validator has pending partial withdrawals and cannot exit yet")
+	}
+	return nil
+}
+
+func ProcessVoluntaryExit(spec *common.Spec, epc *common.EpochsContext, state VoluntaryExitBeaconState, signedExit *phase0.SignedVoluntaryExit) error {
+	if err := ValidateVoluntaryExit(spec, epc, state, signedExit); err != nil {
+		return err
+	}
+	return InitiateValidatorExit(spec, epc, state, signedExit.Message.ValidatorIndex)
+}
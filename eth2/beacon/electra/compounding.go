@@ -0,0 +1,83 @@
+package electra
+
+import (
+	"context"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// HasCompoundingWithdrawalCredential checks if the validator has compounding (0x02) withdrawal credentials.
+func HasCompoundingWithdrawalCredential(validator common.Validator) (bool, error) {
+	creds, err := validator.WithdrawalCredentials()
+	if err != nil {
+		return false, err
+	}
+	return creds[0] == common.COMPOUNDING_WITHDRAWAL_PREFIX, nil
+}
+
+// HasExecutionWithdrawalCredential checks if the validator withdraws to an execution address,
+// with either eth1 (0x01) or compounding (0x02) withdrawal credentials.
+func HasExecutionWithdrawalCredential(validator common.Validator) (bool, error) {
+	creds, err := validator.WithdrawalCredentials()
+	if err != nil {
+		return false, err
+	}
+	return creds[0] == common.ETH1_ADDRESS_WITHDRAWAL_PREFIX || creds[0] == common.COMPOUNDING_WITHDRAWAL_PREFIX, nil
+}
+
+// GetMaxEffectiveBalance returns the effective balance cap of a validator with the given withdrawal credentials.
+func GetMaxEffectiveBalance(spec *common.Spec, withdrawalCreds common.Root) common.Gwei {
+	if withdrawalCreds[0] == common.COMPOUNDING_WITHDRAWAL_PREFIX {
+		return spec.MAX_EFFECTIVE_BALANCE_ELECTRA
+	}
+	return spec.MIN_ACTIVATION_BALANCE
+}
+
+// ProcessEffectiveBalanceUpdates is phase0.ProcessEffectiveBalanceUpdates,
+// with the effective balance capped per validator by GetMaxEffectiveBalance.
+func ProcessEffectiveBalanceUpdates(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, flats []common.FlatValidator, state common.BeaconState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	HYSTERESIS_INCREMENT := spec.EFFECTIVE_BALANCE_INCREMENT / common.Gwei(spec.HYSTERESIS_QUOTIENT)
+	DOWNWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_DOWNWARD_MULTIPLIER)
+	UPWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_UPWARD_MULTIPLIER)
+
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	balIterNext := bals.Iter()
+	for i := common.ValidatorIndex(0); true; i++ {
+		balance, ok, err := balIterNext()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		effBalance := flats[i].EffectiveBalance
+		if balance+DOWNWARD_THRESHOLD < effBalance || effBalance+UPWARD_THRESHOLD < balance {
+			val, err := vals.Validator(i)
+			if err != nil {
+				return err
+			}
+			creds, err := val.WithdrawalCredentials()
+			if err != nil {
+				return err
+			}
+			effBalance = balance - (balance % spec.EFFECTIVE_BALANCE_INCREMENT)
+			if maxEffBalance := GetMaxEffectiveBalance(spec, creds); maxEffBalance < effBalance {
+				effBalance = maxEffBalance
+			}
+			if err := val.SetEffectiveBalance(effBalance); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
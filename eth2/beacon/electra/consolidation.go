@@ -0,0 +1,347 @@
+package electra
+
+import (
+	"context"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+type ConsolidationBeaconState interface {
+	PendingPartialWithdrawalsBeaconState
+	ExitChurnBeaconState
+	ConsolidationBalanceToConsume() (common.Gwei, error)
+	SetConsolidationBalanceToConsume(v common.Gwei) error
+	EarliestConsolidationEpoch() (common.Epoch, error)
+	SetEarliestConsolidationEpoch(v common.Epoch) error
+	PendingConsolidations() (PendingConsolidationsList, error)
+}
+
+// GetConsolidationChurnLimit returns the churn per epoch, in Gwei, that is available to consolidations:
+// the balance churn that is not reserved for activations and exits.
+func GetConsolidationChurnLimit(spec *common.Spec, totalActiveBalance common.Gwei) common.Gwei {
+	return GetBalanceChurnLimit(spec, totalActiveBalance) - GetActivationExitChurnLimit(spec, totalActiveBalance)
+}
+
+// ComputeConsolidationEpochAndUpdateChurn consumes consolidationBalance of the consolidation churn,
+// like ComputeExitEpochAndUpdateChurn does for exits, and returns the exit epoch of the consolidation source.
+func ComputeConsolidationEpochAndUpdateChurn(spec *common.Spec, epc *common.EpochsContext, state ConsolidationBeaconState, consolidationBalance common.Gwei) (common.Epoch, error) {
+	stateEarliest, err := state.EarliestConsolidationEpoch()
+	if err != nil {
+		return 0, err
+	}
+	earliestConsolidationEpoch := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+	if stateEarliest > earliestConsolidationEpoch {
+		earliestConsolidationEpoch = stateEarliest
+	}
+	perEpochConsolidationChurn := GetConsolidationChurnLimit(spec, epc.TotalActiveStake)
+	var consolidationBalanceToConsume common.Gwei
+	// New epoch for consolidations
+	if stateEarliest < earliestConsolidationEpoch {
+		consolidationBalanceToConsume = perEpochConsolidationChurn
+	} else {
+		consolidationBalanceToConsume, err = state.ConsolidationBalanceToConsume()
+		if err != nil {
+			return 0, err
+		}
+	}
+	// Consolidation doesn't fit in the current earliest epoch
+	if consolidationBalance > consolidationBalanceToConsume {
+		balanceToProcess := consolidationBalance - consolidationBalanceToConsume
+		additionalEpochs := (balanceToProcess-1)/perEpochConsolidationChurn + 1
+		earliestConsolidationEpoch += common.Epoch(additionalEpochs)
+		consolidationBalanceToConsume += additionalEpochs * perEpochConsolidationChurn
+	}
+	if err := state.SetConsolidationBalanceToConsume(consolidationBalanceToConsume - consolidationBalance); err != nil {
+		return 0, err
+	}
+	if err := state.SetEarliestConsolidationEpoch(earliestConsolidationEpoch); err != nil {
+		return 0, err
+	}
+	return earliestConsolidationEpoch, nil
+}
+
+func ProcessConsolidationRequests(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state ConsolidationBeaconState, requests []ConsolidationRequest) error {
+	for i := range requests {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ProcessConsolidationRequest(spec, epc, state, &requests[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessConsolidationRequest queues the consolidation of the source validator into the target validator,
+// or switches the source to compounding credentials if source and target are the same.
+// Requests come from the execution layer, and invalid requests are ignored rather than invalidating the block:
+// only state errors are returned.
+func ProcessConsolidationRequest(spec *common.Spec, epc *common.EpochsContext, state ConsolidationBeaconState, req *ConsolidationRequest) error {
+	if ok, err := IsValidSwitchToCompoundingRequest(spec, epc, state, req); err != nil {
+		return err
+	} else if ok {
+		sourceIndex, _ := epc.ValidatorPubkeyCache.ValidatorIndex(req.SourcePubkey)
+		return SwitchToCompoundingValidator(spec, epc, state, sourceIndex)
+	}
+	// Verify that source != target, so a consolidation cannot be used as an exit
+	if req.SourcePubkey == req.TargetPubkey {
+		return nil
+	}
+	pending, err := state.PendingConsolidations()
+	if err != nil {
+		return err
+	}
+	consolidations, err := pending.Consolidations()
+	if err != nil {
+		return err
+	}
+	// If the pending consolidations queue is full, consolidation requests are ignored
+	if uint64(len(consolidations)) >= uint64(spec.PENDING_CONSOLIDATIONS_LIMIT) {
+		return nil
+	}
+	// If there is too little available consolidation churn limit, consolidation requests are ignored
+	if GetConsolidationChurnLimit(spec, epc.TotalActiveStake) <= spec.MIN_ACTIVATION_BALANCE {
+		return nil
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	sourceIndex, ok, err := validatorIndexOf(epc, vals, req.SourcePubkey)
+	if err != nil || !ok {
+		return err
+	}
+	targetIndex, ok, err := validatorIndexOf(epc, vals, req.TargetPubkey)
+	if err != nil || !ok {
+		return err
+	}
+	source, err := vals.Validator(sourceIndex)
+	if err != nil {
+		return err
+	}
+	target, err := vals.Validator(targetIndex)
+	if err != nil {
+		return err
+	}
+	// Verify source withdrawal credentials
+	if ok, err := HasExecutionWithdrawalCredential(source); err != nil || !ok {
+		return err
+	}
+	sourceCreds, err := source.WithdrawalCredentials()
+	if err != nil {
+		return err
+	}
+	if common.Eth1Address(sourceCreds[12:]) != req.SourceAddress {
+		return nil
+	}
+	// Verify that target has compounding withdrawal credentials
+	if ok, err := HasCompoundingWithdrawalCredential(target); err != nil || !ok {
+		return err
+	}
+	currentEpoch := epc.CurrentEpoch.Epoch
+	// Verify the source and the target are active, and not already exiting
+	for _, v := range []common.Validator{source, target} {
+		if ok, err := isActiveAndNotExiting(v, currentEpoch); err != nil || !ok {
+			return err
+		}
+	}
+	// Verify the source has been active long enough
+	activationEpoch, err := source.ActivationEpoch()
+	if err != nil {
+		return err
+	}
+	if currentEpoch < activationEpoch+spec.SHARD_COMMITTEE_PERIOD {
+		return nil
+	}
+	// Verify the source has no pending withdrawals in the queue
+	if pendingBalance, err := GetPendingBalanceToWithdraw(state, sourceIndex); err != nil || pendingBalance > 0 {
+		return err
+	}
+
+	// Initiate source validator exit and append pending consolidation
+	effBalance, err := source.EffectiveBalance()
+	if err != nil {
+		return err
+	}
+	exitEpoch, err := ComputeConsolidationEpochAndUpdateChurn(spec, epc, state, effBalance)
+	if err != nil {
+		return err
+	}
+	if err := source.SetExitEpoch(exitEpoch); err != nil {
+		return err
+	}
+	return pending.Append(PendingConsolidation{SourceIndex: sourceIndex, TargetIndex: targetIndex})
+}
+
+// IsValidSwitchToCompoundingRequest checks if the consolidation request is a request of the source,
+// to switch its own eth1 (0x01) withdrawal credentials to compounding (0x02) credentials.
+func IsValidSwitchToCompoundingRequest(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState, req *ConsolidationRequest) (bool, error) {
+	// Switch to compounding requires source and target be equal
+	if req.SourcePubkey != req.TargetPubkey {
+		return false, nil
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return false, err
+	}
+	sourceIndex, ok, err := validatorIndexOf(epc, vals, req.SourcePubkey)
+	if err != nil || !ok {
+		return false, err
+	}
+	source, err := vals.Validator(sourceIndex)
+	if err != nil {
+		return false, err
+	}
+	// Verify request has been authorized
+	creds, err := source.WithdrawalCredentials()
+	if err != nil {
+		return false, err
+	}
+	if common.Eth1Address(creds[12:]) != req.SourceAddress {
+		return false, nil
+	}
+	// Verify source withdrawal credentials
+	if creds[0] != common.ETH1_ADDRESS_WITHDRAWAL_PREFIX {
+		return false, nil
+	}
+	// Verify the source is active, and not already exiting
+	return isActiveAndNotExiting(source, epc.CurrentEpoch.Epoch)
+}
+
+// SwitchToCompoundingValidator changes the withdrawal credentials prefix of the validator to compounding (0x02).
+// Pending deposits are not processed, so the balance above MIN_ACTIVATION_BALANCE is not queued as pending deposit.
+// Instead it is charged to the activation and exit churn, like an activation of that balance would be,
+// before the next effective balance update lifts the effective balance above MIN_ACTIVATION_BALANCE.
+func SwitchToCompoundingValidator(spec *common.Spec, epc *common.EpochsContext, state ExitChurnBeaconState, index common.ValidatorIndex) error {
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	v, err := vals.Validator(index)
+	if err != nil {
+		return err
+	}
+	creds, err := v.WithdrawalCredentials()
+	if err != nil {
+		return err
+	}
+	creds[0] = common.COMPOUNDING_WITHDRAWAL_PREFIX
+	if err := v.SetWithdrawalCredentials(creds); err != nil {
+		return err
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	balance, err := bals.GetBalance(index)
+	if err != nil {
+		return err
+	}
+	if balance <= spec.MIN_ACTIVATION_BALANCE {
+		return nil
+	}
+	_, err = ComputeExitEpochAndUpdateChurn(spec, epc, state, balance-spec.MIN_ACTIVATION_BALANCE)
+	return err
+}
+
+// ProcessPendingConsolidations moves the balance of consolidated validators to their targets,
+// once the source is withdrawable.
+func ProcessPendingConsolidations(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state ConsolidationBeaconState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	pending, err := state.PendingConsolidations()
+	if err != nil {
+		return err
+	}
+	consolidations, err := pending.Consolidations()
+	if err != nil {
+		return err
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	return processPendingConsolidations(spec, epc, state, pending, consolidations, bals)
+}
+
+// processPendingConsolidations moves the balances of the consolidations that are ready,
+// and dequeues them. The balances may be kept outside of the state, see ProcessEpochSinglePass.
+func processPendingConsolidations(spec *common.Spec, epc *common.EpochsContext, state ConsolidationBeaconState,
+	pending PendingConsolidationsList, consolidations PendingConsolidations, bals common.BalancesRegistry) error {
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	nextEpoch := epc.NextEpoch.Epoch
+	processed := uint64(0)
+	for _, c := range consolidations {
+		source, err := vals.Validator(c.SourceIndex)
+		if err != nil {
+			return err
+		}
+		slashed, err := source.Slashed()
+		if err != nil {
+			return err
+		}
+		if slashed {
+			processed += 1
+			continue
+		}
+		// Validators do not track a withdrawable epoch in this state,
+		// the source is withdrawable MIN_VALIDATOR_WITHDRAWABILITY_DELAY after its exit.
+		exitEpoch, err := source.ExitEpoch()
+		if err != nil {
+			return err
+		}
+		if exitEpoch == common.FAR_FUTURE_EPOCH || exitEpoch+spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY > nextEpoch {
+			break
+		}
+		// Calculate the consolidated balance
+		balance, err := bals.GetBalance(c.SourceIndex)
+		if err != nil {
+			return err
+		}
+		effBalance, err := source.EffectiveBalance()
+		if err != nil {
+			return err
+		}
+		amount := min(balance, effBalance)
+		// Move active balance to target. Excess balance is withdrawable.
+		if err := common.DecreaseBalance(bals, c.SourceIndex, amount); err != nil {
+			return err
+		}
+		if err := common.IncreaseBalance(bals, c.TargetIndex, amount); err != nil {
+			return err
+		}
+		processed += 1
+	}
+	if processed == 0 {
+		return nil
+	}
+	return pending.Dequeue(processed)
+}
+
+func validatorIndexOf(epc *common.EpochsContext, vals common.ValidatorRegistry, pubkey common.BLSPubkey) (common.ValidatorIndex, bool, error) {
+	index, ok := epc.ValidatorPubkeyCache.ValidatorIndex(pubkey)
+	if !ok {
+		return 0, false, nil
+	}
+	count, err := vals.ValidatorCount()
+	if err != nil {
+		return 0, false, err
+	}
+	return index, uint64(index) < count, nil
+}
+
+func isActiveAndNotExiting(v common.Validator, epoch common.Epoch) (bool, error) {
+	activationEpoch, err := v.ActivationEpoch()
+	if err != nil {
+		return false, err
+	}
+	exitEpoch, err := v.ExitEpoch()
+	if err != nil {
+		return false, err
+	}
+	return activationEpoch <= epoch && exitEpoch == common.FAR_FUTURE_EPOCH, nil
+}
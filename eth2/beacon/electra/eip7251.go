@@ -1,6 +1,8 @@
 package electra
 
 import (
+	"fmt"
+
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -235,6 +237,21 @@ func (w *PendingPartialWithdrawalView) WithdrawableEpoch() (common.Epoch, error)
 func (w *PendingPartialWithdrawalView) SetWithdrawableEpoch(epoch common.Epoch) error {
 	return w.Set(_PendingPartialWithdrawalWithdrawableEpoch, Uint64View(epoch))
 }
+func (w *PendingPartialWithdrawalView) Raw() (PendingPartialWithdrawal, error) {
+	index, err := w.Index()
+	if err != nil {
+		return PendingPartialWithdrawal{}, err
+	}
+	amount, err := w.Amount()
+	if err != nil {
+		return PendingPartialWithdrawal{}, err
+	}
+	withdrawableEpoch, err := w.WithdrawableEpoch()
+	if err != nil {
+		return PendingPartialWithdrawal{}, err
+	}
+	return PendingPartialWithdrawal{Index: index, Amount: amount, WithdrawableEpoch: withdrawableEpoch}, nil
+}
 
 type PendingPartialWithdrawals []PendingPartialWithdrawal
 
@@ -287,3 +304,230 @@ func (w *PendingPartialWithdrawalsView) Append(withdrawal PendingPartialWithdraw
 	v := withdrawal.View()
 	return w.ComplexListView.Append(v)
 }
+
+func (w *PendingPartialWithdrawalsView) Dequeue(n uint64) error {
+	length, err := w.Length()
+	if err != nil {
+		return err
+	}
+	if n > length {
+		return fmt.Errorf("cannot dequeue %d of %d pending partial withdrawals", n, length)
+	}
+	remaining := make([]View, 0, length-n)
+	for i := n; i < length; i++ {
+		v, err := w.Get(i)
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, v)
+	}
+	list, err := w.ComplexListTypeDef.FromElements(remaining...)
+	if err != nil {
+		return err
+	}
+	return w.SetBacking(list.Backing())
+}
+
+func (w *PendingPartialWithdrawalsView) Withdrawals() (PendingPartialWithdrawals, error) {
+	length, err := w.Length()
+	if err != nil {
+		return nil, err
+	}
+	out := make(PendingPartialWithdrawals, 0, length)
+	iter := w.ReadonlyIter()
+	for {
+		el, ok, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		raw, err := AsPendingPartialWithdrawal(el, nil)
+		if err != nil {
+			return nil, err
+		}
+		withdrawal, err := raw.Raw()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, withdrawal)
+	}
+	return out, nil
+}
+
+type PendingConsolidation struct {
+	SourceIndex common.ValidatorIndex `json:"source_index" yaml:"source_index"`
+	TargetIndex common.ValidatorIndex `json:"target_index" yaml:"target_index"`
+}
+
+func (c *PendingConsolidation) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(&c.SourceIndex, &c.TargetIndex)
+}
+
+func (c *PendingConsolidation) Serialize(spec *common.Spec, ew *codec.EncodingWriter) error {
+	return ew.Container(&c.SourceIndex, &c.TargetIndex)
+}
+
+func (*PendingConsolidation) ByteLength(spec *common.Spec) uint64 {
+	return PendingConsolidationType.TypeByteLength()
+}
+
+func (*PendingConsolidation) FixedLength(spec *common.Spec) uint64 {
+	return PendingConsolidationType.TypeByteLength()
+}
+
+func (c *PendingConsolidation) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(&c.SourceIndex, &c.TargetIndex)
+}
+
+func (c *PendingConsolidation) View() *PendingConsolidationView {
+	v, _ := PendingConsolidationType.FromFields(
+		Uint64View(c.SourceIndex),
+		Uint64View(c.TargetIndex),
+	)
+	return &PendingConsolidationView{v}
+}
+
+var PendingConsolidationType = ContainerType("PendingConsolidation", []FieldDef{
+	{"source_index", common.ValidatorIndexType},
+	{"target_index", common.ValidatorIndexType},
+})
+
+const (
+	_PendingConsolidationSourceIndex = iota
+	_PendingConsolidationTargetIndex
+)
+
+type PendingConsolidationView struct {
+	*ContainerView
+}
+
+func NewPendingConsolidationView() *PendingConsolidationView {
+	return &PendingConsolidationView{ContainerView: PendingConsolidationType.New()}
+}
+
+func AsPendingConsolidation(v View, err error) (*PendingConsolidationView, error) {
+	c, err := AsContainer(v, err)
+	return &PendingConsolidationView{c}, err
+}
+
+func (c *PendingConsolidationView) SourceIndex() (common.ValidatorIndex, error) {
+	return common.AsValidatorIndex(c.Get(_PendingConsolidationSourceIndex))
+}
+func (c *PendingConsolidationView) TargetIndex() (common.ValidatorIndex, error) {
+	return common.AsValidatorIndex(c.Get(_PendingConsolidationTargetIndex))
+}
+func (c *PendingConsolidationView) Raw() (PendingConsolidation, error) {
+	source, err := c.SourceIndex()
+	if err != nil {
+		return PendingConsolidation{}, err
+	}
+	target, err := c.TargetIndex()
+	if err != nil {
+		return PendingConsolidation{}, err
+	}
+	return PendingConsolidation{SourceIndex: source, TargetIndex: target}, nil
+}
+
+type PendingConsolidations []PendingConsolidation
+
+func (li *PendingConsolidations) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*li)
+		*li = append(*li, PendingConsolidation{})
+		return spec.Wrap(&((*li)[i]))
+	}, PendingConsolidationType.TypeByteLength(), uint64(spec.PENDING_CONSOLIDATIONS_LIMIT))
+}
+
+func (li PendingConsolidations) Serialize(spec *common.Spec, ew *codec.EncodingWriter) error {
+	return ew.List(func(i uint64) codec.Serializable {
+		return spec.Wrap(&li[i])
+	}, PendingConsolidationType.TypeByteLength(), uint64(len(li)))
+}
+
+func (li PendingConsolidations) ByteLength(spec *common.Spec) uint64 {
+	return PendingConsolidationType.TypeByteLength() * uint64(len(li))
+}
+
+func (li *PendingConsolidations) FixedLength(spec *common.Spec) uint64 {
+	return 0 // it's a list, no fixed length
+}
+
+func (li PendingConsolidations) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return spec.Wrap(&li[i])
+		}
+		return nil
+	}, length, uint64(spec.PENDING_CONSOLIDATIONS_LIMIT))
+}
+
+func PendingConsolidationsType(spec *common.Spec) *ComplexListTypeDef {
+	return ComplexListType(PendingConsolidationType, uint64(spec.PENDING_CONSOLIDATIONS_LIMIT))
+}
+
+type PendingConsolidationsView struct{ *ComplexListView }
+
+var _ PendingConsolidationsList = (*PendingConsolidationsView)(nil)
+
+func AsPendingConsolidations(v View, err error) (*PendingConsolidationsView, error) {
+	c, err := AsComplexList(v, err)
+	return &PendingConsolidationsView{c}, err
+}
+
+func (li *PendingConsolidationsView) Append(consolidation PendingConsolidation) error {
+	return li.ComplexListView.Append(consolidation.View())
+}
+
+func (li *PendingConsolidationsView) Consolidations() (PendingConsolidations, error) {
+	length, err := li.Length()
+	if err != nil {
+		return nil, err
+	}
+	out := make(PendingConsolidations, 0, length)
+	iter := li.ReadonlyIter()
+	for {
+		el, ok, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		v, err := AsPendingConsolidation(el, nil)
+		if err != nil {
+			return nil, err
+		}
+		c, err := v.Raw()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (li *PendingConsolidationsView) Dequeue(n uint64) error {
+	length, err := li.Length()
+	if err != nil {
+		return err
+	}
+	if n > length {
+		return fmt.Errorf("cannot dequeue %d of %d pending consolidations", n, length)
+	}
+	remaining := make([]View, 0, length-n)
+	for i := n; i < length; i++ {
+		v, err := li.Get(i)
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, v)
+	}
+	list, err := li.ComplexListTypeDef.FromElements(remaining...)
+	if err != nil {
+		return err
+	}
+	return li.SetBacking(list.Backing())
+}
@@ -12,6 +12,7 @@ func ExecutionRequestsType(spec *common.Spec) *ContainerTypeDef {
 	return ContainerType("ExecutionRequests", []FieldDef{
 		{"deposit_requests", DepositRequestsType(spec)},
 		{"withdrawal_requests", WithdrawalRequestsType(spec)},
+		{"consolidation_requests", ConsolidationRequestsType(spec)},
 	})
 }
 
@@ -25,20 +26,21 @@ func AsExecutionRequest(v View, err error) (*ExecutionRequestView, error) {
 }
 
 type ExecutionRequests struct {
-	Deposits    DepositRequests    `json:"deposits" yaml:"deposits"`
-	Withdrawals WithdrawalRequests `json:"withdrawals" yaml:"withdrawals"`
+	Deposits       DepositRequests       `json:"deposits" yaml:"deposits"`
+	Withdrawals    WithdrawalRequests    `json:"withdrawals" yaml:"withdrawals"`
+	Consolidations ConsolidationRequests `json:"consolidations" yaml:"consolidations"`
 }
 
 func (r *ExecutionRequests) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
-	return dr.Container(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals))
+	return dr.Container(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals), spec.Wrap(&r.Consolidations))
 }
 
 func (r *ExecutionRequests) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
-	return w.Container(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals))
+	return w.Container(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals), spec.Wrap(&r.Consolidations))
 }
 
 func (r *ExecutionRequests) ByteLength(spec *common.Spec) uint64 {
-	return codec.ContainerLength(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals))
+	return codec.ContainerLength(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals), spec.Wrap(&r.Consolidations))
 }
 
 func (r *ExecutionRequests) FixedLength(*common.Spec) uint64 {
@@ -47,7 +49,7 @@ func (r *ExecutionRequests) FixedLength(*common.Spec) uint64 {
 }
 
 func (r *ExecutionRequests) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
-	return hFn.HashTreeRoot(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals))
+	return hFn.HashTreeRoot(spec.Wrap(&r.Deposits), spec.Wrap(&r.Withdrawals), spec.Wrap(&r.Consolidations))
 }
 
 func (r *ExecutionRequests) GetDeposits() DepositRequests {
@@ -58,6 +60,10 @@ func (r *ExecutionRequests) GetWitdrawals() WithdrawalRequests {
 	return r.Withdrawals
 }
 
+func (r *ExecutionRequests) GetConsolidations() ConsolidationRequests {
+	return r.Consolidations
+}
+
 // //////////////////////////////////////////////////////////////
 type DepositRequest struct {
 	Pubkey                common.BLSPubkey    `json:"pubkey" yaml:"pubkey"`
@@ -329,3 +335,123 @@ func (d *WithdrawalRequestsView) Append(withdrawal WithdrawalRequest) error {
 	v := withdrawal.View()
 	return d.ComplexListView.Append(v)
 }
+
+type ConsolidationRequest struct {
+	SourceAddress common.Eth1Address `json:"source_address" yaml:"source_address"`
+	SourcePubkey  common.BLSPubkey   `json:"source_pubkey" yaml:"source_pubkey"`
+	TargetPubkey  common.BLSPubkey   `json:"target_pubkey" yaml:"target_pubkey"`
+}
+
+func (r *ConsolidationRequest) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(&r.SourceAddress, &r.SourcePubkey, &r.TargetPubkey)
+}
+
+func (r *ConsolidationRequest) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.Container(&r.SourceAddress, &r.SourcePubkey, &r.TargetPubkey)
+}
+
+func (r *ConsolidationRequest) ByteLength(spec *common.Spec) uint64 {
+	return codec.ContainerLength(&r.SourceAddress, &r.SourcePubkey, &r.TargetPubkey)
+}
+
+func (r *ConsolidationRequest) FixedLength(spec *common.Spec) uint64 {
+	return 0
+}
+
+func (r *ConsolidationRequest) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	return hFn.HashTreeRoot(&r.SourceAddress, &r.SourcePubkey, &r.TargetPubkey)
+}
+
+func (r *ConsolidationRequest) View() *ConsolidationRequestView {
+	c, _ := ConsolidationRequestType.FromFields(
+		r.SourceAddress.View(),
+		common.ViewPubkey(&r.SourcePubkey),
+		common.ViewPubkey(&r.TargetPubkey),
+	)
+	return &ConsolidationRequestView{c}
+}
+
+var ConsolidationRequestType = ContainerType("ConsolidationRequest", []FieldDef{
+	{"source_address", common.Eth1AddressType},
+	{"source_pubkey", common.BLSPubkeyType},
+	{"target_pubkey", common.BLSPubkeyType},
+})
+
+const (
+	_consolidationRequestSourceAddress = iota
+	_consolidationRequestSourcePubkey
+	_consolidationRequestTargetPubkey
+)
+
+type ConsolidationRequestView struct {
+	*ContainerView
+}
+
+func NewConsolidationRequestView() *ConsolidationRequestView {
+	return &ConsolidationRequestView{ContainerView: ConsolidationRequestType.New()}
+}
+
+func AsConsolidationRequest(v View, err error) (*ConsolidationRequestView, error) {
+	c, err := AsContainer(v, err)
+	return &ConsolidationRequestView{c}, err
+}
+
+func (d *ConsolidationRequestView) SourceAddress() (common.Eth1Address, error) {
+	return common.AsEth1Address(d.Get(_consolidationRequestSourceAddress))
+}
+func (d *ConsolidationRequestView) SourcePubkey() (common.BLSPubkey, error) {
+	return common.AsBLSPubkey(d.Get(_consolidationRequestSourcePubkey))
+}
+func (d *ConsolidationRequestView) TargetPubkey() (common.BLSPubkey, error) {
+	return common.AsBLSPubkey(d.Get(_consolidationRequestTargetPubkey))
+}
+
+type ConsolidationRequests []ConsolidationRequest
+
+func (d *ConsolidationRequests) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*d)
+		*d = append(*d, ConsolidationRequest{})
+		return spec.Wrap(&((*d)[i]))
+	}, ConsolidationRequestType.TypeByteLength(), uint64(spec.MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD))
+}
+
+func (d ConsolidationRequests) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return spec.Wrap(&d[i])
+	}, ConsolidationRequestType.TypeByteLength(), uint64(len(d)))
+}
+
+func (d ConsolidationRequests) ByteLength(spec *common.Spec) uint64 {
+	return ConsolidationRequestType.TypeByteLength() * uint64(len(d))
+}
+
+func (d *ConsolidationRequests) FixedLength(spec *common.Spec) uint64 {
+	return 0 // it's a list, no fixed length
+}
+
+func (li ConsolidationRequests) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return spec.Wrap(&li[i])
+		}
+		return nil
+	}, length, uint64(spec.MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD))
+}
+
+func ConsolidationRequestsType(spec *common.Spec) ListTypeDef {
+	return ListType(ConsolidationRequestType, uint64(spec.MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD))
+}
+
+type ConsolidationRequestsView struct{ *ComplexListView }
+
+func AsConsolidationRequests(v View, err error) (*ConsolidationRequestsView, error) {
+	c, err := AsComplexList(v, err)
+	return &ConsolidationRequestsView{c}, err
+}
+
+func (d *ConsolidationRequestsView) Append(consolidation ConsolidationRequest) error {
+	v := consolidation.View()
+	return d.ComplexListView.Append(v)
+}
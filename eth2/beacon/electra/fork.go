@@ -0,0 +1,191 @@
+package electra
+
+import (
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+)
+
+// UNSET_DEPOSIT_REQUESTS_START_INDEX marks that no deposit request has been processed yet.
+const UNSET_DEPOSIT_REQUESTS_START_INDEX = ^uint64(0)
+
+// UpgradeToElectra upgrades a Deneb state to Electra.
+// The exit churn starts after the latest exit of the pre-state, the deposit and withdrawal queues start empty.
+func UpgradeToElectra(spec *common.Spec, epc *common.EpochsContext, pre *deneb.BeaconStateView) (*BeaconStateView, error) {
+	// yes, super ugly code, but it does transfer compatible subtrees without duplicating data or breaking caches
+	slot, err := pre.Slot()
+	if err != nil {
+		return nil, err
+	}
+	epoch := spec.SlotToEpoch(slot)
+	genesisTime, err := pre.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	genesisValidatorsRoot, err := pre.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	preFork, err := pre.Fork()
+	if err != nil {
+		return nil, err
+	}
+	fork := common.Fork{
+		PreviousVersion: preFork.CurrentVersion,
+		CurrentVersion:  spec.ALPACA_FORK_VERSION,
+		Epoch:           epoch,
+	}
+	latestBlockHeader, err := pre.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	blockRoots, err := pre.BlockRoots()
+	if err != nil {
+		return nil, err
+	}
+	stateRoots, err := pre.StateRoots()
+	if err != nil {
+		return nil, err
+	}
+	rewardAdjustmentFactor, err := pre.RewardAdjustmentFactor()
+	if err != nil {
+		return nil, err
+	}
+	eth1Data, err := pre.Eth1Data()
+	if err != nil {
+		return nil, err
+	}
+	eth1DataVotes, err := pre.Eth1DataVotes()
+	if err != nil {
+		return nil, err
+	}
+	eth1DepositIndex, err := pre.Eth1DepositIndex()
+	if err != nil {
+		return nil, err
+	}
+	validators, err := pre.Validators()
+	if err != nil {
+		return nil, err
+	}
+	balances, err := pre.Balances()
+	if err != nil {
+		return nil, err
+	}
+	reserves, err := pre.Reserves()
+	if err != nil {
+		return nil, err
+	}
+	randaoMixes, err := pre.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	previousEpochParticipation, err := pre.PreviousEpochParticipation()
+	if err != nil {
+		return nil, err
+	}
+	currentEpochParticipation, err := pre.CurrentEpochParticipation()
+	if err != nil {
+		return nil, err
+	}
+	justBits, err := pre.JustificationBits()
+	if err != nil {
+		return nil, err
+	}
+	prevJustCh, err := pre.PreviousJustifiedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	currJustCh, err := pre.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	finCh, err := pre.FinalizedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	inactivityScores, err := pre.InactivityScores()
+	if err != nil {
+		return nil, err
+	}
+	latestExecutionPayloadHeader, err := pre.LatestExecutionPayloadHeader()
+	if err != nil {
+		return nil, err
+	}
+	nextWithdrawalIndex, err := pre.NextWithdrawalIndex()
+	if err != nil {
+		return nil, err
+	}
+	nextWithdrawalValidatorIndex, err := pre.NextWithdrawalValidatorIndex()
+	if err != nil {
+		return nil, err
+	}
+	nextHistoricalSummaries, err := pre.HistoricalSummaries()
+	if err != nil {
+		return nil, err
+	}
+	// New in Electra
+	depositRequestsStartIndex := common.Number(UNSET_DEPOSIT_REQUESTS_START_INDEX)
+	// Exits continue after the latest exit epoch of the pre-state
+	earliestExitEpoch := spec.ComputeActivationExitEpoch(epoch)
+	valIter := validators.Iter()
+	for {
+		v, ok, err := valIter()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		exitEpoch, err := v.ExitEpoch()
+		if err != nil {
+			return nil, err
+		}
+		if exitEpoch != common.FAR_FUTURE_EPOCH && exitEpoch >= earliestExitEpoch {
+			earliestExitEpoch = exitEpoch + 1
+		}
+	}
+	var depositBalanceToConsume common.Gwei
+	exitBalanceToConsume := GetActivationExitChurnLimit(spec, epc.TotalActiveStake)
+	consolidationBalanceToConsume := GetConsolidationChurnLimit(spec, epc.TotalActiveStake)
+	earliestConsolidationEpoch := spec.ComputeActivationExitEpoch(epoch)
+
+	return AsBeaconStateView(BeaconStateType(spec).FromFields(
+		(*view.Uint64View)(&genesisTime),
+		(*view.RootView)(&genesisValidatorsRoot),
+		(*view.Uint64View)(&slot),
+		fork.View(),
+		latestBlockHeader.View(),
+		blockRoots.(view.View),
+		stateRoots.(view.View),
+		(*view.Uint64View)(&rewardAdjustmentFactor),
+		eth1Data.View(),
+		eth1DataVotes.(view.View),
+		(*view.Uint64View)(&eth1DepositIndex),
+		validators.(view.View),
+		balances.(view.View),
+		(*view.Uint64View)(&reserves),
+		randaoMixes.(view.View),
+		previousEpochParticipation,
+		currentEpochParticipation,
+		justBits.View(),
+		prevJustCh.View(),
+		currJustCh.View(),
+		finCh.View(),
+		inactivityScores,
+		latestExecutionPayloadHeader,
+		(*view.Uint64View)(&nextWithdrawalIndex),
+		(*view.Uint64View)(&nextWithdrawalValidatorIndex),
+		nextHistoricalSummaries.(*capella.HistoricalSummariesView),
+		(*view.Uint64View)(&depositRequestsStartIndex),
+		(*view.Uint64View)(&depositBalanceToConsume),
+		(*view.Uint64View)(&exitBalanceToConsume),
+		(*view.Uint64View)(&earliestExitEpoch),
+		(*view.Uint64View)(&consolidationBalanceToConsume),
+		(*view.Uint64View)(&earliestConsolidationEpoch),
+		PendingDepositsType(spec).New(),
+		PendingPartialWithdrawalsType(spec).New(),
+		PendingConsolidationsType(spec).New(),
+	))
+}
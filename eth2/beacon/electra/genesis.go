@@ -0,0 +1,65 @@
+package electra
+
+import (
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// GenesisFromEth1 builds a genesis state that starts at Electra, like phase0.GenesisFromEth1.
+// The execution payload header is the header of the execution genesis block.
+// Both the previous and current fork version are the Electra (ALPACA) fork version.
+// The genesis deposits are applied directly, so the pending deposits start empty,
+// and the churn fields start at zero rather than continuing an exit queue like the fork upgrade does.
+func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, deps []common.Deposit,
+	executionHeader *deneb.ExecutionPayloadHeader, ignoreSignaturesAndProofs bool) (*BeaconStateView, *common.EpochsContext, error) {
+	pre, epc, err := deneb.GenesisFromEth1(spec, eth1BlockHash, time, deps, executionHeader, ignoreSignaturesAndProofs)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := UpgradeToElectra(spec, epc, pre)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetFork(common.Fork{
+		PreviousVersion: spec.ALPACA_FORK_VERSION,
+		CurrentVersion:  spec.ALPACA_FORK_VERSION,
+		Epoch:           common.GENESIS_EPOCH,
+	}); err != nil {
+		return nil, nil, err
+	}
+	emptyBody := BeaconBlockBody{}
+	if err := state.SetLatestBlockHeader(&common.BeaconBlockHeader{
+		BodyRoot: emptyBody.HashTreeRoot(spec, tree.GetHashFn()),
+	}); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetExitBalanceToConsume(0); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetEarliestExitEpoch(common.GENESIS_EPOCH); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetConsolidationBalanceToConsume(0); err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetEarliestConsolidationEpoch(common.GENESIS_EPOCH); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
+
+// KickStartState builds a genesis state that starts at Electra without Eth 1.0 deposits, like phase0.KickStartState.
+func KickStartState(spec *common.Spec, eth1BlockHash common.Root, time common.Timestamp, validators []phase0.KickstartValidatorData,
+	executionHeader *deneb.ExecutionPayloadHeader) (*BeaconStateView, *common.EpochsContext, error) {
+	state, epc, err := GenesisFromEth1(spec, eth1BlockHash, 0, phase0.KickstartDeposits(validators), executionHeader, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := state.SetGenesisTime(time); err != nil {
+		return nil, nil, err
+	}
+	return state, epc, nil
+}
@@ -0,0 +1,85 @@
+package electra
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+func ProcessEpochRegistryUpdates(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, flats []common.FlatValidator, state ExitChurnBeaconState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+
+	registerData, err := phase0.ComputeRegistryProcessData(spec, flats, epc.CurrentEpoch.Epoch)
+	if err != nil {
+		return fmt.Errorf("invalid ProcessEpochRegistryUpdates: %v", err)
+	}
+
+	// process ejections
+	// Modified in Electra:EIP7251: ejections consume the balance-weighted exit churn
+	for _, index := range registerData.IndicesToEject {
+		if err := InitiateValidatorExit(spec, epc, state, index); err != nil {
+			return err
+		}
+	}
+	// The exits were written through a new view of the validators, continue with that.
+	vals, err = state.Validators()
+	if err != nil {
+		return err
+	}
+
+	// Process activation eligibility
+	// Modified in Electra:EIP7251: compounding validators may have an effective balance above MIN_ACTIVATION_BALANCE
+	{
+		eligibilityEpoch := epc.CurrentEpoch.Epoch + 1
+		for i := range flats {
+			flat := &flats[i]
+			if flat.ActivationEligibilityEpoch != common.FAR_FUTURE_EPOCH || flat.EffectiveBalance < spec.MIN_ACTIVATION_BALANCE {
+				continue
+			}
+			val, err := vals.Validator(common.ValidatorIndex(i))
+			if err != nil {
+				return err
+			}
+			if err := val.SetActivationEligibilityEpoch(eligibilityEpoch); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Process activations
+	{
+		finality, err := state.FinalizedCheckpoint()
+		if err != nil {
+			return err
+		}
+		dequeued := registerData.IndicesToMaybeActivate
+		churnLimit := min(uint64(spec.MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT), registerData.ChurnLimit)
+		if uint64(len(dequeued)) > churnLimit {
+			dequeued = dequeued[:churnLimit]
+		}
+		activationEpoch := spec.ComputeActivationExitEpoch(epc.CurrentEpoch.Epoch)
+		for _, index := range dequeued {
+			if flats[index].ActivationEligibilityEpoch > finality.Epoch {
+				// remaining validators all have an activation_eligibility_epoch that is higher anyway, break early
+				// The tie-breaks were already sorted correctly in the IndicesToMaybeActivate queue.
+				break
+			}
+			val, err := vals.Validator(index)
+			if err != nil {
+				return err
+			}
+			if err := val.SetActivationEpoch(activationEpoch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package electra
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+type SinglePassEpochBeaconState interface {
+	altair.AltairLikeBeaconState
+	ExitChurnBeaconState
+	ConsolidationBeaconState
+	capella.HistoricalSummariesBeaconState
+	SetInactivityScores(scores []uint64) error
+}
+
+var _ SinglePassEpochBeaconState = (*BeaconStateView)(nil)
+
+// ProcessEpochSinglePass is equivalent to ProcessEpochStepwise, but computes the registry updates
+// and effective balance updates in a single iteration over the validators,
+// after a single iteration over the eligible validators for the inactivity scores, rewards and penalties.
+// The balances and inactivity scores are kept in memory, and written back to the state once,
+// instead of walking and updating the tree views in every step.
+func ProcessEpochSinglePass(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state SinglePassEpochBeaconState) error {
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return err
+	}
+	attesterData, err := altair.ComputeEpochAttesterData(ctx, spec, epc, flats, state)
+	if err != nil {
+		return err
+	}
+	just := phase0.JustificationStakeData{
+		CurrentEpoch:                  epc.CurrentEpoch.Epoch,
+		TotalActiveStake:              epc.TotalActiveStake,
+		PrevEpochUnslashedTargetStake: attesterData.PrevEpochUnslashedStake.TargetStake,
+		CurrEpochUnslashedTargetStake: attesterData.CurrEpochUnslashedTargetStake,
+	}
+	if err := phase0.ProcessEpochJustification(ctx, spec, &just, state); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	prevEpoch := epc.PreviousEpoch.Epoch
+	currentEpoch := epc.CurrentEpoch.Epoch
+	// Skip the genesis epoch as score updates and rewards are based on the previous epoch participation
+	isGenesis := currentEpoch == common.GENESIS_EPOCH
+	finalized, err := state.FinalizedCheckpoint()
+	if err != nil {
+		return err
+	}
+	finalityDelay := prevEpoch - finalized.Epoch
+	isInactivityLeak := finalityDelay > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
+
+	balsView, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	allBals, err := balsView.AllBalances()
+	if err != nil {
+		return err
+	}
+	if len(allBals) != len(flats) {
+		return fmt.Errorf("balances count %d does not match validators count %d", len(allBals), len(flats))
+	}
+	bals := phase0.Balances(allBals)
+	var scores []uint64
+	var rewards *altair.ValidatorRewards
+	if !isGenesis {
+		scoresView, err := state.InactivityScores()
+		if err != nil {
+			return err
+		}
+		scores, err = scoresView.AllScores()
+		if err != nil {
+			return err
+		}
+		if len(scores) != len(flats) {
+			return fmt.Errorf("inactivity scores count %d does not match validators count %d", len(scores), len(flats))
+		}
+		rewards = altair.NewValidatorRewards(spec, epc, attesterData, isInactivityLeak,
+			state.ForkSettings(spec).InactivityPenaltyQuotient)
+	}
+
+	// The balances of consolidating validators change after the main pass,
+	// their effective balances are updated after processing the consolidations.
+	pending, err := state.PendingConsolidations()
+	if err != nil {
+		return err
+	}
+	consolidations, err := pending.Consolidations()
+	if err != nil {
+		return err
+	}
+	consolidating := make(map[common.ValidatorIndex]struct{}, 2*len(consolidations))
+	for _, c := range consolidations {
+		consolidating[c.SourceIndex] = struct{}{}
+		consolidating[c.TargetIndex] = struct{}{}
+	}
+
+	HYSTERESIS_INCREMENT := spec.EFFECTIVE_BALANCE_INCREMENT / common.Gwei(spec.HYSTERESIS_QUOTIENT)
+	DOWNWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_DOWNWARD_MULTIPLIER)
+	UPWARD_THRESHOLD := HYSTERESIS_INCREMENT * common.Gwei(spec.HYSTERESIS_UPWARD_MULTIPLIER)
+	needsEffectiveBalanceUpdate := func(i common.ValidatorIndex) bool {
+		balance, effBalance := bals[i], flats[i].EffectiveBalance
+		return balance+DOWNWARD_THRESHOLD < effBalance || effBalance+UPWARD_THRESHOLD < balance
+	}
+
+	// Inactivity updates, and rewards and penalties, of eligible validators
+	if !isGenesis {
+		for _, vi := range attesterData.EligibleIndices {
+			flat := &flats[vi]
+			participation := attesterData.PrevParticipation[vi]
+			scores[vi] = altair.UpdateInactivityScore(spec, scores[vi], flat.Slashed, participation, isInactivityLeak)
+			reward, penalty := rewards.Deltas(flat, participation, scores[vi])
+			bal := bals[vi] + reward
+			if bal >= penalty {
+				bal -= penalty
+			} else {
+				bal = 0
+			}
+			bals[vi] = bal
+		}
+	}
+
+	var toEject, toSetEligibility, toMaybeActivate, toUpdateEffectiveBalance []common.ValidatorIndex
+	activeCount := uint64(0)
+	for i := range flats {
+		flat := &flats[i]
+		vi := common.ValidatorIndex(i)
+
+		// Registry updates, based on the effective balances from before this epoch transition
+		active := flat.IsActive(currentEpoch)
+		if active {
+			activeCount++
+		}
+		if active && flat.EffectiveBalance <= spec.EJECTION_BALANCE && flat.ExitEpoch == common.FAR_FUTURE_EPOCH {
+			toEject = append(toEject, vi)
+		}
+		if flat.ActivationEligibilityEpoch == common.FAR_FUTURE_EPOCH && flat.EffectiveBalance >= spec.MIN_ACTIVATION_BALANCE {
+			toSetEligibility = append(toSetEligibility, vi)
+		}
+		if flat.ActivationEpoch == common.FAR_FUTURE_EPOCH && flat.ActivationEligibilityEpoch <= currentEpoch {
+			toMaybeActivate = append(toMaybeActivate, vi)
+		}
+
+		if _, ok := consolidating[vi]; !ok && needsEffectiveBalanceUpdate(vi) {
+			toUpdateEffectiveBalance = append(toUpdateEffectiveBalance, vi)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// process ejections
+	for _, index := range toEject {
+		if err := InitiateValidatorExit(spec, epc, state, index); err != nil {
+			return err
+		}
+	}
+	// InitiateValidatorExit does not use vals: writing through the old view would undo the exits.
+	vals, err = state.Validators()
+	if err != nil {
+		return err
+	}
+	// Process activation eligibility
+	eligibilityEpoch := currentEpoch + 1
+	for _, index := range toSetEligibility {
+		val, err := vals.Validator(index)
+		if err != nil {
+			return err
+		}
+		if err := val.SetActivationEligibilityEpoch(eligibilityEpoch); err != nil {
+			return err
+		}
+	}
+	// Process activations
+	{
+		// Order by the sequence of activation_eligibility_epoch setting and then index
+		sort.Slice(toMaybeActivate, func(i int, j int) bool {
+			a := flats[toMaybeActivate[i]].ActivationEligibilityEpoch
+			b := flats[toMaybeActivate[j]].ActivationEligibilityEpoch
+			if a == b {
+				return toMaybeActivate[i] < toMaybeActivate[j]
+			}
+			return a < b
+		})
+		churnLimit := min(uint64(spec.MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT), spec.GetChurnLimit(activeCount))
+		if uint64(len(toMaybeActivate)) > churnLimit {
+			toMaybeActivate = toMaybeActivate[:churnLimit]
+		}
+		activationEpoch := spec.ComputeActivationExitEpoch(currentEpoch)
+		for _, index := range toMaybeActivate {
+			if flats[index].ActivationEligibilityEpoch > finalized.Epoch {
+				break
+			}
+			val, err := vals.Validator(index)
+			if err != nil {
+				return err
+			}
+			if err := val.SetActivationEpoch(activationEpoch); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := phase0.ProcessEth1DataReset(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := processPendingConsolidations(spec, epc, state, pending, consolidations, &bals); err != nil {
+		return err
+	}
+	for index := range consolidating {
+		if needsEffectiveBalanceUpdate(index) {
+			toUpdateEffectiveBalance = append(toUpdateEffectiveBalance, index)
+		}
+	}
+	for _, index := range toUpdateEffectiveBalance {
+		val, err := vals.Validator(index)
+		if err != nil {
+			return err
+		}
+		creds, err := val.WithdrawalCredentials()
+		if err != nil {
+			return err
+		}
+		balance := bals[index]
+		effBalance := balance - (balance % spec.EFFECTIVE_BALANCE_INCREMENT)
+		if maxEffBalance := GetMaxEffectiveBalance(spec, creds); maxEffBalance < effBalance {
+			effBalance = maxEffBalance
+		}
+		if err := val.SetEffectiveBalance(effBalance); err != nil {
+			return err
+		}
+	}
+	if err := state.SetBalances(bals); err != nil {
+		return err
+	}
+	if !isGenesis {
+		if err := state.SetInactivityScores(scores); err != nil {
+			return err
+		}
+	}
+
+	if err := phase0.ProcessRandaoMixesReset(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := capella.ProcessHistoricalSummariesUpdate(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	if err := altair.ProcessParticipationFlagUpdates(ctx, spec, state); err != nil {
+		return err
+	}
+	return nil
+}
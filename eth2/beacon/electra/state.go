@@ -52,12 +52,17 @@ type BeaconState struct {
 	// Deep history valid from Capella onwards
 	HistoricalSummaries capella.HistoricalSummaries `json:"historical_summaries"`
 	// Deposit & withdrawals
-	DepositRequestsStartIndex common.Number             `json:"deposit_requests_start_index" yaml:"deposit_requests_start_index"`
-	DepositBalanceToConsume   common.Gwei               `json:"deposit_balance_to_consume" yaml:"deposit_balance_to_consume"`
-	ExitBalanceToConsume      common.Gwei               `json:"exit_balance_to_consume" yaml:"exit_balance_to_consume"`
-	EarliestExitEpoch         common.Epoch              `json:"earliest_exit_epoch" yaml:"earliest_exit_epoch"`
+	DepositRequestsStartIndex common.Number `json:"deposit_requests_start_index" yaml:"deposit_requests_start_index"`
+	DepositBalanceToConsume   common.Gwei   `json:"deposit_balance_to_consume" yaml:"deposit_balance_to_consume"`
+	ExitBalanceToConsume      common.Gwei   `json:"exit_balance_to_consume" yaml:"exit_balance_to_consume"`
+	EarliestExitEpoch         common.Epoch  `json:"earliest_exit_epoch" yaml:"earliest_exit_epoch"`
+	// Consolidations
+	ConsolidationBalanceToConsume common.Gwei  `json:"consolidation_balance_to_consume" yaml:"consolidation_balance_to_consume"`
+	EarliestConsolidationEpoch    common.Epoch `json:"earliest_consolidation_epoch" yaml:"earliest_consolidation_epoch"`
+	// Queues
 	PendingDeposits           PendingDeposits           `json:"pending_deposits" yaml:"pending_deposits"`
 	PendingPartialWithdrawals PendingPartialWithdrawals `json:"pending_partial_withdrawals" yaml:"pending_partial_withdrawals"`
+	PendingConsolidations     PendingConsolidations     `json:"pending_consolidations" yaml:"pending_consolidations"`
 }
 
 func (v *BeaconState) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
@@ -78,7 +83,9 @@ func (v *BeaconState) Deserialize(spec *common.Spec, dr *codec.DecodingReader) e
 		&v.DepositRequestsStartIndex,
 		&v.DepositBalanceToConsume, &v.ExitBalanceToConsume,
 		&v.EarliestExitEpoch,
+		&v.ConsolidationBalanceToConsume, &v.EarliestConsolidationEpoch,
 		spec.Wrap(&v.PendingDeposits), spec.Wrap(&v.PendingPartialWithdrawals),
+		spec.Wrap(&v.PendingConsolidations),
 	)
 }
 
@@ -100,7 +107,9 @@ func (v *BeaconState) Serialize(spec *common.Spec, w *codec.EncodingWriter) erro
 		&v.DepositRequestsStartIndex,
 		&v.DepositBalanceToConsume, &v.ExitBalanceToConsume,
 		&v.EarliestExitEpoch,
+		&v.ConsolidationBalanceToConsume, &v.EarliestConsolidationEpoch,
 		spec.Wrap(&v.PendingDeposits), spec.Wrap(&v.PendingPartialWithdrawals),
+		spec.Wrap(&v.PendingConsolidations),
 	)
 }
 
@@ -122,7 +131,9 @@ func (v *BeaconState) ByteLength(spec *common.Spec) uint64 {
 		&v.DepositRequestsStartIndex,
 		&v.DepositBalanceToConsume, &v.ExitBalanceToConsume,
 		&v.EarliestExitEpoch,
+		&v.ConsolidationBalanceToConsume, &v.EarliestConsolidationEpoch,
 		spec.Wrap(&v.PendingDeposits), spec.Wrap(&v.PendingPartialWithdrawals),
+		spec.Wrap(&v.PendingConsolidations),
 	)
 }
 
@@ -148,7 +159,9 @@ func (v *BeaconState) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) common.Ro
 		&v.DepositRequestsStartIndex,
 		&v.DepositBalanceToConsume, &v.ExitBalanceToConsume,
 		&v.EarliestExitEpoch,
+		&v.ConsolidationBalanceToConsume, &v.EarliestConsolidationEpoch,
 		spec.Wrap(&v.PendingDeposits), spec.Wrap(&v.PendingPartialWithdrawals),
+		spec.Wrap(&v.PendingConsolidations),
 	)
 }
 
@@ -185,8 +198,11 @@ const (
 	_depositBalanceToConsume
 	_exitBalanceToConsume
 	_earliestExitEpoch
+	_consolidationBalanceToConsume
+	_earliestConsolidationEpoch
 	_pendingDeposits
 	_pendingPartialWithdrawals
+	_pendingConsolidations
 )
 
 func BeaconStateType(spec *common.Spec) *ContainerTypeDef {
@@ -234,8 +250,11 @@ func BeaconStateType(spec *common.Spec) *ContainerTypeDef {
 		{"deposit_balance_to_consume", common.GweiType},
 		{"exit_balance_to_consume", common.GweiType},
 		{"earliest_exit_epoch", common.EpochType},
+		{"consolidation_balance_to_consume", common.GweiType},
+		{"earliest_consolidation_epoch", common.EpochType},
 		{"pending_deposits", PendingDepositsType(spec)},
 		{"pending_partial_withdrawals", PendingPartialWithdrawalsType(spec)},
+		{"pending_consolidations", PendingConsolidationsType(spec)},
 	})
 }
 
@@ -373,8 +392,9 @@ func (state *BeaconStateView) SetReserves(v common.Number) error {
 
 func (state *BeaconStateView) AddValidator(spec *common.Spec, pub common.BLSPubkey, withdrawalCreds common.Root, balance common.Gwei) error {
 	effBalance := balance - (balance % spec.EFFECTIVE_BALANCE_INCREMENT)
-	if effBalance > spec.MAX_EFFECTIVE_BALANCE {
-		effBalance = spec.MAX_EFFECTIVE_BALANCE
+	// Modified in Electra:EIP7251
+	if maxEffBalance := GetMaxEffectiveBalance(spec, withdrawalCreds); effBalance > maxEffBalance {
+		effBalance = maxEffBalance
 	}
 	validatorRaw := phase0.Validator{
 		Pubkey:                     pub,
@@ -513,6 +533,15 @@ func (state *BeaconStateView) InactivityScores() (*altair.InactivityScoresView,
 	return altair.AsInactivityScores(state.Get(_inactivityScores))
 }
 
+func (state *BeaconStateView) SetInactivityScores(scores []uint64) error {
+	typ := state.Fields[_inactivityScores].Type.(*BasicListTypeDef)
+	scoresView, err := altair.NewInactivityScoresView(scores, typ.ListLimit)
+	if err != nil {
+		return err
+	}
+	return state.Set(_inactivityScores, scoresView)
+}
+
 func (state *BeaconStateView) LatestExecutionPayloadHeader() (*deneb.ExecutionPayloadHeaderView, error) {
 	return deneb.AsExecutionPayloadHeader(state.Get(_latestExecutionPayloadHeader))
 }
@@ -597,12 +626,45 @@ func (state *BeaconStateView) PendingPartialWithdrawals() (PendingPartialWithdra
 	return AsPendingPartialWithdrawals(v, err)
 }
 
+func (state *BeaconStateView) ConsolidationBalanceToConsume() (common.Gwei, error) {
+	v, err := state.Get(_consolidationBalanceToConsume)
+	return common.AsGwei(v, err)
+}
+
+func (state *BeaconStateView) SetConsolidationBalanceToConsume(v common.Gwei) error {
+	return state.Set(_consolidationBalanceToConsume, Uint64View(v))
+}
+
+func (state *BeaconStateView) EarliestConsolidationEpoch() (common.Epoch, error) {
+	v, err := state.Get(_earliestConsolidationEpoch)
+	return common.AsEpoch(v, err)
+}
+
+func (state *BeaconStateView) SetEarliestConsolidationEpoch(v common.Epoch) error {
+	return state.Set(_earliestConsolidationEpoch, Uint64View(v))
+}
+
+func (state *BeaconStateView) PendingConsolidations() (PendingConsolidationsList, error) {
+	v, err := state.Get(_pendingConsolidations)
+	return AsPendingConsolidations(v, err)
+}
+
 type PendingDepositsList interface {
 	Append(deposit PendingDeposit) error
 }
 
+type PendingConsolidationsList interface {
+	Append(consolidation PendingConsolidation) error
+	Consolidations() (PendingConsolidations, error)
+	// Dequeue removes the first n consolidations
+	Dequeue(n uint64) error
+}
+
 type PendingPartialWithdrawalsList interface {
 	Append(withdrawal PendingPartialWithdrawal) error
+	Withdrawals() (PendingPartialWithdrawals, error)
+	// Dequeue removes the first n withdrawals
+	Dequeue(n uint64) error
 }
 
 func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettings {
@@ -612,6 +674,7 @@ func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettin
 		CalcProposerShare: func(whistleblowerReward common.Gwei) common.Gwei {
 			return whistleblowerReward * altair.PROPOSER_WEIGHT / altair.WEIGHT_DENOMINATOR
 		},
+		MaxEffectiveBalance: spec.MAX_EFFECTIVE_BALANCE_ELECTRA,
 	}
 }
 
@@ -630,7 +693,7 @@ func (state *BeaconStateView) Raw(spec *common.Spec) (*BeaconState, error) {
 }
 
 func (state *BeaconStateView) CopyState() (common.BeaconState, error) {
-	return altair.AsBeaconStateView(state.ContainerView.Copy())
+	return AsBeaconStateView(state.ContainerView.Copy())
 }
 
 type ExecutionTrackingBeaconState interface {
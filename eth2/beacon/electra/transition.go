@@ -12,6 +12,12 @@ import (
 )
 
 func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	return ProcessEpochSinglePass(ctx, spec, epc, state)
+}
+
+// ProcessEpochStepwise processes the epoch one step at a time, like the spec does.
+// It produces the same post-state as ProcessEpochSinglePass, and serves as reference to it.
+func (state *BeaconStateView) ProcessEpochStepwise(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
 	vals, err := state.Validators()
 	if err != nil {
 		return err
@@ -39,14 +45,19 @@ func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spe
 	if err := altair.ProcessEpochRewardsAndPenalties(ctx, spec, epc, attesterData, state); err != nil {
 		return err
 	}
-	// Modified in Deneb
-	if err := deneb.ProcessEpochRegistryUpdates(ctx, spec, epc, flats, state); err != nil {
+	// Modified in Electra:EIP7251
+	if err := ProcessEpochRegistryUpdates(ctx, spec, epc, flats, state); err != nil {
 		return err
 	}
 	if err := phase0.ProcessEth1DataReset(ctx, spec, epc, state); err != nil {
 		return err
 	}
-	if err := phase0.ProcessEffectiveBalanceUpdates(ctx, spec, epc, flats, state); err != nil {
+	// New in Electra:EIP7251
+	if err := ProcessPendingConsolidations(ctx, spec, epc, state); err != nil {
+		return err
+	}
+	// Modified in Electra:EIP7251
+	if err := ProcessEffectiveBalanceUpdates(ctx, spec, epc, flats, state); err != nil {
 		return err
 	}
 	if err := phase0.ProcessRandaoMixesReset(ctx, spec, epc, state); err != nil {
@@ -61,57 +72,67 @@ func (state *BeaconStateView) ProcessEpoch(ctx context.Context, spec *common.Spe
 	return nil
 }
 
-func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) error {
-	body, ok := benv.Body.(*deneb.BeaconBlockBody)
+func (state *BeaconStateView) ProcessBlock(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, benv *common.BeaconBlockEnvelope) (common.PayloadStatus, error) {
+	body, ok := benv.Body.(*BeaconBlockBody)
 	if !ok {
-		return fmt.Errorf("unexpected block type %T in Bellatrix ProcessBlock", benv.Body)
+		return common.PayloadUnknown, fmt.Errorf("unexpected block type %T in Electra ProcessBlock", benv.Body)
 	}
 	expectedProposer, err := epc.GetBeaconProposer(benv.Slot)
 	if err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := common.ProcessHeader(ctx, spec, state, &benv.BeaconBlockHeader, expectedProposer); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
-	if err := capella.ProcessWithdrawals(ctx, spec, state, &body.ExecutionPayload); err != nil {
-		return err
+	// Modified in Electra:EIP7251
+	if err := ProcessWithdrawals(ctx, spec, state, &body.ExecutionPayload); err != nil {
+		return common.PayloadUnknown, err
 	}
 	// Modified in Deneb
 	eng, ok := spec.ExecutionEngine.(deneb.ExecutionEngine)
 	if !ok {
-		return fmt.Errorf("provided execution-engine interface does not support Deneb: %T", spec.ExecutionEngine)
+		return common.PayloadUnknown, fmt.Errorf("provided execution-engine interface does not support Deneb: %T", spec.ExecutionEngine)
 	}
-	if err := deneb.ProcessExecutionPayload(ctx, spec, state, body, eng); err != nil {
-		return err
+	// The payload and blob commitments are unchanged since Deneb
+	denebBody := &deneb.BeaconBlockBody{ExecutionPayload: body.ExecutionPayload, BlobKZGCommitments: body.BlobKZGCommitments}
+	status, err := deneb.ProcessExecutionPayload(ctx, spec, state, denebBody, eng)
+	if err != nil {
+		return status, err
 	}
 	if err := phase0.ProcessRandaoReveal(ctx, spec, epc, state, body.RandaoReveal); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	if err := phase0.ProcessEth1Vote(ctx, spec, epc, state, body.Eth1Data); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 	// Safety checks, in case the user of the function provided too many operations
 	if err := body.CheckLimits(spec); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
 
-	if err := phase0.ProcessProposerSlashings(ctx, spec, epc, state, body.ProposerSlashings); err != nil {
-		return err
+	// Modified in Electra:EIP7251
+	if err := ProcessProposerSlashings(ctx, spec, epc, state, body.ProposerSlashings); err != nil {
+		return common.PayloadUnknown, err
 	}
-	if err := phase0.ProcessAttesterSlashings(ctx, spec, epc, state, body.AttesterSlashings); err != nil {
-		return err
+	// Modified in Electra
+	if err := ProcessAttesterSlashings(ctx, spec, epc, state, body.AttesterSlashings); err != nil {
+		return common.PayloadUnknown, err
 	}
-	// Modified in Deneb
-	if err := deneb.ProcessAttestations(ctx, spec, epc, state, body.Attestations); err != nil {
-		return err
+	// Modified in Electra
+	if err := ProcessAttestations(ctx, spec, epc, state, body.Attestations); err != nil {
+		return common.PayloadUnknown, err
 	}
 	// Note: state.AddValidator changed in Altair, but the deposit processing itself stayed the same.
 	if err := phase0.ProcessDeposits(ctx, spec, epc, state, body.Deposits); err != nil {
-		return err
+		return common.PayloadUnknown, err
 	}
-	// Modified in Deneb
-	if err := deneb.ProcessVoluntaryExits(ctx, spec, epc, state, body.VoluntaryExits); err != nil {
-		return err
+	// Modified in Electra:EIP7251
+	if err := ProcessVoluntaryExits(ctx, spec, epc, state, body.VoluntaryExits); err != nil {
+		return common.PayloadUnknown, err
 	}
-	return nil
+	// New in Electra:EIP7251
+	if err := ProcessConsolidationRequests(ctx, spec, epc, state, body.ExecutionRequests.Consolidations); err != nil {
+		return common.PayloadUnknown, err
+	}
+	return status, nil
 }
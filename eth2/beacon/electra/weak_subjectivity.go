@@ -0,0 +1,44 @@
+package electra
+
+import (
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// ComputeWeakSubjectivityPeriod computes the weak subjectivity period of the state, like phase0.ComputeWeakSubjectivityPeriod,
+// but with the balance-weighted churn of Electra.
+func ComputeWeakSubjectivityPeriod(spec *common.Spec, state common.BeaconState) (common.Epoch, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return 0, err
+	}
+	currentEpoch := spec.SlotToEpoch(slot)
+	vals, err := state.Validators()
+	if err != nil {
+		return 0, err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return 0, err
+	}
+	totalActive := common.Gwei(0)
+	for i := range flats {
+		if flats[i].IsActive(currentEpoch) {
+			totalActive += flats[i].EffectiveBalance
+		}
+	}
+	return WeakSubjectivityPeriod(spec, totalActive), nil
+}
+
+// WeakSubjectivityPeriod computes the weak subjectivity period for the given total active balance.
+// Modified in Electra: the validator set can churn by at most the balance churn limit per epoch,
+// top-ups are no longer accounted for separately.
+func WeakSubjectivityPeriod(spec *common.Spec, totalActive common.Gwei) common.Epoch {
+	// Like get_total_active_balance, the total is at least one increment
+	if totalActive < spec.EFFECTIVE_BALANCE_INCREMENT {
+		totalActive = spec.EFFECTIVE_BALANCE_INCREMENT
+	}
+	delta := GetBalanceChurnLimit(spec, totalActive)
+	epochsForValidatorSetChurn := phase0.SAFETY_DECAY * uint64(totalActive) / (2 * uint64(delta) * 100)
+	return spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY + common.Epoch(epochsForValidatorSetChurn)
+}
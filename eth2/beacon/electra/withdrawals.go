@@ -0,0 +1,253 @@
+package electra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+type PendingPartialWithdrawalsBeaconState interface {
+	common.BeaconState
+	PendingPartialWithdrawals() (PendingPartialWithdrawalsList, error)
+}
+
+type BeaconStateWithWithdrawals interface {
+	capella.BeaconStateWithWithdrawals
+	PendingPartialWithdrawalsBeaconState
+}
+
+// IsFullyWithdrawableValidator checks if the validator has execution withdrawal credentials,
+// and is withdrawable (MIN_VALIDATOR_WITHDRAWABILITY_DELAY epochs after exiting) with a balance left to withdraw.
+func IsFullyWithdrawableValidator(spec *common.Spec, validator common.Validator, balance common.Gwei, epoch common.Epoch) (bool, error) {
+	hasExecutionCreds, err := HasExecutionWithdrawalCredential(validator)
+	if err != nil {
+		return false, err
+	}
+	exitEpoch, err := validator.ExitEpoch()
+	if err != nil {
+		return false, err
+	}
+	withdrawableEpoch := exitEpoch + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	if withdrawableEpoch < exitEpoch { // not exiting, or exiting too far in the future to ever be withdrawable
+		return false, nil
+	}
+	return hasExecutionCreds && withdrawableEpoch <= epoch && balance > 0, nil
+}
+
+// IsPartiallyWithdrawableValidator checks if the validator has balance in excess of its max effective balance,
+// while the effective balance itself is at the max.
+func IsPartiallyWithdrawableValidator(spec *common.Spec, validator common.Validator, balance common.Gwei) (bool, error) {
+	creds, err := validator.WithdrawalCredentials()
+	if err != nil {
+		return false, err
+	}
+	hasExecutionCreds, err := HasExecutionWithdrawalCredential(validator)
+	if err != nil {
+		return false, err
+	}
+	effectiveBalance, err := validator.EffectiveBalance()
+	if err != nil {
+		return false, err
+	}
+	maxEffectiveBalance := GetMaxEffectiveBalance(spec, creds)
+	hasMaxEffectiveBalance := effectiveBalance == maxEffectiveBalance
+	hasExcessBalance := balance > maxEffectiveBalance
+	return hasExecutionCreds && hasMaxEffectiveBalance && hasExcessBalance, nil
+}
+
+// GetExpectedWithdrawals returns the withdrawals of the next payload,
+// and the number of pending partial withdrawals that these process.
+// Pending partial withdrawals are processed first, then the regular sweep follows.
+func GetExpectedWithdrawals(state BeaconStateWithWithdrawals, spec *common.Spec) ([]common.Withdrawal, uint64, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, 0, err
+	}
+	epoch := spec.SlotToEpoch(slot)
+	withdrawalIndex, err := state.NextWithdrawalIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+	validatorIndex, err := state.NextWithdrawalValidatorIndex()
+	if err != nil {
+		return nil, 0, err
+	}
+	validators, err := state.Validators()
+	if err != nil {
+		return nil, 0, err
+	}
+	validatorCount, err := validators.ValidatorCount()
+	if err != nil {
+		return nil, 0, err
+	}
+	balances, err := state.Balances()
+	if err != nil {
+		return nil, 0, err
+	}
+	pendingList, err := state.PendingPartialWithdrawals()
+	if err != nil {
+		return nil, 0, err
+	}
+	pending, err := pendingList.Withdrawals()
+	if err != nil {
+		return nil, 0, err
+	}
+	withdrawals := make(common.Withdrawals, 0)
+	// balance that is withdrawn already by earlier withdrawals of this payload, per validator
+	withdrawn := make(map[common.ValidatorIndex]common.Gwei)
+
+	// [New in Electra:EIP7251] Consume pending partial withdrawals
+	processedPartials := uint64(0)
+	for _, w := range pending {
+		if w.WithdrawableEpoch > epoch || len(withdrawals) == int(spec.MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP) {
+			break
+		}
+		validator, err := validators.Validator(w.Index)
+		if err != nil {
+			return nil, 0, err
+		}
+		exitEpoch, err := validator.ExitEpoch()
+		if err != nil {
+			return nil, 0, err
+		}
+		effectiveBalance, err := validator.EffectiveBalance()
+		if err != nil {
+			return nil, 0, err
+		}
+		balance, err := balances.GetBalance(w.Index)
+		if err != nil {
+			return nil, 0, err
+		}
+		balance -= withdrawn[w.Index]
+		if exitEpoch == common.FAR_FUTURE_EPOCH &&
+			effectiveBalance >= spec.MIN_ACTIVATION_BALANCE && balance > spec.MIN_ACTIVATION_BALANCE {
+			amount := min(balance-spec.MIN_ACTIVATION_BALANCE, w.Amount)
+			withdrawals = append(withdrawals, common.Withdrawal{
+				Index:          withdrawalIndex,
+				ValidatorIndex: w.Index,
+				Address:        capella.Eth1WithdrawalCredential(validator),
+				Amount:         amount,
+			})
+			withdrawn[w.Index] += amount
+			withdrawalIndex += 1
+		}
+		processedPartials += 1
+	}
+
+	bound := min(validatorCount, uint64(spec.MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP))
+	for i := uint64(0); i < bound; i++ {
+		validator, err := validators.Validator(validatorIndex)
+		if err != nil {
+			return nil, 0, err
+		}
+		balance, err := balances.GetBalance(validatorIndex)
+		if err != nil {
+			return nil, 0, err
+		}
+		balance -= withdrawn[validatorIndex]
+		if fully, err := IsFullyWithdrawableValidator(spec, validator, balance, epoch); err != nil {
+			return nil, 0, err
+		} else if fully {
+			withdrawals = append(withdrawals, common.Withdrawal{
+				Index:          withdrawalIndex,
+				ValidatorIndex: validatorIndex,
+				Address:        capella.Eth1WithdrawalCredential(validator),
+				Amount:         balance,
+			})
+			withdrawalIndex += 1
+		} else if partially, err := IsPartiallyWithdrawableValidator(spec, validator, balance); err != nil {
+			return nil, 0, err
+		} else if partially {
+			creds, err := validator.WithdrawalCredentials()
+			if err != nil {
+				return nil, 0, err
+			}
+			withdrawals = append(withdrawals, common.Withdrawal{
+				Index:          withdrawalIndex,
+				ValidatorIndex: validatorIndex,
+				Address:        capella.Eth1WithdrawalCredential(validator),
+				Amount:         balance - GetMaxEffectiveBalance(spec, creds),
+			})
+			withdrawalIndex += 1
+		}
+		if len(withdrawals) == int(spec.MAX_WITHDRAWALS_PER_PAYLOAD) {
+			break
+		}
+		validatorIndex = common.ValidatorIndex(uint64(validatorIndex+1) % validatorCount)
+	}
+	return withdrawals, processedPartials, nil
+}
+
+// ProcessWithdrawals is capella.ProcessWithdrawals, with the Electra withdrawals,
+// and it removes the processed pending partial withdrawals from the queue.
+func ProcessWithdrawals(ctx context.Context, spec *common.Spec, state BeaconStateWithWithdrawals, executionPayload capella.ExecutionPayloadWithWithdrawals) error {
+	expectedWithdrawals, processedPartials, err := GetExpectedWithdrawals(state, spec)
+	if err != nil {
+		return err
+	}
+	withdrawals := executionPayload.GetWitdrawals()
+	if len(expectedWithdrawals) != len(withdrawals) {
+		return fmt.Errorf("unexpected number of withdrawals in Electra ProcessWithdrawals: want=%d, got=%d", len(expectedWithdrawals), len(withdrawals))
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return err
+	}
+	for w := 0; w < len(expectedWithdrawals); w++ {
+		withdrawal := withdrawals[w]
+		expectedWithdrawal := expectedWithdrawals[w]
+		if withdrawal.Index != expectedWithdrawal.Index ||
+			withdrawal.ValidatorIndex != expectedWithdrawal.ValidatorIndex ||
+			!bytes.Equal(withdrawal.Address[:], expectedWithdrawal.Address[:]) ||
+			withdrawal.Amount != expectedWithdrawal.Amount {
+			return fmt.Errorf("unexpected withdrawal in Electra ProcessWithdrawals: want=%s, got=%s", expectedWithdrawal, withdrawal)
+		}
+		if err := common.DecreaseBalance(bals, expectedWithdrawal.ValidatorIndex, expectedWithdrawal.Amount); err != nil {
+			return fmt.Errorf("failed to decrease balance: %w", err)
+		}
+	}
+	// [New in Electra:EIP7251] Update pending partial withdrawals
+	if processedPartials > 0 {
+		pending, err := state.PendingPartialWithdrawals()
+		if err != nil {
+			return err
+		}
+		if err := pending.Dequeue(processedPartials); err != nil {
+			return fmt.Errorf("failed to dequeue pending partial withdrawals: %w", err)
+		}
+	}
+	if len(expectedWithdrawals) > 0 {
+		latestWithdrawal := expectedWithdrawals[len(expectedWithdrawals)-1]
+		if err := state.SetNextWithdrawalIndex(latestWithdrawal.Index + 1); err != nil {
+			return fmt.Errorf("failed to set withdrawal index: %w", err)
+		}
+	}
+	validators, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	validatorCount, err := validators.ValidatorCount()
+	if err != nil {
+		return err
+	}
+	if len(expectedWithdrawals) == int(spec.MAX_WITHDRAWALS_PER_PAYLOAD) {
+		latestWithdrawal := expectedWithdrawals[len(expectedWithdrawals)-1]
+		nextValidatorIndex := common.ValidatorIndex(uint64(latestWithdrawal.ValidatorIndex+1) % validatorCount)
+		if err = state.SetNextWithdrawalValidatorIndex(nextValidatorIndex); err != nil {
+			return err
+		}
+	} else {
+		nextValidatorIndex, err := state.NextWithdrawalValidatorIndex()
+		if err != nil {
+			return err
+		}
+		nextValidatorIndex = common.ValidatorIndex((uint64(nextValidatorIndex) + uint64(spec.MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP)) % validatorCount)
+		if err = state.SetNextWithdrawalValidatorIndex(nextValidatorIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
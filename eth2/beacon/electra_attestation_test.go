@@ -0,0 +1,114 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestUpgradeToElectraProcessesAttestations(t *testing.T) {
+	spec := configs.Minimal
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var state common.BeaconState = testGenesisState(t, spec)
+	epc, err := common.NewEpochsContext(spec, state)
+	check(err)
+	state, err = altair.UpgradeToAltair(spec, epc, state.(*phase0.BeaconStateView))
+	check(err)
+	state, err = bellatrix.UpgradeToBellatrix(spec, epc, state.(*altair.BeaconStateView))
+	check(err)
+	state, err = capella.UpgradeToCapella(spec, epc, state.(*bellatrix.BeaconStateView))
+	check(err)
+	state, err = deneb.UpgradeToDeneb(spec, epc, state.(*capella.BeaconStateView))
+	check(err)
+	electraState, err := electra.UpgradeToElectra(spec, epc, state.(*deneb.BeaconStateView))
+	check(err)
+	check(common.ProcessSlots(context.Background(), spec, epc, &StandardUpgradeableBeaconState{BeaconState: electraState}, 1))
+
+	// an EIP-7549 attestation: the committee is in the committee bits, not in the data index
+	committee, err := epc.GetBeaconCommittee(0, 0)
+	check(err)
+	blockRoot, err := common.GetBlockRootAtSlot(spec, electraState, 0)
+	check(err)
+	justified, err := electraState.CurrentJustifiedCheckpoint()
+	check(err)
+	data := phase0.AttestationData{
+		Slot:            0,
+		Index:           0,
+		BeaconBlockRoot: blockRoot,
+		Source:          justified,
+		Target:          common.Checkpoint{Epoch: 0, Root: blockRoot},
+	}
+	dom, err := common.GetDomain(electraState, common.DOMAIN_BEACON_ATTESTER, 0)
+	check(err)
+	signingRoot := common.ComputeSigningRoot(data.HashTreeRoot(tree.GetHashFn()), dom)
+	aggBits := make(electra.AttestationBitsElectra, (len(committee)+1+7)/8)
+	sigs := make([]*blsu.Signature, 0, len(committee))
+	for i, vi := range committee {
+		aggBits[i/8] |= 1 << (i % 8)
+		// the secret keys of the validators of testGenesisState
+		var skBytes [32]byte
+		skBytes[31] = byte(vi + 1)
+		var sk blsu.SecretKey
+		check(sk.Deserialize(&skBytes))
+		sigs = append(sigs, blsu.Sign(&sk, signingRoot[:]))
+	}
+	aggBits[len(committee)/8] |= 1 << (len(committee) % 8)
+	sig, err := blsu.Aggregate(sigs)
+	check(err)
+	commCount, err := epc.GetCommitteeCountPerSlot(0)
+	check(err)
+	commBits := make(electra.CommitteeBits, commCount/8+1)
+	commBits[commCount/8] |= 1 << (commCount % 8)
+	commBits.SetBit(0, true)
+	att := electra.AttestationElectra{
+		AggregationBits: aggBits,
+		Data:            data,
+		Signature:       sig.Serialize(),
+		CommitteeBits:   commBits,
+	}
+
+	// the data index must be 0 in Electra
+	invalid := att
+	invalid.Data.Index = 1
+	if err := electra.ProcessAttestations(context.Background(), spec, epc, electraState, []electra.AttestationElectra{invalid}); err == nil {
+		t.Fatal("expected an attestation with a non-zero data index to be rejected")
+	}
+	check(electra.ProcessAttestations(context.Background(), spec, epc, electraState, []electra.AttestationElectra{att}))
+	participation, err := electraState.CurrentEpochParticipation()
+	check(err)
+	for _, vi := range committee {
+		flags, err := participation.GetFlags(vi)
+		check(err)
+		if flags == 0 {
+			t.Fatalf("expected participation flags of attester %d", vi)
+		}
+	}
+
+	// the Electra block body allows MAX_ATTESTATIONS_ALPACA attestations, fewer than MAX_ATTESTATIONS
+	body := electra.BeaconBlockBody{Attestations: make(electra.AttestationsElectra, spec.MAX_ATTESTATIONS_ALPACA+1)}
+	if err := body.CheckLimits(spec); err == nil {
+		t.Fatal("expected a body with more than MAX_ATTESTATIONS_ALPACA attestations to be rejected")
+	}
+	// and Electra blocks carry Electra bodies
+	denebBody := &deneb.BeaconBlockBody{}
+	benv := &common.BeaconBlockEnvelope{BeaconBlockHeader: common.BeaconBlockHeader{Slot: 1}, Body: denebBody}
+	if _, err := electraState.ProcessBlock(context.Background(), spec, epc, benv); err == nil {
+		t.Fatal("expected a Deneb block body to be rejected by Electra block processing")
+	}
+}
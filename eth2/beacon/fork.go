@@ -9,6 +9,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 )
 
@@ -19,6 +20,7 @@ type ForkDecoder struct {
 	Bellatrix common.ForkDigest
 	Capella   common.ForkDigest
 	Deneb     common.ForkDigest
+	Electra   common.ForkDigest
 	// TODO more forks
 }
 
@@ -30,6 +32,7 @@ func NewForkDecoder(spec *common.Spec, genesisValRoot common.Root) *ForkDecoder
 		Bellatrix: common.ComputeForkDigest(spec.BELLATRIX_FORK_VERSION, genesisValRoot),
 		Capella:   common.ComputeForkDigest(spec.CAPELLA_FORK_VERSION, genesisValRoot),
 		Deneb:     common.ComputeForkDigest(spec.DENEB_FORK_VERSION, genesisValRoot),
+		Electra:   common.ComputeForkDigest(spec.ALPACA_FORK_VERSION, genesisValRoot),
 	}
 }
 
@@ -50,6 +53,8 @@ func (d *ForkDecoder) BlockAllocator(digest common.ForkDigest) (func() OpaqueBlo
 		return func() OpaqueBlock { return new(capella.SignedBeaconBlock) }, nil
 	case d.Deneb:
 		return func() OpaqueBlock { return new(deneb.SignedBeaconBlock) }, nil
+	case d.Electra:
+		return func() OpaqueBlock { return new(electra.SignedBeaconBlock) }, nil
 	default:
 		return nil, fmt.Errorf("unrecognized fork digest: %s", digest)
 	}
@@ -65,6 +70,9 @@ func (d *ForkDecoder) ForkDigest(epoch common.Epoch) common.ForkDigest {
 	} else {
 		// only consider deneb if it's actually set equal or higher than capella, to ignore it if it's missing in a config.
 		if d.Spec.DENEB_FORK_EPOCH >= d.Spec.CAPELLA_FORK_EPOCH && epoch >= d.Spec.DENEB_FORK_EPOCH {
+			if d.Spec.ALPACA_FORK_EPOCH >= d.Spec.DENEB_FORK_EPOCH && epoch >= d.Spec.ALPACA_FORK_EPOCH {
+				return d.Electra
+			}
 			return d.Deneb
 		}
 		return d.Capella
@@ -108,6 +116,13 @@ func (s *StandardUpgradeableBeaconState) UpgradeMaybe(ctx context.Context, spec
 		}
 		s.BeaconState = post
 	}
+	if tpre, ok := s.BeaconState.(*deneb.BeaconStateView); ok && slot == common.Slot(spec.ALPACA_FORK_EPOCH)*spec.SLOTS_PER_EPOCH {
+		post, err := electra.UpgradeToElectra(spec, epc, tpre)
+		if err != nil {
+			return fmt.Errorf("failed to upgrade deneb to electra state: %v", err)
+		}
+		s.BeaconState = post
+	}
 	return nil
 }
 
@@ -170,6 +185,17 @@ func EnvelopeToSignedBeaconBlock(benv *common.BeaconBlockEnvelope) (common.SpecO
 			},
 			Signature: benv.Signature,
 		}, nil
+	case *electra.BeaconBlockBody:
+		return &electra.SignedBeaconBlock{
+			Message: electra.BeaconBlock{
+				Slot:          benv.Slot,
+				ProposerIndex: benv.ProposerIndex,
+				ParentRoot:    benv.ParentRoot,
+				StateRoot:     benv.StateRoot,
+				Body:          *x,
+			},
+			Signature: benv.Signature,
+		}, nil
 	default:
 		return nil, fmt.Errorf("cannot convert beacon block envelope to full signed block, unrecognized body type: %T", x)
 	}
@@ -0,0 +1,140 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+type rewardAdjustmentState interface {
+	RewardAdjustmentFactor() (common.Number, error)
+	Reserves() (common.Number, error)
+}
+
+func TestUpgradeCarriesOverRewardAdjustment(t *testing.T) {
+	spec := *configs.Minimal
+	state := testGenesisState(t, &spec)
+	if err := state.SetRewardAdjustmentFactor(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetReserves(1234); err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(&spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(name string, s rewardAdjustmentState) {
+		if factor, err := s.RewardAdjustmentFactor(); err != nil || factor != 7 {
+			t.Fatalf("%s: expected reward adjustment factor 7, got %d (err: %v)", name, factor, err)
+		}
+		if reserves, err := s.Reserves(); err != nil || reserves != 1234 {
+			t.Fatalf("%s: expected reserves 1234, got %d (err: %v)", name, reserves, err)
+		}
+	}
+	altairState, err := altair.UpgradeToAltair(&spec, epc, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("altair", altairState)
+	bellatrixState, err := bellatrix.UpgradeToBellatrix(&spec, epc, altairState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("bellatrix", bellatrixState)
+	capellaState, err := capella.UpgradeToCapella(&spec, epc, bellatrixState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("capella", capellaState)
+	denebState, err := deneb.UpgradeToDeneb(&spec, epc, capellaState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("deneb", denebState)
+	electraState, err := electra.UpgradeToElectra(&spec, epc, denebState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("electra", electraState)
+}
+
+func TestUpgradeMaybeDenebToElectra(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 1
+	state, epc, err := deneb.KickStartState(&spec, common.Root{0x01}, 1_000_000, testValidators(t, &spec), &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the deneb state copies as a deneb state, to be recognized by the upgrade
+	copied, err := state.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := copied.(*deneb.BeaconStateView); !ok {
+		t.Fatalf("expected deneb state copy, got %T", copied)
+	}
+	upgradeable := &StandardUpgradeableBeaconState{BeaconState: copied}
+	if err := common.ProcessSlots(context.Background(), &spec, epc, upgradeable, common.Slot(spec.ALPACA_FORK_EPOCH)*spec.SLOTS_PER_EPOCH); err != nil {
+		t.Fatal(err)
+	}
+	post, ok := upgradeable.BeaconState.(*electra.BeaconStateView)
+	if !ok {
+		t.Fatalf("expected electra state after the fork slot, got %T", upgradeable.BeaconState)
+	}
+	if copied, err := post.CopyState(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := copied.(*electra.BeaconStateView); !ok {
+		t.Fatalf("expected electra state copy, got %T", copied)
+	}
+	fork, err := post.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.CurrentVersion != spec.ALPACA_FORK_VERSION || fork.PreviousVersion != spec.DENEB_FORK_VERSION {
+		t.Fatalf("unexpected fork versions: %v", fork)
+	}
+}
+
+func TestForkDecoderElectra(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_EPOCH = 2
+	spec.CAPELLA_FORK_EPOCH = 3
+	spec.DENEB_FORK_EPOCH = 4
+	spec.ALPACA_FORK_EPOCH = 5
+	d := NewForkDecoder(&spec, common.Root{0x01})
+	if d.Electra == d.Deneb {
+		t.Fatal("expected different fork digests for deneb and electra")
+	}
+	if digest := d.ForkDigest(4); digest != d.Deneb {
+		t.Fatalf("expected deneb digest before the electra fork, got %s", digest)
+	}
+	if digest := d.ForkDigest(5); digest != d.Electra {
+		t.Fatalf("expected electra digest at the electra fork, got %s", digest)
+	}
+	alloc, err := d.BlockAllocator(d.Electra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := alloc().(*electra.SignedBeaconBlock); !ok {
+		t.Fatalf("expected electra block, got %T", b)
+	}
+
+	// without an electra fork epoch the deneb digest stays
+	spec.ALPACA_FORK_EPOCH = ^common.Epoch(0)
+	if digest := NewForkDecoder(&spec, common.Root{0x01}).ForkDigest(100); digest != d.Deneb {
+		t.Fatalf("expected deneb digest without electra fork, got %s", digest)
+	}
+}
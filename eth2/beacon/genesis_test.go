@@ -0,0 +1,169 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestPostMergeGenesis(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	validators := testValidators(t, &spec)
+	blockHash := common.Root{0xab}
+	eth1BlockHash := common.Root{0x01}
+
+	cases := []struct {
+		fork    ForkName
+		version common.Version
+		genesis func() (common.BeaconState, common.Root, error)
+	}{
+		{Bellatrix, spec.BELLATRIX_FORK_VERSION, func() (common.BeaconState, common.Root, error) {
+			state, _, err := bellatrix.KickStartState(&spec, eth1BlockHash, 1_000_000, validators,
+				&bellatrix.ExecutionPayloadHeader{BlockHash: blockHash})
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			h, err := state.LatestExecutionPayloadHeader()
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			got, err := h.BlockHash()
+			return state, got, err
+		}},
+		{Capella, spec.CAPELLA_FORK_VERSION, func() (common.BeaconState, common.Root, error) {
+			state, _, err := capella.KickStartState(&spec, eth1BlockHash, 1_000_000, validators,
+				&capella.ExecutionPayloadHeader{BlockHash: blockHash})
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			h, err := state.LatestExecutionPayloadHeader()
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			got, err := h.BlockHash()
+			return state, got, err
+		}},
+		{Deneb, spec.DENEB_FORK_VERSION, func() (common.BeaconState, common.Root, error) {
+			state, _, err := deneb.KickStartState(&spec, eth1BlockHash, 1_000_000, validators,
+				&deneb.ExecutionPayloadHeader{BlockHash: blockHash})
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			h, err := state.LatestExecutionPayloadHeader()
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			got, err := h.BlockHash()
+			return state, got, err
+		}},
+		{Electra, spec.ALPACA_FORK_VERSION, func() (common.BeaconState, common.Root, error) {
+			state, _, err := electra.KickStartState(&spec, eth1BlockHash, 1_000_000, validators,
+				&deneb.ExecutionPayloadHeader{BlockHash: blockHash})
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			// the churn fields start at zero, they are not initialized to the churn limits like in the fork upgrade
+			if exitEpoch, err := state.EarliestExitEpoch(); err != nil || exitEpoch != common.GENESIS_EPOCH {
+				t.Fatalf("unexpected earliest exit epoch %d", exitEpoch)
+			}
+			if exitBalance, err := state.ExitBalanceToConsume(); err != nil || exitBalance != 0 {
+				t.Fatalf("unexpected exit balance to consume %d", exitBalance)
+			}
+			if consolidationEpoch, err := state.EarliestConsolidationEpoch(); err != nil || consolidationEpoch != common.GENESIS_EPOCH {
+				t.Fatalf("unexpected earliest consolidation epoch %d", consolidationEpoch)
+			}
+			if consolidationBalance, err := state.ConsolidationBalanceToConsume(); err != nil || consolidationBalance != 0 {
+				t.Fatalf("unexpected consolidation balance to consume %d", consolidationBalance)
+			}
+			if startIndex, err := state.DepositRequestsStartIndex(); err != nil || uint64(startIndex) != electra.UNSET_DEPOSIT_REQUESTS_START_INDEX {
+				t.Fatalf("unexpected deposit requests start index %d", startIndex)
+			}
+			h, err := state.LatestExecutionPayloadHeader()
+			if err != nil {
+				return nil, common.Root{}, err
+			}
+			got, err := h.BlockHash()
+			return state, got, err
+		}},
+	}
+	for _, c := range cases {
+		t.Run(string(c.fork), func(t *testing.T) {
+			state, gotBlockHash, err := c.genesis()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fork, err := StateForkName(state); err != nil || fork != c.fork {
+				t.Fatalf("expected %s state, got %s (%v)", c.fork, fork, err)
+			}
+			if gotBlockHash != blockHash {
+				t.Fatalf("unexpected execution block hash %s", gotBlockHash)
+			}
+			fork, err := state.Fork()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fork.PreviousVersion != c.version || fork.CurrentVersion != c.version || fork.Epoch != common.GENESIS_EPOCH {
+				t.Fatalf("unexpected fork %+v", fork)
+			}
+			participation, err := state.(interface {
+				CurrentEpochParticipation() (*altair.ParticipationRegistryView, error)
+			}).CurrentEpochParticipation()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := participation.Length(); err != nil || n != uint64(len(validators)) {
+				t.Fatalf("expected participation of %d validators, got %d", len(validators), n)
+			}
+			scores, err := state.(interface {
+				InactivityScores() (*altair.InactivityScoresView, error)
+			}).InactivityScores()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := scores.Length(); err != nil || n != uint64(len(validators)) {
+				t.Fatalf("expected inactivity scores of %d validators, got %d", len(validators), n)
+			}
+
+			var buf bytes.Buffer
+			if err := state.Serialize(codec.NewEncodingWriter(&buf)); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := DecodeBeaconState(&spec, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.HashTreeRoot(tree.GetHashFn()) != state.HashTreeRoot(tree.GetHashFn()) {
+				t.Fatal("decoded genesis state does not match")
+			}
+
+			// The state must process empty epochs without upgrading again
+			epc, err := common.NewEpochsContext(&spec, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			up := &StandardUpgradeableBeaconState{BeaconState: state}
+			if err := common.ProcessSlots(context.Background(), &spec, epc, up, spec.SLOTS_PER_EPOCH*2); err != nil {
+				t.Fatal(err)
+			}
+			if fork, err := StateForkName(up.BeaconState); err != nil || fork != c.fork {
+				t.Fatalf("expected %s state after processing slots, got %s (%v)", c.fork, fork, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package phase0
 
 import (
+	"fmt"
+
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/ztyp/codec"
 	"github.com/protolambda/ztyp/tree"
@@ -48,6 +50,50 @@ func (li Balances) View(limit uint64) (*RegistryBalancesView, error) {
 	return AsRegistryBalances(typ.FromElements(tmp...))
 }
 
+var _ common.BalancesRegistry = (*Balances)(nil)
+
+// GetBalance, and the other BalancesRegistry methods, make Balances usable
+// as in-memory registry during processing, before writing it back to the state with SetBalances.
+func (li *Balances) GetBalance(index common.ValidatorIndex) (common.Gwei, error) {
+	if uint64(index) >= uint64(len(*li)) {
+		return 0, fmt.Errorf("balance index out of range: %d", index)
+	}
+	return (*li)[index], nil
+}
+
+func (li *Balances) SetBalance(index common.ValidatorIndex, bal common.Gwei) error {
+	if uint64(index) >= uint64(len(*li)) {
+		return fmt.Errorf("balance index out of range: %d", index)
+	}
+	(*li)[index] = bal
+	return nil
+}
+
+func (li *Balances) AppendBalance(bal common.Gwei) error {
+	*li = append(*li, bal)
+	return nil
+}
+
+func (li *Balances) Iter() (next func() (bal common.Gwei, ok bool, err error)) {
+	i := 0
+	return func() (bal common.Gwei, ok bool, err error) {
+		if i >= len(*li) {
+			return 0, false, nil
+		}
+		bal = (*li)[i]
+		i += 1
+		return bal, true, nil
+	}
+}
+
+func (li *Balances) AllBalances() ([]common.Gwei, error) {
+	return append([]common.Gwei(nil), *li...), nil
+}
+
+func (li *Balances) Length() (uint64, error) {
+	return uint64(len(*li)), nil
+}
+
 func RegistryBalancesType(spec *common.Spec) *BasicListTypeDef {
 	return BasicListType(common.GweiType, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
 }
@@ -78,6 +78,33 @@ func (v *Eth1DataVotesView) Count(dat common.Eth1Data) (uint64, error) {
 	}
 	return count, nil
 }
+func (v *Eth1DataVotesView) Votes() ([]common.Eth1Data, error) {
+	length, err := v.Length()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]common.Eth1Data, 0, length)
+	iter := v.ReadonlyIter()
+	for {
+		el, ok, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		dat, err := common.AsEth1Data(el, nil)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := dat.Raw()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, raw)
+	}
+	return out, nil
+}
 func (v *Eth1DataVotesView) Append(dat common.Eth1Data) error {
 	return v.ComplexListView.Append(dat.View())
 }
@@ -0,0 +1,135 @@
+package phase0
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// Eth1Block is the minimal eth1 block data a proposer needs to vote on eth1 data.
+type Eth1Block struct {
+	Number       uint64              `json:"number" yaml:"number"`
+	Timestamp    common.Timestamp    `json:"timestamp" yaml:"timestamp"`
+	BlockHash    common.Root         `json:"block_hash" yaml:"block_hash"`
+	DepositRoot  common.Root         `json:"deposit_root" yaml:"deposit_root"`
+	DepositCount common.DepositIndex `json:"deposit_count" yaml:"deposit_count"`
+}
+
+func (b *Eth1Block) Eth1Data() common.Eth1Data {
+	return common.Eth1Data{
+		DepositRoot:  b.DepositRoot,
+		DepositCount: b.DepositCount,
+		BlockHash:    b.BlockHash,
+	}
+}
+
+// Eth1BlockSource provides the eth1 blocks to consider for voting, e.g. from an eth1 RPC client or a local cache.
+type Eth1BlockSource interface {
+	// BlocksInTimeRange returns the blocks with a timestamp in the range [minTime, maxTime] (both inclusive),
+	// sorted by ascending block number.
+	BlocksInTimeRange(ctx context.Context, minTime common.Timestamp, maxTime common.Timestamp) ([]Eth1Block, error)
+}
+
+// VotingPeriodStartTime returns the timestamp of the first slot of the eth1 voting period of the given slot.
+func VotingPeriodStartTime(spec *common.Spec, genesisTime common.Timestamp, slot common.Slot) (common.Timestamp, error) {
+	periodSlots := common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH
+	return spec.TimeAtSlot(slot-(slot%periodSlots), genesisTime)
+}
+
+// IsCandidateBlock checks if the eth1 block is within the follow-distance window before the start of the voting period.
+func IsCandidateBlock(spec *common.Spec, block *Eth1Block, periodStart common.Timestamp) bool {
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK) * common.Timestamp(spec.ETH1_FOLLOW_DISTANCE)
+	return block.Timestamp+followTime <= periodStart && block.Timestamp+followTime*2 >= periodStart
+}
+
+// GetEth1Vote computes the eth1 data a proposer should include in a block on top of the given state:
+// the most popular valid vote of the current voting period, or the latest candidate block otherwise.
+// If the eth1 chain is not live, i.e. there are no candidate blocks, the current state eth1 data is repeated.
+func GetEth1Vote(ctx context.Context, spec *common.Spec, state common.BeaconState, src Eth1BlockSource) (common.Eth1Data, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	periodStart, err := VotingPeriodStartTime(spec, genesisTime, slot)
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	stateEth1Data, err := state.Eth1Data()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+
+	followTime := common.Timestamp(spec.SECONDS_PER_ETH1_BLOCK) * common.Timestamp(spec.ETH1_FOLLOW_DISTANCE)
+	if periodStart < followTime {
+		// the candidate window would start before unix time 0, no block can be a candidate.
+		return stateEth1Data, nil
+	}
+	maxTime := periodStart - followTime
+	minTime := common.Timestamp(0)
+	if periodStart > followTime*2 {
+		minTime = periodStart - followTime*2
+	}
+	blocks, err := src.BlocksInTimeRange(ctx, minTime, maxTime)
+	if err != nil {
+		return common.Eth1Data{}, fmt.Errorf("failed to fetch candidate eth1 blocks: %w", err)
+	}
+
+	hFn := tree.GetHashFn()
+	candidates := make(map[common.Root]struct{}, len(blocks))
+	var defaultVote *common.Eth1Data
+	for i := range blocks {
+		b := &blocks[i]
+		if !IsCandidateBlock(spec, b, periodStart) {
+			continue
+		}
+		// Ensure cannot move back to earlier deposit contract states
+		if b.DepositCount < stateEth1Data.DepositCount {
+			continue
+		}
+		dat := b.Eth1Data()
+		candidates[dat.HashTreeRoot(hFn)] = struct{}{}
+		// Default vote on latest eth1 block data in the period range
+		defaultVote = &dat
+	}
+	if defaultVote == nil {
+		defaultVote = &stateEth1Data
+	}
+
+	votesView, err := state.Eth1DataVotes()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	votes, err := votesView.Votes()
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	// Tally the valid votes already cast during this period.
+	// Ties are broken by the earliest vote, since the first occurrence of each vote is tracked.
+	counts := make(map[common.Root]uint64, len(candidates))
+	var order []int
+	for i := range votes {
+		root := votes[i].HashTreeRoot(hFn)
+		if _, ok := candidates[root]; !ok {
+			continue
+		}
+		if counts[root] == 0 {
+			order = append(order, i)
+		}
+		counts[root] += 1
+	}
+	best := defaultVote
+	bestCount := uint64(0)
+	for _, i := range order {
+		if c := counts[votes[i].HashTreeRoot(hFn)]; c > bestCount {
+			best = &votes[i]
+			bestCount = c
+		}
+	}
+	return *best, nil
+}
@@ -0,0 +1,157 @@
+package phase0
+
+import (
+	"context"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+type memEth1Chain []Eth1Block
+
+func (c memEth1Chain) BlocksInTimeRange(ctx context.Context, minTime common.Timestamp, maxTime common.Timestamp) ([]Eth1Block, error) {
+	var out []Eth1Block
+	for _, b := range c {
+		if b.Timestamp >= minTime && b.Timestamp <= maxTime {
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func testEth1VoteState(t *testing.T, spec *common.Spec, genesisTime common.Timestamp, slot common.Slot) *BeaconStateView {
+	validators := make([]KickstartValidatorData, 64)
+	for i := range validators {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = KickstartValidatorData{
+			Pubkey:  pub.Serialize(),
+			Balance: spec.MAX_EFFECTIVE_BALANCE,
+		}
+	}
+	state, _, err := KickStartState(spec, common.Root{0x01}, genesisTime, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.SetSlot(slot); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func testEth1Chain(spec *common.Spec, count uint64, startTime common.Timestamp) memEth1Chain {
+	out := make(memEth1Chain, 0, count)
+	for i := uint64(0); i < count; i++ {
+		out = append(out, Eth1Block{
+			Number:       i,
+			Timestamp:    startTime + common.Timestamp(i*uint64(spec.SECONDS_PER_ETH1_BLOCK)),
+			BlockHash:    common.Root{0xbb, byte(i), byte(i >> 8)},
+			DepositRoot:  common.Root{0xcc},
+			DepositCount: 64,
+		})
+	}
+	return out
+}
+
+func TestGetEth1VoteDefault(t *testing.T) {
+	spec := configs.Minimal
+	genesisTime := common.Timestamp(1_000_000)
+	periodSlots := common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH
+	state := testEth1VoteState(t, spec, genesisTime, periodSlots+3)
+	periodStart, err := VotingPeriodStartTime(spec, genesisTime, periodSlots+3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := testEth1Chain(spec, 1000, genesisTime-common.Timestamp(500*uint64(spec.SECONDS_PER_ETH1_BLOCK)))
+
+	vote, err := GetEth1Vote(context.Background(), spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without votes, the latest candidate block is the default vote.
+	var expected *Eth1Block
+	for i := range chain {
+		if IsCandidateBlock(spec, &chain[i], periodStart) {
+			expected = &chain[i]
+		}
+	}
+	if expected == nil {
+		t.Fatal("expected candidate blocks")
+	}
+	if vote != expected.Eth1Data() {
+		t.Fatalf("unexpected default vote: %v, expected block %d", vote, expected.Number)
+	}
+}
+
+func TestGetEth1VoteMajority(t *testing.T) {
+	spec := configs.Minimal
+	genesisTime := common.Timestamp(1_000_000)
+	periodSlots := common.Slot(spec.EPOCHS_PER_ETH1_VOTING_PERIOD) * spec.SLOTS_PER_EPOCH
+	state := testEth1VoteState(t, spec, genesisTime, periodSlots+3)
+	periodStart, err := VotingPeriodStartTime(spec, genesisTime, periodSlots+3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := testEth1Chain(spec, 1000, genesisTime-common.Timestamp(500*uint64(spec.SECONDS_PER_ETH1_BLOCK)))
+	var candidates []Eth1Block
+	var nonCandidate *Eth1Block
+	for i := range chain {
+		if IsCandidateBlock(spec, &chain[i], periodStart) {
+			candidates = append(candidates, chain[i])
+		} else if nonCandidate == nil {
+			nonCandidate = &chain[i]
+		}
+	}
+	if len(candidates) < 3 || nonCandidate == nil {
+		t.Fatal("expected enough candidate and non-candidate blocks")
+	}
+	votes, err := state.Eth1DataVotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dat := range []common.Eth1Data{
+		nonCandidate.Eth1Data(), nonCandidate.Eth1Data(), nonCandidate.Eth1Data(),
+		candidates[0].Eth1Data(),
+		candidates[1].Eth1Data(), candidates[1].Eth1Data(),
+		candidates[2].Eth1Data(), candidates[2].Eth1Data(),
+	} {
+		if err := votes.Append(dat); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vote, err := GetEth1Vote(context.Background(), spec, state, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The non-candidate vote is ignored, and the tie is broken by the earliest vote.
+	if vote != candidates[1].Eth1Data() {
+		t.Fatalf("unexpected vote: %v, expected block %d", vote, candidates[1].Number)
+	}
+}
+
+func TestGetEth1VoteNoCandidates(t *testing.T) {
+	spec := configs.Minimal
+	genesisTime := common.Timestamp(1_000_000)
+	state := testEth1VoteState(t, spec, genesisTime, 5)
+	vote, err := GetEth1Vote(context.Background(), spec, state, memEth1Chain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := state.Eth1Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vote != expected {
+		t.Fatalf("expected state eth1 data as vote, got %v", vote)
+	}
+}
@@ -2,8 +2,10 @@ package phase0
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
 	"github.com/protolambda/ztyp/tree"
 	. "github.com/protolambda/ztyp/view"
 )
@@ -150,6 +152,31 @@ func GenesisFromEth1(spec *common.Spec, eth1BlockHash common.Root, time common.T
 	return state, epc, nil
 }
 
+// GenesisDeposits adds the merkle proofs to the deposit data, in the order of the deposit contract.
+// The proof of each deposit is against the deposit tree up to and including the deposit,
+// as GenesisFromEth1 verifies them when it does not ignore signatures and proofs.
+func GenesisDeposits(data []common.DepositData) ([]common.Deposit, error) {
+	out := make([]common.Deposit, len(data))
+	depRootsView := NewDepositRootsView()
+	hFn := tree.GetHashFn()
+	for i := range data {
+		depRoot := RootView(data[i].HashTreeRoot(hFn))
+		if err := depRootsView.Append(&depRoot); err != nil {
+			return nil, err
+		}
+		proof, err := merkle.ProveView(depRootsView, uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		if len(proof.Branch) != len(out[i].Proof) {
+			return nil, fmt.Errorf("unexpected deposit proof length %d", len(proof.Branch))
+		}
+		out[i].Data = data[i]
+		copy(out[i].Proof[:], proof.Branch)
+	}
+	return out, nil
+}
+
 func IsValidGenesisState(spec *common.Spec, state common.BeaconState) (bool, error) {
 	genTime, err := state.GenesisTime()
 	if err != nil {
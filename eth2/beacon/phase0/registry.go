@@ -174,7 +174,9 @@ func ComputeRegistryProcessData(spec *common.Spec, flats []common.FlatValidator,
 			continue
 		}
 		if exit > exitQueueEnd {
+			// only the exits at the end of the queue count towards its churn
 			exitQueueEnd = exit
+			exitQueueEndChurn = 0
 		}
 		if exit == exitQueueEnd {
 			exitQueueEndChurn++
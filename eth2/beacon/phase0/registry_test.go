@@ -0,0 +1,34 @@
+package phase0
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestComputeRegistryProcessDataExitQueueChurn(t *testing.T) {
+	spec := configs.Minimal
+	flats := make([]common.FlatValidator, 64)
+	for i := range flats {
+		flats[i] = common.FlatValidator{
+			EffectiveBalance:           spec.MAX_EFFECTIVE_BALANCE,
+			ActivationEligibilityEpoch: common.GENESIS_EPOCH,
+			ActivationEpoch:            common.GENESIS_EPOCH,
+			ExitEpoch:                  common.FAR_FUTURE_EPOCH,
+		}
+	}
+	// an earlier exit before the exit at the end of the queue does not count towards its churn
+	flats[3].ExitEpoch = 15
+	flats[4].ExitEpoch = 20
+	data, err := ComputeRegistryProcessData(spec, flats, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.ChurnLimit <= 1 {
+		t.Fatalf("expected a churn limit above 1, got %d", data.ChurnLimit)
+	}
+	if data.ExitQueueEnd != 20 || data.ExitQueueEndChurn != 1 {
+		t.Fatalf("expected exit queue end 20 with churn 1, got end %d with churn %d", data.ExitQueueEnd, data.ExitQueueEndChurn)
+	}
+}
@@ -409,6 +409,7 @@ func (state *BeaconStateView) ForkSettings(spec *common.Spec) *common.ForkSettin
 		CalcProposerShare: func(whistleblowerReward common.Gwei) common.Gwei {
 			return whistleblowerReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
 		},
+		MaxEffectiveBalance: spec.MAX_EFFECTIVE_BALANCE,
 	}
 }
 
@@ -0,0 +1,87 @@
+package phase0
+
+import (
+	"errors"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// SAFETY_DECAY is the maximum percentage decrease in the safety margin of finality,
+// that is tolerated within the weak subjectivity period.
+const SAFETY_DECAY = 10
+
+const ETH_TO_GWEI = 1_000_000_000
+
+// ComputeWeakSubjectivityPeriod computes the number of epochs after the epoch of the given state,
+// in which a node can still safely sync from the state, as a weak subjectivity checkpoint.
+// This is the phase0 formula, based on the validator count churn: see beacon.ComputeWeakSubjectivityPeriod for any fork.
+func ComputeWeakSubjectivityPeriod(spec *common.Spec, state common.BeaconState) (common.Epoch, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return 0, err
+	}
+	currentEpoch := spec.SlotToEpoch(slot)
+	vals, err := state.Validators()
+	if err != nil {
+		return 0, err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return 0, err
+	}
+	activeCount := uint64(0)
+	totalActive := common.Gwei(0)
+	for i := range flats {
+		if flats[i].IsActive(currentEpoch) {
+			activeCount += 1
+			totalActive += flats[i].EffectiveBalance
+		}
+	}
+	if activeCount == 0 {
+		return 0, errors.New("no active validators, cannot compute weak subjectivity period")
+	}
+	return WeakSubjectivityPeriod(spec, activeCount, totalActive), nil
+}
+
+// WeakSubjectivityPeriod computes the weak subjectivity period for the given active validator count and
+// total active balance. The count must not be zero.
+func WeakSubjectivityPeriod(spec *common.Spec, activeCount uint64, totalActive common.Gwei) common.Epoch {
+	wsPeriod := spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	N := activeCount
+	// Like get_total_active_balance, the total is at least one increment
+	if totalActive < spec.EFFECTIVE_BALANCE_INCREMENT {
+		totalActive = spec.EFFECTIVE_BALANCE_INCREMENT
+	}
+	t := uint64(totalActive) / N / ETH_TO_GWEI
+	T := uint64(spec.MAX_EFFECTIVE_BALANCE) / ETH_TO_GWEI
+	delta := spec.GetChurnLimit(activeCount)
+	Delta := uint64(spec.MAX_DEPOSITS) * uint64(spec.SLOTS_PER_EPOCH)
+	D := uint64(SAFETY_DECAY)
+
+	if T*(200+3*D) < t*(200+12*D) {
+		epochsForValidatorSetChurn := N * (t*(200+12*D) - T*(200+3*D)) / (600 * delta * (2*t + T))
+		epochsForBalanceTopUps := N * (200 + 3*D) / (600 * Delta)
+		if epochsForValidatorSetChurn > epochsForBalanceTopUps {
+			wsPeriod += common.Epoch(epochsForValidatorSetChurn)
+		} else {
+			wsPeriod += common.Epoch(epochsForBalanceTopUps)
+		}
+	} else {
+		wsPeriod += common.Epoch(3 * N * D * t / (200 * Delta * (T - t)))
+	}
+	return wsPeriod
+}
+
+// LatestBlockRoot computes the root of the latest block header in the state,
+// with the state root filled in, if the state was not processed past the slot of the block yet.
+func LatestBlockRoot(state common.BeaconState) (common.Root, error) {
+	header, err := state.LatestBlockHeader()
+	if err != nil {
+		return common.Root{}, err
+	}
+	if header.StateRoot == (common.Root{}) {
+		header.StateRoot = state.HashTreeRoot(tree.GetHashFn())
+	}
+	return header.HashTreeRoot(tree.GetHashFn()), nil
+}
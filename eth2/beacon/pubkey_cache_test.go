@@ -0,0 +1,249 @@
+package beacon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestPubkeyCacheFile(t *testing.T) {
+	spec := configs.Minimal
+	// one more validator than the genesis state, to be added later
+	more := *spec
+	more.SLOTS_PER_EPOCH += 1
+	all := testValidators(t, &more)
+	validators, extra := all[:len(all)-1], all[len(all)-1].Pubkey
+	state, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pubkeys.cache")
+	fileSize := func() int64 {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	const headerSize, recordSize = 8, 48 + 1 + 96
+
+	pc, err := common.OpenPubkeyCache(path, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Count() != common.ValidatorIndex(len(validators)) {
+		t.Fatalf("unexpected count %d", pc.Count())
+	}
+	// validators added to the cache are appended to the file, branches are not
+	grown, err := pc.AddValidator(pc.Count(), extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.AddValidator(pc.Count(), common.BLSPubkey{0xc0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := grown.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(); size != headerSize+int64(len(validators)+1)*recordSize {
+		t.Fatalf("unexpected file size %d", size)
+	}
+
+	// an interrupted write leaves a partial record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := common.OpenPubkeyCache(path, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(); size != headerSize+int64(len(validators)+1)*recordSize {
+		t.Fatalf("expected partial record to be dropped, file size %d", size)
+	}
+	for i, v := range validators {
+		cached, ok := loaded.Pubkey(common.ValidatorIndex(i))
+		if !ok || cached.Compressed != v.Pubkey {
+			t.Fatalf("unexpected pubkey at %d", i)
+		}
+		pub, err := cached.Pubkey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.Serialize() != v.Pubkey {
+			t.Fatalf("unexpected decompressed pubkey at %d", i)
+		}
+	}
+	// the extra validator is known to the file, but not part of the registry
+	if _, ok := loaded.ValidatorIndex(extra); ok {
+		t.Fatal("expected extra validator to be hidden")
+	}
+	if next, err := loaded.AddValidator(loaded.Count(), extra); err != nil || next.Count() != loaded.Count()+1 {
+		t.Fatalf("expected extra validator to be added, got error: %v", err)
+	}
+	if err := loaded.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a registry with a different deposit history truncates the file
+	swapped := append([]phase0.KickstartValidatorData{}, validators...)
+	swapped[2], swapped[3] = swapped[3], swapped[2]
+	other, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, swapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVals, err := other.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPc, err := common.OpenPubkeyCache(path, otherVals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(); size != headerSize+int64(len(validators))*recordSize {
+		t.Fatalf("expected the file to be rewritten from the conflicting record, file size %d", size)
+	}
+	if index, ok := otherPc.ValidatorIndex(swapped[2].Pubkey); !ok || index != 2 {
+		t.Fatalf("unexpected index %d", index)
+	}
+	if err := otherPc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("not a cache"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := common.OpenPubkeyCache(path, vals); err == nil {
+		t.Fatal("expected error for unknown file")
+	}
+}
+
+func TestPubkeyCacheSync(t *testing.T) {
+	spec := configs.Minimal
+	more := *spec
+	more.SLOTS_PER_EPOCH += 2
+	validators := testValidators(t, &more)
+	small := testGenesisState(t, spec)
+	smallVals, err := small.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc, err := common.NewPubkeyCache(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trimmed, err := pc.Sync(smallVals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trimmed.Count() != common.ValidatorIndex(spec.SLOTS_PER_EPOCH) {
+		t.Fatalf("expected %d validators, got %d", spec.SLOTS_PER_EPOCH, trimmed.Count())
+	}
+	last := len(validators) - 1
+	if _, ok := trimmed.ValidatorIndex(validators[last].Pubkey); ok {
+		t.Fatal("expected validators past the registry to be hidden")
+	}
+	grown, err := trimmed.Sync(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grown.Count() != common.ValidatorIndex(len(validators)) {
+		t.Fatalf("expected %d validators, got %d", len(validators), grown.Count())
+	}
+	if index, ok := grown.ValidatorIndex(validators[last].Pubkey); !ok || index != common.ValidatorIndex(last) {
+		t.Fatalf("unexpected index %d", index)
+	}
+}
+
+func TestPubkeyCacheSyncBeforeFork(t *testing.T) {
+	spec := configs.Minimal
+	more := *spec
+	more.SLOTS_PER_EPOCH *= 2
+	validators := testValidators(t, &more)
+	registry := func(indices ...int) common.ValidatorRegistry {
+		data := make([]phase0.KickstartValidatorData, len(indices))
+		for i, index := range indices {
+			data[i] = validators[index]
+		}
+		state, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vals
+	}
+	add := func(pc *common.PubkeyCache, indices ...int) *common.PubkeyCache {
+		for _, index := range indices {
+			var err error
+			pc, err = pc.AddValidator(pc.Count(), validators[index].Pubkey)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return pc
+	}
+	// 6 validators, and a branch that forks out the store at index 3
+	base := add(common.EmptyPubkeyCache(), 0, 1, 2)
+	add(base, 3, 4, 5)
+	forked := add(base, 15)
+
+	// a registry that differs before the fork point of the store, at index 1
+	expected := []int{0, 14, 2, 3, 4, 5, 6, 7}
+	synced, err := forked.Sync(registry(expected...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced.Count() != common.ValidatorIndex(len(expected)) {
+		t.Fatalf("expected %d validators, got %d", len(expected), synced.Count())
+	}
+	for i, index := range expected {
+		pub, ok := synced.Pubkey(common.ValidatorIndex(i))
+		if !ok || pub.Compressed != validators[index].Pubkey {
+			t.Fatalf("unexpected pubkey at index %d", i)
+		}
+		if got, ok := synced.ValidatorIndex(validators[index].Pubkey); !ok || got != common.ValidatorIndex(i) {
+			t.Fatalf("unexpected index %d of validator %d", got, index)
+		}
+	}
+	if _, ok := synced.ValidatorIndex(validators[1].Pubkey); ok {
+		t.Fatal("expected the replaced validator to be unknown")
+	}
+	if _, ok := synced.ValidatorIndex(validators[15].Pubkey); ok {
+		t.Fatal("expected the validator of the forked branch to be unknown")
+	}
+	// the original branch is unaffected
+	original, err := synced.Sync(registry(0, 1, 2, 3, 4, 5, 6, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{1, 3} {
+		if got, ok := original.ValidatorIndex(validators[index].Pubkey); !ok || got != common.ValidatorIndex(index) {
+			t.Fatalf("unexpected index %d of validator %d", got, index)
+		}
+	}
+}
@@ -0,0 +1,567 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// GweiDelta is a signed balance change: rewards are positive, penalties are negative.
+type GweiDelta int64
+
+func (d GweiDelta) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(d), 10))), nil
+}
+
+func (d *GweiDelta) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*d = GweiDelta(v)
+	return nil
+}
+
+func deltaOf(d *common.Deltas, i common.ValidatorIndex) GweiDelta {
+	return GweiDelta(d.Rewards[i]) - GweiDelta(d.Penalties[i])
+}
+
+// ValidatorAttestationRewards is the attestation rewards breakdown of a single validator for an epoch.
+type ValidatorAttestationRewards struct {
+	ValidatorIndex common.ValidatorIndex `json:"validator_index"`
+	Head           GweiDelta             `json:"head"`
+	Target         GweiDelta             `json:"target"`
+	Source         GweiDelta             `json:"source"`
+	// Phase0 only.
+	InclusionDelay GweiDelta `json:"inclusion_delay"`
+	Inactivity     GweiDelta `json:"inactivity"`
+	// Phase0 only. Rewards the validator earned as proposer, for including attestations of the epoch.
+	// Not part of the endpoint data.
+	Proposer GweiDelta `json:"-"`
+}
+
+// IdealAttestationRewards are the rewards of a validator with the given effective balance,
+// if it attested perfectly (and was included with the minimum delay).
+type IdealAttestationRewards struct {
+	EffectiveBalance common.Gwei `json:"effective_balance"`
+	Head             GweiDelta   `json:"head"`
+	Target           GweiDelta   `json:"target"`
+	Source           GweiDelta   `json:"source"`
+	InclusionDelay   GweiDelta   `json:"inclusion_delay"`
+	Inactivity       GweiDelta   `json:"inactivity"`
+}
+
+// AttestationRewardsReport is the data of the /eth/v1/beacon/rewards/attestations/{epoch} endpoint.
+type AttestationRewardsReport struct {
+	// Epoch that was attested to, i.e. the previous epoch of the epoch transition.
+	Epoch        common.Epoch                  `json:"-"`
+	IdealRewards []IdealAttestationRewards     `json:"ideal_rewards"`
+	TotalRewards []ValidatorAttestationRewards `json:"total_rewards"`
+}
+
+// ComputeAttestationRewards computes the attestation rewards and penalties that the next epoch transition applies.
+// The state must be the pre-state of the epoch transition: at the last slot of an epoch, with all blocks of the slot processed.
+// The state is not modified. If indices is nil, the rewards of all eligible validators are included.
+func ComputeAttestationRewards(ctx context.Context, spec *common.Spec, epc *common.EpochsContext,
+	state common.BeaconState, indices []common.ValidatorIndex) (*AttestationRewardsReport, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	if (slot+1)%spec.SLOTS_PER_EPOCH != 0 {
+		return nil, fmt.Errorf("state at slot %d is not the pre-state of an epoch transition", slot)
+	}
+	if epc.CurrentEpoch.Epoch == common.GENESIS_EPOCH {
+		// no rewards in the genesis epoch transition
+		return &AttestationRewardsReport{Epoch: common.GENESIS_EPOCH}, nil
+	}
+	// Justification and inactivity updates come before the rewards in the epoch transition, and affect them.
+	state, err = state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return nil, err
+	}
+	report := &AttestationRewardsReport{Epoch: epc.PreviousEpoch.Epoch}
+	maxEffectiveBalance := state.ForkSettings(spec).MaxEffectiveBalance
+	switch s := state.(type) {
+	case altair.AltairLikeBeaconState:
+		attesterData, err := altair.ComputeEpochAttesterData(ctx, spec, epc, flats, s)
+		if err != nil {
+			return nil, err
+		}
+		just := phase0.JustificationStakeData{
+			CurrentEpoch:                  epc.CurrentEpoch.Epoch,
+			TotalActiveStake:              epc.TotalActiveStake,
+			PrevEpochUnslashedTargetStake: attesterData.PrevEpochUnslashedStake.TargetStake,
+			CurrEpochUnslashedTargetStake: attesterData.CurrEpochUnslashedTargetStake,
+		}
+		if err := phase0.ProcessEpochJustification(ctx, spec, &just, s); err != nil {
+			return nil, err
+		}
+		if err := altair.ProcessInactivityUpdates(ctx, spec, attesterData, s); err != nil {
+			return nil, err
+		}
+		res, err := altair.AttestationRewardsAndPenalties(ctx, spec, epc, attesterData, s)
+		if err != nil {
+			return nil, err
+		}
+		if indices == nil {
+			indices = attesterData.EligibleIndices
+		}
+		for _, vi := range indices {
+			if uint64(vi) >= uint64(len(flats)) {
+				return nil, fmt.Errorf("unknown validator %d", vi)
+			}
+			report.TotalRewards = append(report.TotalRewards, ValidatorAttestationRewards{
+				ValidatorIndex: vi,
+				Head:           deltaOf(res.Head, vi),
+				Target:         deltaOf(res.Target, vi),
+				Source:         deltaOf(res.Source, vi),
+				Inactivity:     deltaOf(res.Inactivity, vi),
+			})
+		}
+		finalized, err := s.FinalizedCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		isInactivityLeak := attesterData.PrevEpoch-finalized.Epoch > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
+		report.IdealRewards = altairIdealRewards(spec, epc, &attesterData.PrevEpochUnslashedStake, isInactivityLeak, maxEffectiveBalance)
+	case phase0.Phase0PendingAttestationsBeaconState:
+		attesterData, err := phase0.ComputeEpochAttesterData(ctx, spec, epc, flats, s)
+		if err != nil {
+			return nil, err
+		}
+		just := phase0.JustificationStakeData{
+			CurrentEpoch:                  epc.CurrentEpoch.Epoch,
+			TotalActiveStake:              epc.TotalActiveStake,
+			PrevEpochUnslashedTargetStake: attesterData.PrevEpochUnslashedStake.TargetStake,
+			CurrEpochUnslashedTargetStake: attesterData.CurrEpochUnslashedTargetStake,
+		}
+		if err := phase0.ProcessEpochJustification(ctx, spec, &just, s); err != nil {
+			return nil, err
+		}
+		res, err := phase0.AttestationRewardsAndPenalties(ctx, spec, epc, attesterData, s)
+		if err != nil {
+			return nil, err
+		}
+		// The inclusion delay deltas include the rewards of the proposers that included the attestations.
+		proposerRewards := make(map[common.ValidatorIndex]common.Gwei)
+		for i := range attesterData.Statuses {
+			status := &attesterData.Statuses[i]
+			if status.Flags.HasMarkers(phase0.PrevSourceAttester | phase0.UnslashedAttester) {
+				baseReward := attesterData.Flats[i].EffectiveBalance * common.Gwei(spec.BASE_REWARD_FACTOR) /
+					epc.TotalActiveStakeSqRoot / common.BASE_REWARDS_PER_EPOCH
+				proposerRewards[status.AttestedProposer] += baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
+			}
+		}
+		if indices == nil {
+			for i := range attesterData.Statuses {
+				if attesterData.Statuses[i].Flags&phase0.EligibleAttester != 0 {
+					indices = append(indices, common.ValidatorIndex(i))
+				}
+			}
+		}
+		for _, vi := range indices {
+			if uint64(vi) >= uint64(len(flats)) {
+				return nil, fmt.Errorf("unknown validator %d", vi)
+			}
+			report.TotalRewards = append(report.TotalRewards, ValidatorAttestationRewards{
+				ValidatorIndex: vi,
+				Head:           deltaOf(res.Head, vi),
+				Target:         deltaOf(res.Target, vi),
+				Source:         deltaOf(res.Source, vi),
+				InclusionDelay: deltaOf(res.InclusionDelay, vi) - GweiDelta(proposerRewards[vi]),
+				Inactivity:     deltaOf(res.Inactivity, vi),
+				Proposer:       GweiDelta(proposerRewards[vi]),
+			})
+		}
+		finalized, err := s.FinalizedCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		isInactivityLeak := attesterData.PrevEpoch-finalized.Epoch > spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY
+		report.IdealRewards = phase0IdealRewards(spec, epc, &attesterData.PrevEpochUnslashedStake, isInactivityLeak, maxEffectiveBalance)
+	default:
+		return nil, fmt.Errorf("unrecognized state type: %T", state)
+	}
+	return report, nil
+}
+
+// idealEffectiveBalances lists every effective balance a validator may have, from one increment up to the maximum:
+// MAX_EFFECTIVE_BALANCE, or MAX_EFFECTIVE_BALANCE_ELECTRA for compounding validators since Electra.
+func idealEffectiveBalances(spec *common.Spec, maxEffectiveBalance common.Gwei) (out []common.Gwei) {
+	for effBal := spec.EFFECTIVE_BALANCE_INCREMENT; effBal <= maxEffectiveBalance; effBal += spec.EFFECTIVE_BALANCE_INCREMENT {
+		out = append(out, effBal)
+	}
+	return out
+}
+
+func phase0IdealRewards(spec *common.Spec, epc *common.EpochsContext, stake *phase0.EpochStakeSummary, isInactivityLeak bool, maxEffectiveBalance common.Gwei) []IdealAttestationRewards {
+	balanceSqRoot := epc.TotalActiveStakeSqRoot
+	totalIncrements := epc.TotalActiveStake / spec.EFFECTIVE_BALANCE_INCREMENT
+	reward := func(baseReward common.Gwei, participating common.Gwei) GweiDelta {
+		if isInactivityLeak {
+			return GweiDelta(baseReward)
+		}
+		return GweiDelta(baseReward * (participating / spec.EFFECTIVE_BALANCE_INCREMENT) / totalIncrements)
+	}
+	var out []IdealAttestationRewards
+	for _, effBal := range idealEffectiveBalances(spec, maxEffectiveBalance) {
+		baseReward := effBal * common.Gwei(spec.BASE_REWARD_FACTOR) / balanceSqRoot / common.BASE_REWARDS_PER_EPOCH
+		proposerReward := baseReward / common.Gwei(spec.PROPOSER_REWARD_QUOTIENT)
+		ideal := IdealAttestationRewards{
+			EffectiveBalance: effBal,
+			Head:             reward(baseReward, stake.HeadStake),
+			Target:           reward(baseReward, stake.TargetStake),
+			Source:           reward(baseReward, stake.SourceStake),
+			InclusionDelay:   GweiDelta(baseReward - proposerReward),
+		}
+		if isInactivityLeak {
+			ideal.Inactivity = -GweiDelta(common.BASE_REWARDS_PER_EPOCH*baseReward - proposerReward)
+		}
+		out = append(out, ideal)
+	}
+	return out
+}
+
+func altairIdealRewards(spec *common.Spec, epc *common.EpochsContext, stake *altair.EpochStakeSummary, isInactivityLeak bool, maxEffectiveBalance common.Gwei) []IdealAttestationRewards {
+	activeIncrements := epc.TotalActiveStake / spec.EFFECTIVE_BALANCE_INCREMENT
+	baseRewardPerIncrement := (spec.EFFECTIVE_BALANCE_INCREMENT * common.Gwei(spec.BASE_REWARD_FACTOR)) / epc.TotalActiveStakeSqRoot
+	reward := func(baseReward common.Gwei, weight common.Gwei, participating common.Gwei) GweiDelta {
+		if isInactivityLeak {
+			return 0
+		}
+		// get_total_balance makes it 1 increment minimum
+		if participating < spec.EFFECTIVE_BALANCE_INCREMENT {
+			participating = spec.EFFECTIVE_BALANCE_INCREMENT
+		}
+		participatingIncrements := participating / spec.EFFECTIVE_BALANCE_INCREMENT
+		return GweiDelta((baseReward * weight) * participatingIncrements / (activeIncrements * altair.WEIGHT_DENOMINATOR))
+	}
+	var out []IdealAttestationRewards
+	for _, effBal := range idealEffectiveBalances(spec, maxEffectiveBalance) {
+		baseReward := (effBal / spec.EFFECTIVE_BALANCE_INCREMENT) * baseRewardPerIncrement
+		out = append(out, IdealAttestationRewards{
+			EffectiveBalance: effBal,
+			Head:             reward(baseReward, altair.TIMELY_HEAD_WEIGHT, stake.HeadStake),
+			Target:           reward(baseReward, altair.TIMELY_TARGET_WEIGHT, stake.TargetStake),
+			Source:           reward(baseReward, altair.TIMELY_SOURCE_WEIGHT, stake.SourceStake),
+		})
+	}
+	return out
+}
+
+// BlockRewards is the data of the /eth/v1/beacon/rewards/blocks/{block_id} endpoint:
+// the proposer reward of a block, split by the operations that it was earned with.
+// Voluntary exits and other operations do not reward the proposer, and are not included.
+type BlockRewards struct {
+	ProposerIndex common.ValidatorIndex `json:"proposer_index"`
+	Total         common.Gwei           `json:"total"`
+	// Rewards for including attestations. Zero in phase0,
+	// where the inclusion rewards are paid in the epoch transition instead (see InclusionDelay of the attestation rewards).
+	Attestations common.Gwei `json:"attestations"`
+	// Rewards for including the sync aggregate. Always zero: blocks of this tree do not carry a sync aggregate,
+	// the field is here to match the shape of the endpoint data.
+	SyncAggregate common.Gwei `json:"sync_aggregate"`
+	// Whistleblower rewards for including proposer slashings.
+	ProposerSlashings common.Gwei `json:"proposer_slashings"`
+	// Whistleblower rewards for including attester slashings.
+	AttesterSlashings common.Gwei `json:"attester_slashings"`
+	// Rewards for including deposits, excluding deposits that top up the balance of the proposer itself.
+	// Not part of the endpoint data.
+	Deposits common.Gwei `json:"-"`
+	// Rewards of the proposer and attester slashings per slashed validator, in processing order.
+	// Not part of the endpoint data.
+	Slashings []SlashingRewards `json:"-"`
+}
+
+// SlashingRewards is the reward for including the slashing of a single validator in a block.
+// The whistleblower reward of the slashing is split into the proposer part, and the remainder for the whistleblower.
+// The proposer of the block is the whistleblower, and receives both parts.
+type SlashingRewards struct {
+	SlashedIndex  common.ValidatorIndex `json:"slashed_index"`
+	Proposer      common.Gwei           `json:"proposer"`
+	Whistleblower common.Gwei           `json:"whistleblower"`
+}
+
+// ComputeBlockRewards computes the proposer rewards of the block, by processing its operations on a copy of the state,
+// and tracking the balance of the proposer.
+// The state must be the pre-state of the block, processed up to the slot of the block.
+// The operations are expected to be valid, the block signature and state root are not verified.
+func ComputeBlockRewards(ctx context.Context, spec *common.Spec, epc *common.EpochsContext,
+	state common.BeaconState, benv *common.BeaconBlockEnvelope) (*BlockRewards, error) {
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	if slot != benv.Slot {
+		return nil, fmt.Errorf("state slot %d does not match block slot %d", slot, benv.Slot)
+	}
+	proposer, err := epc.GetBeaconProposer(benv.Slot)
+	if err != nil {
+		return nil, err
+	}
+	if proposer != benv.ProposerIndex {
+		return nil, fmt.Errorf("block proposer %d does not match expected proposer %d", benv.ProposerIndex, proposer)
+	}
+	state, err = state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	epc = epc.Clone()
+	// proposerBalance reads the balances from the state every time, the operations write through new views.
+	proposerBalance := func() (common.Gwei, error) {
+		balances, err := state.Balances()
+		if err != nil {
+			return 0, err
+		}
+		return balances.GetBalance(proposer)
+	}
+	// measure applies the operations, and returns the balance increase of the proposer.
+	measure := func(apply func() error) (common.Gwei, error) {
+		before, err := proposerBalance()
+		if err != nil {
+			return 0, err
+		}
+		if err := apply(); err != nil {
+			return 0, err
+		}
+		after, err := proposerBalance()
+		if err != nil {
+			return 0, err
+		}
+		// the proposer may slash itself, or be slashed, in its own block
+		if after < before {
+			return 0, nil
+		}
+		return after - before, nil
+	}
+
+	// slashingOp processes a single slashing operation, which slashes (a subset of) the candidate validators.
+	type slashingOp struct {
+		candidates []common.ValidatorIndex
+		process    func() error
+	}
+	var proposerSlashings, attesterSlashings []slashingOp
+	addPhase0Slashings := func(ps []phase0.ProposerSlashing, as []phase0.AttesterSlashing) {
+		for i := range ps {
+			op := &ps[i]
+			proposerSlashings = append(proposerSlashings, slashingOp{
+				candidates: []common.ValidatorIndex{op.SignedHeader1.Message.ProposerIndex},
+				process: func() error {
+					return phase0.ProcessProposerSlashing(spec, epc, state, op)
+				},
+			})
+		}
+		for i := range as {
+			op := &as[i]
+			attesterSlashings = append(attesterSlashings, slashingOp{
+				candidates: op.Attestation1.AttestingIndices,
+				process: func() error {
+					return phase0.ProcessAttesterSlashing(spec, epc, state, op)
+				},
+			})
+		}
+	}
+	var attestations []phase0.Attestation
+	var processAttestations func() error
+	var deposits []common.Deposit
+	switch body := benv.Body.(type) {
+	case *phase0.BeaconBlockBody:
+		addPhase0Slashings(body.ProposerSlashings, body.AttesterSlashings)
+		deposits = body.Deposits
+	case *altair.BeaconBlockBody:
+		addPhase0Slashings(body.ProposerSlashings, body.AttesterSlashings)
+		attestations = body.Attestations
+		deposits = body.Deposits
+	case *bellatrix.BeaconBlockBody:
+		addPhase0Slashings(body.ProposerSlashings, body.AttesterSlashings)
+		attestations = body.Attestations
+		deposits = body.Deposits
+	case *capella.BeaconBlockBody:
+		addPhase0Slashings(body.ProposerSlashings, body.AttesterSlashings)
+		attestations = body.Attestations
+		deposits = body.Deposits
+	case *deneb.BeaconBlockBody:
+		addPhase0Slashings(body.ProposerSlashings, body.AttesterSlashings)
+		attestations = body.Attestations
+		deposits = body.Deposits
+		processAttestations = func() error {
+			s, ok := state.(altair.AltairLikeBeaconState)
+			if !ok {
+				return fmt.Errorf("unexpected state type %T for Deneb block", state)
+			}
+			return deneb.ProcessAttestations(ctx, spec, epc, s, attestations)
+		}
+	case *electra.BeaconBlockBody:
+		s, ok := state.(*electra.BeaconStateView)
+		if !ok {
+			return nil, fmt.Errorf("unexpected state type %T for Electra block", state)
+		}
+		for i := range body.ProposerSlashings {
+			op := &body.ProposerSlashings[i]
+			proposerSlashings = append(proposerSlashings, slashingOp{
+				candidates: []common.ValidatorIndex{op.SignedHeader1.Message.ProposerIndex},
+				process: func() error {
+					return electra.ProcessProposerSlashing(spec, epc, s, op)
+				},
+			})
+		}
+		for i := range body.AttesterSlashings {
+			op := &body.AttesterSlashings[i]
+			attesterSlashings = append(attesterSlashings, slashingOp{
+				candidates: op.Attestation1.AttestingIndices,
+				process: func() error {
+					return electra.ProcessAttesterSlashing(spec, epc, s, op)
+				},
+			})
+		}
+		if len(body.Attestations) > 0 {
+			processAttestations = func() error {
+				return electra.ProcessAttestations(ctx, spec, epc, s, body.Attestations)
+			}
+		}
+		deposits = body.Deposits
+	default:
+		return nil, fmt.Errorf("unrecognized block body type: %T", body)
+	}
+	if processAttestations == nil && len(attestations) > 0 {
+		processAttestations = func() error {
+			s, ok := state.(altair.AltairLikeBeaconState)
+			if !ok {
+				return fmt.Errorf("unexpected state type %T for post-Altair block", state)
+			}
+			return altair.ProcessAttestations(ctx, spec, epc, s, attestations)
+		}
+	}
+
+	// slashed returns which of the validators are slashed in the current state.
+	slashed := func(indices []common.ValidatorIndex) ([]bool, error) {
+		vals, err := state.Validators()
+		if err != nil {
+			return nil, err
+		}
+		out := make([]bool, len(indices))
+		for i, vi := range indices {
+			v, err := vals.Validator(vi)
+			if err != nil {
+				return nil, err
+			}
+			if out[i], err = v.Slashed(); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	settings := state.ForkSettings(spec)
+	out := &BlockRewards{ProposerIndex: proposer}
+	// processSlashings applies the slashings, and returns the balance increase of the proposer.
+	processSlashings := func(ops []slashingOp) (total common.Gwei, err error) {
+		for _, op := range ops {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			before, err := slashed(op.candidates)
+			if err != nil {
+				return 0, err
+			}
+			reward, err := measure(op.process)
+			if err != nil {
+				return 0, err
+			}
+			total += reward
+			after, err := slashed(op.candidates)
+			if err != nil {
+				return 0, err
+			}
+			vals, err := state.Validators()
+			if err != nil {
+				return 0, err
+			}
+			for i, vi := range op.candidates {
+				if before[i] || !after[i] {
+					continue
+				}
+				v, err := vals.Validator(vi)
+				if err != nil {
+					return 0, err
+				}
+				effBal, err := v.EffectiveBalance()
+				if err != nil {
+					return 0, err
+				}
+				whistleblowerReward := effBal / common.Gwei(spec.WHISTLEBLOWER_REWARD_QUOTIENT)
+				proposerReward := settings.CalcProposerShare(whistleblowerReward)
+				out.Slashings = append(out.Slashings, SlashingRewards{
+					SlashedIndex:  vi,
+					Proposer:      proposerReward,
+					Whistleblower: whistleblowerReward - proposerReward,
+				})
+			}
+		}
+		return total, nil
+	}
+
+	if out.ProposerSlashings, err = processSlashings(proposerSlashings); err != nil {
+		return nil, fmt.Errorf("failed to process proposer slashings: %w", err)
+	}
+	if out.AttesterSlashings, err = processSlashings(attesterSlashings); err != nil {
+		return nil, fmt.Errorf("failed to process attester slashings: %w", err)
+	}
+	if processAttestations != nil {
+		if out.Attestations, err = measure(processAttestations); err != nil {
+			return nil, fmt.Errorf("failed to process attestations: %w", err)
+		}
+	}
+	if len(deposits) > 0 {
+		vals, err := state.Validators()
+		if err != nil {
+			return nil, err
+		}
+		v, err := vals.Validator(proposer)
+		if err != nil {
+			return nil, err
+		}
+		pubkey, err := v.Pubkey()
+		if err != nil {
+			return nil, err
+		}
+		// top-ups of the proposer increase its balance, but are not a reward
+		var topUps common.Gwei
+		for i := range deposits {
+			if deposits[i].Data.Pubkey == pubkey {
+				topUps += deposits[i].Data.Amount
+			}
+		}
+		increase, err := measure(func() error {
+			return phase0.ProcessDeposits(ctx, spec, epc, state, deposits)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to process deposits: %w", err)
+		}
+		if increase > topUps {
+			out.Deposits = increase - topUps
+		}
+	}
+	out.Total = out.Attestations + out.SyncAggregate + out.ProposerSlashings + out.AttesterSlashings + out.Deposits
+	return out, nil
+}
@@ -0,0 +1,362 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+func TestComputeAttestationRewards(t *testing.T) {
+	spec := configs.Minimal
+	ctx := context.Background()
+	state := testGenesisState(t, spec)
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// last slot of epoch 1, without any attestations
+	if err := common.ProcessSlots(ctx, spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, spec.SLOTS_PER_EPOCH*2-1); err != nil {
+		t.Fatal(err)
+	}
+	// an attestation of the first slot, included by validator 3
+	committee, err := epc.GetBeaconCommittee(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(committee) != 1 {
+		t.Fatalf("expected a single validator per committee, got %d", len(committee))
+	}
+	attester, includer := committee[0], common.ValidatorIndex(3)
+	blockRoot, err := common.GetBlockRootAtSlot(spec, state, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevAtts, err := state.PreviousEpochAttestations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	att := phase0.PendingAttestation{
+		AggregationBits: phase0.AttestationBits{0b11},
+		Data: phase0.AttestationData{
+			BeaconBlockRoot: blockRoot,
+			Target:          common.Checkpoint{Root: blockRoot},
+		},
+		InclusionDelay: 1,
+		ProposerIndex:  includer,
+	}
+	if err := prevAtts.Append(att.View(spec)); err != nil {
+		t.Fatal(err)
+	}
+
+	// no validator is marked as eligible attester by the attester data
+	report, err := ComputeAttestationRewards(ctx, spec, epc, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.TotalRewards) != 0 {
+		t.Fatalf("expected no eligible validators, got %d", len(report.TotalRewards))
+	}
+	if _, err := ComputeAttestationRewards(ctx, spec, epc, state, []common.ValidatorIndex{common.ValidatorIndex(spec.SLOTS_PER_EPOCH)}); err == nil {
+		t.Fatal("expected error for unknown validator")
+	}
+	indices := make([]common.ValidatorIndex, spec.SLOTS_PER_EPOCH)
+	for i := range indices {
+		indices[i] = common.ValidatorIndex(i)
+	}
+	report, err = ComputeAttestationRewards(ctx, spec, epc, state, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Epoch != 0 {
+		t.Fatalf("expected rewards for epoch 0, got %d", report.Epoch)
+	}
+	if len(report.TotalRewards) != len(indices) {
+		t.Fatalf("expected %d validators, got %d", len(indices), len(report.TotalRewards))
+	}
+	if uint64(len(report.IdealRewards)) != uint64(spec.MAX_EFFECTIVE_BALANCE/spec.EFFECTIVE_BALANCE_INCREMENT) {
+		t.Fatalf("unexpected number of ideal rewards: %d", len(report.IdealRewards))
+	}
+	if r := report.TotalRewards[attester]; r.InclusionDelay <= 0 || r.Proposer != 0 {
+		t.Fatalf("expected an inclusion delay reward for the attester, got %v", r)
+	}
+	if r := report.TotalRewards[includer]; r.Proposer <= 0 || r.InclusionDelay != 0 {
+		t.Fatalf("expected a proposer reward for including the attestation, got %v", r)
+	}
+
+	// the report must match the balance changes of the actual epoch transition
+	pre, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	preBalances, err := pre.AllBalances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.ProcessEpoch(ctx, spec, epc); err != nil {
+		t.Fatal(err)
+	}
+	post, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.TotalRewards {
+		bal, err := post.GetBalance(r.ValidatorIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := r.Head + r.Target + r.Source + r.InclusionDelay + r.Inactivity + r.Proposer
+		if GweiDelta(bal)-GweiDelta(preBalances[r.ValidatorIndex]) != total {
+			t.Fatalf("validator %d: balance changed from %d to %d, but report total is %d",
+				r.ValidatorIndex, preBalances[r.ValidatorIndex], bal, total)
+		}
+	}
+
+	data, err := json.Marshal(report.TotalRewards[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ValidatorAttestationRewards
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != report.TotalRewards[0] {
+		t.Fatalf("JSON round-trip mismatch: %s", data)
+	}
+}
+
+func TestComputeBlockRewardsElectra(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	ctx := context.Background()
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, testValidators(t, &spec), &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := common.ProcessSlots(ctx, &spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, 1); err != nil {
+		t.Fatal(err)
+	}
+	proposer, err := epc.GetBeaconProposer(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slashedIndex := (proposer + 1) % common.ValidatorIndex(spec.SLOTS_PER_EPOCH)
+
+	// two different headers of the slashed validator for the same slot
+	domain, err := common.GetDomain(state, common.DOMAIN_BEACON_PROPOSER, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var skBytes [32]byte
+	skBytes[31] = byte(slashedIndex + 1)
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&skBytes); err != nil {
+		t.Fatal(err)
+	}
+	signedHeader := func(bodyRoot common.Root) common.SignedBeaconBlockHeader {
+		header := common.BeaconBlockHeader{Slot: 1, ProposerIndex: slashedIndex, BodyRoot: bodyRoot}
+		sigRoot := common.ComputeSigningRoot(header.HashTreeRoot(tree.GetHashFn()), domain)
+		return common.SignedBeaconBlockHeader{Message: header, Signature: blsu.Sign(&sk, sigRoot[:]).Serialize()}
+	}
+	block := &electra.SignedBeaconBlock{Message: electra.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+	block.Message.Body.ProposerSlashings = phase0.ProposerSlashings{{
+		SignedHeader1: signedHeader(common.Root{0xaa}),
+		SignedHeader2: signedHeader(common.Root{0xbb}),
+	}}
+	rewards, err := ComputeBlockRewards(ctx, &spec, epc, state, block.Envelope(&spec, common.ForkDigest{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	whistleblowerReward := spec.MAX_EFFECTIVE_BALANCE / common.Gwei(spec.WHISTLEBLOWER_REWARD_QUOTIENT)
+	proposerReward := whistleblowerReward * altair.PROPOSER_WEIGHT / altair.WEIGHT_DENOMINATOR
+	expected := SlashingRewards{
+		SlashedIndex:  slashedIndex,
+		Proposer:      proposerReward,
+		Whistleblower: whistleblowerReward - proposerReward,
+	}
+	if len(rewards.Slashings) != 1 || rewards.Slashings[0] != expected {
+		t.Fatalf("expected slashing rewards %v, got %v", expected, rewards.Slashings)
+	}
+	if rewards.ProposerSlashings != whistleblowerReward || rewards.AttesterSlashings != 0 || rewards.Total != whistleblowerReward {
+		t.Fatalf("unexpected block rewards: %v", rewards)
+	}
+	// the rewards are computed on a copy of the state
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := vals.Validator(slashedIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slashed, err := v.Slashed(); err != nil || slashed {
+		t.Fatal("expected the state to be unchanged")
+	}
+}
+
+func TestRewardsForks(t *testing.T) {
+	type kickStartFn func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error)
+	type blockFn func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope
+	forks := []struct {
+		name      string
+		kickStart kickStartFn
+		block     blockFn
+	}{
+		{"phase0", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			return phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &phase0.SignedBeaconBlock{Message: phase0.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+		{"altair", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			pre, epc, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+			if err != nil {
+				return nil, nil, err
+			}
+			state, err := altair.UpgradeToAltair(spec, epc, pre)
+			return state, epc, err
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &altair.SignedBeaconBlock{Message: altair.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+		{"bellatrix", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			return bellatrix.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &bellatrix.ExecutionPayloadHeader{})
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &bellatrix.SignedBeaconBlock{Message: bellatrix.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+		{"capella", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			return capella.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &capella.ExecutionPayloadHeader{})
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &capella.SignedBeaconBlock{Message: capella.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+		{"deneb", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			return deneb.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &deneb.SignedBeaconBlock{Message: deneb.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+		{"electra", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (common.BeaconState, *common.EpochsContext, error) {
+			return electra.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+		}, func(spec *common.Spec, proposer common.ValidatorIndex, deposits []common.Deposit) *common.BeaconBlockEnvelope {
+			block := &electra.SignedBeaconBlock{Message: electra.BeaconBlock{Slot: 1, ProposerIndex: proposer}}
+			block.Message.Body.Deposits = deposits
+			return block.Envelope(spec, common.ForkDigest{})
+		}},
+	}
+	for i, fork := range forks {
+		t.Run(fork.name, func(t *testing.T) {
+			spec := *configs.Minimal
+			for j, epoch := range []*common.Epoch{&spec.ALTAIR_FORK_EPOCH, &spec.BELLATRIX_FORK_EPOCH,
+				&spec.CAPELLA_FORK_EPOCH, &spec.DENEB_FORK_EPOCH, &spec.ALPACA_FORK_EPOCH} {
+				if j < i {
+					*epoch = 0
+				} else {
+					*epoch = common.FAR_FUTURE_EPOCH
+				}
+			}
+			ctx := context.Background()
+			validators := testValidators(t, &spec)
+			state, epc, err := fork.kickStart(&spec, validators)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := common.ProcessSlots(ctx, &spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, 1); err != nil {
+				t.Fatal(err)
+			}
+			proposer, err := epc.GetBeaconProposer(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a top-up of the proposer, the next deposit after the genesis deposits
+			deposit := common.Deposit{Data: common.DepositData{
+				Pubkey: validators[proposer].Pubkey,
+				Amount: spec.EFFECTIVE_BALANCE_INCREMENT,
+			}}
+			depRoots := phase0.NewDepositRootsView()
+			for range validators {
+				if err := depRoots.Append(&view.RootView{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			root := view.RootView(deposit.Data.HashTreeRoot(tree.GetHashFn()))
+			if err := depRoots.Append(&root); err != nil {
+				t.Fatal(err)
+			}
+			proof, err := merkle.ProveView(depRoots, uint64(len(validators)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(deposit.Proof[:], proof.Branch)
+			if err := state.SetEth1Data(common.Eth1Data{
+				DepositRoot:  depRoots.HashTreeRoot(tree.GetHashFn()),
+				DepositCount: common.DepositIndex(len(validators) + 1),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			rewards, err := ComputeBlockRewards(ctx, &spec, epc, state, fork.block(&spec, proposer, []common.Deposit{deposit}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rewards.Deposits != 0 || rewards.SyncAggregate != 0 || rewards.Total != 0 {
+				t.Fatalf("expected the top-up of the proposer not to be a reward: %v", rewards)
+			}
+			data, err := json.Marshal(rewards)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range []string{"proposer_index", "total", "attestations", "sync_aggregate", "proposer_slashings", "attester_slashings"} {
+				if _, ok := fields[k]; !ok {
+					t.Fatalf("missing endpoint field %q in %s", k, data)
+				}
+			}
+
+			// the ideal rewards go up to the maximum effective balance of the fork
+			if err := common.ProcessSlots(ctx, &spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, spec.SLOTS_PER_EPOCH*2-1); err != nil {
+				t.Fatal(err)
+			}
+			report, err := ComputeAttestationRewards(ctx, &spec, epc, state, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			maxEffectiveBalance := spec.MAX_EFFECTIVE_BALANCE
+			if fork.name == "electra" {
+				maxEffectiveBalance = spec.MAX_EFFECTIVE_BALANCE_ELECTRA
+			}
+			if count := len(report.IdealRewards); uint64(count) != uint64(maxEffectiveBalance/spec.EFFECTIVE_BALANCE_INCREMENT) {
+				t.Fatalf("unexpected number of ideal rewards: %d", count)
+			}
+			if last := report.IdealRewards[len(report.IdealRewards)-1]; last.EffectiveBalance != maxEffectiveBalance {
+				t.Fatalf("expected the ideal rewards to end at %d, got %d", maxEffectiveBalance, last.EffectiveBalance)
+			}
+		})
+	}
+}
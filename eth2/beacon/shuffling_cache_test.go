@@ -0,0 +1,92 @@
+package beacon
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestShufflingCacheBranches(t *testing.T) {
+	spec := configs.Minimal
+	ctx := context.Background()
+	state := testGenesisState(t, spec)
+	epc, err := common.NewEpochsContext(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// second slot of epoch 3: all decision blocks of the previous, current and next epoch are known
+	if err := common.ProcessSlots(ctx, spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, spec.SLOTS_PER_EPOCH*3+1); err != nil {
+		t.Fatal(err)
+	}
+	branch := func() *phase0.BeaconStateView {
+		s, err := state.CopyState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.(*phase0.BeaconStateView)
+	}
+	cache := common.NewShufflingCache(16)
+	a, b := branch(), branch()
+	epcA, err := common.NewEpochsContextWithCache(spec, a, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	epcB, err := common.NewEpochsContextWithCaches(spec, b, epcA.ValidatorPubkeyCache, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epcA.PreviousEpoch != epcB.PreviousEpoch || epcA.CurrentEpoch != epcB.CurrentEpoch ||
+		epcA.NextEpoch != epcB.NextEpoch || epcA.Proposers != epcB.Proposers {
+		t.Fatal("expected branches with the same decision blocks to share shufflings and proposers")
+	}
+	if n := cache.Len(); n != 4 {
+		t.Fatalf("expected 3 shufflings and 1 proposers epoch in the cache, got %d entries", n)
+	}
+	if !reflect.DeepEqual(epcA.CurrentEpoch, epc.CurrentEpoch) || !reflect.DeepEqual(epcA.NextEpoch, epc.NextEpoch) ||
+		!reflect.DeepEqual(epcA.Proposers, epc.Proposers) {
+		t.Fatal("cached context does not match uncached context")
+	}
+
+	// a branch with a different block at the end of the previous epoch:
+	// only the next shuffling and the current proposers depend on it
+	c := branch()
+	roots, err := c.BlockRoots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := roots.SetRoot(spec.SLOTS_PER_EPOCH*3-1, common.Root{0xc0}); err != nil {
+		t.Fatal(err)
+	}
+	epcC, err := common.NewEpochsContextWithCache(spec, c, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epcC.CurrentEpoch != epcA.CurrentEpoch || epcC.PreviousEpoch != epcA.PreviousEpoch {
+		t.Fatal("expected shared shufflings of the previous and current epoch")
+	}
+	if epcC.NextEpoch == epcA.NextEpoch || epcC.Proposers == epcA.Proposers {
+		t.Fatal("expected separate next shuffling and proposers for a different decision block")
+	}
+
+	// the rotation into the next epoch consults the cache, and clones share it
+	epcClone := epcA.Clone()
+	if err := common.ProcessSlots(ctx, spec, epcA, &StandardUpgradeableBeaconState{BeaconState: a}, spec.SLOTS_PER_EPOCH*4); err != nil {
+		t.Fatal(err)
+	}
+	if err := common.ProcessSlots(ctx, spec, epcClone, &StandardUpgradeableBeaconState{BeaconState: b}, spec.SLOTS_PER_EPOCH*4); err != nil {
+		t.Fatal(err)
+	}
+	if epcA.NextEpoch != epcClone.NextEpoch || epcA.Proposers != epcClone.Proposers {
+		t.Fatal("expected rotated contexts to share shufflings and proposers")
+	}
+	if err := common.ProcessSlots(ctx, spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, spec.SLOTS_PER_EPOCH*4); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(epcA.NextEpoch, epc.NextEpoch) || !reflect.DeepEqual(epcA.Proposers, epc.Proposers) {
+		t.Fatal("rotated cached context does not match uncached context")
+	}
+}
@@ -0,0 +1,316 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+// stepwiseEpochState processes epochs with the stepwise reference implementation.
+type stepwiseEpochState struct {
+	*electra.BeaconStateView
+}
+
+func (s stepwiseEpochState) ProcessEpoch(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	return s.BeaconStateView.ProcessEpochStepwise(ctx, spec, epc)
+}
+
+// testParticipationFlags returns the participation of validator i in the given epoch:
+// most validators participate in the first epochs, after which participation is too low to justify,
+// and the chain enters an inactivity leak.
+func testParticipationFlags(epoch common.Epoch, i int) altair.ParticipationFlags {
+	if epoch < 3 {
+		if i%5 == 0 {
+			return altair.TIMELY_SOURCE_FLAG
+		}
+		return altair.TIMELY_SOURCE_FLAG | altair.TIMELY_TARGET_FLAG | altair.TIMELY_HEAD_FLAG
+	}
+	if i%4 == 0 {
+		return altair.TIMELY_SOURCE_FLAG | altair.TIMELY_TARGET_FLAG
+	}
+	return 0
+}
+
+func setTestParticipation(t *testing.T, state altair.AltairLikeBeaconState, epoch common.Epoch) {
+	t.Helper()
+	prev, err := state.PreviousEpochParticipation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	curr, err := state.CurrentEpochParticipation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := prev.Length()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < int(count); i++ {
+		if epoch > 0 {
+			if err := prev.SetFlags(common.ValidatorIndex(i), testParticipationFlags(epoch-1, i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := curr.SetFlags(common.ValidatorIndex(i), testParticipationFlags(epoch, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcessEpochSinglePass(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY = 1
+	more := spec
+	more.SLOTS_PER_EPOCH = 64
+	validators := testValidators(t, &more)
+	for i := range validators {
+		validators[i].WithdrawalCredentials = eth1Credentials(i)
+	}
+	validators[5].WithdrawalCredentials = compoundingCredentials(5)
+	validators[5].Balance = 100_000_000_000
+	validators[6].Balance = 40_000_000_000
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator := func(i common.ValidatorIndex) common.Validator {
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(err error) {
+		if err != nil {
+			t.Helper()
+			t.Fatal(err)
+		}
+	}
+	// a slashed validator
+	check(validator(1).MakeSlashed())
+	// a validator to eject
+	check(validator(2).SetEffectiveBalance(spec.EJECTION_BALANCE))
+	check(bals.SetBalance(2, spec.EJECTION_BALANCE-spec.EFFECTIVE_BALANCE_INCREMENT))
+	// a new validator, not yet eligible for activation
+	check(validator(3).SetActivationEligibilityEpoch(common.FAR_FUTURE_EPOCH))
+	check(validator(3).SetActivationEpoch(common.FAR_FUTURE_EPOCH))
+	// a validator in the activation queue
+	check(validator(4).SetActivationEpoch(common.FAR_FUTURE_EPOCH))
+	// a balance below the effective balance
+	check(bals.SetBalance(7, 30_500_000_000))
+	// a consolidation that is processed after the first epoch, and one that stays pending
+	check(validator(8).SetExitEpoch(1))
+	check(validator(9).SetExitEpoch(20))
+	pending, err := state.PendingConsolidations()
+	check(err)
+	check(pending.Append(electra.PendingConsolidation{SourceIndex: 8, TargetIndex: 5}))
+	check(pending.Append(electra.PendingConsolidation{SourceIndex: 9, TargetIndex: 5}))
+	scores, err := state.InactivityScores()
+	check(err)
+	for i := range validators {
+		check(scores.SetScore(common.ValidatorIndex(i), uint64(i*3)))
+	}
+
+	stepwiseCopy, err := state.CopyState()
+	check(err)
+	stepwise := stepwiseCopy.(*electra.BeaconStateView)
+	stepwiseEpc := epc.Clone()
+	ctx := context.Background()
+	for epoch := common.Epoch(0); epoch < 10; epoch++ {
+		setTestParticipation(t, state, epoch)
+		setTestParticipation(t, stepwise, epoch)
+		next, err := spec.EpochStartSlot(epoch + 1)
+		check(err)
+		check(common.ProcessSlots(ctx, &spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, next))
+		check(common.ProcessSlots(ctx, &spec, stepwiseEpc, &StandardUpgradeableBeaconState{BeaconState: stepwiseEpochState{stepwise}}, next))
+		if a, b := state.HashTreeRoot(tree.GetHashFn()), stepwise.HashTreeRoot(tree.GetHashFn()); a != b {
+			t.Fatalf("post-state of epoch %d differs: single-pass %s, stepwise %s", epoch, a, b)
+		}
+	}
+
+	// check the test covered the interesting cases
+	if exitEpoch, err := validator(2).ExitEpoch(); err != nil || exitEpoch == common.FAR_FUTURE_EPOCH {
+		t.Fatal("expected validator 2 to be ejected")
+	}
+	if activationEpoch, err := validator(4).ActivationEpoch(); err != nil || activationEpoch == common.FAR_FUTURE_EPOCH {
+		t.Fatal("expected validator 4 to be activated")
+	}
+	pending, err = state.PendingConsolidations()
+	check(err)
+	if consolidations, err := pending.Consolidations(); err != nil || len(consolidations) != 1 {
+		t.Fatalf("expected one remaining consolidation, got %v", consolidations)
+	}
+	finalized, err := state.FinalizedCheckpoint()
+	check(err)
+	if epc.PreviousEpoch.Epoch-finalized.Epoch <= spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY {
+		t.Fatalf("expected an inactivity leak, finalized epoch %d", finalized.Epoch)
+	}
+}
+
+type singlePassForkState interface {
+	altair.SinglePassEpochBeaconState
+	ProcessEpochStepwise(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error
+}
+
+// stepwiseForkState processes epochs of the forks before Electra with the stepwise reference implementation.
+type stepwiseForkState struct {
+	singlePassForkState
+}
+
+func (s stepwiseForkState) ProcessEpoch(ctx context.Context, spec *common.Spec, epc *common.EpochsContext) error {
+	return s.singlePassForkState.ProcessEpochStepwise(ctx, spec, epc)
+}
+
+func TestProcessEpochSinglePassAltairToDeneb(t *testing.T) {
+	forks := []struct {
+		name      string
+		kickStart func(spec *common.Spec, validators []phase0.KickstartValidatorData) (singlePassForkState, *common.EpochsContext, error)
+	}{
+		{"altair", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (singlePassForkState, *common.EpochsContext, error) {
+			pre, epc, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+			if err != nil {
+				return nil, nil, err
+			}
+			state, err := altair.UpgradeToAltair(spec, epc, pre)
+			return state, epc, err
+		}},
+		{"bellatrix", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (singlePassForkState, *common.EpochsContext, error) {
+			return bellatrix.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &bellatrix.ExecutionPayloadHeader{})
+		}},
+		{"capella", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (singlePassForkState, *common.EpochsContext, error) {
+			return capella.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &capella.ExecutionPayloadHeader{})
+		}},
+		{"deneb", func(spec *common.Spec, validators []phase0.KickstartValidatorData) (singlePassForkState, *common.EpochsContext, error) {
+			return deneb.KickStartState(spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+		}},
+	}
+	for i, fork := range forks {
+		t.Run(fork.name, func(t *testing.T) {
+			spec := *configs.Minimal
+			for j, epoch := range []*common.Epoch{&spec.ALTAIR_FORK_EPOCH, &spec.BELLATRIX_FORK_EPOCH,
+				&spec.CAPELLA_FORK_EPOCH, &spec.DENEB_FORK_EPOCH, &spec.ALPACA_FORK_EPOCH} {
+				if j <= i {
+					*epoch = 0
+				} else {
+					*epoch = common.FAR_FUTURE_EPOCH
+				}
+			}
+			spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY = 1
+			more := spec
+			more.SLOTS_PER_EPOCH = 64
+			validators := testValidators(t, &more)
+			for j := range validators {
+				validators[j].WithdrawalCredentials = eth1Credentials(j)
+			}
+			validators[6].Balance = 40_000_000_000
+			state, epc, err := fork.kickStart(&spec, validators)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testProcessEpochSinglePassFork(t, &spec, state, epc)
+		})
+	}
+}
+
+func testProcessEpochSinglePassFork(t *testing.T, spec *common.Spec, state singlePassForkState, epc *common.EpochsContext) {
+	check := func(err error) {
+		if err != nil {
+			t.Helper()
+			t.Fatal(err)
+		}
+	}
+	validator := func(i common.ValidatorIndex) common.Validator {
+		vals, err := state.Validators()
+		check(err)
+		v, err := vals.Validator(i)
+		check(err)
+		return v
+	}
+	bals, err := state.Balances()
+	check(err)
+	// a slashed validator
+	check(validator(1).MakeSlashed())
+	// validators to eject, behind the exits that are already in the exit queue
+	for _, i := range []common.ValidatorIndex{2, 11} {
+		check(validator(i).SetEffectiveBalance(spec.EJECTION_BALANCE))
+		check(bals.SetBalance(i, spec.EJECTION_BALANCE-spec.EFFECTIVE_BALANCE_INCREMENT))
+	}
+	check(validator(8).SetExitEpoch(15))
+	check(validator(9).SetExitEpoch(20))
+	// a new validator, not yet eligible for activation
+	check(validator(3).SetActivationEligibilityEpoch(common.FAR_FUTURE_EPOCH))
+	check(validator(3).SetActivationEpoch(common.FAR_FUTURE_EPOCH))
+	// validators in the activation queue, more than the churn limit
+	for i := common.ValidatorIndex(12); i < 20; i++ {
+		check(validator(i).SetActivationEpoch(common.FAR_FUTURE_EPOCH))
+	}
+	// a balance below the effective balance
+	check(bals.SetBalance(7, 30_500_000_000))
+	scores, err := state.InactivityScores()
+	check(err)
+	count, err := scores.Length()
+	check(err)
+	for i := uint64(0); i < count; i++ {
+		check(scores.SetScore(common.ValidatorIndex(i), i*3))
+	}
+
+	stepwiseCopy, err := state.CopyState()
+	check(err)
+	stepwise := stepwiseCopy.(singlePassForkState)
+	stepwiseEpc := epc.Clone()
+	ctx := context.Background()
+	for epoch := common.Epoch(0); epoch < 10; epoch++ {
+		setTestParticipation(t, state, epoch)
+		setTestParticipation(t, stepwise, epoch)
+		next, err := spec.EpochStartSlot(epoch + 1)
+		check(err)
+		check(common.ProcessSlots(ctx, spec, epc, &StandardUpgradeableBeaconState{BeaconState: state}, next))
+		check(common.ProcessSlots(ctx, spec, stepwiseEpc, &StandardUpgradeableBeaconState{BeaconState: stepwiseForkState{stepwise}}, next))
+		if a, b := state.HashTreeRoot(tree.GetHashFn()), stepwise.HashTreeRoot(tree.GetHashFn()); a != b {
+			t.Fatalf("post-state of epoch %d differs: single-pass %s, stepwise %s", epoch, a, b)
+		}
+	}
+
+	// check the test covered the interesting cases
+	exit2, err := validator(2).ExitEpoch()
+	check(err)
+	exit11, err := validator(11).ExitEpoch()
+	check(err)
+	if exit2 != 20 || exit11 != 21 {
+		t.Fatalf("expected validators 2 and 11 to be ejected at the end of the exit queue, got exit epochs %d and %d", exit2, exit11)
+	}
+	if activationEpoch, err := validator(12).ActivationEpoch(); err != nil || activationEpoch == common.FAR_FUTURE_EPOCH {
+		t.Fatal("expected validator 12 to be activated")
+	}
+	if eligibilityEpoch, err := validator(3).ActivationEligibilityEpoch(); err != nil || eligibilityEpoch == common.FAR_FUTURE_EPOCH {
+		t.Fatal("expected validator 3 to be eligible for activation")
+	}
+	finalized, err := state.FinalizedCheckpoint()
+	check(err)
+	if epc.PreviousEpoch.Epoch-finalized.Epoch <= spec.MIN_EPOCHS_TO_INACTIVITY_PENALTY {
+		t.Fatalf("expected an inactivity leak, finalized epoch %d", finalized.Epoch)
+	}
+}
@@ -0,0 +1,322 @@
+package beacon
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+)
+
+// ValidatorStatus is a validator status as defined in the beacon-API.
+type ValidatorStatus string
+
+const (
+	StatusPendingInitialized ValidatorStatus = "pending_initialized"
+	StatusPendingQueued      ValidatorStatus = "pending_queued"
+	StatusActiveOngoing      ValidatorStatus = "active_ongoing"
+	StatusActiveExiting      ValidatorStatus = "active_exiting"
+	StatusActiveSlashed      ValidatorStatus = "active_slashed"
+	StatusExitedUnslashed    ValidatorStatus = "exited_unslashed"
+	StatusExitedSlashed      ValidatorStatus = "exited_slashed"
+	StatusWithdrawalPossible ValidatorStatus = "withdrawal_possible"
+	StatusWithdrawalDone     ValidatorStatus = "withdrawal_done"
+)
+
+func (s ValidatorStatus) String() string {
+	return string(s)
+}
+
+// IsPending is true for the pending_initialized and pending_queued statuses
+func (s ValidatorStatus) IsPending() bool {
+	return s == StatusPendingInitialized || s == StatusPendingQueued
+}
+
+// IsActive is true for the active_ongoing, active_exiting and active_slashed statuses
+func (s ValidatorStatus) IsActive() bool {
+	return s == StatusActiveOngoing || s == StatusActiveExiting || s == StatusActiveSlashed
+}
+
+// WithdrawableEpoch returns the epoch from which an exited validator can be withdrawn.
+// Validators do not track a withdrawable epoch in this state format,
+// the minimum withdrawability delay after the exit is used instead. FAR_FUTURE_EPOCH if the validator is not exiting.
+func WithdrawableEpoch(spec *common.Spec, v *common.FlatValidator) common.Epoch {
+	if v.ExitEpoch == common.FAR_FUTURE_EPOCH {
+		return common.FAR_FUTURE_EPOCH
+	}
+	return v.ExitEpoch + spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+}
+
+// GetValidatorStatus classifies the validator at the given epoch, with the given (actual, not effective) balance.
+func GetValidatorStatus(spec *common.Spec, v *common.FlatValidator, balance common.Gwei, epoch common.Epoch) ValidatorStatus {
+	if epoch < v.ActivationEpoch {
+		if v.ActivationEligibilityEpoch == common.FAR_FUTURE_EPOCH {
+			return StatusPendingInitialized
+		}
+		return StatusPendingQueued
+	}
+	if epoch < v.ExitEpoch {
+		if v.ExitEpoch == common.FAR_FUTURE_EPOCH {
+			return StatusActiveOngoing
+		}
+		if v.Slashed {
+			return StatusActiveSlashed
+		}
+		return StatusActiveExiting
+	}
+	if epoch < WithdrawableEpoch(spec, v) {
+		if v.Slashed {
+			return StatusExitedSlashed
+		}
+		return StatusExitedUnslashed
+	}
+	if balance != 0 {
+		return StatusWithdrawalPossible
+	}
+	return StatusWithdrawalDone
+}
+
+// Number of epochs between an epoch and its finalization, when the chain finalizes normally.
+const finalityDelay common.Epoch = 2
+
+// ValidatorLifecycle is the status of a validator, with the (estimated) epochs of the next steps in its lifecycle.
+// Epochs that are already known from the state are exact, others are estimates, or FAR_FUTURE_EPOCH if unknown.
+type ValidatorLifecycle struct {
+	Index  common.ValidatorIndex `json:"index"`
+	Status ValidatorStatus       `json:"status"`
+	// Position in the activation queue, 0 is next. Only meaningful for pending validators with a known activation estimate.
+	QueuePosition     uint64       `json:"queue_position"`
+	ActivationEpoch   common.Epoch `json:"activation_epoch"`
+	ExitEpoch         common.Epoch `json:"exit_epoch"`
+	WithdrawableEpoch common.Epoch `json:"withdrawable_epoch"`
+}
+
+// QueueEstimator estimates when validators activate and exit, based on the current queues and churn limits.
+//
+// The estimates assume that the churn limit stays the same, that no other validators join the queues,
+// and that the chain finalizes normally: queued validators become eligible for activation
+// once their activation eligibility epoch is finalized, assumed to be finalityDelay epochs later.
+type QueueEstimator struct {
+	spec         *common.Spec
+	flats        []common.FlatValidator
+	balances     []common.Gwei
+	currentEpoch common.Epoch
+
+	activationChurn uint64
+	exitChurn       uint64
+	// validators in the activation queue, in order of activation
+	queue []common.ValidatorIndex
+	// validator index -> position in queue
+	positions map[common.ValidatorIndex]uint64
+	// estimated activation epoch per position in queue
+	activations []common.Epoch
+	// the registry update epoch that dequeues the back of the queue, and how many validators it dequeues
+	queueEndEpoch common.Epoch
+	queueEndCount uint64
+
+	exitQueueEnd      common.Epoch
+	exitQueueEndChurn uint64
+
+	// Nil before Electra
+	electraExitChurn *ElectraExitChurn
+	// Exit churn per epoch in Gwei, Electra only
+	exitBalanceChurn common.Gwei
+}
+
+// ElectraExitChurn is the state of the Electra exit queue, which is weighted by the effective balance of exiting validators.
+type ElectraExitChurn struct {
+	EarliestExitEpoch    common.Epoch
+	ExitBalanceToConsume common.Gwei
+}
+
+// NewQueueEstimator creates an estimator for the registry at the given current and finalized epochs.
+// The balances are the actual balances of the validators, to tell completed withdrawals apart.
+// The exit churn is the exit queue of an Electra state, and must be nil before Electra.
+func NewQueueEstimator(spec *common.Spec, flats []common.FlatValidator, balances []common.Gwei,
+	currentEpoch common.Epoch, finalizedEpoch common.Epoch, exitChurn *ElectraExitChurn) (*QueueEstimator, error) {
+	if len(flats) != len(balances) {
+		return nil, fmt.Errorf("got %d validators, but %d balances", len(flats), len(balances))
+	}
+	e := &QueueEstimator{
+		spec:             spec,
+		flats:            flats,
+		balances:         balances,
+		currentEpoch:     currentEpoch,
+		positions:        make(map[common.ValidatorIndex]uint64),
+		electraExitChurn: exitChurn,
+	}
+	activeCount := uint64(0)
+	totalActive := common.Gwei(0)
+	e.exitQueueEnd = spec.ComputeActivationExitEpoch(currentEpoch)
+	for i := range flats {
+		v := &flats[i]
+		if v.IsActive(currentEpoch) {
+			activeCount++
+			totalActive += v.EffectiveBalance
+		}
+		if v.ActivationEpoch == common.FAR_FUTURE_EPOCH && v.ActivationEligibilityEpoch != common.FAR_FUTURE_EPOCH {
+			e.queue = append(e.queue, common.ValidatorIndex(i))
+		}
+		if v.ExitEpoch != common.FAR_FUTURE_EPOCH {
+			if v.ExitEpoch > e.exitQueueEnd {
+				e.exitQueueEnd = v.ExitEpoch
+				e.exitQueueEndChurn = 0
+			}
+			if v.ExitEpoch == e.exitQueueEnd {
+				e.exitQueueEndChurn++
+			}
+		}
+	}
+	e.exitChurn = spec.GetChurnLimit(activeCount)
+	if exitChurn != nil {
+		// like the total active balance of the epochs context, at least one increment
+		if totalActive < spec.EFFECTIVE_BALANCE_INCREMENT {
+			totalActive = spec.EFFECTIVE_BALANCE_INCREMENT
+		}
+		e.exitBalanceChurn = electra.GetActivationExitChurnLimit(spec, totalActive)
+	}
+	e.activationChurn = e.exitChurn
+	// Deneb limits the activation churn
+	if currentEpoch >= spec.DENEB_FORK_EPOCH && uint64(spec.MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT) < e.activationChurn {
+		e.activationChurn = uint64(spec.MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT)
+	}
+	// Order by the sequence of activation_eligibility_epoch setting and then index, like the registry updates
+	sort.Slice(e.queue, func(i, j int) bool {
+		a, b := flats[e.queue[i]].ActivationEligibilityEpoch, flats[e.queue[j]].ActivationEligibilityEpoch
+		if a == b {
+			return e.queue[i] < e.queue[j]
+		}
+		return a < b
+	})
+	e.activations = make([]common.Epoch, len(e.queue))
+	// the epoch of the registry update that dequeues the validator, starting with the current epoch transition.
+	processEpoch := currentEpoch
+	count := uint64(0)
+	for i, vi := range e.queue {
+		e.positions[vi] = uint64(i)
+		// the eligibility epoch must be finalized before dequeueing
+		eligibleAt := flats[vi].ActivationEligibilityEpoch
+		if eligibleAt > finalizedEpoch {
+			eligibleAt += finalityDelay
+		} else {
+			eligibleAt = currentEpoch
+		}
+		if eligibleAt > processEpoch {
+			processEpoch = eligibleAt
+			count = 0
+		}
+		if count >= e.activationChurn {
+			processEpoch++
+			count = 0
+		}
+		count++
+		e.activations[i] = spec.ComputeActivationExitEpoch(processEpoch)
+	}
+	e.queueEndEpoch, e.queueEndCount = processEpoch, count
+	return e, nil
+}
+
+// NewQueueEstimatorFromState creates an estimator for the registry of the state.
+func NewQueueEstimatorFromState(spec *common.Spec, epc *common.EpochsContext, state common.BeaconState) (*QueueEstimator, error) {
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	flats, err := common.FlattenValidators(vals)
+	if err != nil {
+		return nil, err
+	}
+	bals, err := state.Balances()
+	if err != nil {
+		return nil, err
+	}
+	balances, err := bals.AllBalances()
+	if err != nil {
+		return nil, err
+	}
+	finalized, err := state.FinalizedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := state.(*StandardUpgradeableBeaconState); ok {
+		state = s.BeaconState
+	}
+	var exitChurn *ElectraExitChurn
+	if s, ok := state.(electra.ExitChurnBeaconState); ok {
+		exitChurn = new(ElectraExitChurn)
+		if exitChurn.EarliestExitEpoch, err = s.EarliestExitEpoch(); err != nil {
+			return nil, err
+		}
+		if exitChurn.ExitBalanceToConsume, err = s.ExitBalanceToConsume(); err != nil {
+			return nil, err
+		}
+	}
+	return NewQueueEstimator(spec, flats, balances, epc.CurrentEpoch.Epoch, finalized.Epoch, exitChurn)
+}
+
+// QueueLength is the number of validators in the activation queue.
+func (e *QueueEstimator) QueueLength() uint64 {
+	return uint64(len(e.queue))
+}
+
+// NextExitEpoch estimates the exit epoch of the validator, if it initiates an exit in the current epoch.
+// Since Electra the exit queue is weighted by the effective balance of the validator.
+func (e *QueueEstimator) NextExitEpoch(index common.ValidatorIndex) (common.Epoch, error) {
+	if uint64(index) >= uint64(len(e.flats)) {
+		return 0, fmt.Errorf("unknown validator %d", index)
+	}
+	if e.electraExitChurn != nil {
+		// like electra.ComputeExitEpochAndUpdateChurn, without updating the churn
+		exitEpoch := e.spec.ComputeActivationExitEpoch(e.currentEpoch)
+		balanceToConsume := e.exitBalanceChurn
+		if e.electraExitChurn.EarliestExitEpoch >= exitEpoch {
+			exitEpoch = e.electraExitChurn.EarliestExitEpoch
+			balanceToConsume = e.electraExitChurn.ExitBalanceToConsume
+		}
+		if exitBalance := e.flats[index].EffectiveBalance; exitBalance > balanceToConsume {
+			exitEpoch += common.Epoch((exitBalance-balanceToConsume-1)/e.exitBalanceChurn + 1)
+		}
+		return exitEpoch, nil
+	}
+	if e.exitQueueEndChurn >= e.exitChurn {
+		return e.exitQueueEnd + 1, nil
+	}
+	return e.exitQueueEnd, nil
+}
+
+// Lifecycle returns the status of the validator, with the estimated epochs of its activation, exit and withdrawability.
+// The exit of a validator that is not exiting yet is unknown (FAR_FUTURE_EPOCH), see NextExitEpoch for an estimate.
+func (e *QueueEstimator) Lifecycle(index common.ValidatorIndex) (*ValidatorLifecycle, error) {
+	if uint64(index) >= uint64(len(e.flats)) {
+		return nil, fmt.Errorf("unknown validator %d", index)
+	}
+	v := &e.flats[index]
+	out := &ValidatorLifecycle{
+		Index:             index,
+		Status:            GetValidatorStatus(e.spec, v, e.balances[index], e.currentEpoch),
+		ActivationEpoch:   v.ActivationEpoch,
+		ExitEpoch:         v.ExitEpoch,
+		WithdrawableEpoch: WithdrawableEpoch(e.spec, v),
+	}
+	if v.ActivationEpoch != common.FAR_FUTURE_EPOCH {
+		return out, nil
+	}
+	if pos, ok := e.positions[index]; ok {
+		out.QueuePosition = pos
+		out.ActivationEpoch = e.activations[pos]
+		return out, nil
+	}
+	// Validators with a full effective balance become eligible in the next epoch transition, and join the back of the queue.
+	if v.EffectiveBalance == e.spec.MAX_EFFECTIVE_BALANCE {
+		out.QueuePosition = uint64(len(e.queue))
+		processEpoch := e.currentEpoch + 1 + finalityDelay
+		if e.queueEndEpoch > processEpoch {
+			processEpoch = e.queueEndEpoch
+		}
+		if processEpoch == e.queueEndEpoch && e.queueEndCount >= e.activationChurn {
+			processEpoch++
+		}
+		out.ActivationEpoch = e.spec.ComputeActivationExitEpoch(processEpoch)
+	}
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestValidatorStatus(t *testing.T) {
+	spec := configs.Minimal
+	far := common.FAR_FUTURE_EPOCH
+	maxEB := spec.MAX_EFFECTIVE_BALANCE
+	delay := spec.MIN_VALIDATOR_WITHDRAWABILITY_DELAY
+	cases := []struct {
+		v        common.FlatValidator
+		balance  common.Gwei
+		expected ValidatorStatus
+	}{
+		{common.FlatValidator{ActivationEligibilityEpoch: far, ActivationEpoch: far, ExitEpoch: far}, 1, StatusPendingInitialized},
+		{common.FlatValidator{ActivationEligibilityEpoch: 3, ActivationEpoch: far, ExitEpoch: far}, maxEB, StatusPendingQueued},
+		{common.FlatValidator{ActivationEpoch: 0, ExitEpoch: far}, maxEB, StatusActiveOngoing},
+		{common.FlatValidator{ActivationEpoch: 0, ExitEpoch: 20}, maxEB, StatusActiveExiting},
+		{common.FlatValidator{ActivationEpoch: 0, ExitEpoch: 20, Slashed: true}, maxEB, StatusActiveSlashed},
+		{common.FlatValidator{ActivationEpoch: 0, ExitEpoch: 9}, maxEB, StatusExitedUnslashed},
+		{common.FlatValidator{ActivationEpoch: 0, ExitEpoch: 9, Slashed: true}, maxEB, StatusExitedSlashed},
+	}
+	for i, c := range cases {
+		if got := GetValidatorStatus(spec, &c.v, c.balance, 10); got != c.expected {
+			t.Errorf("case %d: expected %s, got %s", i, c.expected, got)
+		}
+	}
+	// withdrawable after the withdrawability delay
+	exited := common.FlatValidator{ActivationEpoch: 0, ExitEpoch: 9}
+	for i, c := range []struct {
+		balance  common.Gwei
+		expected ValidatorStatus
+	}{{maxEB, StatusWithdrawalPossible}, {0, StatusWithdrawalDone}} {
+		if got := GetValidatorStatus(spec, &exited, c.balance, 9+delay); got != c.expected {
+			t.Errorf("case %d: expected %s, got %s", i, c.expected, got)
+		}
+	}
+}
+
+func TestQueueEstimator(t *testing.T) {
+	spec := configs.Minimal
+	far := common.FAR_FUTURE_EPOCH
+	maxEB := spec.MAX_EFFECTIVE_BALANCE
+	var flats []common.FlatValidator
+	// active validators, enough for the minimum churn limit
+	for i := 0; i < 64; i++ {
+		flats = append(flats, common.FlatValidator{EffectiveBalance: maxEB, ActivationEpoch: 0, ExitEpoch: far})
+	}
+	churn := spec.GetChurnLimit(64)
+	// queued validators, eligible since epoch 5 (finalized), and one eligible since epoch 9 (not finalized)
+	queued := int(churn) + 1
+	for i := 0; i < queued; i++ {
+		flats = append(flats, common.FlatValidator{EffectiveBalance: maxEB, ActivationEligibilityEpoch: 5, ActivationEpoch: far, ExitEpoch: far})
+	}
+	late := common.ValidatorIndex(len(flats))
+	flats = append(flats, common.FlatValidator{EffectiveBalance: maxEB, ActivationEligibilityEpoch: 9, ActivationEpoch: far, ExitEpoch: far})
+	fresh := common.ValidatorIndex(len(flats))
+	flats = append(flats, common.FlatValidator{EffectiveBalance: maxEB, ActivationEligibilityEpoch: far, ActivationEpoch: far, ExitEpoch: far})
+	balances := make([]common.Gwei, len(flats))
+	for i := range balances {
+		balances[i] = maxEB
+	}
+
+	const current, finalized = 10, 8
+	e, err := NewQueueEstimator(spec, flats, balances, current, finalized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.QueueLength() != uint64(queued+1) {
+		t.Fatalf("expected queue length %d, got %d", queued+1, e.QueueLength())
+	}
+	lifecycle := func(i common.ValidatorIndex) *ValidatorLifecycle {
+		t.Helper()
+		l, err := e.Lifecycle(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	first := lifecycle(64)
+	if first.Status != StatusPendingQueued || first.QueuePosition != 0 || first.ActivationEpoch != spec.ComputeActivationExitEpoch(current) {
+		t.Fatalf("unexpected first queued validator: %+v", first)
+	}
+	// the last of the first batch does not fit in the churn of the current epoch
+	overflow := lifecycle(common.ValidatorIndex(64 + queued - 1))
+	if overflow.ActivationEpoch != spec.ComputeActivationExitEpoch(current+1) {
+		t.Fatalf("unexpected overflow validator: %+v", overflow)
+	}
+	// eligibility epoch 9 is expected to be finalized at epoch 11
+	if l := lifecycle(late); l.ActivationEpoch != spec.ComputeActivationExitEpoch(9+finalityDelay) {
+		t.Fatalf("unexpected late validator: %+v", l)
+	}
+	l := lifecycle(fresh)
+	if l.Status != StatusPendingInitialized || l.QueuePosition != e.QueueLength() ||
+		l.ActivationEpoch != spec.ComputeActivationExitEpoch(current+1+finalityDelay) {
+		t.Fatalf("unexpected fresh validator: %+v", l)
+	}
+	if l := lifecycle(0); l.Status != StatusActiveOngoing || l.ExitEpoch != far {
+		t.Fatalf("unexpected active validator: %+v", l)
+	}
+	if exit, err := e.NextExitEpoch(0); err != nil || exit != spec.ComputeActivationExitEpoch(current) {
+		t.Fatalf("unexpected next exit epoch %d, error: %v", exit, err)
+	}
+}
+
+func TestQueueEstimatorElectraExits(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	validators := testValidators(t, &spec)
+	for i := range validators {
+		validators[i].WithdrawalCredentials = eth1Credentials(i)
+	}
+	// a compounding validator, which takes more of the exit churn
+	validators[5].WithdrawalCredentials = compoundingCredentials(5)
+	validators[5].Balance = 200_000_000_000
+	state, epc, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	churn := electra.GetActivationExitChurnLimit(&spec, epc.TotalActiveStake)
+	for i, c := range []ElectraExitChurn{
+		// no exits queued yet
+		{EarliestExitEpoch: 0, ExitBalanceToConsume: 0},
+		// exits are queued up to a later epoch, with some churn left in that epoch
+		{EarliestExitEpoch: 20, ExitBalanceToConsume: churn / 2},
+		{EarliestExitEpoch: 20, ExitBalanceToConsume: 0},
+	} {
+		for _, index := range []common.ValidatorIndex{0, 5} {
+			stateCopy, err := state.CopyState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := stateCopy.(*electra.BeaconStateView)
+			if err := s.SetEarliestExitEpoch(c.EarliestExitEpoch); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetExitBalanceToConsume(c.ExitBalanceToConsume); err != nil {
+				t.Fatal(err)
+			}
+			e, err := NewQueueEstimatorFromState(&spec, epc, &StandardUpgradeableBeaconState{BeaconState: s})
+			if err != nil {
+				t.Fatal(err)
+			}
+			estimate, err := e.NextExitEpoch(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := electra.InitiateValidatorExit(&spec, epc, s, index); err != nil {
+				t.Fatal(err)
+			}
+			vals, err := s.Validators()
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := vals.Validator(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exitEpoch, err := v.ExitEpoch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if estimate != exitEpoch {
+				t.Errorf("case %d, validator %d: estimated exit epoch %d, but exits at %d", i, index, estimate, exitEpoch)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package beacon
+
+import (
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// ComputeWeakSubjectivityPeriod computes the number of epochs after the epoch of the given state,
+// in which a node can still safely sync from the state, as a weak subjectivity checkpoint.
+// The churn that bounds the period depends on the fork of the state.
+func ComputeWeakSubjectivityPeriod(spec *common.Spec, state common.BeaconState) (common.Epoch, error) {
+	if s, ok := state.(*StandardUpgradeableBeaconState); ok {
+		state = s.BeaconState
+	}
+	switch state.(type) {
+	case *electra.BeaconStateView:
+		return electra.ComputeWeakSubjectivityPeriod(spec, state)
+	default:
+		return phase0.ComputeWeakSubjectivityPeriod(spec, state)
+	}
+}
+
+// WeakSubjectivityStore is the part of the forkchoice store that is relevant to weak subjectivity checks.
+type WeakSubjectivityStore interface {
+	// CurrentSlot is the slot of the wall-clock time of the store.
+	CurrentSlot() common.Slot
+}
+
+// IsWithinWeakSubjectivityPeriod checks if the current slot of the store is still within
+// the weak subjectivity period of the given weak subjectivity state and checkpoint.
+// An error is returned if the state does not match the checkpoint.
+func IsWithinWeakSubjectivityPeriod(spec *common.Spec, store WeakSubjectivityStore, wsState common.BeaconState, wsCheckpoint common.Checkpoint) (bool, error) {
+	// Validate the input state against the input weak subjectivity checkpoint
+	blockRoot, err := phase0.LatestBlockRoot(wsState)
+	if err != nil {
+		return false, err
+	}
+	if blockRoot != wsCheckpoint.Root {
+		return false, fmt.Errorf("weak subjectivity state latest block root %s does not match checkpoint root %s", blockRoot, wsCheckpoint.Root)
+	}
+	slot, err := wsState.Slot()
+	if err != nil {
+		return false, err
+	}
+	wsStateEpoch := spec.SlotToEpoch(slot)
+	if wsStateEpoch != wsCheckpoint.Epoch {
+		return false, fmt.Errorf("weak subjectivity state epoch %d does not match checkpoint epoch %d", wsStateEpoch, wsCheckpoint.Epoch)
+	}
+	wsPeriod, err := ComputeWeakSubjectivityPeriod(spec, wsState)
+	if err != nil {
+		return false, err
+	}
+	currentEpoch := spec.SlotToEpoch(store.CurrentSlot())
+	return currentEpoch <= wsStateEpoch+wsPeriod, nil
+}
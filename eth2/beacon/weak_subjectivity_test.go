@@ -0,0 +1,179 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+const gwei = common.Gwei(phase0.ETH_TO_GWEI)
+
+// Example values of the weak-subjectivity guide of the phase0 spec
+func TestWeakSubjectivityPeriodPhase0(t *testing.T) {
+	for _, c := range []struct {
+		avgBalance common.Gwei
+		count      uint64
+		period     common.Epoch
+	}{
+		{28 * gwei, 32768, 504},
+		{28 * gwei, 65536, 752},
+		{28 * gwei, 131072, 1248},
+		{28 * gwei, 262144, 2241},
+		{28 * gwei, 524288, 2241},
+		{28 * gwei, 1048576, 2241},
+		{32 * gwei, 32768, 665},
+		{32 * gwei, 65536, 1075},
+		{32 * gwei, 131072, 1894},
+		{32 * gwei, 262144, 3532},
+		{32 * gwei, 524288, 3532},
+		{32 * gwei, 1048576, 3532},
+	} {
+		got := phase0.WeakSubjectivityPeriod(configs.Mainnet, c.count, c.avgBalance*common.Gwei(c.count))
+		if got != c.period {
+			t.Errorf("avg balance %d, %d validators: expected period %d, got %d", c.avgBalance/gwei, c.count, c.period, got)
+		}
+	}
+}
+
+// Example values of the weak-subjectivity guide of the Electra spec
+func TestWeakSubjectivityPeriodElectra(t *testing.T) {
+	for _, c := range []struct {
+		totalActive common.Gwei
+		period      common.Epoch
+	}{
+		{1_048_576 * gwei, 665},
+		{2_097_152 * gwei, 1075},
+		{4_194_304 * gwei, 1894},
+		{8_388_608 * gwei, 3532},
+		{16_777_216 * gwei, 3532},
+		{33_554_432 * gwei, 3532},
+	} {
+		if got := electra.WeakSubjectivityPeriod(configs.Mainnet, c.totalActive); got != c.period {
+			t.Errorf("total active balance %d: expected period %d, got %d", c.totalActive/gwei, c.period, got)
+		}
+	}
+}
+
+func TestComputeWeakSubjectivityPeriodFork(t *testing.T) {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 0
+	spec.BELLATRIX_FORK_EPOCH = 0
+	spec.CAPELLA_FORK_EPOCH = 0
+	spec.DENEB_FORK_EPOCH = 0
+	spec.ALPACA_FORK_EPOCH = 0
+	// a low balance churn, for the few validators to make a difference in the Electra period
+	spec.MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA = spec.EFFECTIVE_BALANCE_INCREMENT
+	validators := testValidators(t, &spec)
+	count := uint64(len(validators))
+	total := spec.MAX_EFFECTIVE_BALANCE * common.Gwei(count)
+
+	phase0State, _, err := phase0.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	period, err := ComputeWeakSubjectivityPeriod(&spec, phase0State)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := phase0.WeakSubjectivityPeriod(&spec, count, total); period != expected {
+		t.Fatalf("phase0: expected period %d, got %d", expected, period)
+	}
+
+	electraState, _, err := electra.KickStartState(&spec, common.Root{0x01}, 1_000_000, validators, &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	period, err = ComputeWeakSubjectivityPeriod(&spec, &StandardUpgradeableBeaconState{BeaconState: electraState})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := electra.WeakSubjectivityPeriod(&spec, total); period != expected {
+		t.Fatalf("electra: expected period %d, got %d", expected, period)
+	}
+	if period == phase0.WeakSubjectivityPeriod(&spec, count, total) {
+		t.Fatal("expected the electra period to differ from the phase0 period")
+	}
+}
+
+type testWeakSubjectivityStore common.Slot
+
+func (s testWeakSubjectivityStore) CurrentSlot() common.Slot {
+	return common.Slot(s)
+}
+
+func TestVerifyCheckpointAnchor(t *testing.T) {
+	spec := configs.Minimal
+	genesis := testGenesisState(t, spec)
+	genesisBlock := &phase0.SignedBeaconBlock{Message: phase0.BeaconBlock{
+		StateRoot: genesis.HashTreeRoot(tree.GetHashFn()),
+	}}
+	block := genesisBlock.Envelope(spec, common.ForkDigest{})
+
+	// the anchor state is at the start of epoch 1, the genesis block is followed by gap slots
+	stateCopy, err := genesis.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, stateCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &StandardUpgradeableBeaconState{BeaconState: stateCopy}
+	if err := common.ProcessSlots(context.Background(), spec, epc, state, spec.SLOTS_PER_EPOCH); err != nil {
+		t.Fatal(err)
+	}
+	anchor, err := VerifyCheckpointAnchor(spec, state, block, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := common.Checkpoint{Epoch: 1, Root: block.BlockRoot}
+	if anchor.Checkpoint != expected {
+		t.Fatalf("unexpected anchor checkpoint %s", anchor.Checkpoint)
+	}
+	if _, err := VerifyCheckpointAnchor(spec, state, block, &expected); err != nil {
+		t.Fatalf("expected the anchor to match the trusted checkpoint: %v", err)
+	}
+	if _, err := VerifyCheckpointAnchor(spec, state, block, &common.Checkpoint{Epoch: 1, Root: common.Root{0xaa}}); err == nil {
+		t.Fatal("expected error for different trusted checkpoint")
+	}
+	// the genesis state itself anchors the genesis block at epoch 0
+	if anchor, err := VerifyCheckpointAnchor(spec, genesis, block, nil); err != nil || anchor.Checkpoint.Epoch != 0 {
+		t.Fatalf("expected genesis anchor, got error: %v", err)
+	}
+	// a block that is not the latest block of the state
+	other := *genesisBlock
+	other.Message.StateRoot = common.Root{0xbb}
+	if _, err := VerifyCheckpointAnchor(spec, state, other.Envelope(spec, common.ForkDigest{}), nil); err == nil {
+		t.Fatal("expected error for block that does not match the state")
+	}
+	// a state that is not at the start of an epoch
+	if err := common.ProcessSlots(context.Background(), spec, epc, state, spec.SLOTS_PER_EPOCH+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyCheckpointAnchor(spec, state, block, nil); err == nil {
+		t.Fatal("expected error for state that is not at the start of an epoch")
+	}
+
+	period, err := ComputeWeakSubjectivityPeriod(spec, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesisCheckpoint := common.Checkpoint{Epoch: 0, Root: block.BlockRoot}
+	lastSlot := common.Slot(period+1)*spec.SLOTS_PER_EPOCH - 1
+	if ok, err := IsWithinWeakSubjectivityPeriod(spec, testWeakSubjectivityStore(lastSlot), genesis, genesisCheckpoint); err != nil || !ok {
+		t.Fatalf("expected slot %d to be within the weak subjectivity period, error: %v", lastSlot, err)
+	}
+	if ok, err := IsWithinWeakSubjectivityPeriod(spec, testWeakSubjectivityStore(lastSlot+1), genesis, genesisCheckpoint); err != nil || ok {
+		t.Fatalf("expected slot %d to be outside of the weak subjectivity period, error: %v", lastSlot+1, err)
+	}
+	if _, err := IsWithinWeakSubjectivityPeriod(spec, testWeakSubjectivityStore(0), genesis, expected); err == nil {
+		t.Fatal("expected error for checkpoint that does not match the state")
+	}
+}
@@ -0,0 +1,194 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+)
+
+// ForecastWithdrawal is a withdrawal that is expected to be included in the execution payload of a future slot.
+type ForecastWithdrawal struct {
+	Slot       common.Slot       `json:"slot"`
+	Time       common.Timestamp  `json:"time"`
+	Withdrawal common.Withdrawal `json:"withdrawal"`
+}
+
+// WithdrawalForecaster projects the withdrawal sweep of a Capella, Deneb or Electra state forward.
+//
+// The forecast assumes that every slot has a block, and that balances only change by the withdrawals themselves:
+// rewards, penalties and new withdrawal requests after the state are not accounted for.
+type WithdrawalForecaster struct {
+	spec        *common.Spec
+	genesisTime common.Timestamp
+	// the slot of the state, forecasts start with the slot after
+	slot common.Slot
+	// copy of the state, never modified, every forecast runs the withdrawals on a copy of its own
+	state capella.BeaconStateWithWithdrawals
+	// number of validators, and pending partial withdrawals (Electra), that the sweep goes through
+	validatorCount uint64
+	pendingCount   uint64
+}
+
+// NewWithdrawalForecaster creates a forecaster from a copy of the state.
+// Electra states are recognized through electra.BeaconStateWithWithdrawals, and have their pending partial withdrawals projected too.
+func NewWithdrawalForecaster(spec *common.Spec, state capella.BeaconStateWithWithdrawals) (*WithdrawalForecaster, error) {
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	slot, err := state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	validatorCount, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	var pendingCount uint64
+	if est, ok := state.(electra.BeaconStateWithWithdrawals); ok {
+		pending, err := est.PendingPartialWithdrawals()
+		if err != nil {
+			return nil, err
+		}
+		withdrawals, err := pending.Withdrawals()
+		if err != nil {
+			return nil, err
+		}
+		pendingCount = uint64(len(withdrawals))
+	}
+	stateCopy, err := copyWithdrawalsState(state)
+	if err != nil {
+		return nil, err
+	}
+	return &WithdrawalForecaster{
+		spec:           spec,
+		genesisTime:    genesisTime,
+		slot:           slot,
+		state:          stateCopy,
+		validatorCount: validatorCount,
+		pendingCount:   pendingCount,
+	}, nil
+}
+
+func copyWithdrawalsState(state capella.BeaconStateWithWithdrawals) (capella.BeaconStateWithWithdrawals, error) {
+	out, err := state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	st, ok := out.(capella.BeaconStateWithWithdrawals)
+	if !ok {
+		return nil, fmt.Errorf("state copy of type %T does not have withdrawals", out)
+	}
+	return st, nil
+}
+
+// Forecast returns the expected withdrawals of the given number of slots after the state.
+func (f *WithdrawalForecaster) Forecast(slots uint64) ([]ForecastWithdrawal, error) {
+	var out []ForecastWithdrawal
+	err := f.run(slots, func(w ForecastWithdrawal) bool {
+		out = append(out, w)
+		return true
+	})
+	return out, err
+}
+
+// Until returns the expected withdrawals after the state, up to and including the first withdrawal of the given validator.
+// The forecast stops after maxSlots, the last withdrawal is then not of the validator.
+func (f *WithdrawalForecaster) Until(index common.ValidatorIndex, maxSlots uint64) ([]ForecastWithdrawal, error) {
+	if uint64(index) >= f.validatorCount {
+		return nil, fmt.Errorf("unknown validator %d", index)
+	}
+	var out []ForecastWithdrawal
+	err := f.run(maxSlots, func(w ForecastWithdrawal) bool {
+		out = append(out, w)
+		return w.Withdrawal.ValidatorIndex != index
+	})
+	return out, err
+}
+
+// NextWithdrawal returns the expected next withdrawal of the given validator,
+// or nil if the validator is not expected to withdraw within a full sweep of the registry.
+func (f *WithdrawalForecaster) NextWithdrawal(index common.ValidatorIndex) (*ForecastWithdrawal, error) {
+	if uint64(index) >= f.validatorCount {
+		return nil, fmt.Errorf("unknown validator %d", index)
+	}
+	// A slot sweeps MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP validators, unless its payload is full.
+	// Until the sweep reaches the validator, every validator it passes withdraws at most once,
+	// and every pending partial withdrawal is processed at most once, which bounds the number of full payloads.
+	sweep := min(f.validatorCount, uint64(f.spec.MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP))
+	perPayload := uint64(f.spec.MAX_WITHDRAWALS_PER_PAYLOAD)
+	maxSlots := ceilDiv(f.validatorCount, sweep) + ceilDiv(f.validatorCount+f.pendingCount, perPayload) + 1
+	withdrawals, err := f.Until(index, maxSlots)
+	if err != nil {
+		return nil, err
+	}
+	if len(withdrawals) == 0 || withdrawals[len(withdrawals)-1].Withdrawal.ValidatorIndex != index {
+		return nil, nil
+	}
+	return &withdrawals[len(withdrawals)-1], nil
+}
+
+func ceilDiv(a, b uint64) uint64 {
+	return (a + b - 1) / b
+}
+
+// payloadWithdrawals are the withdrawals of a simulated execution payload.
+type payloadWithdrawals []common.Withdrawal
+
+func (p payloadWithdrawals) GetWitdrawals() []common.Withdrawal {
+	return p
+}
+
+// run applies the withdrawals of the given number of slots to a copy of the state,
+// with capella.ProcessWithdrawals, or electra.ProcessWithdrawals for Electra states.
+// The simulation stops early when fn returns false.
+func (f *WithdrawalForecaster) run(slots uint64, fn func(w ForecastWithdrawal) bool) error {
+	if f.validatorCount == 0 {
+		return nil
+	}
+	state, err := copyWithdrawalsState(f.state)
+	if err != nil {
+		return err
+	}
+	est, isElectra := state.(electra.BeaconStateWithWithdrawals)
+	for i := uint64(1); i <= slots; i++ {
+		slot := f.slot + common.Slot(i)
+		t, err := f.spec.TimeAtSlot(slot, f.genesisTime)
+		if err != nil {
+			return err
+		}
+		if err := state.SetSlot(slot); err != nil {
+			return err
+		}
+		var withdrawals []common.Withdrawal
+		if isElectra {
+			withdrawals, _, err = electra.GetExpectedWithdrawals(est, f.spec)
+			if err != nil {
+				return fmt.Errorf("failed to get withdrawals of slot %d: %w", slot, err)
+			}
+			err = electra.ProcessWithdrawals(context.Background(), f.spec, est, payloadWithdrawals(withdrawals))
+		} else {
+			withdrawals, err = capella.GetExpectedWithdrawals(state, f.spec)
+			if err != nil {
+				return fmt.Errorf("failed to get withdrawals of slot %d: %w", slot, err)
+			}
+			err = capella.ProcessWithdrawals(context.Background(), f.spec, state, payloadWithdrawals(withdrawals))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to process withdrawals of slot %d: %w", slot, err)
+		}
+		for _, w := range withdrawals {
+			if !fn(ForecastWithdrawal{Slot: slot, Time: t, Withdrawal: w}) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func eth1Credentials(i int) (out common.Root) {
+	out[0] = common.ETH1_ADDRESS_WITHDRAWAL_PREFIX
+	out[31] = byte(i)
+	return
+}
+
+func TestWithdrawalForecasterCapella(t *testing.T) {
+	spec := configs.Minimal
+	state := capella.NewBeaconStateView(spec)
+	if err := state.SetGenesisTime(1000); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := state.AddValidator(spec, common.BLSPubkey{byte(i)}, eth1Credentials(i), spec.MAX_EFFECTIVE_BALANCE+common.Gwei(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := NewWithdrawalForecaster(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := f.Forecast(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sweep skims the excess balance of validators 1 to 9, validator 0 has none
+	if len(forecast) != 9 {
+		t.Fatalf("expected 9 withdrawals, got %d", len(forecast))
+	}
+	for i, w := range forecast {
+		index := common.ValidatorIndex(i + 1)
+		if w.Withdrawal.ValidatorIndex != index || w.Withdrawal.Amount != common.Gwei(index) {
+			t.Fatalf("withdrawal %d: expected %d gwei of validator %d, got %s", i, index, index, w.Withdrawal)
+		}
+	}
+	// the forecast must match the withdrawals of the state transition, slot by slot
+	i := 0
+	for slot := 1; slot <= 3; slot++ {
+		expected, err := capella.GetExpectedWithdrawals(state, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range expected {
+			if got := forecast[i]; got.Withdrawal != w || got.Slot != common.Slot(slot) {
+				t.Fatalf("slot %d: expected %s, got %s at slot %d", slot, w, got.Withdrawal, got.Slot)
+			}
+			i++
+		}
+		if err := capella.ProcessWithdrawals(context.Background(), spec, state, payloadWithdrawals(expected)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	next, err := f.NextWithdrawal(9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == nil || next.Slot != 3 || next.Time != 1000+3*spec.SECONDS_PER_SLOT || next.Withdrawal.Amount != 9 {
+		t.Fatalf("unexpected next withdrawal: %+v", next)
+	}
+	if next, err := f.NextWithdrawal(0); err != nil || next != nil {
+		t.Fatalf("expected no withdrawal of validator 0, got %+v", next)
+	}
+	until, err := f.Until(5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(until) != 5 || until[4].Withdrawal.ValidatorIndex != 5 {
+		t.Fatalf("expected sweep to stop at validator 5, got %d withdrawals", len(until))
+	}
+}
+
+func TestWithdrawalForecasterNextWithdrawalFullPayloads(t *testing.T) {
+	spec := configs.Minimal
+	state := capella.NewBeaconStateView(spec)
+	// every payload is full, the sweep only advances by MAX_WITHDRAWALS_PER_PAYLOAD validators per slot
+	count := 10 * uint64(spec.MAX_VALIDATORS_PER_WITHDRAWALS_SWEEP)
+	for i := uint64(0); i < count; i++ {
+		if err := state.AddValidator(spec, common.BLSPubkey{byte(i), byte(i >> 8)}, eth1Credentials(int(i)), spec.MAX_EFFECTIVE_BALANCE+1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := NewWithdrawalForecaster(spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := common.ValidatorIndex(count - 1)
+	next, err := f.NextWithdrawal(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := common.Slot(count / uint64(spec.MAX_WITHDRAWALS_PER_PAYLOAD)); next == nil || next.Slot != expected {
+		t.Fatalf("expected the withdrawal of the last validator at slot %d, got %+v", expected, next)
+	}
+}
+
+func TestWithdrawalForecasterElectra(t *testing.T) {
+	spec := *configs.Minimal
+	spec.MAX_PENDING_PARTIAL_WITHDRAWALS = 64
+	state := electra.NewBeaconStateView(&spec)
+	for i := 0; i < 10; i++ {
+		if err := state.AddValidator(&spec, common.BLSPubkey{byte(i)}, eth1Credentials(i), spec.MAX_EFFECTIVE_BALANCE+100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := state.PendingPartialWithdrawals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []electra.PendingPartialWithdrawal{
+		{Index: 7, Amount: 30, WithdrawableEpoch: 0},
+		{Index: 8, Amount: 1000, WithdrawableEpoch: 0},
+		{Index: 9, Amount: 10, WithdrawableEpoch: 0},
+	} {
+		if err := pending.Append(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := NewWithdrawalForecaster(&spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := f.Forecast(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type expectation struct {
+		slot   common.Slot
+		index  common.ValidatorIndex
+		amount common.Gwei
+	}
+	for i, e := range []expectation{
+		// pending partial withdrawals, limited per sweep, and capped to the excess balance,
+		// followed by the sweep, which skims the excess balance of the validators
+		{1, 7, 30}, {1, 8, 100}, {1, 0, 100}, {1, 1, 100},
+		{2, 9, 10}, {2, 2, 100}, {2, 3, 100}, {2, 4, 100},
+		// the sweep skims what the pending partial withdrawals left, and skips validator 8 without excess
+		{3, 5, 100}, {3, 6, 100}, {3, 7, 70}, {3, 9, 90},
+	} {
+		got := forecast[i]
+		if got.Slot != e.slot || got.Withdrawal.ValidatorIndex != e.index || got.Withdrawal.Amount != e.amount {
+			t.Fatalf("withdrawal %d: expected %+v, got %+v", i, e, got)
+		}
+		if got.Withdrawal.Index != common.WithdrawalIndex(i) {
+			t.Fatalf("withdrawal %d has index %d", i, got.Withdrawal.Index)
+		}
+	}
+	// the forecast must match the Electra state transition, which also dequeues the processed partials
+	i := 0
+	for slot := 1; slot <= 3; slot++ {
+		expected, _, err := electra.GetExpectedWithdrawals(state, &spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range expected {
+			if got := forecast[i]; got.Withdrawal != w || got.Slot != common.Slot(slot) {
+				t.Fatalf("slot %d: expected %s, got %s at slot %d", slot, w, got.Withdrawal, got.Slot)
+			}
+			i++
+		}
+		if err := electra.ProcessWithdrawals(context.Background(), &spec, state, payloadWithdrawals(expected)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err = state.PendingPartialWithdrawals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining, err := pending.Withdrawals(); err != nil || len(remaining) != 0 {
+		t.Fatalf("expected all pending partial withdrawals to be processed, %d remain", len(remaining))
+	}
+}
+
+func TestWithdrawalForecasterPendingPartialsOfSameValidator(t *testing.T) {
+	spec := *configs.Minimal
+	state := electra.NewBeaconStateView(&spec)
+	for i := 0; i < 10; i++ {
+		if err := state.AddValidator(&spec, common.BLSPubkey{byte(i)}, eth1Credentials(i), spec.MAX_EFFECTIVE_BALANCE+100); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := state.PendingPartialWithdrawals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second withdrawal is capped to what the first one leaves of the excess balance
+	for _, w := range []electra.PendingPartialWithdrawal{
+		{Index: 7, Amount: 60, WithdrawableEpoch: 0},
+		{Index: 7, Amount: 60, WithdrawableEpoch: 0},
+	} {
+		if err := pending.Append(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := NewWithdrawalForecaster(&spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := f.Forecast(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecast) < 2 || forecast[0].Withdrawal.Amount != 60 || forecast[1].Withdrawal.Amount != 40 {
+		t.Fatalf("expected partial withdrawals of 60 and 40 gwei, got %+v", forecast)
+	}
+	// the forecast runs on a copy, the state itself is not changed
+	bals, err := state.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal, err := bals.GetBalance(7); err != nil || bal != spec.MAX_EFFECTIVE_BALANCE+100 {
+		t.Fatalf("expected unchanged balance, got %d", bal)
+	}
+}
+
+func TestWithdrawalForecasterUnknownPendingPartial(t *testing.T) {
+	spec := *configs.Minimal
+	state := electra.NewBeaconStateView(&spec)
+	for i := 0; i < 4; i++ {
+		if err := state.AddValidator(&spec, common.BLSPubkey{byte(i)}, eth1Credentials(i), spec.MAX_EFFECTIVE_BALANCE); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := state.PendingPartialWithdrawals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pending.Append(electra.PendingPartialWithdrawal{Index: 10, Amount: 1, WithdrawableEpoch: 0}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewWithdrawalForecaster(&spec, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Forecast(1); err == nil {
+		t.Fatal("expected error for pending partial withdrawal of unknown validator")
+	}
+}
@@ -0,0 +1,222 @@
+package clock
+
+import (
+	"context"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// TimeSource provides the current time and timers, to make the clock injectable.
+// SystemTime uses the system clock, FakeClock can be controlled by tests.
+type TimeSource interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemTime struct{}
+
+func (systemTime) Now() time.Time {
+	return time.Now()
+}
+
+func (systemTime) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemTime is the TimeSource of the system clock.
+var SystemTime TimeSource = systemTime{}
+
+// Interval is the sub-slot interval: a slot is split in IntervalsPerSlot parts of equal duration.
+type Interval uint64
+
+const (
+	// Blocks are proposed at the start of the slot.
+	IntervalBlock Interval = iota
+	// Attestations are made at 1/3 of the slot.
+	IntervalAttest
+	// Aggregates are made at 2/3 of the slot.
+	IntervalAggregate
+)
+
+const IntervalsPerSlot = 3
+
+func (i Interval) String() string {
+	switch i {
+	case IntervalBlock:
+		return "block"
+	case IntervalAttest:
+		return "attest"
+	case IntervalAggregate:
+		return "aggregate"
+	default:
+		return "unknown"
+	}
+}
+
+// Tick is the start of a sub-slot interval.
+type Tick struct {
+	Slot     common.Slot
+	Epoch    common.Epoch
+	Interval Interval
+	// The scheduled time of the tick. The tick may be delivered later.
+	Time time.Time
+}
+
+// Clock tells the slot, epoch and interval, relative to genesis, of the time of its time source.
+type Clock struct {
+	spec        *common.Spec
+	genesisTime common.Timestamp
+	src         TimeSource
+}
+
+// NewClock creates a clock for the chain with the given genesis time. If src is nil, SystemTime is used.
+func NewClock(spec *common.Spec, genesisTime common.Timestamp, src TimeSource) *Clock {
+	if src == nil {
+		src = SystemTime
+	}
+	return &Clock{spec: spec, genesisTime: genesisTime, src: src}
+}
+
+func (c *Clock) slotDuration() time.Duration {
+	return time.Duration(c.spec.SECONDS_PER_SLOT) * time.Second
+}
+
+func (c *Clock) intervalDuration() time.Duration {
+	return c.slotDuration() / IntervalsPerSlot
+}
+
+// GenesisTime returns the genesis time as configured in the clock.
+func (c *Clock) GenesisTime() common.Timestamp {
+	return c.genesisTime
+}
+
+// Now returns the current time of the time source.
+func (c *Clock) Now() time.Time {
+	return c.src.Now()
+}
+
+// SinceGenesis returns the time elapsed since genesis, negative before genesis.
+func (c *Clock) SinceGenesis() time.Duration {
+	return c.src.Now().Sub(time.Unix(int64(c.genesisTime), 0))
+}
+
+// IsBeforeGenesis is true if genesis has not happened yet.
+func (c *Clock) IsBeforeGenesis() bool {
+	return c.SinceGenesis() < 0
+}
+
+// CurrentSlot returns the current slot, clipped to genesis.
+func (c *Clock) CurrentSlot() common.Slot {
+	return c.SlotAfter(0)
+}
+
+// CurrentEpoch returns the current epoch, clipped to genesis.
+func (c *Clock) CurrentEpoch() common.Epoch {
+	return c.spec.SlotToEpoch(c.CurrentSlot())
+}
+
+// CurrentInterval returns the current sub-slot interval, IntervalBlock before genesis.
+func (c *Clock) CurrentInterval() Interval {
+	since := c.SinceGenesis()
+	if since < 0 {
+		return IntervalBlock
+	}
+	return Interval((since % c.slotDuration()) / c.intervalDuration())
+}
+
+// SlotAfter returns the slot after the given duration elapsed. The duration may be negative. It clips on genesis.
+// This implements gossipval.SlotAfter.
+func (c *Clock) SlotAfter(delta time.Duration) common.Slot {
+	since := c.SinceGenesis() + delta
+	if since < 0 {
+		return 0
+	}
+	return common.Slot(since / c.slotDuration())
+}
+
+// SlotStart returns the start time of the slot.
+func (c *Clock) SlotStart(slot common.Slot) (time.Time, error) {
+	t, err := c.spec.TimeAtSlot(slot, c.genesisTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(t), 0), nil
+}
+
+// IntervalStart returns the start time of the interval within the slot.
+func (c *Clock) IntervalStart(slot common.Slot, interval Interval) (time.Time, error) {
+	start, err := c.SlotStart(slot)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return start.Add(time.Duration(interval) * c.intervalDuration()), nil
+}
+
+// nextTick returns the first tick at or after the given time, not earlier than genesis.
+func (c *Clock) nextTick(t time.Time) Tick {
+	genesis := time.Unix(int64(c.genesisTime), 0)
+	since := t.Sub(genesis)
+	var n int64
+	if since > 0 {
+		step := c.intervalDuration()
+		n = int64(since / step)
+		if since%step != 0 {
+			n++
+		}
+	}
+	slot := common.Slot(n / IntervalsPerSlot)
+	return Tick{
+		Slot:     slot,
+		Epoch:    c.spec.SlotToEpoch(slot),
+		Interval: Interval(n % IntervalsPerSlot),
+		Time:     genesis.Add(time.Duration(n) * c.intervalDuration()),
+	}
+}
+
+// Ticks delivers a Tick at the start of every interval on the returned channel, starting with the next interval,
+// until the context is canceled, after which the channel is closed.
+// Ticks that are missed, e.g. because the receiver was too slow or the system was suspended, are skipped:
+// only the latest tick is delivered, and tick times may jump forward.
+func (c *Clock) Ticks(ctx context.Context, buffer int) <-chan Tick {
+	out := make(chan Tick, buffer)
+	go func() {
+		defer close(out)
+		next := c.nextTick(c.src.Now())
+		for {
+			wait := next.Time.Sub(c.src.Now())
+			if wait > 0 {
+				select {
+				case <-c.src.After(wait):
+				case <-ctx.Done():
+					return
+				}
+			}
+			// skip to the latest tick if we woke up late
+			now := c.src.Now()
+			if latest := c.nextTick(now); latest.Time.After(now) {
+				if prev := c.prevTick(latest); prev.Time.After(next.Time) {
+					next = prev
+				}
+			} else {
+				next = latest
+			}
+			select {
+			case out <- next:
+			case <-ctx.Done():
+				return
+			}
+			next = c.nextTick(next.Time.Add(1))
+		}
+	}()
+	return out
+}
+
+// prevTick returns the tick before the given tick, or the tick itself if it is the genesis tick.
+func (c *Clock) prevTick(t Tick) Tick {
+	if t.Slot == 0 && t.Interval == IntervalBlock {
+		return t
+	}
+	return c.nextTick(t.Time.Add(-c.intervalDuration()))
+}
@@ -0,0 +1,87 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/gossipval"
+)
+
+var _ gossipval.SlotAfter = (*Clock)(nil)
+
+func TestClock(t *testing.T) {
+	spec := configs.Minimal
+	const genesis = 1000
+	slotDur := time.Duration(spec.SECONDS_PER_SLOT) * time.Second
+	fake := NewFakeClock(time.Unix(genesis, 0).Add(-time.Second))
+	c := NewClock(spec, genesis, fake)
+	if !c.IsBeforeGenesis() || c.CurrentSlot() != 0 || c.SlotAfter(-time.Hour) != 0 {
+		t.Fatal("expected clock to clip on genesis")
+	}
+	fake.Advance(time.Second + slotDur*time.Duration(spec.SLOTS_PER_EPOCH) + slotDur/3)
+	if c.IsBeforeGenesis() {
+		t.Fatal("expected genesis to have passed")
+	}
+	if c.CurrentSlot() != spec.SLOTS_PER_EPOCH || c.CurrentEpoch() != 1 || c.CurrentInterval() != IntervalAttest {
+		t.Fatalf("unexpected slot %d, epoch %d, interval %s", c.CurrentSlot(), c.CurrentEpoch(), c.CurrentInterval())
+	}
+	if s := c.SlotAfter(slotDur); s != spec.SLOTS_PER_EPOCH+1 {
+		t.Fatalf("unexpected slot after: %d", s)
+	}
+	start, err := c.IntervalStart(spec.SLOTS_PER_EPOCH, IntervalAggregate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(fake.Now().Add(slotDur / 3)) {
+		t.Fatalf("unexpected interval start: %s", start)
+	}
+}
+
+func TestTicks(t *testing.T) {
+	spec := configs.Minimal
+	const genesis = 1000
+	interval := time.Duration(spec.SECONDS_PER_SLOT) * time.Second / IntervalsPerSlot
+	fake := NewFakeClock(time.Unix(genesis, 0).Add(-time.Second))
+	c := NewClock(spec, genesis, fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := c.Ticks(ctx, 10)
+
+	expect := func(slot common.Slot, i Interval) {
+		t.Helper()
+		tick := <-ticks
+		if tick.Slot != slot || tick.Interval != i {
+			t.Fatalf("expected tick at slot %d interval %s, got slot %d interval %s", slot, i, tick.Slot, tick.Interval)
+		}
+		at, err := c.IntervalStart(slot, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tick.Time.Equal(at) {
+			t.Fatalf("unexpected tick time %s, expected %s", tick.Time, at)
+		}
+	}
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	expect(0, IntervalBlock)
+	fake.BlockUntil(1)
+	fake.Advance(interval)
+	expect(0, IntervalAttest)
+	fake.BlockUntil(1)
+	fake.Advance(interval)
+	expect(0, IntervalAggregate)
+	// missed ticks are skipped
+	fake.BlockUntil(1)
+	fake.Advance(interval*IntervalsPerSlot*2 + interval/2)
+	expect(2, IntervalAggregate)
+	fake.BlockUntil(1)
+	fake.Advance(interval / 2)
+	expect(3, IntervalBlock)
+
+	cancel()
+	for range ticks {
+	}
+}
@@ -0,0 +1,81 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type fakeTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// FakeClock is a TimeSource that only moves when told to, for deterministic tests of timing logic.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// signaled whenever a timer is added
+	cond *sync.Cond
+}
+
+var _ TimeSource = (*FakeClock)(nil)
+
+// NewFakeClock creates a fake clock, starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	f := &FakeClock{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.timers = append(f.timers, &fakeTimer{deadline: f.now.Add(d), ch: ch})
+	f.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward, and fires all timers that expire.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	remaining := f.timers[:0]
+	for _, t := range f.timers {
+		if !t.deadline.After(f.now) {
+			t.ch <- f.now
+		} else {
+			remaining = append(remaining, t)
+		}
+	}
+	f.timers = remaining
+}
+
+// Set moves the clock to the given time, firing all timers that expire. Time cannot move backwards.
+func (f *FakeClock) Set(t time.Time) {
+	if d := t.Sub(f.Now()); d > 0 {
+		f.Advance(d)
+	}
+}
+
+// BlockUntil waits until at least n timers are waiting for the clock to advance.
+// This lets tests wait for a goroutine to schedule its next timer before advancing the clock.
+func (f *FakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
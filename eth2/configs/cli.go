@@ -20,6 +20,7 @@ type SpecOptions struct {
 	CapellaPreset   string `ask:"--preset-capella" help:"Eth2 capella spec preset, name or path to YAML"`
 	DenebPreset     string `ask:"--preset-deneb" help:"Eth2 deneb spec preset, name or path to YAML"`
 	ElectraPreset   string `ask:"--preset-electra" help:"Eth2 electra spec preset, name or path to YAML"`
+	EIP7594Preset   string `ask:"--preset-eip7594" help:"Eth2 EIP-7594 spec preset, name or path to YAML"`
 
 	// TODO: execution engine config for Bellatrix
 	// TODO: trusted setup config for Sharding
@@ -33,6 +34,7 @@ type LegacyConfig struct {
 	common.CapellaPreset   `yaml:",inline"`
 	common.DenebPreset     `yaml:",inline"`
 	common.ElectraPreset   `yaml:",inline"`
+	common.EIP7594Preset   `yaml:",inline"`
 	common.Config          `yaml:",inline"`
 }
 
@@ -62,6 +64,7 @@ func (c *SpecOptions) Spec() (*common.Spec, error) {
 			spec.CapellaPreset = legacy.CapellaPreset
 			spec.DenebPreset = legacy.DenebPreset
 			spec.ElectraPreset = legacy.ElectraPreset
+			spec.EIP7594Preset = legacy.EIP7594Preset
 			spec.Config = legacy.Config
 		}
 	}
@@ -177,6 +180,22 @@ func (c *SpecOptions) Spec() (*common.Spec, error) {
 			return nil, fmt.Errorf("failed to decode electra preset: %v", err)
 		}
 	}
+
+	switch c.EIP7594Preset {
+	case "mainnet":
+		spec.EIP7594Preset = Mainnet.EIP7594Preset
+	case "minimal":
+		spec.EIP7594Preset = Minimal.EIP7594Preset
+	default:
+		f, err := os.Open(c.EIP7594Preset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open eip7594 preset file: %v", err)
+		}
+		dec := yaml.NewDecoder(f)
+		if err := dec.Decode(&spec.EIP7594Preset); err != nil {
+			return nil, fmt.Errorf("failed to decode eip7594 preset: %v", err)
+		}
+	}
 	spec.ExecutionEngine = nil
 	return &spec, nil
 }
@@ -190,4 +209,5 @@ func (c *SpecOptions) Default() {
 	c.CapellaPreset = "mainnet"
 	c.DenebPreset = "mainnet"
 	c.ElectraPreset = "mainnet"
+	c.EIP7594Preset = "mainnet"
 }
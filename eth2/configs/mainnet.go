@@ -67,80 +67,99 @@ var Mainnet = &common.Spec{
 		KZG_COMMITMENT_INCLUSION_PROOF_DEPTH: 17,
 	},
 	ElectraPreset: common.ElectraPreset{
-		MAX_PENDING_DEPOSITS:                 134217728,
-		MAX_PENDING_PARTIAL_WITHDRAWALS:      134217728,
-		MAX_DEPOSIT_REQUESTS_PER_PAYLOAD:     8192,
-		MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD:  16,
-		MAX_VALIDATORS_PER_COMMITTEE_ELECTRA: 131072,
-		MAX_ATTESTATIONS_ALPACA:              8,
-		MAX_ATTESTING_INDICES:                131072,
-		COMMITTEE_BITS:                       8,
+		MIN_ACTIVATION_BALANCE:                     32_000_000_000,
+		MAX_EFFECTIVE_BALANCE_ELECTRA:              2048_000_000_000,
+		MAX_PENDING_DEPOSITS:                       134217728,
+		MAX_PENDING_PARTIAL_WITHDRAWALS:            134217728,
+		PENDING_CONSOLIDATIONS_LIMIT:               262144,
+		MAX_DEPOSIT_REQUESTS_PER_PAYLOAD:           8192,
+		MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD:        16,
+		MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD:     2,
+		MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP: 8,
+		MAX_VALIDATORS_PER_COMMITTEE_ELECTRA:       131072,
+		MAX_ATTESTATIONS_ALPACA:                    8,
+		MAX_ATTESTING_INDICES:                      131072,
+		COMMITTEE_BITS:                             8,
+	},
+	EIP7594Preset: common.EIP7594Preset{
+		FIELD_ELEMENTS_PER_CELL:               64,
+		FIELD_ELEMENTS_PER_EXT_BLOB:           8192,
+		KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH: 4,
 	},
 	Config: common.Config{
-		PRESET_BASE:                           "mainnet",
-		CONFIG_NAME:                           "mainnet",
-		TERMINAL_TOTAL_DIFFICULTY:             view.MustUint256("58750000000000000000000"),
-		TERMINAL_BLOCK_HASH:                   common.Bytes32{},
-		TERMINAL_BLOCK_HASH_ACTIVATION_EPOCH:  ^common.Epoch(0),
-		MIN_GENESIS_ACTIVE_VALIDATOR_COUNT:    1 << 14,
-		MIN_GENESIS_TIME:                      1606824000,
-		GENESIS_FORK_VERSION:                  common.Version{0x00, 0x00, 0x00, 0x00},
-		GENESIS_DELAY:                         604800,
-		ALTAIR_FORK_VERSION:                   common.Version{0x01, 0x00, 0x00, 0x00},
-		ALTAIR_FORK_EPOCH:                     common.Epoch(74240),
-		BELLATRIX_FORK_VERSION:                common.Version{0x02, 0x00, 0x00, 0x00},
-		BELLATRIX_FORK_EPOCH:                  common.Epoch(144896),
-		CAPELLA_FORK_VERSION:                  common.Version{0x03, 0x00, 0x00, 0x00},
-		CAPELLA_FORK_EPOCH:                    common.Epoch(194048),
-		DENEB_FORK_VERSION:                    common.Version{0x04, 0x00, 0x00, 0x00},
-		DENEB_FORK_EPOCH:                      common.Epoch(269568),
-		EIP6110_FORK_VERSION:                  common.Version{0x05, 0x00, 0x00, 0x00},
-		EIP6110_FORK_EPOCH:                    ^common.Epoch(0),
-		EIP7002_FORK_VERSION:                  common.Version{0x05, 0x00, 0x00, 0x00},
-		EIP7002_FORK_EPOCH:                    ^common.Epoch(0),
-		WHISK_FORK_VERSION:                    common.Version{0x06, 0x00, 0x00, 0x00},
-		WHISK_FORK_EPOCH:                      ^common.Epoch(0),
-		SECONDS_PER_SLOT:                      12,
-		SECONDS_PER_ETH1_BLOCK:                14,
-		MIN_VALIDATOR_WITHDRAWABILITY_DELAY:   256,
-		SHARD_COMMITTEE_PERIOD:                256,
-		ETH1_FOLLOW_DISTANCE:                  2048,
-		INACTIVITY_SCORE_BIAS:                 4,
-		INACTIVITY_SCORE_RECOVERY_RATE:        16,
-		EJECTION_BALANCE:                      16_000_000_000,
-		MIN_PER_EPOCH_CHURN_LIMIT:             4,
-		CHURN_LIMIT_QUOTIENT:                  1 << 16,
-		MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT:  8,
-		PROPOSER_SCORE_BOOST:                  40,
-		REORG_HEAD_WEIGHT_THRESHOLD:           20,
-		REORG_PARENT_WEIGHT_THRESHOLD:         160,
-		REORG_MAX_EPOCHS_SINCE_FINALIZATION:   2,
-		DEPOSIT_CHAIN_ID:                      1,
-		DEPOSIT_NETWORK_ID:                    1,
-		DEPOSIT_CONTRACT_ADDRESS:              [20]byte{0x00, 0x00, 0x00, 0x00, 0x21, 0x9a, 0xb5, 0x40, 0x35, 0x6c, 0xBB, 0x83, 0x9C, 0xbe, 0x05, 0x30, 0x3d, 0x77, 0x05, 0xFa},
-		GOSSIP_MAX_SIZE:                       10 * (1 << 20),
-		MAX_REQUEST_BLOCKS:                    1024,
-		EPOCHS_PER_SUBNET_SUBSCRIPTION:        256,
-		MIN_EPOCHS_FOR_BLOCK_REQUESTS:         33024,
-		MAX_CHUNK_SIZE:                        10485760,
-		TTFB_TIMEOUT:                          5,
-		RESP_TIMEOUT:                          10,
-		ATTESTATION_PROPAGATION_SLOT_RANGE:    32,
-		MAXIMUM_GOSSIP_CLOCK_DISPARITY:        500,
-		MESSAGE_DOMAIN_INVALID_SNAPPY:         common.NetworkMessageDomain{0, 0, 0, 0},
-		MESSAGE_DOMAIN_VALID_SNAPPY:           common.NetworkMessageDomain{1, 0, 0, 0},
-		SUBNETS_PER_NODE:                      2,
-		ATTESTATION_SUBNET_COUNT:              64,
-		ATTESTATION_SUBNET_EXTRA_BITS:         0,
-		ATTESTATION_SUBNET_PREFIX_BITS:        6,
-		MAX_REQUEST_BLOCKS_DENEB:              128,
-		MAX_REQUEST_BLOB_SIDECARS:             768,
-		MIN_EPOCHS_FOR_BLOB_SIDECARS_REQUESTS: 4096,
-		BLOB_SIDECAR_SUBNET_COUNT:             6,
-		WHISK_EPOCHS_PER_SHUFFLING_PHASE:      256,
-		WHISK_PROPOSER_SELECTION_GAP:          2,
-		EIP7594_FORK_VERSION:                  common.Version{6, 0, 0, 1},
-		EIP7594_FORK_EPOCH:                    ^common.Epoch(0),
+		PRESET_BASE:                                  "mainnet",
+		CONFIG_NAME:                                  "mainnet",
+		TERMINAL_TOTAL_DIFFICULTY:                    view.MustUint256("58750000000000000000000"),
+		TERMINAL_BLOCK_HASH:                          common.Bytes32{},
+		TERMINAL_BLOCK_HASH_ACTIVATION_EPOCH:         ^common.Epoch(0),
+		MIN_GENESIS_ACTIVE_VALIDATOR_COUNT:           1 << 14,
+		MIN_GENESIS_TIME:                             1606824000,
+		GENESIS_FORK_VERSION:                         common.Version{0x00, 0x00, 0x00, 0x00},
+		GENESIS_DELAY:                                604800,
+		ALTAIR_FORK_VERSION:                          common.Version{0x01, 0x00, 0x00, 0x00},
+		ALTAIR_FORK_EPOCH:                            common.Epoch(74240),
+		BELLATRIX_FORK_VERSION:                       common.Version{0x02, 0x00, 0x00, 0x00},
+		BELLATRIX_FORK_EPOCH:                         common.Epoch(144896),
+		CAPELLA_FORK_VERSION:                         common.Version{0x03, 0x00, 0x00, 0x00},
+		CAPELLA_FORK_EPOCH:                           common.Epoch(194048),
+		DENEB_FORK_VERSION:                           common.Version{0x04, 0x00, 0x00, 0x00},
+		DENEB_FORK_EPOCH:                             common.Epoch(269568),
+		EIP6110_FORK_VERSION:                         common.Version{0x05, 0x00, 0x00, 0x00},
+		EIP6110_FORK_EPOCH:                           ^common.Epoch(0),
+		EIP7002_FORK_VERSION:                         common.Version{0x05, 0x00, 0x00, 0x00},
+		EIP7002_FORK_EPOCH:                           ^common.Epoch(0),
+		WHISK_FORK_VERSION:                           common.Version{0x06, 0x00, 0x00, 0x00},
+		WHISK_FORK_EPOCH:                             ^common.Epoch(0),
+		SECONDS_PER_SLOT:                             12,
+		SECONDS_PER_ETH1_BLOCK:                       14,
+		MIN_VALIDATOR_WITHDRAWABILITY_DELAY:          256,
+		SHARD_COMMITTEE_PERIOD:                       256,
+		ETH1_FOLLOW_DISTANCE:                         2048,
+		INACTIVITY_SCORE_BIAS:                        4,
+		INACTIVITY_SCORE_RECOVERY_RATE:               16,
+		EJECTION_BALANCE:                             16_000_000_000,
+		MIN_PER_EPOCH_CHURN_LIMIT:                    4,
+		CHURN_LIMIT_QUOTIENT:                         1 << 16,
+		MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT:         8,
+		MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA:            128_000_000_000,
+		MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT:    256_000_000_000,
+		PROPOSER_SCORE_BOOST:                         40,
+		REORG_HEAD_WEIGHT_THRESHOLD:                  20,
+		REORG_PARENT_WEIGHT_THRESHOLD:                160,
+		REORG_MAX_EPOCHS_SINCE_FINALIZATION:          2,
+		DEPOSIT_CHAIN_ID:                             1,
+		DEPOSIT_NETWORK_ID:                           1,
+		DEPOSIT_CONTRACT_ADDRESS:                     [20]byte{0x00, 0x00, 0x00, 0x00, 0x21, 0x9a, 0xb5, 0x40, 0x35, 0x6c, 0xBB, 0x83, 0x9C, 0xbe, 0x05, 0x30, 0x3d, 0x77, 0x05, 0xFa},
+		GOSSIP_MAX_SIZE:                              10 * (1 << 20),
+		MAX_REQUEST_BLOCKS:                           1024,
+		EPOCHS_PER_SUBNET_SUBSCRIPTION:               256,
+		MIN_EPOCHS_FOR_BLOCK_REQUESTS:                33024,
+		MAX_CHUNK_SIZE:                               10485760,
+		TTFB_TIMEOUT:                                 5,
+		RESP_TIMEOUT:                                 10,
+		ATTESTATION_PROPAGATION_SLOT_RANGE:           32,
+		MAXIMUM_GOSSIP_CLOCK_DISPARITY:               500,
+		MESSAGE_DOMAIN_INVALID_SNAPPY:                common.NetworkMessageDomain{0, 0, 0, 0},
+		MESSAGE_DOMAIN_VALID_SNAPPY:                  common.NetworkMessageDomain{1, 0, 0, 0},
+		SUBNETS_PER_NODE:                             2,
+		ATTESTATION_SUBNET_COUNT:                     64,
+		ATTESTATION_SUBNET_EXTRA_BITS:                0,
+		ATTESTATION_SUBNET_PREFIX_BITS:               6,
+		MAX_REQUEST_BLOCKS_DENEB:                     128,
+		MAX_REQUEST_BLOB_SIDECARS:                    768,
+		MIN_EPOCHS_FOR_BLOB_SIDECARS_REQUESTS:        4096,
+		BLOB_SIDECAR_SUBNET_COUNT:                    6,
+		WHISK_EPOCHS_PER_SHUFFLING_PHASE:             256,
+		WHISK_PROPOSER_SELECTION_GAP:                 2,
+		EIP7594_FORK_VERSION:                         common.Version{6, 0, 0, 1},
+		EIP7594_FORK_EPOCH:                           ^common.Epoch(0),
+		NUMBER_OF_COLUMNS:                            128,
+		NUMBER_OF_CUSTODY_GROUPS:                     128,
+		DATA_COLUMN_SIDECAR_SUBNET_COUNT:             128,
+		MAX_REQUEST_DATA_COLUMN_SIDECARS:             16384,
+		SAMPLES_PER_SLOT:                             8,
+		CUSTODY_REQUIREMENT:                          4,
+		MIN_EPOCHS_FOR_DATA_COLUMN_SIDECARS_REQUESTS: 4096,
 	},
 	ExecutionEngine: nil,
 }
@@ -66,71 +66,100 @@ var Minimal = &common.Spec{
 		MAX_BLOBS_PER_BLOCK:                  6,
 		KZG_COMMITMENT_INCLUSION_PROOF_DEPTH: 9,
 	},
+	ElectraPreset: common.ElectraPreset{
+		MIN_ACTIVATION_BALANCE:                     32_000_000_000,
+		MAX_EFFECTIVE_BALANCE_ELECTRA:              2048_000_000_000,
+		MAX_PENDING_DEPOSITS:                       134217728,
+		MAX_PENDING_PARTIAL_WITHDRAWALS:            64,
+		PENDING_CONSOLIDATIONS_LIMIT:               64,
+		MAX_DEPOSIT_REQUESTS_PER_PAYLOAD:           4,
+		MAX_WITHDRAWAL_REQUESTS_PER_PAYLOAD:        2,
+		MAX_CONSOLIDATION_REQUESTS_PER_PAYLOAD:     2,
+		MAX_PENDING_PARTIALS_PER_WITHDRAWALS_SWEEP: 2,
+		MAX_VALIDATORS_PER_COMMITTEE_ELECTRA:       8192,
+		MAX_ATTESTATIONS_ALPACA:                    8,
+		MAX_ATTESTING_INDICES:                      8192,
+		COMMITTEE_BITS:                             4,
+	},
+	EIP7594Preset: common.EIP7594Preset{
+		FIELD_ELEMENTS_PER_CELL:               64,
+		FIELD_ELEMENTS_PER_EXT_BLOB:           8192,
+		KZG_COMMITMENTS_INCLUSION_PROOF_DEPTH: 4,
+	},
 	Config: common.Config{
-		PRESET_BASE:                           "minimal",
-		CONFIG_NAME:                           "minimal",
-		TERMINAL_TOTAL_DIFFICULTY:             view.MustUint256("115792089237316195423570985008687907853269984665640564039457584007913129638912"),
-		TERMINAL_BLOCK_HASH:                   common.Bytes32{},
-		TERMINAL_BLOCK_HASH_ACTIVATION_EPOCH:  ^common.Epoch(0),
-		MIN_GENESIS_ACTIVE_VALIDATOR_COUNT:    64,
-		MIN_GENESIS_TIME:                      1578009600,
-		GENESIS_FORK_VERSION:                  common.Version{0x00, 0x00, 0x00, 0x01},
-		GENESIS_DELAY:                         300,
-		ALTAIR_FORK_VERSION:                   common.Version{0x01, 0x00, 0x00, 0x01},
-		ALTAIR_FORK_EPOCH:                     ^common.Epoch(0),
-		BELLATRIX_FORK_VERSION:                common.Version{0x02, 0x00, 0x00, 0x01},
-		BELLATRIX_FORK_EPOCH:                  ^common.Epoch(0),
-		CAPELLA_FORK_VERSION:                  common.Version{0x03, 0x00, 0x00, 0x01},
-		CAPELLA_FORK_EPOCH:                    ^common.Epoch(0),
-		DENEB_FORK_VERSION:                    common.Version{0x04, 0x00, 0x00, 0x01},
-		DENEB_FORK_EPOCH:                      ^common.Epoch(0),
-		EIP6110_FORK_VERSION:                  common.Version{0x05, 0x00, 0x00, 0x01},
-		EIP6110_FORK_EPOCH:                    ^common.Epoch(0),
-		EIP7002_FORK_VERSION:                  common.Version{0x05, 0x00, 0x00, 0x01},
-		EIP7002_FORK_EPOCH:                    ^common.Epoch(0),
-		WHISK_FORK_VERSION:                    common.Version{0x06, 0x00, 0x00, 0x01},
-		WHISK_FORK_EPOCH:                      ^common.Epoch(0),
-		SECONDS_PER_SLOT:                      6,
-		SECONDS_PER_ETH1_BLOCK:                14,
-		MIN_VALIDATOR_WITHDRAWABILITY_DELAY:   256,
-		SHARD_COMMITTEE_PERIOD:                64,
-		ETH1_FOLLOW_DISTANCE:                  16,
-		INACTIVITY_SCORE_BIAS:                 4,
-		INACTIVITY_SCORE_RECOVERY_RATE:        16,
-		EJECTION_BALANCE:                      16_000_000_000,
-		MIN_PER_EPOCH_CHURN_LIMIT:             2,
-		CHURN_LIMIT_QUOTIENT:                  32,
-		MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT:  4,
-		PROPOSER_SCORE_BOOST:                  40,
-		REORG_HEAD_WEIGHT_THRESHOLD:           20,
-		REORG_PARENT_WEIGHT_THRESHOLD:         160,
-		REORG_MAX_EPOCHS_SINCE_FINALIZATION:   2,
-		DEPOSIT_CHAIN_ID:                      5,
-		DEPOSIT_NETWORK_ID:                    5,
-		DEPOSIT_CONTRACT_ADDRESS:              [20]byte{0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90},
-		GOSSIP_MAX_SIZE:                       10 * (1 << 20),
-		MAX_REQUEST_BLOCKS:                    1024,
-		EPOCHS_PER_SUBNET_SUBSCRIPTION:        256,
-		MIN_EPOCHS_FOR_BLOCK_REQUESTS:         272,
-		MAX_CHUNK_SIZE:                        10485760,
-		TTFB_TIMEOUT:                          5,
-		RESP_TIMEOUT:                          10,
-		ATTESTATION_PROPAGATION_SLOT_RANGE:    32,
-		MAXIMUM_GOSSIP_CLOCK_DISPARITY:        500,
-		MESSAGE_DOMAIN_INVALID_SNAPPY:         common.NetworkMessageDomain{0, 0, 0, 0},
-		MESSAGE_DOMAIN_VALID_SNAPPY:           common.NetworkMessageDomain{1, 0, 0, 0},
-		SUBNETS_PER_NODE:                      2,
-		ATTESTATION_SUBNET_COUNT:              64,
-		ATTESTATION_SUBNET_EXTRA_BITS:         0,
-		ATTESTATION_SUBNET_PREFIX_BITS:        6,
-		MAX_REQUEST_BLOCKS_DENEB:              128,
-		MAX_REQUEST_BLOB_SIDECARS:             768,
-		MIN_EPOCHS_FOR_BLOB_SIDECARS_REQUESTS: 4096,
-		BLOB_SIDECAR_SUBNET_COUNT:             6,
-		WHISK_EPOCHS_PER_SHUFFLING_PHASE:      4,
-		WHISK_PROPOSER_SELECTION_GAP:          1,
-		EIP7594_FORK_VERSION:                  common.Version{6, 0, 0, 1},
-		EIP7594_FORK_EPOCH:                    ^common.Epoch(0),
+		PRESET_BASE:                                  "minimal",
+		CONFIG_NAME:                                  "minimal",
+		TERMINAL_TOTAL_DIFFICULTY:                    view.MustUint256("115792089237316195423570985008687907853269984665640564039457584007913129638912"),
+		TERMINAL_BLOCK_HASH:                          common.Bytes32{},
+		TERMINAL_BLOCK_HASH_ACTIVATION_EPOCH:         ^common.Epoch(0),
+		MIN_GENESIS_ACTIVE_VALIDATOR_COUNT:           64,
+		MIN_GENESIS_TIME:                             1578009600,
+		GENESIS_FORK_VERSION:                         common.Version{0x00, 0x00, 0x00, 0x01},
+		GENESIS_DELAY:                                300,
+		ALTAIR_FORK_VERSION:                          common.Version{0x01, 0x00, 0x00, 0x01},
+		ALTAIR_FORK_EPOCH:                            ^common.Epoch(0),
+		BELLATRIX_FORK_VERSION:                       common.Version{0x02, 0x00, 0x00, 0x01},
+		BELLATRIX_FORK_EPOCH:                         ^common.Epoch(0),
+		CAPELLA_FORK_VERSION:                         common.Version{0x03, 0x00, 0x00, 0x01},
+		CAPELLA_FORK_EPOCH:                           ^common.Epoch(0),
+		DENEB_FORK_VERSION:                           common.Version{0x04, 0x00, 0x00, 0x01},
+		DENEB_FORK_EPOCH:                             ^common.Epoch(0),
+		EIP6110_FORK_VERSION:                         common.Version{0x05, 0x00, 0x00, 0x01},
+		EIP6110_FORK_EPOCH:                           ^common.Epoch(0),
+		EIP7002_FORK_VERSION:                         common.Version{0x05, 0x00, 0x00, 0x01},
+		EIP7002_FORK_EPOCH:                           ^common.Epoch(0),
+		WHISK_FORK_VERSION:                           common.Version{0x06, 0x00, 0x00, 0x01},
+		WHISK_FORK_EPOCH:                             ^common.Epoch(0),
+		SECONDS_PER_SLOT:                             6,
+		SECONDS_PER_ETH1_BLOCK:                       14,
+		MIN_VALIDATOR_WITHDRAWABILITY_DELAY:          256,
+		SHARD_COMMITTEE_PERIOD:                       64,
+		ETH1_FOLLOW_DISTANCE:                         16,
+		INACTIVITY_SCORE_BIAS:                        4,
+		INACTIVITY_SCORE_RECOVERY_RATE:               16,
+		EJECTION_BALANCE:                             16_000_000_000,
+		MIN_PER_EPOCH_CHURN_LIMIT:                    2,
+		CHURN_LIMIT_QUOTIENT:                         32,
+		MAX_PER_EPOCH_ACTIVATION_CHURN_LIMIT:         4,
+		MIN_PER_EPOCH_CHURN_LIMIT_ELECTRA:            64_000_000_000,
+		MAX_PER_EPOCH_ACTIVATION_EXIT_CHURN_LIMIT:    128_000_000_000,
+		PROPOSER_SCORE_BOOST:                         40,
+		REORG_HEAD_WEIGHT_THRESHOLD:                  20,
+		REORG_PARENT_WEIGHT_THRESHOLD:                160,
+		REORG_MAX_EPOCHS_SINCE_FINALIZATION:          2,
+		DEPOSIT_CHAIN_ID:                             5,
+		DEPOSIT_NETWORK_ID:                           5,
+		DEPOSIT_CONTRACT_ADDRESS:                     [20]byte{0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90},
+		GOSSIP_MAX_SIZE:                              10 * (1 << 20),
+		MAX_REQUEST_BLOCKS:                           1024,
+		EPOCHS_PER_SUBNET_SUBSCRIPTION:               256,
+		MIN_EPOCHS_FOR_BLOCK_REQUESTS:                272,
+		MAX_CHUNK_SIZE:                               10485760,
+		TTFB_TIMEOUT:                                 5,
+		RESP_TIMEOUT:                                 10,
+		ATTESTATION_PROPAGATION_SLOT_RANGE:           32,
+		MAXIMUM_GOSSIP_CLOCK_DISPARITY:               500,
+		MESSAGE_DOMAIN_INVALID_SNAPPY:                common.NetworkMessageDomain{0, 0, 0, 0},
+		MESSAGE_DOMAIN_VALID_SNAPPY:                  common.NetworkMessageDomain{1, 0, 0, 0},
+		SUBNETS_PER_NODE:                             2,
+		ATTESTATION_SUBNET_COUNT:                     64,
+		ATTESTATION_SUBNET_EXTRA_BITS:                0,
+		ATTESTATION_SUBNET_PREFIX_BITS:               6,
+		MAX_REQUEST_BLOCKS_DENEB:                     128,
+		MAX_REQUEST_BLOB_SIDECARS:                    768,
+		MIN_EPOCHS_FOR_BLOB_SIDECARS_REQUESTS:        4096,
+		BLOB_SIDECAR_SUBNET_COUNT:                    6,
+		WHISK_EPOCHS_PER_SHUFFLING_PHASE:             4,
+		WHISK_PROPOSER_SELECTION_GAP:                 1,
+		EIP7594_FORK_VERSION:                         common.Version{6, 0, 0, 1},
+		EIP7594_FORK_EPOCH:                           ^common.Epoch(0),
+		NUMBER_OF_COLUMNS:                            128,
+		NUMBER_OF_CUSTODY_GROUPS:                     128,
+		DATA_COLUMN_SIDECAR_SUBNET_COUNT:             128,
+		MAX_REQUEST_DATA_COLUMN_SIDECARS:             16384,
+		SAMPLES_PER_SLOT:                             8,
+		CUSTODY_REQUIREMENT:                          4,
+		MIN_EPOCHS_FOR_DATA_COLUMN_SIDECARS_REQUESTS: 4096,
 	},
 	ExecutionEngine: nil,
 }
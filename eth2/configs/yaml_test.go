@@ -138,3 +138,54 @@ func TestYamlDecodingMinimalDeneb(t *testing.T) {
 		t.Fatal("Failed to load minimal deneb preset")
 	}
 }
+
+func TestYamlDecodingMainnetElectra(t *testing.T) {
+	var conf common.ElectraPreset
+	if err := yaml.Unmarshal(mustLoad("presets", "mainnet", "electra"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, Mainnet.ElectraPreset) {
+		t.Fatal("Failed to load mainnet electra preset")
+	}
+}
+
+func TestYamlDecodingMinimalElectra(t *testing.T) {
+	var conf common.ElectraPreset
+	if err := yaml.Unmarshal(mustLoad("presets", "minimal", "electra"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, Minimal.ElectraPreset) {
+		t.Fatal("Failed to load minimal electra preset")
+	}
+}
+
+func TestYamlDecodingMainnetEIP7594(t *testing.T) {
+	var conf common.EIP7594Preset
+	if err := yaml.Unmarshal(mustLoad("presets", "mainnet", "eip7594"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, Mainnet.EIP7594Preset) {
+		t.Fatal("Failed to load mainnet eip7594 preset")
+	}
+}
+
+func TestYamlDecodingMinimalEIP7594(t *testing.T) {
+	var conf common.EIP7594Preset
+	if err := yaml.Unmarshal(mustLoad("presets", "minimal", "eip7594"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, Minimal.EIP7594Preset) {
+		t.Fatal("Failed to load minimal eip7594 preset")
+	}
+}
+
+func TestElectraPresetsComplete(t *testing.T) {
+	for name, spec := range map[string]*common.Spec{"mainnet": Mainnet, "minimal": Minimal} {
+		preset := reflect.ValueOf(spec.ElectraPreset)
+		for i := 0; i < preset.NumField(); i++ {
+			if preset.Field(i).IsZero() {
+				t.Errorf("%s electra preset does not set %s", name, preset.Type().Field(i).Name)
+			}
+		}
+	}
+}
@@ -11,8 +11,8 @@ import (
 
 type NoOpExecutionEngine struct{}
 
-func (n NoOpExecutionEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (valid bool, err error) {
-	return true, nil
+func (n NoOpExecutionEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (status common.PayloadStatus, err error) {
+	return common.PayloadValid, nil
 }
 
 func (n NoOpExecutionEngine) DenebIsValidVersionedHashes(ctx context.Context, payload *deneb.ExecutionPayload, versionedHashes []common.Hash32) (bool, error) {
@@ -23,16 +23,16 @@ func (n NoOpExecutionEngine) DenebIsValidBlockHash(ctx context.Context, payload
 	return true, nil
 }
 
-func (n NoOpExecutionEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (valid bool, err error) {
-	return true, nil
+func (n NoOpExecutionEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (status common.PayloadStatus, err error) {
+	return common.PayloadValid, nil
 }
 
 func (n NoOpExecutionEngine) CapellaIsValidBlockHash(ctx context.Context, payload *capella.ExecutionPayload) (bool, error) {
 	return true, nil
 }
 
-func (n NoOpExecutionEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (valid bool, err error) {
-	return true, nil
+func (n NoOpExecutionEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (status common.PayloadStatus, err error) {
+	return common.PayloadValid, nil
 }
 
 func (n NoOpExecutionEngine) BellatrixIsValidBlockHash(ctx context.Context, payload *bellatrix.ExecutionPayload) (bool, error) {
@@ -0,0 +1,193 @@
+package forkchoice
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ForkchoiceEvent is one of: *HeadEvent, *ReorgEvent, *JustifiedEvent, *FinalizedEvent, *InvalidatedEvent
+type ForkchoiceEvent interface {
+	String() string
+}
+
+// HeadEvent is emitted when the head changes, after a block or vote is processed, or when Forkchoice.Head is called.
+type HeadEvent struct {
+	// Old head, zeroed if this is the first head that is computed.
+	Old NodeRef
+	New NodeRef
+}
+
+func (ev *HeadEvent) String() string {
+	return fmt.Sprintf("head changed from %s to %s", ev.Old, ev.New)
+}
+
+// ReorgEvent is emitted, after the HeadEvent, when the new head does not descend from the old head.
+type ReorgEvent struct {
+	// Depth is the number of slots between the common ancestor and the old head.
+	Depth uint64
+	// CommonAncestor is the latest block that both the old and new head descend from.
+	CommonAncestor NodeRef
+	OldHead        NodeRef
+	NewHead        NodeRef
+}
+
+func (ev *ReorgEvent) String() string {
+	return fmt.Sprintf("reorg of depth %d from %s to %s, common ancestor %s",
+		ev.Depth, ev.OldHead, ev.NewHead, ev.CommonAncestor)
+}
+
+// JustifiedEvent is emitted when the justified checkpoint changes.
+type JustifiedEvent struct {
+	Old Checkpoint
+	New Checkpoint
+}
+
+func (ev *JustifiedEvent) String() string {
+	return fmt.Sprintf("justified checkpoint changed from %s to %s", ev.Old.String(), ev.New.String())
+}
+
+// FinalizedEvent is emitted when the finalized checkpoint changes.
+type FinalizedEvent struct {
+	Old Checkpoint
+	New Checkpoint
+}
+
+func (ev *FinalizedEvent) String() string {
+	return fmt.Sprintf("finalized checkpoint changed from %s to %s", ev.Old.String(), ev.New.String())
+}
+
+// InvalidatedEvent is emitted when the payload of a block is marked as invalid.
+// All descendants of the block are invalid as well.
+type InvalidatedEvent struct {
+	BlockRoot Root
+	// As passed to SetPayloadInvalid, may be nil.
+	LatestValidHash *Root
+}
+
+func (ev *InvalidatedEvent) String() string {
+	return fmt.Sprintf("payload of block %s marked invalid", ev.BlockRoot)
+}
+
+// eventFeed queues events while the forkchoice is locked, and delivers them to subscribers after unlocking,
+// so that subscribers can safely call back into the forkchoice.
+type eventFeed struct {
+	mu      sync.Mutex
+	pending []ForkchoiceEvent
+	subs    map[uint64]func(ev ForkchoiceEvent)
+	nextID  uint64
+	// held while delivering, to keep the order of events the same for all subscribers.
+	deliverMu sync.Mutex
+}
+
+func (f *eventFeed) queue(ev ForkchoiceEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.subs) == 0 {
+		return
+	}
+	f.pending = append(f.pending, ev)
+}
+
+func (f *eventFeed) subscribed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs) > 0
+}
+
+func (f *eventFeed) subscribe(fn func(ev ForkchoiceEvent)) (unsubscribe func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[uint64]func(ev ForkchoiceEvent))
+	}
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = fn
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	}
+}
+
+func (f *eventFeed) flush() {
+	f.deliverMu.Lock()
+	defer f.deliverMu.Unlock()
+	f.mu.Lock()
+	pending := f.pending
+	f.pending = nil
+	subs := make([]func(ev ForkchoiceEvent), 0, len(f.subs))
+	for _, fn := range f.subs {
+		subs = append(subs, fn)
+	}
+	f.mu.Unlock()
+	for _, ev := range pending {
+		for _, fn := range subs {
+			fn(ev)
+		}
+	}
+}
+
+// Subscribe registers a handler for forkchoice events, see ForkchoiceEvent.
+// Handlers are called synchronously, after the forkchoice change, by the goroutine that made the change.
+// Handlers may call the forkchoice, but should not block for long.
+// While there are subscribers, every processed block and vote recomputes the head, to detect head changes.
+func (fc *ProtoForkChoice) Subscribe(fn func(ev ForkchoiceEvent)) (unsubscribe func()) {
+	return fc.events.subscribe(fn)
+}
+
+// SubscribeChan is like Subscribe, but sends the events to the channel.
+// Sending does not block the forkchoice: an event is dropped if the channel buffer is full.
+// The returned dropped function counts the dropped events, for the subscriber to detect
+// that it missed events, e.g. to re-read the head and checkpoints of the forkchoice.
+func (fc *ProtoForkChoice) SubscribeChan(ch chan<- ForkchoiceEvent) (unsubscribe func(), dropped func() uint64) {
+	var mu sync.Mutex
+	var count uint64
+	unsubscribe = fc.Subscribe(func(ev ForkchoiceEvent) {
+		select {
+		case ch <- ev:
+		default:
+			mu.Lock()
+			count++
+			mu.Unlock()
+		}
+	})
+	return unsubscribe, func() uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+// onHead queues the head change, and a reorg if the new head does not descend from the previous head.
+// Must be called with the forkchoice lock held.
+func (fc *ProtoForkChoice) onHead(head NodeRef) {
+	prev := fc.head
+	if prev != nil && *prev == head {
+		return
+	}
+	fc.head = &head
+	ev := &HeadEvent{New: head}
+	if prev != nil {
+		ev.Old = *prev
+	}
+	fc.events.queue(ev)
+	if prev == nil {
+		return
+	}
+	// the previous head may have been pruned, in which case we cannot tell the reorg depth.
+	ancestor, err := fc.protoArray.CommonAncestor(*prev, head)
+	if err != nil || ancestor == *prev {
+		return
+	}
+	// Report the block node, not the empty slot node, the chains may have forked out of.
+	if slot, ok := fc.protoArray.GetSlot(ancestor.Root); ok {
+		ancestor.Slot = slot
+	}
+	fc.events.queue(&ReorgEvent{
+		Depth:          uint64(prev.Slot - ancestor.Slot),
+		CommonAncestor: ancestor,
+		OldHead:        *prev,
+		NewHead:        head,
+	})
+}
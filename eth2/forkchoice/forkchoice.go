@@ -20,6 +20,10 @@ type ProtoForkChoice struct {
 	justified Checkpoint
 	finalized Checkpoint
 	spec      *common.Spec
+
+	// Last head computed by Head(), to detect head changes and reorgs.
+	head   *NodeRef
+	events eventFeed
 }
 
 var _ Forkchoice = (*ProtoForkChoice)(nil)
@@ -46,6 +50,56 @@ func NewForkChoice(spec *common.Spec, finalized Checkpoint, justified Checkpoint
 	return fc, nil
 }
 
+// ForkChoiceStore is the full internal state of a ProtoForkChoice, used to snapshot and restore it.
+type ForkChoiceStore struct {
+	Graph     ForkchoiceGraph
+	Votes     VoteStore
+	Balances  []Gwei
+	Pin       *NodeRef
+	Justified Checkpoint
+	Finalized Checkpoint
+}
+
+// RestoreForkChoice creates a forkchoice from a previously captured store.
+// Unlike NewForkChoice, the vote weights are not recomputed: the graph and votes are expected to be consistent already.
+func RestoreForkChoice(spec *common.Spec, store *ForkChoiceStore) (Forkchoice, error) {
+	if store.Justified.Epoch < store.Finalized.Epoch {
+		return nil, fmt.Errorf("justified epoch %d lower than finalized epoch %d", store.Justified.Epoch, store.Finalized.Epoch)
+	}
+	if unknown, _ := store.Graph.InSubtree(store.Finalized.Root, store.Justified.Root); unknown {
+		return nil, fmt.Errorf("unknown justified checkpoint %s or finalized checkpoint %s", store.Justified, store.Finalized)
+	}
+	fc := &ProtoForkChoice{
+		protoArray: store.Graph,
+		voteStore:  store.Votes,
+		balances:   store.Balances,
+		justified:  store.Justified,
+		finalized:  store.Finalized,
+		spec:       spec,
+	}
+	if store.Pin != nil {
+		if err := fc.SetPin(store.Pin.Root, store.Pin.Slot); err != nil {
+			return nil, err
+		}
+	}
+	return fc, nil
+}
+
+// ReadStore calls fn with the internal state of the forkchoice, while holding the lock.
+// The store, and its contents, must not be retained or modified after fn returns.
+func (fc *ProtoForkChoice) ReadStore(fn func(store *ForkChoiceStore) error) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fn(&ForkChoiceStore{
+		Graph:     fc.protoArray,
+		Votes:     fc.voteStore,
+		Balances:  fc.balances,
+		Pin:       fc.pin,
+		Justified: fc.justified,
+		Finalized: fc.finalized,
+	})
+}
+
 func (fc *ProtoForkChoice) Pin() *NodeRef {
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
@@ -75,6 +129,7 @@ func (fc *ProtoForkChoice) SetPin(root Root, slot Slot) error {
 // The justification/finalization trigger must be within the pinned subtree (if any).
 func (fc *ProtoForkChoice) UpdateJustified(ctx context.Context, trigger Root, justified Checkpoint, finalized Checkpoint,
 	justifiedStateBalances func() ([]Gwei, error)) error {
+	defer fc.events.flush()
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 	// Old/same data? Ignore the change.
@@ -83,18 +138,25 @@ func (fc *ProtoForkChoice) UpdateJustified(ctx context.Context, trigger Root, ju
 	}
 	if fc.pin != nil && trigger != fc.pin.Root {
 		// check trigger against pin, to ensure no justification/finalization of data that conflicts with the pin.
-		if unknown, inSubtree := fc.InSubtree(fc.pin.Root, trigger); unknown {
+		if unknown, inSubtree := fc.protoArray.InSubtree(fc.pin.Root, trigger); unknown {
 			return fmt.Errorf("cannot justify/finalize with unknown trigger when forkchoice is pinned")
 		} else if !inSubtree {
 			return fmt.Errorf("cannot justify/finalize outside of pinned forkchoice tree")
 		}
 	}
 
+	prevJustified := fc.justified
 	prevFinalized := fc.finalized
 
-	if err := fc.updateJustified(justified, finalized, justifiedStateBalances); err != nil {
+	if err := fc.updateJustified(finalized, justified, justifiedStateBalances); err != nil {
 		return err
 	}
+	if prevJustified != justified {
+		fc.events.queue(&JustifiedEvent{Old: prevJustified, New: justified})
+	}
+	if prevFinalized != finalized {
+		fc.events.queue(&FinalizedEvent{Old: prevFinalized, New: finalized})
+	}
 
 	// prune if we finalized something, and undo the pin.
 	if prevFinalized != finalized {
@@ -115,7 +177,7 @@ func (fc *ProtoForkChoice) updateJustified(finalized Checkpoint, justified Check
 
 	// check if new finalized checkpoint is valid
 	if fc.finalized != finalized {
-		if unknown, inSubtree := fc.InSubtree(fc.finalized.Root, finalized.Root); unknown {
+		if unknown, inSubtree := fc.protoArray.InSubtree(fc.finalized.Root, finalized.Root); unknown {
 			return fmt.Errorf("unknown finalized checkpoint: %s", finalized)
 		} else if !inSubtree || fc.finalized.Epoch > finalized.Epoch {
 			return fmt.Errorf("new finalized checkpoint %s is outside of finalized subtree: %s",
@@ -123,7 +185,7 @@ func (fc *ProtoForkChoice) updateJustified(finalized Checkpoint, justified Check
 		}
 	}
 	if fc.justified != justified {
-		if unknown, inSubtree := fc.InSubtree(fc.finalized.Root, justified.Root); unknown {
+		if unknown, inSubtree := fc.protoArray.InSubtree(fc.finalized.Root, justified.Root); unknown {
 			return fmt.Errorf("unknown justified checkpoint: %s", justified)
 		} else if !inSubtree || fc.finalized.Epoch > justified.Epoch {
 			return fmt.Errorf("new justified checkpoint %s is outside of finalized subtree: %s",
@@ -176,14 +238,20 @@ func (fc *ProtoForkChoice) Finalized() Checkpoint {
 }
 
 func (fc *ProtoForkChoice) ProcessAttestation(index ValidatorIndex, blockRoot Root, headSlot Slot) (ok bool) {
+	defer fc.events.flush()
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 	// only add the vote if we can. Don't add if it's not within view.
+	// The head slot may be a gap slot after the block, but not before it.
 	blockSlot, ok := fc.protoArray.GetSlot(blockRoot)
-	if !ok || blockSlot < headSlot {
+	if !ok || blockSlot > headSlot {
 		return false
 	}
-	return fc.voteStore.ProcessAttestation(index, blockRoot, headSlot)
+	if !fc.voteStore.ProcessAttestation(index, blockRoot, headSlot) {
+		return false
+	}
+	fc.updateHead()
+	return true
 }
 
 func (fc *ProtoForkChoice) CanonicalChain(anchorRoot Root, anchorSlot Slot) ([]ExtendedNodeRef, error) {
@@ -199,9 +267,55 @@ func (fc *ProtoForkChoice) ProcessSlot(parentRoot Root, slot Slot, justifiedEpoc
 }
 
 func (fc *ProtoForkChoice) ProcessBlock(parentRoot Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch) (ok bool) {
+	defer fc.events.flush()
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
-	return fc.protoArray.ProcessBlock(parentRoot, blockRoot, blockSlot, justifiedEpoch, finalizedEpoch)
+	if !fc.protoArray.ProcessBlock(parentRoot, blockRoot, blockSlot, justifiedEpoch, finalizedEpoch) {
+		return false
+	}
+	fc.updateHead()
+	return true
+}
+
+func (fc *ProtoForkChoice) ProcessBlockWithPayload(parentRoot Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch,
+	executionBlockHash Root, status ExecutionStatus) (ok bool) {
+	defer fc.events.flush()
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if !fc.protoArray.ProcessBlockWithPayload(parentRoot, blockRoot, blockSlot, justifiedEpoch, finalizedEpoch, executionBlockHash, status) {
+		return false
+	}
+	fc.updateHead()
+	return true
+}
+
+func (fc *ProtoForkChoice) SetPayloadValid(blockRoot Root) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.protoArray.SetPayloadValid(blockRoot)
+}
+
+func (fc *ProtoForkChoice) SetPayloadInvalid(blockRoot Root, latestValidHash *Root) error {
+	defer fc.events.flush()
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if err := fc.protoArray.SetPayloadInvalid(blockRoot, latestValidHash); err != nil {
+		return err
+	}
+	fc.events.queue(&InvalidatedEvent{BlockRoot: blockRoot, LatestValidHash: latestValidHash})
+	return nil
+}
+
+func (fc *ProtoForkChoice) ExecutionStatus(blockRoot Root) (status ExecutionStatus, ok bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.protoArray.ExecutionStatus(blockRoot)
+}
+
+func (fc *ProtoForkChoice) IsOptimistic(blockRoot Root) (optimistic bool, ok bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.protoArray.IsOptimistic(blockRoot)
 }
 
 func (fc *ProtoForkChoice) InSubtree(anchor Root, root Root) (unknown bool, inSubtree bool) {
@@ -216,6 +330,12 @@ func (fc *ProtoForkChoice) Search(anchor NodeRef, parentRoot *Root, slot *Slot)
 	return fc.protoArray.Search(anchor, parentRoot, slot)
 }
 
+func (fc *ProtoForkChoice) CommonAncestor(a NodeRef, b NodeRef) (ancestor NodeRef, err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.protoArray.CommonAncestor(a, b)
+}
+
 func (fc *ProtoForkChoice) ClosestToSlot(anchor Root, slot Slot) (ref NodeRef, err error) {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
@@ -244,8 +364,26 @@ func (fc *ProtoForkChoice) FindHead(anchorRoot Root, anchorSlot Slot) (NodeRef,
 }
 
 func (fc *ProtoForkChoice) Head() (NodeRef, error) {
+	defer fc.events.flush()
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
+	return fc.computeHead()
+}
+
+// updateHead recomputes the head after a block or vote is processed, to emit the head and reorg events.
+// Without subscribers the head is only computed when requested, to not recompute the weights for every vote.
+// Errors are left to the next Head call to report.
+// Must be called with the forkchoice lock held.
+func (fc *ProtoForkChoice) updateHead() {
+	if !fc.events.subscribed() {
+		return
+	}
+	_, _ = fc.computeHead()
+}
+
+// computeHead finds the head from the pin, or else the justified checkpoint, and queues the head change.
+// Must be called with the forkchoice lock held.
+func (fc *ProtoForkChoice) computeHead() (NodeRef, error) {
 	if err := fc.updateVotesMaybe(); err != nil {
 		return NodeRef{}, err
 	}
@@ -255,5 +393,10 @@ func (fc *ProtoForkChoice) Head() (NodeRef, error) {
 		root = fc.pin.Root
 		slot = fc.pin.Slot
 	}
-	return fc.protoArray.FindHead(root, slot)
+	head, err := fc.protoArray.FindHead(root, slot)
+	if err != nil {
+		return NodeRef{}, err
+	}
+	fc.onHead(head)
+	return head, nil
 }
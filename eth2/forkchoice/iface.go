@@ -2,6 +2,7 @@ package forkchoice
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 )
@@ -25,6 +26,8 @@ type ForkchoiceView interface {
 	FindHead(anchorRoot Root, anchorSlot Slot) (NodeRef, error)
 	InSubtree(anchor Root, root Root) (unknown bool, inSubtree bool)
 	Search(anchor NodeRef, parentRoot *Root, slot *Slot) (nonCanon []NodeRef, canon []NodeRef, err error)
+	// CommonAncestor finds the latest node that both a and b descend from (or are equal to).
+	CommonAncestor(a NodeRef, b NodeRef) (ancestor NodeRef, err error)
 }
 
 type ForkchoiceNodeInput interface {
@@ -32,9 +35,70 @@ type ForkchoiceNodeInput interface {
 	ProcessBlock(parent Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch) (ok bool)
 }
 
+// ExecutionStatus is the status of the execution payload of a node in the forkchoice.
+type ExecutionStatus uint8
+
+const (
+	// ExecutionValid: the payload is validated, or the block has no payload (pre-merge).
+	ExecutionValid ExecutionStatus = iota
+	// ExecutionOptimistic: the payload is not validated yet, the block was imported optimistically.
+	ExecutionOptimistic
+	// ExecutionInvalid: the payload, or one of its ancestors, is invalid. The node cannot be part of the head chain.
+	ExecutionInvalid
+)
+
+func (s ExecutionStatus) String() string {
+	switch s {
+	case ExecutionValid:
+		return "VALID"
+	case ExecutionOptimistic:
+		return "OPTIMISTIC"
+	case ExecutionInvalid:
+		return "INVALID"
+	default:
+		return fmt.Sprintf("ExecutionStatus(%d)", uint8(s))
+	}
+}
+
+// ExecutionStatusFromPayload converts the payload status of the execution engine into the forkchoice status.
+func ExecutionStatusFromPayload(status common.PayloadStatus) ExecutionStatus {
+	switch {
+	case status == common.PayloadValid:
+		return ExecutionValid
+	case status.IsInvalid():
+		return ExecutionInvalid
+	default:
+		// a payload that is not validated, or of which the status is unknown, is not assumed to be valid
+		return ExecutionOptimistic
+	}
+}
+
+type ForkchoiceExecutionView interface {
+	// ExecutionStatus returns the execution status of the given block.
+	ExecutionStatus(blockRoot Root) (status ExecutionStatus, ok bool)
+	// IsOptimistic returns true if the payload of the given block is not validated yet.
+	IsOptimistic(blockRoot Root) (optimistic bool, ok bool)
+}
+
+type ForkchoiceExecutionInput interface {
+	// ProcessBlockWithPayload is like ProcessBlock, but also tracks the execution block hash and status of the payload.
+	// Blocks without payload (pre-merge) have a zeroed block hash, and are valid.
+	ProcessBlockWithPayload(parent Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch,
+		executionBlockHash Root, status ExecutionStatus) (ok bool)
+	// SetPayloadValid marks the payload of the block, and those of all its ancestors, as valid.
+	SetPayloadValid(blockRoot Root) error
+	// SetPayloadInvalid marks the payload of the block as invalid, and all its descendants.
+	// If the latestValidHash is not nil, the ancestors after the payload with the latestValidHash are invalidated too,
+	// and the payload with the latestValidHash is marked as valid.
+	// A zero latestValidHash invalidates all ancestors after the merge.
+	SetPayloadInvalid(blockRoot Root, latestValidHash *Root) error
+}
+
 type ForkchoiceGraph interface {
 	ForkchoiceView
 	ForkchoiceNodeInput
+	ForkchoiceExecutionView
+	ForkchoiceExecutionInput
 	Indices() map[NodeRef]NodeIndex
 	ApplyScoreChanges(deltas []SignedGwei, justifiedEpoch Epoch, finalizedEpoch Epoch) error
 	OnPrune(ctx context.Context, anchorRoot Root, anchorSlot Slot) error
@@ -56,6 +120,8 @@ type VoteStore interface {
 type Forkchoice interface {
 	ForkchoiceView
 	ForkchoiceNodeInput
+	ForkchoiceExecutionView
+	ForkchoiceExecutionInput
 	VoteInput
 	UpdateJustified(ctx context.Context, trigger Root, justified Checkpoint, finalized Checkpoint,
 		justifiedStateBalances func() ([]Gwei, error)) error
@@ -64,4 +130,6 @@ type Forkchoice interface {
 	Justified() Checkpoint
 	Finalized() Checkpoint
 	Head() (NodeRef, error)
+	// Subscribe registers a handler for forkchoice events, see ForkchoiceEvent.
+	Subscribe(fn func(ev ForkchoiceEvent)) (unsubscribe func())
 }
@@ -0,0 +1,28 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+)
+
+func TestProcessAttestationHeadSlot(t *testing.T) {
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a := Root{0x0a}
+	fc, err := NewProtoForkChoice(configs.Minimal, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10, 10, 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.ProcessBlock(genesis.Root, a, 2, 0, 0)
+	if !fc.ProcessAttestation(0, a, 2) {
+		t.Fatal("expected vote for the block at its own slot to be accepted")
+	}
+	if !fc.ProcessAttestation(1, a, 3) {
+		t.Fatal("expected vote for the block at a later gap slot to be accepted")
+	}
+	if fc.ProcessAttestation(2, a, 1) {
+		t.Fatal("expected vote for the block at a slot before the block to be rejected")
+	}
+}
@@ -0,0 +1,110 @@
+package proto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+)
+
+func TestForkChoiceEvents(t *testing.T) {
+	spec := configs.Minimal
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a, b, c := Root{0x0a}, Root{0x0b}, Root{0x0c}
+	fc, err := NewProtoForkChoice(spec, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10, 10, 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []ForkchoiceEvent
+	unsubscribe := fc.Subscribe(func(ev ForkchoiceEvent) {
+		events = append(events, ev)
+	})
+	expectEvents := func(expected ...ForkchoiceEvent) {
+		t.Helper()
+		if len(events) != len(expected) {
+			t.Fatalf("expected %d events, got %d: %v", len(expected), len(events), events)
+		}
+		for i, ev := range events {
+			if ev.String() != expected[i].String() {
+				t.Fatalf("event %d: expected %q, got %q", i, expected[i], ev)
+			}
+		}
+		events = nil
+	}
+	head := func() NodeRef {
+		t.Helper()
+		h, err := fc.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	// processing a block moves the head, without polling the head
+	headA := NodeRef{Root: a, Slot: 1}
+	fc.ProcessBlock(genesis.Root, a, 1, 0, 0)
+	expectEvents(&HeadEvent{New: headA})
+	headB := NodeRef{Root: b, Slot: 2}
+	fc.ProcessBlockWithPayload(a, b, 2, 0, 0, Root{0xbb}, ExecutionOptimistic)
+	expectEvents(&HeadEvent{Old: headA, New: headB})
+	fc.ProcessAttestation(0, b, 2)
+	expectEvents()
+	if h := head(); h != headB {
+		t.Fatalf("expected head %s, got %s", headB, h)
+	}
+	expectEvents()
+
+	// competing block, the votes for it move the head
+	headC := NodeRef{Root: c, Slot: 3}
+	fc.ProcessBlock(a, c, 3, 0, 0)
+	expectEvents()
+	fc.ProcessAttestation(1, c, 3)
+	fc.ProcessAttestation(2, c, 3)
+	expectEvents(&HeadEvent{Old: headB, New: headC},
+		&ReorgEvent{Depth: 1, CommonAncestor: headA, OldHead: headB, NewHead: headC})
+
+	if err := fc.SetPayloadInvalid(b, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(&InvalidatedEvent{BlockRoot: b})
+
+	justified := Checkpoint{Root: a, Epoch: 1}
+	if err := fc.UpdateJustified(context.Background(), a, justified, genesis, func() ([]Gwei, error) {
+		return []Gwei{10, 10, 10}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents(&JustifiedEvent{Old: genesis, New: justified})
+
+	unsubscribe()
+	d := Root{0x0d}
+	fc.ProcessBlockWithPayload(c, d, 4, 1, 0, Root{0xdd}, ExecutionOptimistic)
+	if err := fc.SetPayloadInvalid(d, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectEvents()
+}
+
+func TestForkChoiceEventsChanDropped(t *testing.T) {
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a, b := Root{0x0a}, Root{0x0b}
+	fc, err := NewProtoForkChoice(configs.Minimal, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan ForkchoiceEvent, 1)
+	unsubscribe, dropped := fc.(*ProtoForkChoice).SubscribeChan(ch)
+	defer unsubscribe()
+	fc.ProcessBlock(genesis.Root, a, 1, 0, 0)
+	fc.ProcessBlock(a, b, 2, 0, 0)
+	if n := dropped(); n != 1 {
+		t.Fatalf("expected 1 dropped event, got %d", n)
+	}
+	ev := <-ch
+	if expected := (&HeadEvent{New: NodeRef{Root: a, Slot: 1}}).String(); ev.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, ev)
+	}
+}
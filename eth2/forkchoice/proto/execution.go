@@ -0,0 +1,135 @@
+package proto
+
+import (
+	"fmt"
+
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+)
+
+// blockNodeIndex returns the index of the first known node of the given block root:
+// the block node itself, or the first slot node after it if the block node was pruned.
+func (pr *ProtoArray) blockNodeIndex(blockRoot Root) (NodeIndex, error) {
+	slot, ok := pr.blockSlots[blockRoot]
+	if !ok {
+		return NONE, fmt.Errorf("unknown block %s", blockRoot)
+	}
+	index, ok := pr.indices[NodeRef{Root: blockRoot, Slot: slot}]
+	if !ok {
+		return NONE, fmt.Errorf("missing node for block %s at slot %d", blockRoot, slot)
+	}
+	return index, nil
+}
+
+// setStatus changes the execution status of all nodes of the given block roots.
+func (pr *ProtoArray) setStatus(roots map[Root]struct{}, status ExecutionStatus) {
+	if len(roots) == 0 {
+		return
+	}
+	for i := range pr.nodes {
+		node := &pr.nodes[i]
+		if _, ok := roots[node.Ref.Root]; ok {
+			node.ExecutionStatus = status
+		}
+	}
+	pr.updatedConnections = false
+}
+
+// setAncestorsValid marks the node at the index, and all its ancestors, as valid.
+// It stops at the first valid ancestor, since the ancestors of a valid node are valid already.
+func (pr *ProtoArray) setAncestorsValid(index NodeIndex) error {
+	roots := make(map[Root]struct{})
+	for index != NONE && index >= pr.indexOffset {
+		node := &pr.nodes[index-pr.indexOffset]
+		if node.ExecutionStatus == ExecutionValid {
+			break
+		}
+		if node.ExecutionStatus == ExecutionInvalid {
+			return fmt.Errorf("block %s has an invalid payload, but is the ancestor of a valid payload", node.Ref.Root)
+		}
+		roots[node.Ref.Root] = struct{}{}
+		index = node.TransitionParent
+	}
+	pr.setStatus(roots, ExecutionValid)
+	return nil
+}
+
+// SetPayloadValid marks the payload of the block, and those of all its ancestors, as valid.
+func (pr *ProtoArray) SetPayloadValid(blockRoot Root) error {
+	index, err := pr.blockNodeIndex(blockRoot)
+	if err != nil {
+		return err
+	}
+	return pr.setAncestorsValid(index)
+}
+
+// SetPayloadInvalid marks the payload of the block as invalid, and all its descendants.
+// If the latestValidHash is not nil, the ancestors after the payload with the latestValidHash are invalidated too,
+// and the payload with the latestValidHash is marked as valid.
+// A zero latestValidHash invalidates all ancestors after the merge.
+// If the latestValidHash is not found within the optimistic ancestors, only the block itself is invalidated.
+func (pr *ProtoArray) SetPayloadInvalid(blockRoot Root, latestValidHash *Root) error {
+	index, err := pr.blockNodeIndex(blockRoot)
+	if err != nil {
+		return err
+	}
+	node := &pr.nodes[index-pr.indexOffset]
+	if node.ExecutionStatus == ExecutionValid {
+		return fmt.Errorf("cannot invalidate block %s, its payload is valid", blockRoot)
+	}
+	invalid := map[Root]struct{}{blockRoot: {}}
+	if latestValidHash != nil && node.ExecutionBlockHash != *latestValidHash {
+		candidates := make(map[Root]struct{})
+		found := NONE
+		for i := node.TransitionParent; i != NONE && i >= pr.indexOffset; {
+			n := &pr.nodes[i-pr.indexOffset]
+			if n.Ref.Root != blockRoot {
+				if n.ExecutionBlockHash == *latestValidHash {
+					found = i
+					break
+				}
+				if n.ExecutionStatus == ExecutionValid {
+					break
+				}
+				candidates[n.Ref.Root] = struct{}{}
+			}
+			i = n.TransitionParent
+		}
+		if found != NONE {
+			for root := range candidates {
+				invalid[root] = struct{}{}
+			}
+			if err := pr.setAncestorsValid(found); err != nil {
+				return err
+			}
+		}
+	}
+	// Nodes are ordered: parents always come before their children.
+	// So a single pass is enough to invalidate all descendants.
+	for i := range pr.nodes {
+		n := &pr.nodes[i]
+		if _, ok := invalid[n.Ref.Root]; ok {
+			n.ExecutionStatus = ExecutionInvalid
+			continue
+		}
+		if p := n.TransitionParent; p != NONE && p >= pr.indexOffset && pr.nodes[p-pr.indexOffset].ExecutionStatus == ExecutionInvalid {
+			n.ExecutionStatus = ExecutionInvalid
+		}
+	}
+	pr.updatedConnections = false
+	return nil
+}
+
+// ExecutionStatus returns the execution status of the given block.
+func (pr *ProtoArray) ExecutionStatus(blockRoot Root) (status ExecutionStatus, ok bool) {
+	index, err := pr.blockNodeIndex(blockRoot)
+	if err != nil {
+		return ExecutionValid, false
+	}
+	return pr.nodes[index-pr.indexOffset].ExecutionStatus, true
+}
+
+// IsOptimistic returns true if the payload of the given block is not validated yet.
+func (pr *ProtoArray) IsOptimistic(blockRoot Root) (optimistic bool, ok bool) {
+	status, ok := pr.ExecutionStatus(blockRoot)
+	return status == ExecutionOptimistic, ok
+}
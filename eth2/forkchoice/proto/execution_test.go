@@ -0,0 +1,82 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+)
+
+func TestOptimisticInvalidation(t *testing.T) {
+	genesis := Root{0x01}
+	a, b, c, d, e := Root{0x0a}, Root{0x0b}, Root{0x0c}, Root{0x0d}, Root{0x0e}
+	pr := NewProtoArray(Root{}, genesis, 0, 0, 0, nil)
+	for _, blk := range []struct {
+		parent, root Root
+		slot         Slot
+	}{{genesis, a, 1}, {a, b, 2}, {b, c, 3}, {a, d, 3}} {
+		// use the block root as execution block hash
+		if !pr.ProcessBlockWithPayload(blk.parent, blk.root, blk.slot, 0, 0, blk.root, ExecutionOptimistic) {
+			t.Fatalf("failed to add block %s", blk.root)
+		}
+	}
+	if opt, ok := pr.IsOptimistic(c); !ok || !opt {
+		t.Fatal("expected optimistic block")
+	}
+	if opt, ok := pr.IsOptimistic(genesis); !ok || opt {
+		t.Fatal("expected valid genesis")
+	}
+
+	// c and its ancestors after a are invalid, a is valid.
+	if err := pr.SetPayloadInvalid(c, &a); err != nil {
+		t.Fatal(err)
+	}
+	for root, expected := range map[Root]ExecutionStatus{
+		genesis: ExecutionValid, a: ExecutionValid, b: ExecutionInvalid, c: ExecutionInvalid, d: ExecutionOptimistic,
+	} {
+		if status, ok := pr.ExecutionStatus(root); !ok || status != expected {
+			t.Errorf("block %s: expected status %s, got %s", root, expected, status)
+		}
+	}
+	head, err := pr.FindHead(genesis, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Root != d {
+		t.Fatalf("expected head %s, got %s", d, head.Root)
+	}
+
+	// descendants of invalid blocks are invalid too
+	if !pr.ProcessBlockWithPayload(c, e, 4, 0, 0, e, ExecutionOptimistic) {
+		t.Fatal("failed to add block")
+	}
+	if status, _ := pr.ExecutionStatus(e); status != ExecutionInvalid {
+		t.Fatalf("expected invalid descendant, got %s", status)
+	}
+	if err := pr.SetPayloadValid(e); err == nil {
+		t.Fatal("expected error when validating invalid payload")
+	}
+
+	if err := pr.SetPayloadValid(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := pr.SetPayloadInvalid(d, nil); err == nil {
+		t.Fatal("expected error when invalidating valid payload")
+	}
+}
+
+func TestExecutionStatusFromPayload(t *testing.T) {
+	for status, expected := range map[common.PayloadStatus]ExecutionStatus{
+		common.PayloadUnknown:          ExecutionOptimistic,
+		common.PayloadValid:            ExecutionValid,
+		common.PayloadSyncing:          ExecutionOptimistic,
+		common.PayloadAccepted:         ExecutionOptimistic,
+		common.PayloadInvalid:          ExecutionInvalid,
+		common.PayloadInvalidBlockHash: ExecutionInvalid,
+	} {
+		if got := ExecutionStatusFromPayload(status); got != expected {
+			t.Errorf("payload status %s: expected %s, got %s", status, expected, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package proto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+)
+
+func TestUpdateJustifiedCheckpoints(t *testing.T) {
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a, b := Root{0x0a}, Root{0x0b}
+	fc, err := NewProtoForkChoice(configs.Minimal, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10, 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.ProcessBlock(genesis.Root, a, 8, 0, 0)
+	fc.ProcessBlock(a, b, 16, 2, 1)
+
+	// a justified checkpoint that is newer than the finalized checkpoint
+	justified := Checkpoint{Root: b, Epoch: 2}
+	finalized := Checkpoint{Root: a, Epoch: 1}
+	if err := fc.UpdateJustified(context.Background(), b, justified, finalized, func() ([]Gwei, error) {
+		return []Gwei{10, 10}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fc.Justified(); got != justified {
+		t.Fatalf("expected justified checkpoint %s, got %s", justified, got)
+	}
+	if got := fc.Finalized(); got != finalized {
+		t.Fatalf("expected finalized checkpoint %s, got %s", finalized, got)
+	}
+}
+
+func TestUpdateJustifiedPinned(t *testing.T) {
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a, b := Root{0x0a}, Root{0x0b}
+	fc, err := NewProtoForkChoice(configs.Minimal, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10, 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.ProcessBlock(genesis.Root, a, 8, 0, 0)
+	fc.ProcessBlock(a, b, 16, 1, 0)
+	if err := fc.SetPin(a, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	// the trigger is not the pin itself, and has to be checked against the pinned subtree
+	done := make(chan error, 1)
+	go func() {
+		done <- fc.UpdateJustified(context.Background(), b, Checkpoint{Root: a, Epoch: 1}, genesis, func() ([]Gwei, error) {
+			return []Gwei{10, 10}, nil
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UpdateJustified did not return, deadlock on the forkchoice lock")
+	}
+	if got := fc.Justified(); got.Root != a {
+		t.Fatalf("expected justified checkpoint of %s, got %s", a, got)
+	}
+}
@@ -28,6 +28,10 @@ type ProtoNode struct {
 	BestChild NodeIndex
 	// Relative to ForkchoiceParent relations
 	BestDescendant NodeIndex
+	// Block hash of the execution payload of the block of Ref.Root, zeroed if there is none (pre-merge).
+	ExecutionBlockHash Root
+	// Status of the execution payload of the block of Ref.Root.
+	ExecutionStatus ExecutionStatus
 }
 
 type NodeSinkFn func(ctx context.Context, ref NodeRef, canonical bool) error
@@ -349,9 +353,16 @@ func (pr *ProtoArray) ProcessSlot(parent Root, slot Slot, justifiedEpoch Epoch,
 		return
 	}
 	parentIndex := NONE
+	// Slot nodes share the execution payload of the parent block.
+	var execHash Root
+	execStatus := ExecutionValid
 	parentSlot, ok := pr.blockSlots[parent]
 	if ok {
 		parentIndex = pr.indices[NodeRef{Root: parent, Slot: parentSlot}]
+		if parentNode, err := pr.getNode(parentIndex); err == nil {
+			execHash = parentNode.ExecutionBlockHash
+			execStatus = parentNode.ExecutionStatus
+		}
 		for i := parentSlot + 1; i < slot; i++ {
 			nodeRef := NodeRef{Root: parent, Slot: i}
 			// remember the last node before (up to and including same slot)
@@ -374,6 +385,9 @@ func (pr *ProtoArray) ProcessSlot(parent Root, slot Slot, justifiedEpoch Epoch,
 				Weight:           0,
 				BestChild:        NONE,
 				BestDescendant:   NONE,
+
+				ExecutionBlockHash: execHash,
+				ExecutionStatus:    execStatus,
 			})
 			// remember the node as parent for the next
 			parentIndex = nodeIndex
@@ -392,6 +406,9 @@ func (pr *ProtoArray) ProcessSlot(parent Root, slot Slot, justifiedEpoch Epoch,
 		Weight:           0,
 		BestChild:        NONE,
 		BestDescendant:   NONE,
+
+		ExecutionBlockHash: execHash,
+		ExecutionStatus:    execStatus,
 	})
 	// Connections are out of sync, i.e. array needs work before next find-head can return the proper head.
 	pr.updatedConnections = false
@@ -401,7 +418,16 @@ func (pr *ProtoArray) ProcessSlot(parent Root, slot Slot, justifiedEpoch Epoch,
 // If justified or finalized in-between, make sure to call OnSlot with accurate details first.
 //
 // The parent root of the genesis block should be zeroed.
+// The block is registered with a valid execution status and no payload, see ProcessBlockWithPayload.
 func (pr *ProtoArray) ProcessBlock(parent Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch) (ok bool) {
+	return pr.ProcessBlockWithPayload(parent, blockRoot, blockSlot, justifiedEpoch, finalizedEpoch, Root{}, ExecutionValid)
+}
+
+// ProcessBlockWithPayload is like ProcessBlock, but registers the execution block hash and status of the payload.
+// A block that builds on a block with an invalid payload is invalid as well.
+// A block with a valid payload makes the payloads of all its ancestors valid.
+func (pr *ProtoArray) ProcessBlockWithPayload(parent Root, blockRoot Root, blockSlot Slot, justifiedEpoch Epoch, finalizedEpoch Epoch,
+	executionBlockHash Root, status ExecutionStatus) (ok bool) {
 	blockRef := NodeRef{Root: blockRoot, Slot: blockSlot}
 	// If the block is already known, simply ignore it.
 	if _, ok := pr.indices[blockRef]; ok {
@@ -429,6 +455,10 @@ func (pr *ProtoArray) ProcessBlock(parent Root, blockRoot Root, blockSlot Slot,
 	if !ok {
 		panic("OnSlot failed to add node for block slot (transition parent)")
 	}
+	parentStatus := pr.nodes[transitionParentIndex-pr.indexOffset].ExecutionStatus
+	if parentStatus == ExecutionInvalid {
+		status = ExecutionInvalid
+	}
 	nodeIndex := pr.indexOffset + NodeIndex(len(pr.nodes))
 	pr.blockSlots[blockRoot] = blockSlot
 	pr.indices[blockRef] = nodeIndex
@@ -442,7 +472,14 @@ func (pr *ProtoArray) ProcessBlock(parent Root, blockRoot Root, blockSlot Slot,
 		Weight:           0,
 		BestChild:        NONE,
 		BestDescendant:   NONE,
+
+		ExecutionBlockHash: executionBlockHash,
+		ExecutionStatus:    status,
 	})
+	if status == ExecutionValid && parentStatus == ExecutionOptimistic {
+		// A valid payload implies valid ancestors. The parent is not invalid, so neither are its ancestors.
+		_ = pr.setAncestorsValid(transitionParentIndex)
+	}
 	// Connections are out of sync, i.e. array needs work before next find-head can return the proper head.
 	pr.updatedConnections = false
 	return true
@@ -562,6 +599,37 @@ func (pr *ProtoArray) inSubtree(anchorIndex NodeIndex, lookupIndex NodeIndex) (u
 	return false, false
 }
 
+// CommonAncestor finds the latest node that both a and b descend from (or are equal to).
+func (pr *ProtoArray) CommonAncestor(a NodeRef, b NodeRef) (ancestor NodeRef, err error) {
+	ai, ok := pr.indices[a]
+	if !ok {
+		return NodeRef{}, fmt.Errorf("unknown node %s", a)
+	}
+	bi, ok := pr.indices[b]
+	if !ok {
+		return NodeRef{}, fmt.Errorf("unknown node %s", b)
+	}
+	// Parents always have a lower index than their children: move the later node back until they meet.
+	for ai != bi {
+		if ai > bi {
+			ai, bi = bi, ai
+		}
+		node, err := pr.getNode(bi)
+		if err != nil {
+			return NodeRef{}, err
+		}
+		bi = node.TransitionParent
+		if bi == NONE || bi < pr.indexOffset {
+			return NodeRef{}, fmt.Errorf("no common ancestor of %s and %s", a, b)
+		}
+	}
+	node, err := pr.getNode(ai)
+	if err != nil {
+		return NodeRef{}, err
+	}
+	return node.Ref, nil
+}
+
 var HeadUnknownErr = errors.New("array has invalid state, head has no index")
 
 type prunedNode struct {
@@ -734,7 +802,9 @@ func (pr *ProtoArray) nodeLeadsToViableHead(node *ProtoNode) (bool, error) {
 // https://github.com/ethereum/eth2.0-specs/blob/v0.11.1/specs/phase0/fork-choice.md#filter_block_tree
 //
 // Any node that has a different finalized or justified epoch should not be viable for the head.
+// Nodes with an invalid execution payload are not viable either. Optimistic nodes are.
 func (pr *ProtoArray) isNodeViableForHead(node *ProtoNode) bool {
-	return (node.JustifiedEpoch == pr.justifiedEpoch || pr.justifiedEpoch == common.GENESIS_EPOCH) &&
+	return node.ExecutionStatus != ExecutionInvalid &&
+		(node.JustifiedEpoch == pr.justifiedEpoch || pr.justifiedEpoch == common.GENESIS_EPOCH) &&
 		(node.FinalizedEpoch == pr.finalizedEpoch || pr.finalizedEpoch == common.GENESIS_EPOCH)
 }
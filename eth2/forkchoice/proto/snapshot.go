@@ -0,0 +1,331 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+)
+
+// Upper bound of nodes in a ProtoArraySnapshot, only used for SSZ list bounds and merkleization.
+const SNAPSHOT_NODES_LIMIT = 1 << 24
+
+const protoNodeSize = 40 + 8 + 8 + 32 + 8 + 8 + 8 + 8 + 8 + 32 + 1
+
+func (n *ProtoNode) Deserialize(dr *codec.DecodingReader) error {
+	var weight Uint64View
+	if err := dr.FixedLenContainer(&n.Ref,
+		(*Uint64View)(&n.TransitionParent), (*Uint64View)(&n.ForkchoiceParent),
+		&n.ParentRoot, &n.JustifiedEpoch, &n.FinalizedEpoch, &weight,
+		(*Uint64View)(&n.BestChild), (*Uint64View)(&n.BestDescendant),
+		&n.ExecutionBlockHash, (*Uint8View)(&n.ExecutionStatus)); err != nil {
+		return err
+	}
+	n.Weight = SignedGwei(weight)
+	return nil
+}
+
+func (n *ProtoNode) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&n.Ref,
+		Uint64View(n.TransitionParent), Uint64View(n.ForkchoiceParent),
+		&n.ParentRoot, n.JustifiedEpoch, n.FinalizedEpoch, Uint64View(n.Weight),
+		Uint64View(n.BestChild), Uint64View(n.BestDescendant),
+		&n.ExecutionBlockHash, Uint8View(n.ExecutionStatus))
+}
+
+func (n *ProtoNode) ByteLength() uint64 {
+	return protoNodeSize
+}
+
+func (n *ProtoNode) FixedLength() uint64 {
+	return protoNodeSize
+}
+
+func (n *ProtoNode) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(&n.Ref,
+		Uint64View(n.TransitionParent), Uint64View(n.ForkchoiceParent),
+		n.ParentRoot, n.JustifiedEpoch, n.FinalizedEpoch, Uint64View(n.Weight),
+		Uint64View(n.BestChild), Uint64View(n.BestDescendant),
+		n.ExecutionBlockHash, Uint8View(n.ExecutionStatus))
+}
+
+type ProtoNodes []ProtoNode
+
+func (li *ProtoNodes) Deserialize(dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*li)
+		*li = append(*li, ProtoNode{})
+		return &(*li)[i]
+	}, protoNodeSize, SNAPSHOT_NODES_LIMIT)
+}
+
+func (li ProtoNodes) Serialize(w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return &li[i]
+	}, protoNodeSize, uint64(len(li)))
+}
+
+func (li ProtoNodes) ByteLength() (out uint64) {
+	return protoNodeSize * uint64(len(li))
+}
+
+func (li *ProtoNodes) FixedLength() uint64 {
+	return 0
+}
+
+func (li ProtoNodes) HashTreeRoot(hFn tree.HashFn) Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &li[i]
+		}
+		return nil
+	}, length, SNAPSHOT_NODES_LIMIT)
+}
+
+// ProtoArraySnapshot is the serializable form of a ProtoArray.
+// The indices and block slots are derived from the nodes when restoring.
+type ProtoArraySnapshot struct {
+	IndexOffset    NodeIndex
+	JustifiedEpoch Epoch
+	FinalizedEpoch Epoch
+	Nodes          ProtoNodes
+}
+
+func (s *ProtoArraySnapshot) Deserialize(dr *codec.DecodingReader) error {
+	return dr.Container((*Uint64View)(&s.IndexOffset), &s.JustifiedEpoch, &s.FinalizedEpoch, &s.Nodes)
+}
+
+func (s *ProtoArraySnapshot) Serialize(w *codec.EncodingWriter) error {
+	return w.Container(Uint64View(s.IndexOffset), s.JustifiedEpoch, s.FinalizedEpoch, &s.Nodes)
+}
+
+func (s *ProtoArraySnapshot) ByteLength() uint64 {
+	return codec.ContainerLength(Uint64View(s.IndexOffset), s.JustifiedEpoch, s.FinalizedEpoch, &s.Nodes)
+}
+
+func (s *ProtoArraySnapshot) FixedLength() uint64 {
+	return 0
+}
+
+func (s *ProtoArraySnapshot) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(Uint64View(s.IndexOffset), s.JustifiedEpoch, s.FinalizedEpoch, &s.Nodes)
+}
+
+// Snapshot copies the nodes and other state of the proto-array.
+func (pr *ProtoArray) Snapshot() *ProtoArraySnapshot {
+	nodes := make(ProtoNodes, len(pr.nodes))
+	copy(nodes, pr.nodes)
+	return &ProtoArraySnapshot{
+		IndexOffset:    pr.indexOffset,
+		JustifiedEpoch: pr.justifiedEpoch,
+		FinalizedEpoch: pr.finalizedEpoch,
+		Nodes:          nodes,
+	}
+}
+
+// Restore creates a new ProtoArray from the snapshot. The snapshot nodes are copied, not retained.
+func (s *ProtoArraySnapshot) Restore(sink NodeSink) (*ProtoArray, error) {
+	if len(s.Nodes) == 0 {
+		return nil, errors.New("snapshot has no nodes")
+	}
+	end := s.IndexOffset + NodeIndex(len(s.Nodes))
+	pr := &ProtoArray{
+		sink:           sink,
+		indexOffset:    s.IndexOffset,
+		justifiedEpoch: s.JustifiedEpoch,
+		finalizedEpoch: s.FinalizedEpoch,
+		nodes:          make([]ProtoNode, len(s.Nodes)),
+		indices:        make(map[NodeRef]NodeIndex, len(s.Nodes)),
+		blockSlots:     make(map[Root]Slot, len(s.Nodes)),
+		// Best child and descendant are restored as-is, but recomputing them is cheap and avoids trusting them.
+		updatedConnections: false,
+	}
+	copy(pr.nodes, s.Nodes)
+	for i := range pr.nodes {
+		node := &pr.nodes[i]
+		index := s.IndexOffset + NodeIndex(i)
+		// Parents always come before their children. Parents before the offset have been pruned.
+		for _, p := range []NodeIndex{node.TransitionParent, node.ForkchoiceParent} {
+			if p != NONE && p >= index {
+				return nil, fmt.Errorf("node %d (%s) has parent %d that does not precede it", index, node.Ref, p)
+			}
+		}
+		for _, c := range []NodeIndex{node.BestChild, node.BestDescendant} {
+			if c != NONE && (c < s.IndexOffset || c >= end) {
+				return nil, fmt.Errorf("node %d (%s) has best child or descendant %d out of range", index, node.Ref, c)
+			}
+		}
+		if _, ok := pr.indices[node.Ref]; ok {
+			return nil, fmt.Errorf("duplicate node %s", node.Ref)
+		}
+		pr.indices[node.Ref] = index
+		if slot, ok := pr.blockSlots[node.Ref.Root]; !ok || node.Ref.Slot < slot {
+			pr.blockSlots[node.Ref.Root] = node.Ref.Slot
+		}
+	}
+	return pr, nil
+}
+
+func (v *VoteTracker) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&v.Current, &v.Next, &v.CurrentTargetEpoch, &v.NextTargetEpoch)
+}
+
+func (v *VoteTracker) Serialize(w *codec.EncodingWriter) error {
+	return w.FixedLenContainer(&v.Current, &v.Next, v.CurrentTargetEpoch, v.NextTargetEpoch)
+}
+
+func (v *VoteTracker) ByteLength() uint64 {
+	return 40 + 40 + 8 + 8
+}
+
+func (v *VoteTracker) FixedLength() uint64 {
+	return 40 + 40 + 8 + 8
+}
+
+func (v *VoteTracker) HashTreeRoot(hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(&v.Current, &v.Next, v.CurrentTargetEpoch, v.NextTargetEpoch)
+}
+
+// VoteTrackers has a vote tracker for each validator, it is bounded by the validator registry limit.
+type VoteTrackers []VoteTracker
+
+func (li *VoteTrackers) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.List(func() codec.Deserializable {
+		i := len(*li)
+		*li = append(*li, VoteTracker{})
+		return &(*li)[i]
+	}, 40+40+8+8, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
+}
+
+func (li VoteTrackers) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.List(func(i uint64) codec.Serializable {
+		return &li[i]
+	}, 40+40+8+8, uint64(len(li)))
+}
+
+func (li VoteTrackers) ByteLength(spec *common.Spec) (out uint64) {
+	return (40 + 40 + 8 + 8) * uint64(len(li))
+}
+
+func (li *VoteTrackers) FixedLength(spec *common.Spec) uint64 {
+	return 0
+}
+
+func (li VoteTrackers) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) Root {
+	length := uint64(len(li))
+	return hFn.ComplexListHTR(func(i uint64) tree.HTR {
+		if i < length {
+			return &li[i]
+		}
+		return nil
+	}, length, uint64(spec.VALIDATOR_REGISTRY_LIMIT))
+}
+
+// Snapshot copies the votes of the vote store.
+func (st *ProtoVoteStore) Snapshot() VoteTrackers {
+	votes := make(VoteTrackers, len(st.votes))
+	copy(votes, st.votes)
+	return votes
+}
+
+// RestoreProtoVoteStore creates a vote store with a copy of the given votes.
+// The store is marked as changed, the next deltas computation will apply any pending votes.
+func RestoreProtoVoteStore(spec *common.Spec, votes VoteTrackers) *ProtoVoteStore {
+	st := &ProtoVoteStore{spec: spec, votes: make([]VoteTracker, len(votes)), changed: true}
+	copy(st.votes, votes)
+	return st
+}
+
+// ForkChoiceSnapshot is the serializable form of the full forkchoice store:
+// the proto-array nodes, the votes, the justified balances, the checkpoints and the pin.
+type ForkChoiceSnapshot struct {
+	Justified Checkpoint
+	Finalized Checkpoint
+	// If the forkchoice is pinned, the Pin is set.
+	Pinned   bool
+	Pin      NodeRef
+	Balances common.GweiList
+	Graph    ProtoArraySnapshot
+	Votes    VoteTrackers
+}
+
+func (s *ForkChoiceSnapshot) Deserialize(spec *common.Spec, dr *codec.DecodingReader) error {
+	return dr.Container(&s.Justified, &s.Finalized, (*BoolView)(&s.Pinned), &s.Pin,
+		spec.Wrap(&s.Balances), &s.Graph, spec.Wrap(&s.Votes))
+}
+
+func (s *ForkChoiceSnapshot) Serialize(spec *common.Spec, w *codec.EncodingWriter) error {
+	return w.Container(&s.Justified, &s.Finalized, BoolView(s.Pinned), &s.Pin,
+		spec.Wrap(&s.Balances), &s.Graph, spec.Wrap(&s.Votes))
+}
+
+func (s *ForkChoiceSnapshot) ByteLength(spec *common.Spec) uint64 {
+	return codec.ContainerLength(&s.Justified, &s.Finalized, BoolView(s.Pinned), &s.Pin,
+		spec.Wrap(&s.Balances), &s.Graph, spec.Wrap(&s.Votes))
+}
+
+func (s *ForkChoiceSnapshot) FixedLength(spec *common.Spec) uint64 {
+	return 0
+}
+
+func (s *ForkChoiceSnapshot) HashTreeRoot(spec *common.Spec, hFn tree.HashFn) Root {
+	return hFn.HashTreeRoot(&s.Justified, &s.Finalized, BoolView(s.Pinned), &s.Pin,
+		spec.Wrap(&s.Balances), &s.Graph, spec.Wrap(&s.Votes))
+}
+
+// SnapshotForkChoice captures the full state of a forkchoice created with NewProtoForkChoice.
+func SnapshotForkChoice(fc Forkchoice) (*ForkChoiceSnapshot, error) {
+	pfc, ok := fc.(*ProtoForkChoice)
+	if !ok {
+		return nil, fmt.Errorf("unsupported forkchoice type %T", fc)
+	}
+	var snap *ForkChoiceSnapshot
+	err := pfc.ReadStore(func(store *ForkChoiceStore) error {
+		graph, ok := store.Graph.(*ProtoArray)
+		if !ok {
+			return fmt.Errorf("unsupported forkchoice graph type %T", store.Graph)
+		}
+		votes, ok := store.Votes.(*ProtoVoteStore)
+		if !ok {
+			return fmt.Errorf("unsupported vote store type %T", store.Votes)
+		}
+		snap = &ForkChoiceSnapshot{
+			Justified: store.Justified,
+			Finalized: store.Finalized,
+			Balances:  append(common.GweiList(nil), store.Balances...),
+			Graph:     *graph.Snapshot(),
+			Votes:     votes.Snapshot(),
+		}
+		if store.Pin != nil {
+			snap.Pinned = true
+			snap.Pin = *store.Pin
+		}
+		return nil
+	})
+	return snap, err
+}
+
+// Restore creates a new forkchoice from the snapshot. Pruned nodes are reported to the sink.
+func (s *ForkChoiceSnapshot) Restore(spec *common.Spec, sink NodeSink) (Forkchoice, error) {
+	graph, err := s.Graph.Restore(sink)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore proto-array: %w", err)
+	}
+	store := &ForkChoiceStore{
+		Graph:     graph,
+		Votes:     RestoreProtoVoteStore(spec, s.Votes),
+		Balances:  append([]Gwei(nil), s.Balances...),
+		Justified: s.Justified,
+		Finalized: s.Finalized,
+	}
+	if s.Pinned {
+		pin := s.Pin
+		store.Pin = &pin
+	}
+	return RestoreForkChoice(spec, store)
+}
@@ -0,0 +1,97 @@
+package proto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/configs"
+	. "github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+)
+
+func TestForkChoiceSnapshot(t *testing.T) {
+	spec := configs.Minimal
+	genesis := Checkpoint{Root: Root{0x01}, Epoch: 0}
+	a, b, c, d := Root{0x0a}, Root{0x0b}, Root{0x0c}, Root{0x0d}
+	fc, err := NewProtoForkChoice(spec, genesis, genesis, genesis.Root, 0, Root{},
+		[]Gwei{10, 10, 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.ProcessBlock(genesis.Root, a, 1, 0, 0)
+	fc.ProcessBlock(a, b, 2, 0, 0)
+	fc.ProcessBlockWithPayload(a, c, 3, 0, 0, Root{0xcc}, ExecutionOptimistic)
+	fc.ProcessAttestation(0, b, 2)
+	fc.ProcessAttestation(1, c, 3)
+	fc.ProcessAttestation(2, c, 3)
+	head, err := fc.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Root != c {
+		t.Fatalf("expected head %s, got %s", c, head.Root)
+	}
+	// a pending vote, not applied to the weights yet
+	fc.ProcessBlock(b, d, 9, 0, 0)
+	fc.ProcessAttestation(1, d, 9)
+
+	snap, err := SnapshotForkChoice(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := snap.Serialize(spec, codec.NewEncodingWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if uint64(buf.Len()) != snap.ByteLength(spec) {
+		t.Fatalf("serialized %d bytes, but expected %d", buf.Len(), snap.ByteLength(spec))
+	}
+	var decoded ForkChoiceSnapshot
+	if err := decoded.Deserialize(spec, codec.NewDecodingReader(bytes.NewReader(buf.Bytes()), uint64(buf.Len()))); err != nil {
+		t.Fatal(err)
+	}
+	hFn := tree.GetHashFn()
+	if snap.HashTreeRoot(spec, hFn) != decoded.HashTreeRoot(spec, hFn) {
+		t.Fatal("decoded snapshot does not match")
+	}
+
+	restored, err := decoded.Restore(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := fc.(*ProtoForkChoice)
+	var origIndices, restoredIndices map[NodeRef]NodeIndex
+	_ = original.ReadStore(func(store *ForkChoiceStore) error {
+		origIndices = store.Graph.Indices()
+		return nil
+	})
+	_ = restored.(*ProtoForkChoice).ReadStore(func(store *ForkChoiceStore) error {
+		restoredIndices = store.Graph.Indices()
+		return nil
+	})
+	if len(origIndices) != len(restoredIndices) {
+		t.Fatalf("expected %d indices, got %d", len(origIndices), len(restoredIndices))
+	}
+	for ref, i := range origIndices {
+		if restoredIndices[ref] != i {
+			t.Fatalf("node %s: expected index %d, got %d", ref, i, restoredIndices[ref])
+		}
+	}
+	if status, _ := restored.ExecutionStatus(c); status != ExecutionOptimistic {
+		t.Fatalf("expected optimistic status, got %s", status)
+	}
+
+	// Both apply the pending vote, and should agree on the new head.
+	origHead, err := fc.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredHead, err := restored.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origHead != restoredHead || origHead.Root != d {
+		t.Fatalf("expected head %s, got %s and %s", d, origHead, restoredHead)
+	}
+}
@@ -0,0 +1,90 @@
+package interop
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// WithdrawalAddress is the execution address that the interop validator with the given index withdraws to:
+// 0xee, followed by zeroes and the big-endian index.
+func WithdrawalAddress(index uint64) (out common.Eth1Address) {
+	out[0] = 0xee
+	for i := 0; i < 8; i++ {
+		out[19-i] = byte(index >> (8 * i))
+	}
+	return
+}
+
+// WithdrawalCredentials returns the withdrawal credentials of the given prefix.
+// BLS (0x00) credentials commit to the hash of the pubkey, which interop validators also use as withdrawal key.
+// Execution address (0x01) and compounding (0x02) credentials commit to the address.
+func WithdrawalCredentials(prefix byte, pubkey common.BLSPubkey, address common.Eth1Address) (out common.Root, err error) {
+	switch prefix {
+	case common.BLS_WITHDRAWAL_PREFIX:
+		out = sha256.Sum256(pubkey[:])
+	case common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, common.COMPOUNDING_WITHDRAWAL_PREFIX:
+		copy(out[12:], address[:])
+	default:
+		return common.Root{}, fmt.Errorf("unknown withdrawal prefix 0x%02x", prefix)
+	}
+	out[0] = prefix
+	return out, nil
+}
+
+// DepositData returns the signed deposit data of the interop validator with the given index,
+// with withdrawal credentials of the given prefix, and the WithdrawalAddress of the index.
+func DepositData(spec *common.Spec, index uint64, prefix byte, amount common.Gwei) (*common.DepositData, error) {
+	sk, err := SecretKey(index)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := pubkeyOf(sk)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := WithdrawalCredentials(prefix, pub, WithdrawalAddress(index))
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := common.NewDepositData(spec, sk, creds, amount)
+	return data, err
+}
+
+// GenesisDeposits returns the signed deposits of the interop validators with index 0 up to count,
+// with the proofs that phase0.GenesisFromEth1 verifies.
+func GenesisDeposits(spec *common.Spec, count uint64, prefix byte, amount common.Gwei) ([]common.Deposit, error) {
+	data := make([]common.DepositData, 0, count)
+	for i := uint64(0); i < count; i++ {
+		d, err := DepositData(spec, i, prefix, amount)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, *d)
+	}
+	return phase0.GenesisDeposits(data)
+}
+
+// KickstartValidators returns the validator data of the interop validators with index 0 up to count,
+// for phase0.KickStartState and the genesis builders of later forks.
+func KickstartValidators(count uint64, prefix byte, balance common.Gwei) ([]phase0.KickstartValidatorData, error) {
+	_, pubs, err := Keys(count)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]phase0.KickstartValidatorData, count)
+	for i, pub := range pubs {
+		creds, err := WithdrawalCredentials(prefix, pub, WithdrawalAddress(uint64(i)))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = phase0.KickstartValidatorData{
+			Pubkey:                pub,
+			WithdrawalCredentials: creds,
+			Balance:               balance,
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package interop
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestKeys(t *testing.T) {
+	sks, pubs, err := Keys(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk := sks[0].Serialize()
+	if got := hex.EncodeToString(sk[:]); got != "25295f0d1d592a90b333e26e85149708208e9f8e8bc18f6c77bd62f8ad7a6866" {
+		t.Fatalf("unexpected secret key 0: %s", got)
+	}
+	if got := hex.EncodeToString(pubs[0][:]); got != "a99a76ed7796f7be22d5b7e85deeb7c5677e88e511e0b337618f8c4eb61349b4bf2d153f649f7b53359fe8b94a38e44c" {
+		t.Fatalf("unexpected pubkey 0: %s", got)
+	}
+	pub, err := Pubkey(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub != pubs[1] {
+		t.Fatal("Pubkey does not match Keys")
+	}
+}
+
+func TestGenesisDeposits(t *testing.T) {
+	spec := configs.Minimal
+	count := uint64(spec.SLOTS_PER_EPOCH)
+	for _, prefix := range []byte{common.BLS_WITHDRAWAL_PREFIX, common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, common.COMPOUNDING_WITHDRAWAL_PREFIX} {
+		deps, err := GenesisDeposits(spec, count, prefix, spec.MAX_EFFECTIVE_BALANCE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// signatures and proofs are verified
+		state, _, err := phase0.GenesisFromEth1(spec, common.Root{0x01}, 0, deps, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vals, err := state.Validators()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := vals.ValidatorCount(); err != nil || n != count {
+			t.Fatalf("prefix 0x%02x: expected %d validators, got %d", prefix, count, n)
+		}
+		v, err := vals.Validator(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds, err := v.WithdrawalCredentials()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds[0] != prefix {
+			t.Fatalf("expected withdrawal prefix 0x%02x, got 0x%02x", prefix, creds[0])
+		}
+		if prefix != common.BLS_WITHDRAWAL_PREFIX && common.Eth1Address(creds[12:]) != WithdrawalAddress(3) {
+			t.Fatalf("unexpected withdrawal address in %s", creds)
+		}
+	}
+	if _, err := WithdrawalCredentials(0x03, common.BLSPubkey{}, common.Eth1Address{}); err == nil {
+		t.Fatal("expected error for unknown prefix")
+	}
+}
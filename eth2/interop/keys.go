@@ -0,0 +1,74 @@
+// Package interop implements the deterministic validator keys and deposits of the interop mock-start,
+// for test networks and simulations that sign everything with known keys.
+package interop
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	blsu "github.com/protolambda/bls12-381-util"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// BLS12-381 curve order
+var curveOrder, _ = new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+
+// SecretKey derives the interop secret key of the validator with the given index:
+// the little-endian integer of sha256(uint256_le(index)), modulo the curve order.
+func SecretKey(index uint64) (*blsu.SecretKey, error) {
+	var preimage [32]byte
+	binary.LittleEndian.PutUint64(preimage[:8], index)
+	h := sha256.Sum256(preimage[:])
+	// the hash is interpreted as little-endian, big.Int takes big-endian bytes
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	k := new(big.Int).SetBytes(h[:])
+	k.Mod(k, curveOrder)
+	var out [32]byte
+	k.FillBytes(out[:])
+	var sk blsu.SecretKey
+	if err := sk.Deserialize(&out); err != nil {
+		return nil, fmt.Errorf("invalid interop key %d: %w", index, err)
+	}
+	return &sk, nil
+}
+
+// Pubkey returns the pubkey of the interop secret key of the given index.
+func Pubkey(index uint64) (common.BLSPubkey, error) {
+	sk, err := SecretKey(index)
+	if err != nil {
+		return common.BLSPubkey{}, err
+	}
+	return pubkeyOf(sk)
+}
+
+// Keys returns the interop secret keys and pubkeys of the validators with index 0 up to count.
+func Keys(count uint64) ([]*blsu.SecretKey, []common.BLSPubkey, error) {
+	sks := make([]*blsu.SecretKey, count)
+	pubs := make([]common.BLSPubkey, count)
+	for i := uint64(0); i < count; i++ {
+		sk, err := SecretKey(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		pub, err := pubkeyOf(sk)
+		if err != nil {
+			return nil, nil, err
+		}
+		sks[i] = sk
+		pubs[i] = pub
+	}
+	return sks, pubs, nil
+}
+
+func pubkeyOf(sk *blsu.SecretKey) (common.BLSPubkey, error) {
+	pk, err := blsu.SkToPk(sk)
+	if err != nil {
+		return common.BLSPubkey{}, err
+	}
+	return pk.Serialize(), nil
+}
@@ -0,0 +1,541 @@
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+var forkOrder = map[beacon.ForkName]int{
+	beacon.Phase0:    0,
+	beacon.Altair:    1,
+	beacon.Bellatrix: 2,
+	beacon.Capella:   3,
+	beacon.Deneb:     4,
+	beacon.Electra:   5,
+}
+
+func forkAtLeast(fork beacon.ForkName, min beacon.ForkName) bool {
+	return forkOrder[fork] >= forkOrder[min]
+}
+
+// blockParts is the fork-agnostic content of a block, converted into the block type of the fork when signing.
+type blockParts struct {
+	slot       common.Slot
+	proposer   common.ValidatorIndex
+	parentRoot common.Root
+
+	randaoReveal common.BLSSignature
+	eth1Data     common.Eth1Data
+	graffiti     common.Root
+
+	proposerSlashings phase0.ProposerSlashings
+	attesterSlashings phase0.AttesterSlashings
+	// pre-Electra attestations
+	attestations phase0.Attestations
+	// Electra attestations
+	attestationsElectra electra.AttestationsElectra
+	deposits            phase0.Deposits
+	voluntaryExits      phase0.VoluntaryExits
+
+	// the Deneb payload is a superset of the Bellatrix and Capella payloads. Nil before Bellatrix.
+	payload *deneb.ExecutionPayload
+}
+
+// blockParts collects the contents of a block at the slot, given the state processed up to that slot.
+func (s *Simulator) blockParts(ctx context.Context, state common.BeaconState, epc *common.EpochsContext, fork beacon.ForkName,
+	parent common.Root, parentSlot common.Slot, slot common.Slot) (*blockParts, error) {
+	spec := s.spec
+	proposer, err := epc.GetBeaconProposer(slot)
+	if err != nil {
+		return nil, err
+	}
+	epoch := spec.SlotToEpoch(slot)
+	parts := &blockParts{
+		slot:       slot,
+		proposer:   proposer,
+		parentRoot: parent,
+		graffiti:   s.Graffiti,
+	}
+	randaoDomain, err := common.GetDomain(state, common.DOMAIN_RANDAO, epoch)
+	if err != nil {
+		return nil, err
+	}
+	parts.randaoReveal, err = s.sign(state, proposer, common.ComputeSigningRoot(epoch.HashTreeRoot(tree.GetHashFn()), randaoDomain))
+	if err != nil {
+		return nil, err
+	}
+	parts.eth1Data, err = s.eth1Vote()
+	if err != nil {
+		return nil, err
+	}
+	// The vote of this block may already change the eth1 data that the deposits are checked against.
+	voted, err := state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	if err := phase0.ProcessEth1Vote(ctx, spec, epc, voted, parts.eth1Data); err != nil {
+		return nil, err
+	}
+	parts.deposits, err = s.pendingDeposits(voted)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(s.proposerSlashings) > 0 && uint64(len(parts.proposerSlashings)) < uint64(spec.MAX_PROPOSER_SLASHINGS) {
+		op, err := s.proposerSlashing(state, s.proposerSlashings[0], slot)
+		if err != nil {
+			return nil, err
+		}
+		s.proposerSlashings = s.proposerSlashings[1:]
+		parts.proposerSlashings = append(parts.proposerSlashings, *op)
+	}
+	for len(s.attesterSlashings) > 0 && uint64(len(parts.attesterSlashings)) < uint64(spec.MAX_ATTESTER_SLASHINGS) {
+		op, err := s.attesterSlashing(state, s.attesterSlashings[0], slot)
+		if err != nil {
+			return nil, err
+		}
+		s.attesterSlashings = s.attesterSlashings[1:]
+		parts.attesterSlashings = append(parts.attesterSlashings, *op)
+	}
+	for len(s.exits) > 0 && uint64(len(parts.voluntaryExits)) < uint64(spec.MAX_VOLUNTARY_EXITS) {
+		op, err := s.voluntaryExit(state, s.exits[0], epoch, forkAtLeast(fork, beacon.Deneb))
+		if err != nil {
+			return nil, err
+		}
+		s.exits = s.exits[1:]
+		parts.voluntaryExits = append(parts.voluntaryExits, *op)
+	}
+
+	if err := s.addAttestations(parts, state, epc, fork, parentSlot); err != nil {
+		return nil, err
+	}
+
+	if forkAtLeast(fork, beacon.Bellatrix) {
+		parts.payload, err = s.executionPayload(state, fork, slot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// addAttestations adds the aggregate attestations of the committees of the slots since the parent block,
+// as far as they can still be included.
+// The attestations vote for the parent block, which is the latest block up to their slot.
+func (s *Simulator) addAttestations(parts *blockParts, state common.BeaconState, epc *common.EpochsContext, fork beacon.ForkName, parentSlot common.Slot) error {
+	spec := s.spec
+	slot := parts.slot
+	currentEpoch := spec.SlotToEpoch(slot)
+	from, err := spec.EpochStartSlot(currentEpoch.Previous())
+	if err != nil {
+		return err
+	}
+	if slot > spec.SLOTS_PER_EPOCH && slot-spec.SLOTS_PER_EPOCH > from {
+		from = slot - spec.SLOTS_PER_EPOCH
+	}
+	if parentSlot > from {
+		from = parentSlot
+	}
+	currentJustified, err := state.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return err
+	}
+	previousJustified, err := state.PreviousJustifiedCheckpoint()
+	if err != nil {
+		return err
+	}
+	for attSlot := from; attSlot+spec.MIN_ATTESTATION_INCLUSION_DELAY <= slot; attSlot++ {
+		targetEpoch := spec.SlotToEpoch(attSlot)
+		targetSlot, err := spec.EpochStartSlot(targetEpoch)
+		if err != nil {
+			return err
+		}
+		targetRoot, err := common.GetBlockRootAtSlot(spec, state, targetSlot)
+		if err != nil {
+			return err
+		}
+		source := previousJustified
+		if targetEpoch == currentEpoch {
+			source = currentJustified
+		}
+		data := phase0.AttestationData{
+			Slot:            attSlot,
+			BeaconBlockRoot: parts.parentRoot,
+			Source:          source,
+			Target:          common.Checkpoint{Epoch: targetEpoch, Root: targetRoot},
+		}
+		domain, err := common.GetDomain(state, common.DOMAIN_BEACON_ATTESTER, targetEpoch)
+		if err != nil {
+			return err
+		}
+		committeeCount, err := epc.GetCommitteeCountPerSlot(targetEpoch)
+		if err != nil {
+			return err
+		}
+		// Electra aggregates all committees of the slot in a single attestation
+		var electraAtt *electra.AttestationElectra
+		var electraBits []bool
+		var electraAttesters []common.ValidatorIndex
+		for index := uint64(0); index < committeeCount; index++ {
+			committee, err := epc.GetBeaconCommittee(attSlot, common.CommitteeIndex(index))
+			if err != nil {
+				return err
+			}
+			bits := make([]bool, len(committee))
+			var attesters []common.ValidatorIndex
+			for i, vi := range committee {
+				if s.participates(attSlot, vi) {
+					bits[i] = true
+					attesters = append(attesters, vi)
+				}
+			}
+			if len(attesters) == 0 {
+				continue
+			}
+			if forkAtLeast(fork, beacon.Electra) {
+				if electraAtt == nil {
+					electraAtt = &electra.AttestationElectra{
+						Data:          data,
+						CommitteeBits: electra.CommitteeBits(newBitlist(committeeCount)),
+					}
+				}
+				electraAtt.CommitteeBits.SetBit(index, true)
+				electraBits = append(electraBits, bits...)
+				electraAttesters = append(electraAttesters, attesters...)
+				continue
+			}
+			committeeData := data
+			committeeData.Index = common.CommitteeIndex(index)
+			sig, err := s.aggregateSign(state, attesters, common.ComputeSigningRoot(committeeData.HashTreeRoot(tree.GetHashFn()), domain))
+			if err != nil {
+				return err
+			}
+			aggBits := phase0.AttestationBits(newBitlist(uint64(len(bits))))
+			for i, b := range bits {
+				aggBits.SetBit(uint64(i), b)
+			}
+			parts.attestations = append(parts.attestations, phase0.Attestation{
+				AggregationBits: aggBits,
+				Data:            committeeData,
+				Signature:       sig,
+			})
+		}
+		if electraAtt != nil {
+			electraAtt.AggregationBits = electra.AttestationBitsElectra(newBitlist(uint64(len(electraBits))))
+			for i, b := range electraBits {
+				electraAtt.AggregationBits.SetBit(uint64(i), b)
+			}
+			electraAtt.Signature, err = s.aggregateSign(state, electraAttesters, common.ComputeSigningRoot(data.HashTreeRoot(tree.GetHashFn()), domain))
+			if err != nil {
+				return err
+			}
+			parts.attestationsElectra = append(parts.attestationsElectra, *electraAtt)
+		}
+	}
+	// Keep the latest attestations if there are too many
+	if x := uint64(len(parts.attestations)); x > uint64(spec.MAX_ATTESTATIONS) {
+		parts.attestations = parts.attestations[x-uint64(spec.MAX_ATTESTATIONS):]
+	}
+	if x := uint64(len(parts.attestationsElectra)); x > uint64(spec.MAX_ATTESTATIONS_ALPACA) {
+		parts.attestationsElectra = parts.attestationsElectra[x-uint64(spec.MAX_ATTESTATIONS_ALPACA):]
+	}
+	return nil
+}
+
+// newBitlist creates a SSZ bitlist of the given length, with all bits unset.
+func newBitlist(length uint64) []byte {
+	out := make([]byte, length/8+1)
+	out[length/8] = 1 << (length % 8)
+	return out
+}
+
+// executionPayload builds a payload on top of the latest payload in the state, with the expected withdrawals.
+// The execution engine is not simulated: the payload is empty, and its block hash is derived from the parent hash.
+func (s *Simulator) executionPayload(state common.BeaconState, fork beacon.ForkName, slot common.Slot) (*deneb.ExecutionPayload, error) {
+	var parentHash common.Hash32
+	var parentNumber Uint64View
+	switch st := state.(type) {
+	case *bellatrix.BeaconStateView:
+		h, err := st.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := h.Raw()
+		if err != nil {
+			return nil, err
+		}
+		parentHash, parentNumber = raw.BlockHash, raw.BlockNumber
+	case *capella.BeaconStateView:
+		h, err := st.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := h.Raw()
+		if err != nil {
+			return nil, err
+		}
+		parentHash, parentNumber = raw.BlockHash, raw.BlockNumber
+	case *deneb.BeaconStateView:
+		h, err := st.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := h.Raw()
+		if err != nil {
+			return nil, err
+		}
+		parentHash, parentNumber = raw.BlockHash, raw.BlockNumber
+	case *electra.BeaconStateView:
+		h, err := st.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := h.Raw()
+		if err != nil {
+			return nil, err
+		}
+		parentHash, parentNumber = raw.BlockHash, raw.BlockNumber
+	default:
+		return nil, fmt.Errorf("state type %T has no execution payload", state)
+	}
+	mixes, err := state.RandaoMixes()
+	if err != nil {
+		return nil, err
+	}
+	prevRandao, err := mixes.GetRandomMix(s.spec.SlotToEpoch(slot))
+	if err != nil {
+		return nil, err
+	}
+	genesisTime, err := state.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := s.spec.TimeAtSlot(slot, genesisTime)
+	if err != nil {
+		return nil, err
+	}
+	var withdrawals common.Withdrawals
+	if forkAtLeast(fork, beacon.Electra) {
+		wst, ok := state.(electra.BeaconStateWithWithdrawals)
+		if !ok {
+			return nil, fmt.Errorf("state type %T has no Electra withdrawals", state)
+		}
+		withdrawals, _, err = electra.GetExpectedWithdrawals(wst, s.spec)
+		if err != nil {
+			return nil, err
+		}
+	} else if forkAtLeast(fork, beacon.Capella) {
+		wst, ok := state.(capella.BeaconStateWithWithdrawals)
+		if !ok {
+			return nil, fmt.Errorf("state type %T has no withdrawals", state)
+		}
+		withdrawals, err = capella.GetExpectedWithdrawals(wst, s.spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var preimage [32 + 8]byte
+	copy(preimage[:32], parentHash[:])
+	binary.LittleEndian.PutUint64(preimage[32:], uint64(slot))
+	return &deneb.ExecutionPayload{
+		ParentHash:  parentHash,
+		PrevRandao:  prevRandao,
+		BlockNumber: parentNumber + 1,
+		GasLimit:    30_000_000,
+		Timestamp:   timestamp,
+		BlockHash:   sha256.Sum256(preimage[:]),
+		Withdrawals: withdrawals,
+	}, nil
+}
+
+// signedBlock converts the parts into a signed block of the given fork.
+func (p *blockParts) signedBlock(fork beacon.ForkName, stateRoot common.Root, sig common.BLSSignature) (beacon.OpaqueBlock, error) {
+	switch fork {
+	case beacon.Phase0:
+		return &phase0.SignedBeaconBlock{
+			Message: phase0.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: phase0.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: p.attesterSlashings,
+					Attestations:      p.attestations,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+				},
+			},
+			Signature: sig,
+		}, nil
+	case beacon.Altair:
+		return &altair.SignedBeaconBlock{
+			Message: altair.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: altair.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: p.attesterSlashings,
+					Attestations:      p.attestations,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+				},
+			},
+			Signature: sig,
+		}, nil
+	case beacon.Bellatrix:
+		return &bellatrix.SignedBeaconBlock{
+			Message: bellatrix.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: bellatrix.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: p.attesterSlashings,
+					Attestations:      p.attestations,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+					ExecutionPayload: bellatrix.ExecutionPayload{
+						ParentHash:    p.payload.ParentHash,
+						FeeRecipient:  p.payload.FeeRecipient,
+						StateRoot:     p.payload.StateRoot,
+						ReceiptsRoot:  p.payload.ReceiptsRoot,
+						LogsBloom:     p.payload.LogsBloom,
+						PrevRandao:    p.payload.PrevRandao,
+						BlockNumber:   p.payload.BlockNumber,
+						GasLimit:      p.payload.GasLimit,
+						GasUsed:       p.payload.GasUsed,
+						Timestamp:     p.payload.Timestamp,
+						ExtraData:     p.payload.ExtraData,
+						BaseFeePerGas: p.payload.BaseFeePerGas,
+						BlockHash:     p.payload.BlockHash,
+						Transactions:  p.payload.Transactions,
+					},
+				},
+			},
+			Signature: sig,
+		}, nil
+	case beacon.Capella:
+		return &capella.SignedBeaconBlock{
+			Message: capella.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: capella.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: p.attesterSlashings,
+					Attestations:      p.attestations,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+					ExecutionPayload: capella.ExecutionPayload{
+						ParentHash:    p.payload.ParentHash,
+						FeeRecipient:  p.payload.FeeRecipient,
+						StateRoot:     p.payload.StateRoot,
+						ReceiptsRoot:  p.payload.ReceiptsRoot,
+						LogsBloom:     p.payload.LogsBloom,
+						PrevRandao:    p.payload.PrevRandao,
+						BlockNumber:   p.payload.BlockNumber,
+						GasLimit:      p.payload.GasLimit,
+						GasUsed:       p.payload.GasUsed,
+						Timestamp:     p.payload.Timestamp,
+						ExtraData:     p.payload.ExtraData,
+						BaseFeePerGas: p.payload.BaseFeePerGas,
+						BlockHash:     p.payload.BlockHash,
+						Transactions:  p.payload.Transactions,
+						Withdrawals:   p.payload.Withdrawals,
+					},
+				},
+			},
+			Signature: sig,
+		}, nil
+	case beacon.Deneb:
+		return &deneb.SignedBeaconBlock{
+			Message: deneb.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: deneb.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: p.attesterSlashings,
+					Attestations:      p.attestations,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+					ExecutionPayload:  *p.payload,
+				},
+			},
+			Signature: sig,
+		}, nil
+	case beacon.Electra:
+		attesterSlashings := make(electra.AttesterSlashingsElectra, 0, len(p.attesterSlashings))
+		for _, sl := range p.attesterSlashings {
+			attesterSlashings = append(attesterSlashings, electra.AttesterSlashingElectra{
+				Attestation1: electraIndexed(&sl.Attestation1),
+				Attestation2: electraIndexed(&sl.Attestation2),
+			})
+		}
+		return &electra.SignedBeaconBlock{
+			Message: electra.BeaconBlock{
+				Slot:          p.slot,
+				ProposerIndex: p.proposer,
+				ParentRoot:    p.parentRoot,
+				StateRoot:     stateRoot,
+				Body: electra.BeaconBlockBody{
+					RandaoReveal:      p.randaoReveal,
+					Eth1Data:          p.eth1Data,
+					Graffiti:          p.graffiti,
+					ProposerSlashings: p.proposerSlashings,
+					AttesterSlashings: attesterSlashings,
+					Attestations:      p.attestationsElectra,
+					Deposits:          p.deposits,
+					VoluntaryExits:    p.voluntaryExits,
+					ExecutionPayload:  *p.payload,
+				},
+			},
+			Signature: sig,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized fork: %q", fork)
+	}
+}
+
+func electraIndexed(att *phase0.IndexedAttestation) electra.IndexedAttestationElectra {
+	return electra.IndexedAttestationElectra{
+		AttestingIndices: electra.CommitteeIndicesElectra(att.AttestingIndices),
+		Data:             att.Data,
+		Signature:        att.Signature,
+	}
+}
@@ -0,0 +1,89 @@
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/codec"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// TestCaseMeta is the meta.yaml of a blocks test case, as used by the sanity/blocks, finality and transition spec tests.
+type TestCaseMeta struct {
+	BlocksCount uint64 `yaml:"blocks_count"`
+	// Transition tests only: the fork of the post-state, and the epoch it activated at.
+	PostFork  beacon.ForkName `yaml:"post_fork,omitempty"`
+	ForkEpoch *uint64         `yaml:"fork_epoch,omitempty"`
+}
+
+// WriteTestCase writes the chain from the pre block up to and including the post block to the directory,
+// in the format of the spec tests: pre.ssz_snappy, blocks_<i>.ssz_snappy, post.ssz_snappy and meta.yaml.
+// If the chain crosses a fork boundary, the meta includes the post fork and its epoch, like the transition tests.
+func (s *Simulator) WriteTestCase(dir string, pre common.Root, post common.Root) error {
+	blocks, err := s.Chain(pre, post)
+	if err != nil {
+		return err
+	}
+	preState, err := s.State(pre)
+	if err != nil {
+		return err
+	}
+	postState, err := s.State(post)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := writeSSZSnappy(filepath.Join(dir, "pre.ssz_snappy"), preState.Serialize); err != nil {
+		return err
+	}
+	for i, b := range blocks {
+		serialize := func(w *codec.EncodingWriter) error {
+			return b.Signed.Serialize(s.spec, w)
+		}
+		if err := writeSSZSnappy(filepath.Join(dir, fmt.Sprintf("blocks_%d.ssz_snappy", i)), serialize); err != nil {
+			return err
+		}
+	}
+	if err := writeSSZSnappy(filepath.Join(dir, "post.ssz_snappy"), postState.Serialize); err != nil {
+		return err
+	}
+	meta := TestCaseMeta{BlocksCount: uint64(len(blocks))}
+	preFork, err := beacon.StateForkName(preState)
+	if err != nil {
+		return err
+	}
+	postFork, err := beacon.StateForkName(postState)
+	if err != nil {
+		return err
+	}
+	if preFork != postFork {
+		fork, err := postState.Fork()
+		if err != nil {
+			return err
+		}
+		meta.PostFork = postFork
+		forkEpoch := uint64(fork.Epoch)
+		meta.ForkEpoch = &forkEpoch
+	}
+	data, err := yaml.Marshal(&meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "meta.yaml"), data, 0o644)
+}
+
+func writeSSZSnappy(path string, serialize func(w *codec.EncodingWriter) error) error {
+	var buf bytes.Buffer
+	if err := serialize(codec.NewEncodingWriter(&buf)); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return os.WriteFile(path, snappy.Encode(nil, buf.Bytes()), 0o644)
+}
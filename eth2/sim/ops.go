@@ -0,0 +1,270 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/interop"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+// SlashProposer queues a proposer slashing of the validator, for two conflicting headers at the slot of the next block.
+func (s *Simulator) SlashProposer(index common.ValidatorIndex) {
+	s.proposerSlashings = append(s.proposerSlashings, index)
+}
+
+// SlashAttesters queues an attester slashing of the validators, for a double vote in the epoch of the next block.
+func (s *Simulator) SlashAttesters(indices ...common.ValidatorIndex) {
+	s.attesterSlashings = append(s.attesterSlashings, append([]common.ValidatorIndex(nil), indices...))
+}
+
+// Exit queues a voluntary exit of the validator, in the epoch of the next block.
+func (s *Simulator) Exit(index common.ValidatorIndex) {
+	s.exits = append(s.exits, index)
+}
+
+// Deposit adds a deposit of a new interop validator to the simulated deposit contract, and returns its pubkey.
+// Proposers vote for the eth1 data with the deposit, and include it once the vote passes.
+func (s *Simulator) Deposit(amount common.Gwei) (common.BLSPubkey, error) {
+	data, err := s.newDeposit(amount)
+	if err != nil {
+		return common.BLSPubkey{}, err
+	}
+	return data.Pubkey, nil
+}
+
+// TopUp adds a deposit to the balance of an existing validator, as known in the head state,
+// to the simulated deposit contract.
+func (s *Simulator) TopUp(index common.ValidatorIndex, amount common.Gwei) error {
+	state := s.entries[s.head].state
+	vals, err := state.Validators()
+	if err != nil {
+		return err
+	}
+	v, err := vals.Validator(index)
+	if err != nil {
+		return err
+	}
+	pub, err := v.Pubkey()
+	if err != nil {
+		return err
+	}
+	creds, err := v.WithdrawalCredentials()
+	if err != nil {
+		return err
+	}
+	sk, ok := s.keys[pub]
+	if !ok {
+		return fmt.Errorf("no key for validator %d", index)
+	}
+	_, err = s.addDeposit(sk, creds, amount)
+	return err
+}
+
+// newDeposit creates the key of the next interop validator, and adds its deposit.
+func (s *Simulator) newDeposit(amount common.Gwei) (*common.DepositData, error) {
+	id := s.nextInteropID
+	sk, err := interop.SecretKey(id)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := blsu.SkToPk(sk)
+	if err != nil {
+		return nil, err
+	}
+	pub := common.BLSPubkey(pk.Serialize())
+	s.keys[pub] = sk
+	s.nextInteropID += 1
+	creds, err := interop.WithdrawalCredentials(common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, pub, interop.WithdrawalAddress(id))
+	if err != nil {
+		return nil, err
+	}
+	return s.addDeposit(sk, creds, amount)
+}
+
+func (s *Simulator) addDeposit(sk *blsu.SecretKey, creds common.Root, amount common.Gwei) (*common.DepositData, error) {
+	data, _, err := common.NewDepositData(s.spec, sk, creds, amount)
+	if err != nil {
+		return nil, err
+	}
+	s.deposits = append(s.deposits, *data)
+	return data, nil
+}
+
+// depositTree returns the deposit contract tree with the first count deposits.
+func (s *Simulator) depositTree(count uint64) (*phase0.DepositRootsView, error) {
+	if t, ok := s.depositTrees[count]; ok {
+		return t, nil
+	}
+	if count > uint64(len(s.deposits)) {
+		return nil, fmt.Errorf("deposit count %d is higher than the %d known deposits", count, len(s.deposits))
+	}
+	t := phase0.NewDepositRootsView()
+	hFn := tree.GetHashFn()
+	for i := uint64(0); i < count; i++ {
+		root := RootView(s.deposits[i].HashTreeRoot(hFn))
+		if err := t.Append(&root); err != nil {
+			return nil, err
+		}
+	}
+	s.depositTrees[count] = t
+	return t, nil
+}
+
+// eth1Vote is the eth1 data that includes all known deposits.
+func (s *Simulator) eth1Vote() (common.Eth1Data, error) {
+	count := uint64(len(s.deposits))
+	t, err := s.depositTree(count)
+	if err != nil {
+		return common.Eth1Data{}, err
+	}
+	return common.Eth1Data{
+		DepositRoot:  t.HashTreeRoot(tree.GetHashFn()),
+		DepositCount: common.DepositIndex(count),
+		BlockHash:    eth1BlockHash(count),
+	}, nil
+}
+
+// pendingDeposits returns the deposits, with proofs, that the state expects to be included in the next block.
+func (s *Simulator) pendingDeposits(state common.BeaconState) ([]common.Deposit, error) {
+	eth1Data, err := state.Eth1Data()
+	if err != nil {
+		return nil, err
+	}
+	depositIndex, err := state.Eth1DepositIndex()
+	if err != nil {
+		return nil, err
+	}
+	if depositIndex >= eth1Data.DepositCount {
+		return nil, nil
+	}
+	count := uint64(eth1Data.DepositCount - depositIndex)
+	if count > uint64(s.spec.MAX_DEPOSITS) {
+		count = uint64(s.spec.MAX_DEPOSITS)
+	}
+	t, err := s.depositTree(uint64(eth1Data.DepositCount))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]common.Deposit, 0, count)
+	for i := uint64(depositIndex); i < uint64(depositIndex)+count; i++ {
+		proof, err := merkle.ProveView(t, i)
+		if err != nil {
+			return nil, err
+		}
+		if len(proof.Branch) != len(common.DepositProof{}) {
+			return nil, fmt.Errorf("unexpected deposit proof length %d", len(proof.Branch))
+		}
+		dep := common.Deposit{Data: s.deposits[i]}
+		copy(dep.Proof[:], proof.Branch)
+		out = append(out, dep)
+	}
+	return out, nil
+}
+
+// proposerSlashing creates two conflicting signed headers of the validator at the given slot.
+func (s *Simulator) proposerSlashing(state common.BeaconState, index common.ValidatorIndex, slot common.Slot) (*phase0.ProposerSlashing, error) {
+	domain, err := common.GetDomain(state, common.DOMAIN_BEACON_PROPOSER, s.spec.SlotToEpoch(slot))
+	if err != nil {
+		return nil, err
+	}
+	var out phase0.ProposerSlashing
+	for i, signed := range []*common.SignedBeaconBlockHeader{&out.SignedHeader1, &out.SignedHeader2} {
+		signed.Message = common.BeaconBlockHeader{
+			Slot:          slot,
+			ProposerIndex: index,
+			BodyRoot:      common.Root{byte(i + 1)},
+		}
+		signingRoot := common.ComputeSigningRoot(signed.Message.HashTreeRoot(tree.GetHashFn()), domain)
+		signed.Signature, err = s.sign(state, index, signingRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &out, nil
+}
+
+// attesterSlashing creates two conflicting attestations (a double vote) of the validators at the given slot.
+func (s *Simulator) attesterSlashing(state common.BeaconState, indices []common.ValidatorIndex, slot common.Slot) (*phase0.AttesterSlashing, error) {
+	indices = append([]common.ValidatorIndex(nil), indices...)
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	unique := indices[:0]
+	for i, v := range indices {
+		if i == 0 || v != indices[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	source, err := state.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	epoch := s.spec.SlotToEpoch(slot)
+	domain, err := common.GetDomain(state, common.DOMAIN_BEACON_ATTESTER, epoch)
+	if err != nil {
+		return nil, err
+	}
+	var out phase0.AttesterSlashing
+	for i, att := range []*phase0.IndexedAttestation{&out.Attestation1, &out.Attestation2} {
+		att.AttestingIndices = unique
+		att.Data = phase0.AttestationData{
+			Slot:            slot,
+			BeaconBlockRoot: common.Root{byte(i + 1)},
+			Source:          source,
+			Target:          common.Checkpoint{Epoch: epoch, Root: common.Root{byte(i + 1)}},
+		}
+		signingRoot := common.ComputeSigningRoot(att.Data.HashTreeRoot(tree.GetHashFn()), domain)
+		att.Signature, err = s.aggregateSign(state, unique, signingRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &out, nil
+}
+
+// voluntaryExit creates a signed exit of the validator at the given epoch.
+func (s *Simulator) voluntaryExit(state common.BeaconState, index common.ValidatorIndex, epoch common.Epoch, capellaDomain bool) (*phase0.SignedVoluntaryExit, error) {
+	var domain common.BLSDomain
+	if capellaDomain {
+		// EIP-7044: exits are signed with the Capella domain from Deneb on
+		genesisValRoot, err := state.GenesisValidatorsRoot()
+		if err != nil {
+			return nil, err
+		}
+		domain = common.ComputeDomain(common.DOMAIN_VOLUNTARY_EXIT, s.spec.CAPELLA_FORK_VERSION, genesisValRoot)
+	} else {
+		var err error
+		domain, err = common.GetDomain(state, common.DOMAIN_VOLUNTARY_EXIT, epoch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	exit := phase0.VoluntaryExit{Epoch: epoch, ValidatorIndex: index}
+	sig, err := s.sign(state, index, common.ComputeSigningRoot(exit.HashTreeRoot(tree.GetHashFn()), domain))
+	if err != nil {
+		return nil, err
+	}
+	return &phase0.SignedVoluntaryExit{Message: exit, Signature: sig}, nil
+}
+
+// aggregateSign signs the signing root with the keys of all the validators, and aggregates the signatures.
+func (s *Simulator) aggregateSign(state common.BeaconState, indices []common.ValidatorIndex, signingRoot common.Root) (common.BLSSignature, error) {
+	sigs := make([]*blsu.Signature, 0, len(indices))
+	for _, i := range indices {
+		sk, err := s.validatorKey(state, i)
+		if err != nil {
+			return common.BLSSignature{}, err
+		}
+		sigs = append(sigs, blsu.Sign(sk, signingRoot[:]))
+	}
+	agg, err := blsu.Aggregate(sigs)
+	if err != nil {
+		return common.BLSSignature{}, err
+	}
+	return agg.Serialize(), nil
+}
@@ -0,0 +1,311 @@
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/execution"
+)
+
+// Block is a block produced by the simulator.
+type Block struct {
+	Root     common.Root
+	Fork     beacon.ForkName
+	Signed   beacon.OpaqueBlock
+	Envelope *common.BeaconBlockEnvelope
+}
+
+type entry struct {
+	// nil for the genesis entry
+	block *Block
+	// post-state of the block, never modified: it is copied before processing anything on top of it.
+	state common.BeaconState
+	epc   *common.EpochsContext
+}
+
+// Simulator builds a deterministic chain of fully signed blocks, starting from a phase0 genesis of interop validators.
+//
+// Every block is proposed by the expected proposer of its slot, on top of a chosen parent block:
+// skipped slots are slots without a block, and forks are blocks that build on the same parent.
+// A block includes the attestations of the committees since its parent, eth1 votes and the deposits they enable,
+// the queued slashings and exits, and from Bellatrix on an execution payload with the expected withdrawals.
+//
+// Blocks are applied with full validation (proposer signature, operation signatures and state root),
+// and the state upgrades through beacon.StandardUpgradeableBeaconState, at the fork epochs of the spec.
+type Simulator struct {
+	spec        *common.Spec
+	genesisRoot common.Root
+
+	keys map[common.BLSPubkey]*blsu.SecretKey
+	// deposit data of all deposits, in deposit contract order, including the genesis deposits
+	deposits      []common.DepositData
+	depositTrees  map[uint64]*phase0.DepositRootsView
+	nextInteropID uint64
+
+	entries map[common.Root]*entry
+	head    common.Root
+
+	proposerSlashings []common.ValidatorIndex
+	attesterSlashings [][]common.ValidatorIndex
+	exits             []common.ValidatorIndex
+
+	// Participation is the fraction of the committees that attests, between 0 and 1.
+	// Which validators attest is a deterministic function of the slot and validator index.
+	Participation float64
+	// Graffiti is included in every block.
+	Graffiti common.Root
+}
+
+// NewSimulator creates a simulator with a genesis state of the given number of interop validators.
+// The spec is copied, and gets a no-op execution engine if it does not have any.
+// Deposits use 0x01 withdrawal credentials, with the address derived from the validator index.
+func NewSimulator(spec *common.Spec, validatorCount uint64, genesisTime common.Timestamp) (*Simulator, error) {
+	specCopy := *spec
+	if specCopy.ExecutionEngine == nil {
+		specCopy.ExecutionEngine = execution.NoOpExecutionEngine{}
+	}
+	s := &Simulator{
+		spec:          &specCopy,
+		keys:          make(map[common.BLSPubkey]*blsu.SecretKey),
+		depositTrees:  make(map[uint64]*phase0.DepositRootsView),
+		entries:       make(map[common.Root]*entry),
+		Participation: 1,
+	}
+	deps := make([]common.Deposit, 0, validatorCount)
+	for i := uint64(0); i < validatorCount; i++ {
+		data, err := s.newDeposit(specCopy.MAX_EFFECTIVE_BALANCE)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, common.Deposit{Data: *data})
+	}
+	// Proofs are not checked: the deposit root is computed from the same deposits during genesis.
+	state, epc, err := phase0.GenesisFromEth1(s.spec, eth1BlockHash(validatorCount), 0, deps, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genesis: %w", err)
+	}
+	if err := state.SetGenesisTime(genesisTime); err != nil {
+		return nil, err
+	}
+	header, err := state.LatestBlockHeader()
+	if err != nil {
+		return nil, err
+	}
+	header.StateRoot = state.HashTreeRoot(tree.GetHashFn())
+	s.genesisRoot = header.HashTreeRoot(tree.GetHashFn())
+	s.entries[s.genesisRoot] = &entry{state: state, epc: epc}
+	s.head = s.genesisRoot
+	return s, nil
+}
+
+// Spec returns the spec of the simulated chain, with execution engine.
+func (s *Simulator) Spec() *common.Spec {
+	return s.spec
+}
+
+// GenesisRoot returns the root of the genesis block, i.e. the latest header of the genesis state.
+func (s *Simulator) GenesisRoot() common.Root {
+	return s.genesisRoot
+}
+
+// Head returns the block that ProposeBlock builds on.
+func (s *Simulator) Head() common.Root {
+	return s.head
+}
+
+// SetHead changes the block that ProposeBlock builds on, e.g. to continue on another fork.
+func (s *Simulator) SetHead(root common.Root) error {
+	if _, ok := s.entries[root]; !ok {
+		return fmt.Errorf("unknown block %s", root)
+	}
+	s.head = root
+	return nil
+}
+
+// Block returns the produced block with the given root. Genesis is not a produced block.
+func (s *Simulator) Block(root common.Root) (*Block, bool) {
+	e, ok := s.entries[root]
+	if !ok || e.block == nil {
+		return nil, false
+	}
+	return e.block, true
+}
+
+// State returns a copy of the post-state of the block with the given root, or of genesis.
+func (s *Simulator) State(root common.Root) (common.BeaconState, error) {
+	e, ok := s.entries[root]
+	if !ok {
+		return nil, fmt.Errorf("unknown block %s", root)
+	}
+	return e.state.CopyState()
+}
+
+// Chain returns the blocks after the from block, up to and including the to block, in order.
+// It errors if the from block is not an ancestor of the to block.
+func (s *Simulator) Chain(from common.Root, to common.Root) ([]*Block, error) {
+	var out []*Block
+	for root := to; root != from; {
+		e, ok := s.entries[root]
+		if !ok {
+			return nil, fmt.Errorf("unknown block %s", root)
+		}
+		if e.block == nil {
+			return nil, fmt.Errorf("block %s is not an ancestor of %s", from, to)
+		}
+		out = append(out, e.block)
+		root = e.block.Envelope.ParentRoot
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+// ProposeBlock proposes a block at the given slot on top of the head, and makes it the new head.
+// The slots between the head and the given slot are skipped.
+func (s *Simulator) ProposeBlock(ctx context.Context, slot common.Slot) (*Block, error) {
+	b, err := s.ProposeBlockOn(ctx, s.head, slot)
+	if err != nil {
+		return nil, err
+	}
+	s.head = b.Root
+	return b, nil
+}
+
+// ProposeBlockOn proposes a block at the given slot on top of the given parent block, without changing the head.
+// Queued operations are taken from the queue by the block, also if the block turns out to be invalid.
+func (s *Simulator) ProposeBlockOn(ctx context.Context, parent common.Root, slot common.Slot) (*Block, error) {
+	pre, ok := s.entries[parent]
+	if !ok {
+		return nil, fmt.Errorf("unknown parent block %s", parent)
+	}
+	parentSlot, err := pre.state.Slot()
+	if err != nil {
+		return nil, err
+	}
+	if slot <= parentSlot {
+		return nil, fmt.Errorf("block slot %d must be after parent slot %d", slot, parentSlot)
+	}
+	preState, err := pre.state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	epc := pre.epc.Clone()
+	up := &beacon.StandardUpgradeableBeaconState{BeaconState: preState}
+	if err := common.ProcessSlots(ctx, s.spec, epc, up, slot); err != nil {
+		return nil, fmt.Errorf("failed to process slots up to %d: %w", slot, err)
+	}
+	state := up.BeaconState
+	fork, err := beacon.StateForkName(state)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := s.blockParts(ctx, state, epc, fork, parent, parentSlot, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build block at slot %d: %w", slot, err)
+	}
+	forkData, err := state.Fork()
+	if err != nil {
+		return nil, err
+	}
+	genesisValRoot, err := state.GenesisValidatorsRoot()
+	if err != nil {
+		return nil, err
+	}
+	digest := common.ComputeForkDigest(forkData.CurrentVersion, genesisValRoot)
+
+	// Process the block on a copy of the state, to compute the state root
+	block, err := parts.signedBlock(fork, common.Root{}, common.BLSSignature{})
+	if err != nil {
+		return nil, err
+	}
+	trial, err := state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := trial.ProcessBlock(ctx, s.spec, epc.Clone(), block.Envelope(s.spec, digest)); err != nil {
+		return nil, fmt.Errorf("failed to process block at slot %d: %w", slot, err)
+	}
+	stateRoot := trial.HashTreeRoot(tree.GetHashFn())
+	block, err = parts.signedBlock(fork, stateRoot, common.BLSSignature{})
+	if err != nil {
+		return nil, err
+	}
+	blockRoot := block.Envelope(s.spec, digest).BlockRoot
+	domain := common.ComputeDomain(common.DOMAIN_BEACON_PROPOSER, forkData.CurrentVersion, genesisValRoot)
+	sig, err := s.sign(state, parts.proposer, common.ComputeSigningRoot(blockRoot, domain))
+	if err != nil {
+		return nil, err
+	}
+	block, err = parts.signedBlock(fork, stateRoot, sig)
+	if err != nil {
+		return nil, err
+	}
+	benv := block.Envelope(s.spec, digest)
+	// Apply the final block with full validation, like any other block would be
+	if _, err := common.PostSlotTransition(ctx, s.spec, epc, state, benv, true); err != nil {
+		return nil, fmt.Errorf("produced invalid block at slot %d: %w", slot, err)
+	}
+	b := &Block{Root: benv.BlockRoot, Fork: fork, Signed: block, Envelope: benv}
+	s.entries[b.Root] = &entry{block: b, state: state, epc: epc}
+	return b, nil
+}
+
+// sign signs the signing root with the key of the validator.
+func (s *Simulator) sign(state common.BeaconState, index common.ValidatorIndex, signingRoot common.Root) (common.BLSSignature, error) {
+	sk, err := s.validatorKey(state, index)
+	if err != nil {
+		return common.BLSSignature{}, err
+	}
+	return blsu.Sign(sk, signingRoot[:]).Serialize(), nil
+}
+
+func (s *Simulator) validatorKey(state common.BeaconState, index common.ValidatorIndex) (*blsu.SecretKey, error) {
+	vals, err := state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	v, err := vals.Validator(index)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := v.Pubkey()
+	if err != nil {
+		return nil, err
+	}
+	sk, ok := s.keys[pub]
+	if !ok {
+		return nil, fmt.Errorf("no key for validator %d", index)
+	}
+	return sk, nil
+}
+
+// participates decides deterministically if the validator attests at the given slot.
+func (s *Simulator) participates(slot common.Slot, index common.ValidatorIndex) bool {
+	if s.Participation >= 1 {
+		return true
+	}
+	if s.Participation <= 0 {
+		return false
+	}
+	var preimage [16]byte
+	binary.LittleEndian.PutUint64(preimage[:8], uint64(slot))
+	binary.LittleEndian.PutUint64(preimage[8:], uint64(index))
+	h := sha256.Sum256(preimage[:])
+	return float64(binary.LittleEndian.Uint64(h[:8])) < s.Participation*(1<<64)
+}
+
+// eth1BlockHash is the simulated eth1 block hash of the block that includes the given number of deposits.
+func eth1BlockHash(depositCount uint64) (out common.Root) {
+	var preimage [8]byte
+	binary.LittleEndian.PutUint64(preimage[:], depositCount)
+	return sha256.Sum256(append([]byte("eth1"), preimage[:]...))
+}
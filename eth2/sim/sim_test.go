@@ -0,0 +1,383 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/protolambda/ztyp/tree"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/execution"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forkchoice/proto"
+)
+
+func forkSpec() *common.Spec {
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_EPOCH = 2
+	spec.CAPELLA_FORK_EPOCH = 3
+	spec.DENEB_FORK_EPOCH = 4
+	spec.ALPACA_FORK_EPOCH = 5
+	// allow exits right away
+	spec.SHARD_COMMITTEE_PERIOD = 0
+	return &spec
+}
+
+func TestSimulatorForks(t *testing.T) {
+	ctx := context.Background()
+	spec := forkSpec()
+	s, err := NewSimulator(spec, 64, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Participation = 0.9
+	depositPub, err := s.Deposit(spec.MAX_EFFECTIVE_BALANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := spec.SLOTS_PER_EPOCH * 6
+	for slot := common.Slot(1); slot <= end; slot++ {
+		// skip a slot in every epoch, including the first slot of the Deneb epoch
+		if slot%spec.SLOTS_PER_EPOCH == 3 || slot == spec.SLOTS_PER_EPOCH*4 {
+			continue
+		}
+		switch slot {
+		case 4:
+			s.SlashProposer(1)
+		case 12:
+			s.SlashAttesters(2, 3)
+		case 20:
+			s.Exit(4)
+		case spec.SLOTS_PER_EPOCH*5 + 1:
+			s.SlashAttesters(5)
+		}
+		b, err := s.ProposeBlock(ctx, slot)
+		if err != nil {
+			t.Fatalf("slot %d: %v", slot, err)
+		}
+		if expected := beacon.ForkNameAtSlot(spec, slot); b.Fork != expected {
+			t.Fatalf("slot %d: expected %s block, got %s", slot, expected, b.Fork)
+		}
+	}
+	state, err := s.State(s.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork, err := beacon.StateForkName(state); err != nil || fork != beacon.Electra {
+		t.Fatalf("expected electra state, got %s (%v)", fork, err)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := vals.ValidatorCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 65 {
+		t.Fatalf("expected deposit to be included, got %d validators", count)
+	}
+	v, err := vals.Validator(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, err := v.Pubkey(); err != nil || pub != depositPub {
+		t.Fatalf("unexpected pubkey of deposited validator: %s", pub)
+	}
+	for _, i := range []common.ValidatorIndex{1, 2, 3, 5} {
+		v, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slashed, err := v.Slashed(); err != nil || !slashed {
+			t.Fatalf("expected validator %d to be slashed", i)
+		}
+	}
+	v, err = vals.Validator(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exit, err := v.ExitEpoch(); err != nil || exit == common.FAR_FUTURE_EPOCH {
+		t.Fatalf("expected validator 4 to exit")
+	}
+	if justified, err := state.CurrentJustifiedCheckpoint(); err != nil || justified.Epoch == 0 {
+		t.Fatalf("expected the chain to justify, got %v", justified)
+	}
+
+	dir := t.TempDir()
+	if err := s.WriteTestCase(dir, s.GenesisRoot(), s.Head()); err != nil {
+		t.Fatal(err)
+	}
+	replay(t, s, dir, state.HashTreeRoot(tree.GetHashFn()))
+}
+
+func TestSimulatorElectraBlocks(t *testing.T) {
+	ctx := context.Background()
+	spec := *configs.Minimal
+	spec.ALTAIR_FORK_EPOCH = 1
+	spec.BELLATRIX_FORK_EPOCH = 1
+	spec.CAPELLA_FORK_EPOCH = 1
+	spec.DENEB_FORK_EPOCH = 1
+	spec.ALPACA_FORK_EPOCH = 1
+	s, err := NewSimulator(&spec, 64, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last *Block
+	for slot := common.Slot(1); slot <= spec.SLOTS_PER_EPOCH*3+1; slot++ {
+		if slot == spec.SLOTS_PER_EPOCH+2 {
+			s.SlashAttesters(6, 7)
+		}
+		last, err = s.ProposeBlock(ctx, slot)
+		if err != nil {
+			t.Fatalf("slot %d: %v", slot, err)
+		}
+	}
+	block, ok := last.Signed.(*electra.SignedBeaconBlock)
+	if !ok {
+		t.Fatalf("expected electra block, got %T", last.Signed)
+	}
+	if len(block.Message.Body.Attestations) == 0 {
+		t.Fatal("expected the electra block to include attestations")
+	}
+	state, err := s.State(s.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the electra attestations are counted for justification, and the electra attester slashing is applied
+	if justified, err := state.CurrentJustifiedCheckpoint(); err != nil || justified.Epoch < 2 {
+		t.Fatalf("expected the electra chain to justify, got %v", justified)
+	}
+	vals, err := state.Validators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []common.ValidatorIndex{6, 7} {
+		v, err := vals.Validator(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slashed, err := v.Slashed(); err != nil || !slashed {
+			t.Fatalf("expected validator %d to be slashed", i)
+		}
+	}
+
+	// the attestations limit of Electra bodies is MAX_ATTESTATIONS_ALPACA, not MAX_ATTESTATIONS
+	body := block.Message.Body
+	body.Attestations = make(electra.AttestationsElectra, spec.MAX_ATTESTATIONS_ALPACA+1)
+	if err := body.CheckLimits(&spec); err == nil {
+		t.Fatal("expected too many attestations")
+	}
+}
+
+func TestSimulatorBranches(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSimulator(configs.Minimal, 64, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.ProposeBlock(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.ProposeBlockOn(ctx, a.Root, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.ProposeBlockOn(ctx, a.Root, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Head() != a.Root {
+		t.Fatal("ProposeBlockOn must not change the head")
+	}
+	if b.Envelope.ParentRoot != a.Root || c.Envelope.ParentRoot != a.Root {
+		t.Fatal("expected both branches to build on the first block")
+	}
+	if err := s.SetHead(b.Root); err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.ProposeBlock(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := s.Chain(s.GenesisRoot(), d.Root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 3 || chain[0] != a || chain[1] != b || chain[2] != d {
+		t.Fatal("unexpected chain")
+	}
+	if _, err := s.Chain(c.Root, d.Root); err == nil {
+		t.Fatal("expected error for a block on another branch")
+	}
+}
+
+// replay reads a written test case and applies its blocks, like the spec test runners do.
+func replay(t *testing.T, s *Simulator, dir string, expectedPost common.Root) {
+	t.Helper()
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := snappy.Decode(nil, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	metaData, err := os.ReadFile(filepath.Join(dir, "meta.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta TestCaseMeta
+	if err := yaml.Unmarshal(metaData, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.PostFork != beacon.Electra || meta.ForkEpoch == nil || *meta.ForkEpoch != 5 {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+	spec := s.Spec()
+	pre, err := beacon.DecodeBeaconState(spec, read("pre.ssz_snappy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(spec, pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &beacon.StandardUpgradeableBeaconState{BeaconState: pre}
+	for i := uint64(0); i < meta.BlocksCount; i++ {
+		block, _, err := beacon.DecodeSignedBeaconBlock(spec, read(fmt.Sprintf("blocks_%d.ssz_snappy", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		slot := block.Envelope(spec, common.ForkDigest{}).Slot
+		if err := common.ProcessSlots(context.Background(), spec, epc, state, slot); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		// the decoded envelope has no fork digest, it depends on the state
+		fork, err := state.Fork()
+		if err != nil {
+			t.Fatal(err)
+		}
+		genesisValRoot, err := state.GenesisValidatorsRoot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		benv := block.Envelope(spec, common.ComputeForkDigest(fork.CurrentVersion, genesisValRoot))
+		if _, err := common.PostSlotTransition(context.Background(), spec, epc, state, benv, true); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+	}
+	if root := state.HashTreeRoot(tree.GetHashFn()); root != expectedPost {
+		t.Fatalf("replay ended at state %s, expected %s", root, expectedPost)
+	}
+	post, err := beacon.DecodeBeaconState(spec, read("post.ssz_snappy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := post.HashTreeRoot(tree.GetHashFn()); root != expectedPost {
+		t.Fatalf("post state %s does not match %s", root, expectedPost)
+	}
+}
+
+// syncingEngine reports every payload as SYNCING, like an execution engine that is still syncing.
+type syncingEngine struct {
+	execution.NoOpExecutionEngine
+}
+
+func (syncingEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (common.PayloadStatus, error) {
+	return common.PayloadSyncing, nil
+}
+
+func (syncingEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (common.PayloadStatus, error) {
+	return common.PayloadSyncing, nil
+}
+
+func (syncingEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (common.PayloadStatus, error) {
+	return common.PayloadSyncing, nil
+}
+
+func TestSimulatorOptimisticImport(t *testing.T) {
+	ctx := context.Background()
+	spec := forkSpec()
+	s, err := NewSimulator(spec, 64, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := spec.SLOTS_PER_EPOCH * 5
+	for slot := common.Slot(1); slot <= end; slot++ {
+		if _, err := s.ProposeBlock(ctx, slot); err != nil {
+			t.Fatalf("slot %d: %v", slot, err)
+		}
+	}
+	blocks, err := s.Chain(s.GenesisRoot(), s.Head())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// import the chain with an execution engine that is still syncing
+	syncingSpec := *s.Spec()
+	syncingSpec.ExecutionEngine = syncingEngine{}
+	genesis, err := s.State(s.GenesisRoot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre, err := genesis.CopyState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	epc, err := common.NewEpochsContext(&syncingSpec, pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &beacon.StandardUpgradeableBeaconState{BeaconState: pre}
+	anchor := common.Checkpoint{Root: s.GenesisRoot()}
+	fc, err := proto.NewProtoForkChoice(&syncingSpec, anchor, anchor, s.GenesisRoot(), 0, common.Root{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		status, err := common.StateTransition(ctx, &syncingSpec, epc, state, b.Envelope, true)
+		if err != nil {
+			t.Fatalf("slot %d: %v", b.Envelope.Slot, err)
+		}
+		var blockHash common.Root
+		switch body := b.Envelope.Body.(type) {
+		case *bellatrix.BeaconBlockBody:
+			blockHash = body.ExecutionPayload.BlockHash
+		case *capella.BeaconBlockBody:
+			blockHash = body.ExecutionPayload.BlockHash
+		case *deneb.BeaconBlockBody:
+			blockHash = body.ExecutionPayload.BlockHash
+		case *electra.BeaconBlockBody:
+			blockHash = body.ExecutionPayload.BlockHash
+		}
+		if !fc.ProcessBlockWithPayload(b.Envelope.ParentRoot, b.Root, b.Envelope.Slot, 0, 0,
+			blockHash, forkchoice.ExecutionStatusFromPayload(status)) {
+			t.Fatalf("slot %d: forkchoice did not accept the block", b.Envelope.Slot)
+		}
+		optimistic, ok := fc.IsOptimistic(b.Root)
+		if !ok {
+			t.Fatalf("slot %d: block unknown to the forkchoice", b.Envelope.Slot)
+		}
+		// blocks of Bellatrix and later forks have a payload, the engine cannot validate it yet
+		if expected := forkAtLeast(b.Fork, beacon.Bellatrix); optimistic != expected || status.IsOptimistic() != expected {
+			t.Fatalf("slot %d (%s): expected optimistic %v, got %v (payload %s)", b.Envelope.Slot, b.Fork, expected, optimistic, status)
+		}
+	}
+}
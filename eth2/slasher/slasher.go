@@ -0,0 +1,227 @@
+package slasher
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/ztyp/tree"
+)
+
+// AttesterSlashingSink receives detected attester slashings, e.g. the pool.AttesterSlashingPool
+type AttesterSlashingSink interface {
+	AddAttesterSlashing(ctx context.Context, sl *phase0.AttesterSlashing) error
+}
+
+// ProposerSlashingSink receives detected proposer slashings, e.g. the pool.ProposerSlashingPool
+type ProposerSlashingSink interface {
+	AddProposerSlashing(ctx context.Context, sl *phase0.ProposerSlashing) error
+}
+
+type voteKey struct {
+	validator common.ValidatorIndex
+	target    common.Epoch
+}
+
+// vote is the first observed vote of a validator for a target epoch.
+type vote struct {
+	dataRoot common.Root
+	// the attestation that the vote was first observed in, which includes the validator.
+	// Attestations are shared between the votes of all validators that they recorded a vote for.
+	att *phase0.IndexedAttestation
+}
+
+type proposalKey struct {
+	proposer common.ValidatorIndex
+	slot     common.Slot
+}
+
+// Slasher detects double votes, surround votes and double proposals in the attestations and block headers it observes,
+// and adds the resulting slashings to the sinks.
+// Messages are expected to be valid (signatures checked) before they are passed to the slasher.
+//
+// Attestations are tracked for the last HistoryLength epochs: surround votes are detected with
+// compact min-max span arrays per validator, and double votes with the data root of the vote per validator and target.
+// Every vote keeps the attestation it was observed in, so the slashing of a validator
+// is always built from an earlier attestation that includes that validator.
+type Slasher struct {
+	sync.Mutex
+	spec    *common.Spec
+	history common.Epoch
+
+	attesterSink AttesterSlashingSink
+	proposerSink ProposerSlashingSink
+
+	// highest target epoch observed, the history window ends here.
+	latest common.Epoch
+
+	spans map[common.ValidatorIndex]*validatorSpans
+	// (validator, target) -> first observed vote
+	votes map[voteKey]vote
+	// (proposer, slot) -> first observed header
+	proposals map[proposalKey]*common.SignedBeaconBlockHeader
+}
+
+// NewSlasher creates a slasher that tracks the given number of epochs of history.
+// Slashings are added to the sinks, either may be nil to not detect that kind of slashing.
+func NewSlasher(spec *common.Spec, history common.Epoch,
+	attesterSink AttesterSlashingSink, proposerSink ProposerSlashingSink) (*Slasher, error) {
+	if history == 0 || history >= math.MaxUint16 {
+		return nil, fmt.Errorf("history length must be between 0 and %d epochs, got %d", math.MaxUint16, history)
+	}
+	return &Slasher{
+		spec:         spec,
+		history:      history,
+		attesterSink: attesterSink,
+		proposerSink: proposerSink,
+		spans:        make(map[common.ValidatorIndex]*validatorSpans),
+		votes:        make(map[voteKey]vote),
+		proposals:    make(map[proposalKey]*common.SignedBeaconBlockHeader),
+	}, nil
+}
+
+// inWindow checks if the epoch is within the history of the slasher.
+func (s *Slasher) inWindow(epoch common.Epoch) bool {
+	return epoch+s.history > s.latest
+}
+
+// OnAttestation checks the attesting validators for double and surround votes against the previously observed attestations,
+// and records the votes. The detected slashings are added to the attester slashing sink, and returned.
+// At most one slashing is created per conflicting attestation, covering all the validators that are slashable with it.
+func (s *Slasher) OnAttestation(ctx context.Context, att *phase0.IndexedAttestation) ([]*phase0.AttesterSlashing, error) {
+	source, target := att.Data.Source.Epoch, att.Data.Target.Epoch
+	if source > target {
+		return nil, fmt.Errorf("attestation source epoch %d is after target epoch %d", source, target)
+	}
+	if target-source >= s.history {
+		return nil, fmt.Errorf("attestation source epoch %d is too far behind target epoch %d for history of %d epochs",
+			source, target, s.history)
+	}
+	dataRoot := att.Data.HashTreeRoot(tree.GetHashFn())
+
+	s.Lock()
+	if target > s.latest {
+		s.latest = target
+	}
+	if !s.inWindow(target) {
+		s.Unlock()
+		return nil, fmt.Errorf("attestation target epoch %d is outside of the history window", target)
+	}
+	var conflicts []*phase0.IndexedAttestation
+	seen := make(map[*phase0.IndexedAttestation]struct{})
+	addConflict := func(prev *phase0.IndexedAttestation) {
+		if _, ok := seen[prev]; !ok {
+			seen[prev] = struct{}{}
+			conflicts = append(conflicts, prev)
+		}
+	}
+	for _, vi := range att.AttestingIndices {
+		key := voteKey{validator: vi, target: target}
+		if prev, ok := s.votes[key]; ok {
+			if prev.dataRoot != dataRoot {
+				addConflict(prev.att)
+			}
+			// the spans already include a vote with the same source and target
+			continue
+		}
+		s.votes[key] = vote{dataRoot: dataRoot, att: att}
+
+		spans, ok := s.spans[vi]
+		if !ok {
+			spans = newValidatorSpans(s.history)
+			s.spans[vi] = spans
+		}
+		spans.advance(target)
+		if t, ok := spans.surrounding(source, target); ok {
+			if prev, ok := s.votes[voteKey{validator: vi, target: t}]; ok {
+				addConflict(prev.att)
+			}
+		}
+		if t, ok := spans.surroundedBy(source, target); ok {
+			if prev, ok := s.votes[voteKey{validator: vi, target: t}]; ok {
+				addConflict(prev.att)
+			}
+		}
+		spans.update(source, target)
+	}
+	var slashings []*phase0.AttesterSlashing
+	for _, prev := range conflicts {
+		sl := &phase0.AttesterSlashing{Attestation1: *prev, Attestation2: *att}
+		// the surrounding attestation comes first
+		if phase0.IsSurroundVote(&att.Data, &prev.Data) {
+			sl.Attestation1, sl.Attestation2 = *att, *prev
+		}
+		slashings = append(slashings, sl)
+	}
+	s.Unlock()
+
+	if s.attesterSink != nil {
+		for _, sl := range slashings {
+			if err := s.attesterSink.AddAttesterSlashing(ctx, sl); err != nil {
+				return slashings, fmt.Errorf("failed to add attester slashing: %w", err)
+			}
+		}
+	}
+	return slashings, nil
+}
+
+// OnBlockHeader checks if the proposer already proposed a different block at the same slot,
+// and records the proposal. A detected slashing is added to the proposer slashing sink, and returned.
+func (s *Slasher) OnBlockHeader(ctx context.Context, header *common.SignedBeaconBlockHeader) (*phase0.ProposerSlashing, error) {
+	epoch := s.spec.SlotToEpoch(header.Message.Slot)
+	s.Lock()
+	if epoch > s.latest {
+		s.latest = epoch
+	}
+	if !s.inWindow(epoch) {
+		s.Unlock()
+		return nil, fmt.Errorf("block header slot %d is outside of the history window", header.Message.Slot)
+	}
+	key := proposalKey{proposer: header.Message.ProposerIndex, slot: header.Message.Slot}
+	prev, ok := s.proposals[key]
+	if !ok {
+		s.proposals[key] = header
+		s.Unlock()
+		return nil, nil
+	}
+	s.Unlock()
+	hFn := tree.GetHashFn()
+	if prev.Message.HashTreeRoot(hFn) == header.Message.HashTreeRoot(hFn) {
+		return nil, nil
+	}
+	sl := &phase0.ProposerSlashing{SignedHeader1: *prev, SignedHeader2: *header}
+	if s.proposerSink != nil {
+		if err := s.proposerSink.AddProposerSlashing(ctx, sl); err != nil {
+			return sl, fmt.Errorf("failed to add proposer slashing: %w", err)
+		}
+	}
+	return sl, nil
+}
+
+// Prune removes the votes and proposals that are outside of the history window,
+// as if an attestation for the given epoch was observed.
+func (s *Slasher) Prune(epoch common.Epoch) {
+	s.Lock()
+	defer s.Unlock()
+	if epoch > s.latest {
+		s.latest = epoch
+	}
+	for key := range s.votes {
+		if !s.inWindow(key.target) {
+			delete(s.votes, key)
+		}
+	}
+	for key := range s.proposals {
+		if !s.inWindow(s.spec.SlotToEpoch(key.slot)) {
+			delete(s.proposals, key)
+		}
+	}
+	for vi, spans := range s.spans {
+		if !s.inWindow(spans.latest) {
+			delete(s.spans, vi)
+		}
+	}
+}
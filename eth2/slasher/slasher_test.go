@@ -0,0 +1,193 @@
+package slasher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/pool"
+)
+
+func attestation(source, target common.Epoch, root common.Root, indices ...common.ValidatorIndex) *phase0.IndexedAttestation {
+	return &phase0.IndexedAttestation{
+		AttestingIndices: indices,
+		Data: phase0.AttestationData{
+			Slot:            common.Slot(target) * 8,
+			BeaconBlockRoot: root,
+			Source:          common.Checkpoint{Epoch: source},
+			Target:          common.Checkpoint{Epoch: target},
+		},
+	}
+}
+
+func TestSlasherAttestations(t *testing.T) {
+	spec := configs.Minimal
+	attPool := pool.NewAttesterSlashingPool(spec)
+	s, err := NewSlasher(spec, 16, attPool, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	expect := func(att *phase0.IndexedAttestation, count int) []*phase0.AttesterSlashing {
+		t.Helper()
+		out, err := s.OnAttestation(ctx, att)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != count {
+			t.Fatalf("expected %d slashings, got %d", count, len(out))
+		}
+		for _, sl := range out {
+			if !phase0.IsSlashableAttestationData(&sl.Attestation1.Data, &sl.Attestation2.Data) {
+				t.Fatalf("slashing is not slashable: %v vs %v", sl.Attestation1.Data, sl.Attestation2.Data)
+			}
+		}
+		return out
+	}
+	expect(attestation(2, 3, common.Root{1}, 0, 1, 2), 0)
+	expect(attestation(3, 4, common.Root{1}, 0, 1, 2), 0)
+	// repeated attestation is fine
+	expect(attestation(3, 4, common.Root{1}, 1), 0)
+
+	// double vote
+	sl := expect(attestation(3, 4, common.Root{2}, 1, 3), 1)
+	if sl[0].Attestation1.Data.BeaconBlockRoot != (common.Root{1}) {
+		t.Fatal("expected the first vote to be the first attestation")
+	}
+
+	// surrounding the earlier votes of validator 0 and 2, one slashing covers both
+	sl = expect(attestation(1, 5, common.Root{3}, 0, 2, 6), 1)
+	if sl[0].Attestation1.Data.Source.Epoch != 1 || sl[0].Attestation2.Data.Target.Epoch != 3 {
+		t.Fatal("expected surrounding attestation first")
+	}
+
+	// surrounded by the previous vote of 6
+	sl = expect(attestation(2, 4, common.Root{4}, 5, 6), 1)
+	if sl[0].Attestation1.Data.Source.Epoch != 1 || len(sl[0].Attestation2.AttestingIndices) != 2 {
+		t.Fatal("expected surrounding attestation first")
+	}
+	// validator 5 did not vote before, and is not surrounded by its own vote
+	expect(attestation(10, 11, common.Root{5}, 5), 0)
+
+	if len(attPool.All()) != 3 {
+		t.Fatalf("expected 3 slashings in the pool, got %d", len(attPool.All()))
+	}
+
+	// move the window past the old votes
+	s.Prune(30)
+	if _, err := s.OnAttestation(ctx, attestation(2, 4, common.Root{6}, 2)); err == nil {
+		t.Fatal("expected attestation outside of window to be rejected")
+	}
+	expect(attestation(28, 30, common.Root{7}, 2), 0)
+}
+
+func TestSlasherAggregatesWithSameData(t *testing.T) {
+	spec := configs.Minimal
+	s, err := NewSlasher(spec, 16, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, att := range []*phase0.IndexedAttestation{
+		attestation(2, 3, common.Root{1}, 1, 2),
+		// same data, different validators
+		attestation(2, 3, common.Root{1}, 3),
+	} {
+		if sl, err := s.OnAttestation(ctx, att); err != nil {
+			t.Fatal(err)
+		} else if len(sl) != 0 {
+			t.Fatal("unexpected slashing")
+		}
+	}
+	// double vote of validator 3, which is only in the second aggregate
+	sl, err := s.OnAttestation(ctx, attestation(2, 3, common.Root{2}, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sl) != 1 {
+		t.Fatalf("expected 1 slashing, got %d", len(sl))
+	}
+	if indices := sl[0].Attestation1.AttestingIndices; len(indices) != 1 || indices[0] != 3 {
+		t.Fatalf("expected the earlier attestation of validator 3, got indices %v", indices)
+	}
+}
+
+func TestSpanRingCompression(t *testing.T) {
+	// a window that is not a multiple of the chunk length
+	window := common.Epoch(3*spanChunkLen + 5)
+	spans := newValidatorSpans(window)
+	expanded := func() (count int) {
+		for _, r := range []*spanRing{&spans.minSpans, &spans.maxSpans} {
+			for i := range r.chunks {
+				if r.chunks[i].values != nil {
+					count++
+				}
+			}
+		}
+		return count
+	}
+	// the first vote fills the min spans of all earlier epochs with different distances
+	spans.advance(1)
+	spans.update(0, 1)
+	for target := common.Epoch(2); target < 3*window; target++ {
+		spans.advance(target)
+		if _, ok := spans.surrounding(target-1, target); ok {
+			t.Fatalf("unexpected surrounding vote at target %d", target)
+		}
+		if _, ok := spans.surroundedBy(target-1, target); ok {
+			t.Fatalf("unexpected surrounded vote at target %d", target)
+		}
+		spans.update(target-1, target)
+	}
+	// attesting every epoch, all chunks but the ones around the latest epochs compress again
+	if count := expanded(); count > 2 {
+		t.Fatalf("expected at most 2 expanded chunks, got %d", count)
+	}
+	// surround votes are still detected
+	latest := 3*window - 1
+	if target, ok := spans.surrounding(latest-10, latest+1); !ok || target != latest-8 {
+		t.Fatalf("expected surrounded vote with target %d, got %d", latest-8, target)
+	}
+	if target, ok := spans.surroundedBy(latest-1, latest); ok {
+		t.Fatalf("unexpected surrounding vote with target %d", target)
+	}
+}
+
+func TestSlasherProposals(t *testing.T) {
+	spec := configs.Minimal
+	propPool := pool.NewProposerSlashingPool(spec)
+	s, err := NewSlasher(spec, 16, nil, propPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	header := func(proposer common.ValidatorIndex, slot common.Slot, body common.Root) *common.SignedBeaconBlockHeader {
+		return &common.SignedBeaconBlockHeader{Message: common.BeaconBlockHeader{
+			Slot: slot, ProposerIndex: proposer, BodyRoot: body,
+		}}
+	}
+	for i, h := range []*common.SignedBeaconBlockHeader{
+		header(1, 10, common.Root{1}),
+		header(1, 10, common.Root{1}),
+		header(1, 11, common.Root{2}),
+		header(2, 10, common.Root{2}),
+	} {
+		if sl, err := s.OnBlockHeader(ctx, h); err != nil {
+			t.Fatal(err)
+		} else if sl != nil {
+			t.Fatalf("header %d: unexpected slashing", i)
+		}
+	}
+	sl, err := s.OnBlockHeader(ctx, header(1, 10, common.Root{3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sl == nil || sl.SignedHeader1.Message.BodyRoot != (common.Root{1}) {
+		t.Fatal("expected proposer slashing with the first header")
+	}
+	if len(propPool.All()) != 1 {
+		t.Fatalf("expected 1 slashing in the pool, got %d", len(propPool.All()))
+	}
+}
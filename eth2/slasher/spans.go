@@ -0,0 +1,170 @@
+package slasher
+
+import (
+	"math"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+)
+
+// noMinSpan marks an epoch without any attestation with a later source epoch.
+const noMinSpan = math.MaxUint16
+
+// spanChunkLen is the number of epochs per chunk of the span ring buffers.
+const spanChunkLen = 32
+
+// spanChunk is a chunk of a span ring buffer. If all spans in the chunk are equal,
+// as they are without attestations, or with a validator attesting every epoch with the same distance between source and target,
+// then the chunk is compressed to just the fill value.
+type spanChunk struct {
+	fill   uint16
+	values *[spanChunkLen]uint16
+}
+
+// spanRing is a ring buffer of spans, indexed by epoch modulo the window length, stored in compressed chunks.
+type spanRing struct {
+	window common.Epoch
+	chunks []spanChunk
+}
+
+func newSpanRing(window common.Epoch, fill uint16) spanRing {
+	chunks := make([]spanChunk, (window+spanChunkLen-1)/spanChunkLen)
+	for i := range chunks {
+		chunks[i].fill = fill
+	}
+	return spanRing{window: window, chunks: chunks}
+}
+
+func (r *spanRing) get(epoch common.Epoch) uint16 {
+	i := epoch % r.window
+	c := &r.chunks[i/spanChunkLen]
+	if c.values == nil {
+		return c.fill
+	}
+	return c.values[i%spanChunkLen]
+}
+
+func (r *spanRing) set(epoch common.Epoch, span uint16) {
+	i := epoch % r.window
+	c := &r.chunks[i/spanChunkLen]
+	if c.values == nil {
+		if c.fill == span {
+			return
+		}
+		c.values = new([spanChunkLen]uint16)
+		for j := range c.values {
+			c.values[j] = c.fill
+		}
+	}
+	c.values[i%spanChunkLen] = span
+	// the last chunk is only partially used if the window is not a multiple of the chunk length
+	used := r.window - (i - i%spanChunkLen)
+	if used > spanChunkLen {
+		used = spanChunkLen
+	}
+	for _, v := range c.values[:used] {
+		if v != span {
+			return
+		}
+	}
+	c.fill = span
+	c.values = nil
+}
+
+// validatorSpans tracks the min and max target distances of the attestations of a single validator,
+// in ring buffers over the history window, indexed by epoch modulo the window length.
+//
+// For an epoch e:
+//   - minSpans[e] is the minimum of (target - e) over the attestations with source > e
+//   - maxSpans[e] is the maximum of (target - e) over the attestations with source < e
+//
+// An attestation (s, t) surrounds an earlier attestation if minSpans[s] < t - s,
+// and is surrounded by an earlier attestation if maxSpans[s] > t - s.
+//
+// The ring buffers are chunked, and chunks of equal spans are compressed:
+// a validator that attests every epoch, with the same distance between source and target,
+// only takes a few bytes per chunk, instead of a span per epoch of the window.
+type validatorSpans struct {
+	minSpans spanRing
+	maxSpans spanRing
+	// latest is the highest epoch covered by the ring buffers.
+	// Epochs in (latest - window, latest] are valid, all others are reset when the window moves.
+	latest common.Epoch
+}
+
+func newValidatorSpans(window common.Epoch) *validatorSpans {
+	return &validatorSpans{
+		minSpans: newSpanRing(window, noMinSpan),
+		maxSpans: newSpanRing(window, 0),
+	}
+}
+
+func (v *validatorSpans) window() common.Epoch {
+	return v.minSpans.window
+}
+
+// inWindow checks if the epoch is covered by the ring buffers.
+func (v *validatorSpans) inWindow(epoch common.Epoch) bool {
+	return epoch <= v.latest && epoch+v.window() > v.latest
+}
+
+// advance moves the window forward to cover the given epoch, resetting the spans of the epochs it moves past.
+func (v *validatorSpans) advance(epoch common.Epoch) {
+	if epoch <= v.latest {
+		return
+	}
+	w := v.window()
+	start := v.latest + 1
+	if epoch-v.latest > w {
+		start = epoch + 1 - w
+	}
+	for e := start; e <= epoch; e++ {
+		v.minSpans.set(e, noMinSpan)
+		v.maxSpans.set(e, 0)
+	}
+	v.latest = epoch
+}
+
+// surrounding returns the target epoch of an earlier attestation that is surrounded by (source, target).
+func (v *validatorSpans) surrounding(source, target common.Epoch) (common.Epoch, bool) {
+	if !v.inWindow(source) {
+		return 0, false
+	}
+	span := v.minSpans.get(source)
+	if span == noMinSpan || common.Epoch(span) >= target-source {
+		return 0, false
+	}
+	return source + common.Epoch(span), true
+}
+
+// surroundedBy returns the target epoch of an earlier attestation that surrounds (source, target).
+func (v *validatorSpans) surroundedBy(source, target common.Epoch) (common.Epoch, bool) {
+	if !v.inWindow(source) {
+		return 0, false
+	}
+	span := v.maxSpans.get(source)
+	if common.Epoch(span) <= target-source {
+		return 0, false
+	}
+	return source + common.Epoch(span), true
+}
+
+// update adds the attestation (source, target) to the spans.
+// The window must already cover the target epoch.
+func (v *validatorSpans) update(source, target common.Epoch) {
+	// min spans of the epochs before the source, stop when the existing span is already smaller.
+	for e := source; e > 0 && v.inWindow(e-1); e-- {
+		dist := uint16(target - (e - 1))
+		if v.minSpans.get(e-1) <= dist {
+			break
+		}
+		v.minSpans.set(e-1, dist)
+	}
+	// max spans of the epochs between source and target, stop when the existing span is already larger.
+	for e := source + 1; e < target && v.inWindow(e); e++ {
+		dist := uint16(target - e)
+		if v.maxSpans.get(e) >= dist {
+			break
+		}
+		v.maxSpans.set(e, dist)
+	}
+}
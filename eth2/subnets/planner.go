@@ -0,0 +1,160 @@
+package subnets
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+)
+
+// AttesterDuty is the attestation duty of a validator: the committee it attests in,
+// and whether it aggregates the attestations of that committee (see phase0.IsAggregator).
+type AttesterDuty struct {
+	Slot             common.Slot
+	CommitteeIndex   common.CommitteeIndex
+	CommitteesAtSlot uint64
+	IsAggregator     bool
+}
+
+// Subscription is a short-lived subnet subscription, from the start of FromSlot until the end of UntilSlot.
+type Subscription struct {
+	Subnet    uint64
+	FromSlot  common.Slot
+	UntilSlot common.Slot
+}
+
+// EpochPlan lists the subnets to be subscribed to during an epoch.
+type EpochPlan struct {
+	Epoch common.Epoch
+	// LongLived are the subnets of the node itself, sorted.
+	LongLived []uint64
+	// ShortLived are the subscriptions for aggregation duties, on subnets that are not long-lived.
+	// Sorted by FromSlot, then subnet.
+	ShortLived []Subscription
+	// Attnets to advertise in the MetaData and ENR: only the long-lived subnets.
+	Attnets common.AttnetBits
+}
+
+// Subnets returns all the subnets of the plan, sorted and without duplicates.
+func (p *EpochPlan) Subnets() []uint64 {
+	var all common.AttnetBits
+	for _, s := range p.LongLived {
+		all.SetBit(s, true)
+	}
+	for _, s := range p.ShortLived {
+		all.SetBit(s.Subnet, true)
+	}
+	var out []uint64
+	for i := uint64(0); i < common.ATTESTATION_SUBNET_COUNT; i++ {
+		if all.GetBit(i) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Planner plans the attestation subnets of a node, for the duties of the validators attached to it.
+type Planner struct {
+	spec   *common.Spec
+	nodeID common.NodeID
+	// Number of slots to join a subnet ahead of an aggregation duty, to find peers on the subnet in time.
+	lookahead common.Slot
+}
+
+func NewPlanner(spec *common.Spec, nodeID common.NodeID, lookahead common.Slot) *Planner {
+	return &Planner{spec: spec, nodeID: nodeID, lookahead: lookahead}
+}
+
+// Plan returns the subnets of the given epoch. Duties of other epochs are ignored.
+// Duties without aggregation do not need a subscription: attestations are only published to the subnet.
+func (p *Planner) Plan(epoch common.Epoch, duties []AttesterDuty) (*EpochPlan, error) {
+	longLived, err := ComputeSubscribedSubnets(p.spec, p.nodeID, epoch)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(longLived, func(i, j int) bool {
+		return longLived[i] < longLived[j]
+	})
+	plan := &EpochPlan{
+		Epoch:     epoch,
+		LongLived: longLived,
+		Attnets:   Attnets(longLived),
+	}
+	for i, duty := range duties {
+		if !duty.IsAggregator || p.spec.SlotToEpoch(duty.Slot) != epoch {
+			continue
+		}
+		if duty.CommitteesAtSlot == 0 {
+			return nil, fmt.Errorf("duty %d: no committees at slot %d", i, duty.Slot)
+		}
+		subnet, err := phase0.ComputeSubnetForAttestation(p.spec, duty.CommitteesAtSlot, duty.Slot, duty.CommitteeIndex)
+		if err != nil {
+			return nil, fmt.Errorf("duty %d: %w", i, err)
+		}
+		from := common.Slot(0)
+		if duty.Slot > p.lookahead {
+			from = duty.Slot - p.lookahead
+		}
+		// Joining ahead may start in the previous epoch, the long-lived subnets must cover that epoch too.
+		covered := plan.Attnets.GetBit(subnet)
+		if fromEpoch := p.spec.SlotToEpoch(from); covered && fromEpoch != epoch {
+			prev, err := ComputeSubscribedSubnets(p.spec, p.nodeID, fromEpoch)
+			if err != nil {
+				return nil, err
+			}
+			prevAttnets := Attnets(prev)
+			covered = prevAttnets.GetBit(subnet)
+		}
+		if covered {
+			continue
+		}
+		plan.ShortLived = append(plan.ShortLived, Subscription{Subnet: subnet, FromSlot: from, UntilSlot: duty.Slot})
+	}
+	plan.ShortLived = mergeSubscriptions(plan.ShortLived)
+	return plan, nil
+}
+
+// PlanRange returns the plans of count epochs, starting at the given epoch.
+func (p *Planner) PlanRange(start common.Epoch, count uint64, duties []AttesterDuty) ([]EpochPlan, error) {
+	plans := make([]EpochPlan, 0, count)
+	for i := uint64(0); i < count; i++ {
+		plan, err := p.Plan(start+common.Epoch(i), duties)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, nil
+}
+
+// mergeSubscriptions joins the subscriptions to the same subnet that overlap or are adjacent.
+func mergeSubscriptions(subs []Subscription) []Subscription {
+	if len(subs) == 0 {
+		return nil
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Subnet != subs[j].Subnet {
+			return subs[i].Subnet < subs[j].Subnet
+		}
+		return subs[i].FromSlot < subs[j].FromSlot
+	})
+	out := subs[:1]
+	for _, s := range subs[1:] {
+		last := &out[len(out)-1]
+		if s.Subnet == last.Subnet && s.FromSlot <= last.UntilSlot+1 {
+			if s.UntilSlot > last.UntilSlot {
+				last.UntilSlot = s.UntilSlot
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].FromSlot != out[j].FromSlot {
+			return out[i].FromSlot < out[j].FromSlot
+		}
+		return out[i].Subnet < out[j].Subnet
+	})
+	return out
+}
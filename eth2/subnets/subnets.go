@@ -0,0 +1,59 @@
+package subnets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+)
+
+const NODE_ID_BITS = 256
+
+// ComputeSubscribedSubnet returns the index-th long-lived attestation subnet of the node at the given epoch.
+// The subnets of a node rotate every EPOCHS_PER_SUBNET_SUBSCRIPTION epochs,
+// offset by the node ID so that not all nodes rotate at the same epoch.
+func ComputeSubscribedSubnet(spec *common.Spec, nodeID common.NodeID, epoch common.Epoch, index uint64) (uint64, error) {
+	prefixBits := uint64(spec.ATTESTATION_SUBNET_PREFIX_BITS)
+	if prefixBits == 0 || prefixBits > 32 {
+		return 0, fmt.Errorf("unsupported attestation subnet prefix bits: %d", prefixBits)
+	}
+	period := uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION)
+	if period == 0 {
+		return 0, fmt.Errorf("invalid zero subnet subscription period")
+	}
+	id := new(big.Int).SetBytes(nodeID[:])
+	nodeIDPrefix := new(big.Int).Rsh(id, uint(NODE_ID_BITS-prefixBits)).Uint64()
+	nodeOffset := new(big.Int).Mod(id, new(big.Int).SetUint64(period)).Uint64()
+
+	var seedInput [8]byte
+	binary.LittleEndian.PutUint64(seedInput[:], (uint64(epoch)+nodeOffset)/period)
+	permutationSeed := hashing.Hash(seedInput[:])
+	permutatedPrefix := common.PermuteIndex(uint8(spec.SHUFFLE_ROUND_COUNT),
+		common.ValidatorIndex(nodeIDPrefix), uint64(1)<<prefixBits, permutationSeed)
+	return (uint64(permutatedPrefix) + index) % common.ATTESTATION_SUBNET_COUNT, nil
+}
+
+// ComputeSubscribedSubnets returns the SUBNETS_PER_NODE long-lived attestation subnets of the node at the given epoch.
+func ComputeSubscribedSubnets(spec *common.Spec, nodeID common.NodeID, epoch common.Epoch) ([]uint64, error) {
+	out := make([]uint64, 0, spec.SUBNETS_PER_NODE)
+	for i := uint64(0); i < uint64(spec.SUBNETS_PER_NODE); i++ {
+		subnet, err := ComputeSubscribedSubnet(spec, nodeID, epoch, i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, subnet)
+	}
+	return out, nil
+}
+
+// Attnets returns the bitfield of the given subnets, as advertised in the MetaData and ENR of a node.
+func Attnets(subnets []uint64) (out common.AttnetBits) {
+	for _, s := range subnets {
+		if s < common.ATTESTATION_SUBNET_COUNT {
+			out.SetBit(s, true)
+		}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+package subnets
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+func TestSubscribedSubnets(t *testing.T) {
+	spec := configs.Mainnet
+	// node offset 0x10: the subnets rotate when epoch + 0x10 is a multiple of EPOCHS_PER_SUBNET_SUBSCRIPTION
+	nodeID := common.NodeID{0: 0xab, 31: 0x10}
+	rotation := common.Epoch(uint64(spec.EPOCHS_PER_SUBNET_SUBSCRIPTION) - 0x10)
+	first, err := ComputeSubscribedSubnets(spec, nodeID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(first)) != uint64(spec.SUBNETS_PER_NODE) {
+		t.Fatalf("expected %d subnets, got %d", spec.SUBNETS_PER_NODE, len(first))
+	}
+	if first[1] != (first[0]+1)%common.ATTESTATION_SUBNET_COUNT {
+		t.Fatalf("expected consecutive subnets, got %v", first)
+	}
+	for epoch := common.Epoch(1); epoch < rotation; epoch++ {
+		subnets, err := ComputeSubscribedSubnets(spec, nodeID, epoch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subnets[0] != first[0] {
+			t.Fatalf("epoch %d: subnets changed before the end of the subscription period", epoch)
+		}
+	}
+	// nodes that share the prefix and the offset share their subnets
+	other := common.NodeID{0: 0xa9, 5: 0x77, 31: 0x10}
+	otherSubnets, err := ComputeSubscribedSubnets(spec, other, 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherSubnets[0] != first[0] {
+		t.Fatalf("expected shared subnets for the same prefix, got %v and %v", first, otherSubnets)
+	}
+	attnets := Attnets(first)
+	for i := uint64(0); i < common.ATTESTATION_SUBNET_COUNT; i++ {
+		if attnets.GetBit(i) != (i == first[0] || i == first[1]) {
+			t.Fatalf("unexpected attnets %s for subnets %v", attnets, first)
+		}
+	}
+}
+
+func TestPlanner(t *testing.T) {
+	spec := configs.Mainnet
+	nodeID := common.NodeID{0: 0x42}
+	planner := NewPlanner(spec, nodeID, 2)
+	epoch := common.Epoch(3)
+	longLived, err := ComputeSubscribedSubnets(spec, nodeID, epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevLongLived, err := ComputeSubscribedSubnets(spec, nodeID, epoch-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _ := spec.EpochStartSlot(epoch)
+	// with 64 committees per slot, the subnet is the committee index
+	dutyAt := func(slot common.Slot, subnet uint64, aggregator bool) AttesterDuty {
+		return AttesterDuty{
+			Slot:             slot,
+			CommitteeIndex:   common.CommitteeIndex(subnet),
+			CommitteesAtSlot: common.ATTESTATION_SUBNET_COUNT,
+			IsAggregator:     aggregator,
+		}
+	}
+	notLongLived := (longLived[1] + 10) % common.ATTESTATION_SUBNET_COUNT
+	duties := []AttesterDuty{
+		// two aggregation duties on the same subnet, merged into one subscription
+		dutyAt(start+4, notLongLived, true),
+		dutyAt(start+6, notLongLived, true),
+		// attesting without aggregation does not need a subscription
+		dutyAt(start+5, (notLongLived+1)%common.ATTESTATION_SUBNET_COUNT, false),
+		// aggregation on a long-lived subnet does not need an extra subscription
+		dutyAt(start+3, longLived[0], true),
+		// next epoch, planned separately
+		dutyAt(start+spec.SLOTS_PER_EPOCH, notLongLived, true),
+	}
+	plan, err := planner.Plan(epoch, duties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Subscription{{Subnet: notLongLived, FromSlot: start + 2, UntilSlot: start + 6}}
+	if len(plan.ShortLived) != 1 || plan.ShortLived[0] != expected[0] {
+		t.Fatalf("expected short-lived subscriptions %v, got %v", expected, plan.ShortLived)
+	}
+	if plan.Attnets != Attnets(longLived) {
+		t.Fatalf("expected only long-lived subnets to be advertised, got %s", plan.Attnets)
+	}
+	if subnets := plan.Subnets(); len(subnets) != 3 {
+		t.Fatalf("expected 3 subnets, got %v", subnets)
+	}
+
+	// joining ahead of a duty at the start of the epoch starts in the previous epoch,
+	// where the long-lived subnets of this epoch may not be subscribed to yet
+	plan, err = planner.Plan(epoch, []AttesterDuty{dutyAt(start, longLived[0], true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevAttnets := Attnets(prevLongLived)
+	if covered := prevAttnets.GetBit(longLived[0]); covered != (len(plan.ShortLived) == 0) {
+		t.Fatalf("unexpected short-lived subscriptions %v, long-lived subnets of the previous epoch: %v", plan.ShortLived, prevLongLived)
+	}
+
+	plans, err := planner.PlanRange(epoch, 2, duties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 || plans[1].Epoch != epoch+1 || len(plans[1].ShortLived) > 1 {
+		t.Fatalf("unexpected plans %v", plans)
+	}
+	if _, err := planner.Plan(epoch, []AttesterDuty{{Slot: start, IsAggregator: true}}); err == nil {
+		t.Fatal("expected error for duty without committees")
+	}
+}
@@ -0,0 +1,168 @@
+package merkle
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// LengthKey is the path element to select the length mix-in of a list type.
+const LengthKey = "__len__"
+
+// ConcatGindices concatenates generalized indices: the result points to the last gindex,
+// relative to the subtree of the previous gindex, relative to the subtree of the gindex before it, etc.
+func ConcatGindices(gindices ...tree.Gindex64) (tree.Gindex64, error) {
+	out := tree.RootGindex
+	for _, g := range gindices {
+		if g == 0 {
+			return 0, fmt.Errorf("invalid zero gindex")
+		}
+		depth := g.Depth()
+		if out.Depth()+depth >= 64 {
+			return 0, fmt.Errorf("gindex overflow: cannot concatenate %d with %d", out, g)
+		}
+		anchor := tree.Gindex64(1) << depth
+		out = out<<depth | (g ^ anchor)
+	}
+	return out, nil
+}
+
+// PathGindex resolves the path within the given type to a generalized index,
+// and returns the type of the node it points to.
+// Path elements are field names (strings) for containers, and integer indices for lists and vectors.
+// The LengthKey selects the length mix-in of a list.
+// For packed basic lists and vectors (e.g. balances), the gindex of the 32-byte chunk with the element is returned.
+// After a basic element, or the length of a list, no further path elements are accepted.
+func PathGindex(typ view.TypeDef, path ...interface{}) (tree.Gindex64, view.TypeDef, error) {
+	gindex := tree.RootGindex
+	for i, p := range path {
+		var (
+			depth uint8
+			pos   uint64
+			next  view.TypeDef
+			list  bool
+		)
+		switch t := typ.(type) {
+		case *view.ContainerTypeDef:
+			name, ok := p.(string)
+			if !ok {
+				return 0, nil, fmt.Errorf("path element %d: expected field name for container %s, got %v", i, t.ContainerName, p)
+			}
+			found := false
+			for j, f := range t.Fields {
+				if f.Name == name {
+					pos = uint64(j)
+					next = f.Type
+					found = true
+					break
+				}
+			}
+			if !found {
+				return 0, nil, fmt.Errorf("path element %d: container %s has no field %q", i, t.ContainerName, name)
+			}
+			depth = tree.CoverDepth(t.FieldCount())
+		case *view.ComplexListTypeDef:
+			if isLengthKey(p) {
+				return pathLength(gindex, i, path)
+			}
+			idx, err := pathIndex(i, p, t.Limit())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next, list = idx, t.ElementType(), true
+			depth = tree.CoverDepth(t.Limit())
+		case *view.ComplexVectorTypeDef:
+			idx, err := pathIndex(i, p, t.Length())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next = idx, t.ElementType()
+			depth = tree.CoverDepth(t.Length())
+		case *view.BasicListTypeDef:
+			if isLengthKey(p) {
+				return pathLength(gindex, i, path)
+			}
+			idx, err := pathIndex(i, p, t.Limit())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next, list = idx/t.ElementsPerBottomNode(), t.ElementType(), true
+			depth = tree.CoverDepth(t.BottomNodeLimit())
+		case *view.BasicVectorTypeDef:
+			idx, err := pathIndex(i, p, t.Length())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next = idx/t.ElementsPerBottomNode(), t.ElementType()
+			depth = tree.CoverDepth(t.BottomNodeLength())
+		case *view.BitListTypeDef:
+			if isLengthKey(p) {
+				return pathLength(gindex, i, path)
+			}
+			idx, err := pathIndex(i, p, t.Limit())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next, list = idx/256, view.BoolType, true
+			depth = tree.CoverDepth(t.BottomNodeLimit())
+		case *view.BitVectorTypeDef:
+			idx, err := pathIndex(i, p, t.Length())
+			if err != nil {
+				return 0, nil, err
+			}
+			pos, next = idx/256, view.BoolType
+			depth = tree.CoverDepth(t.BottomNodeLength())
+		default:
+			return 0, nil, fmt.Errorf("path element %d: cannot navigate into type %s", i, typ.String())
+		}
+		if list {
+			// navigate into the contents, left of the length mix-in
+			gindex = gindex << 1
+		}
+		if uint32(depth)+gindex.Depth() >= 64 {
+			return 0, nil, fmt.Errorf("path element %d: gindex overflow", i)
+		}
+		gindex = gindex<<depth | tree.Gindex64(pos)
+		// Packed basic elements are within the same chunk, the path cannot go any deeper.
+		if _, basic := next.(view.BasicTypeDef); basic && i+1 < len(path) {
+			return 0, nil, fmt.Errorf("path element %d: cannot navigate into basic type %s", i+1, next.String())
+		}
+		typ = next
+	}
+	return gindex, typ, nil
+}
+
+func isLengthKey(p interface{}) bool {
+	s, ok := p.(string)
+	return ok && s == LengthKey
+}
+
+func pathLength(gindex tree.Gindex64, i int, path []interface{}) (tree.Gindex64, view.TypeDef, error) {
+	if i+1 < len(path) {
+		return 0, nil, fmt.Errorf("path element %d: cannot navigate into list length", i+1)
+	}
+	return gindex<<1 | 1, view.Uint64Type, nil
+}
+
+func pathIndex(i int, p interface{}, limit uint64) (uint64, error) {
+	// accept any integer type, e.g. typed indices like common.ValidatorIndex
+	var idx uint64
+	v := reflect.ValueOf(p)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, fmt.Errorf("path element %d: negative index %d", i, v.Int())
+		}
+		idx = uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		idx = v.Uint()
+	default:
+		return 0, fmt.Errorf("path element %d: expected integer index, got %v", i, p)
+	}
+	if idx >= limit {
+		return 0, fmt.Errorf("path element %d: index %d out of bounds, limit is %d", i, idx, limit)
+	}
+	return idx, nil
+}
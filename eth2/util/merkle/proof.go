@@ -0,0 +1,185 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/protolambda/zrnt/eth2/util/hashing"
+	"github.com/protolambda/ztyp/tree"
+	"github.com/protolambda/ztyp/view"
+)
+
+// SingleProof proves a single leaf against the root of the tree it was generated from.
+type SingleProof struct {
+	Gindex tree.Gindex64 `json:"gindex" yaml:"gindex"`
+	Leaf   tree.Root     `json:"leaf" yaml:"leaf"`
+	// Branch of sibling nodes, from the bottom (sibling of the leaf) up to the top (a child of the root).
+	Branch []tree.Root `json:"branch" yaml:"branch"`
+}
+
+// Verify checks the proof against the given root.
+// This is the same as VerifyMerkleBranch, with the depth and index derived from the gindex.
+func (p *SingleProof) Verify(root tree.Root) bool {
+	depth := uint64(p.Gindex.Depth())
+	if p.Gindex == 0 || uint64(len(p.Branch)) != depth {
+		return false
+	}
+	index := uint64(p.Gindex) ^ (uint64(1) << depth)
+	return VerifyMerkleBranch(p.Leaf, p.Branch, depth, index, root)
+}
+
+// MultiProof proves multiple leaves at once, sharing the helper nodes between the leaves.
+type MultiProof struct {
+	Gindices []tree.Gindex64 `json:"gindices" yaml:"gindices"`
+	Leaves   []tree.Root     `json:"leaves" yaml:"leaves"`
+	// Helper nodes, ordered like HelperIndices(Gindices)
+	Helpers []tree.Root `json:"helpers" yaml:"helpers"`
+}
+
+// Verify checks the proof against the given root.
+func (p *MultiProof) Verify(root tree.Root) bool {
+	computed, err := CalculateMultiMerkleRoot(p.Leaves, p.Helpers, p.Gindices)
+	if err != nil {
+		return false
+	}
+	return computed == root
+}
+
+// BuildSingleProof collects the leaf and branch of the node at the gindex in the tree.
+func BuildSingleProof(node tree.Node, gindex tree.Gindex64) (*SingleProof, error) {
+	if gindex == 0 {
+		return nil, errors.New("invalid zero gindex")
+	}
+	hFn := tree.GetHashFn()
+	leaf, err := node.Getter(gindex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get leaf at gindex %d: %w", gindex, err)
+	}
+	branch := make([]tree.Root, 0, gindex.Depth())
+	for g := gindex; g > 1; g >>= 1 {
+		sibling, err := node.Getter(g ^ 1)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get branch node at gindex %d: %w", g^1, err)
+		}
+		branch = append(branch, sibling.MerkleRoot(hFn))
+	}
+	return &SingleProof{Gindex: gindex, Leaf: leaf.MerkleRoot(hFn), Branch: branch}, nil
+}
+
+// BuildMultiProof collects the leaves at the gindices, and the helper nodes to verify them together.
+func BuildMultiProof(node tree.Node, gindices ...tree.Gindex64) (*MultiProof, error) {
+	hFn := tree.GetHashFn()
+	leaves := make([]tree.Root, 0, len(gindices))
+	for _, g := range gindices {
+		if g == 0 {
+			return nil, errors.New("invalid zero gindex")
+		}
+		leaf, err := node.Getter(g)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get leaf at gindex %d: %w", g, err)
+		}
+		leaves = append(leaves, leaf.MerkleRoot(hFn))
+	}
+	helperIndices := HelperIndices(gindices)
+	helpers := make([]tree.Root, 0, len(helperIndices))
+	for _, g := range helperIndices {
+		n, err := node.Getter(g)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get helper node at gindex %d: %w", g, err)
+		}
+		helpers = append(helpers, n.MerkleRoot(hFn))
+	}
+	return &MultiProof{Gindices: gindices, Leaves: leaves, Helpers: helpers}, nil
+}
+
+// ProveView builds a single proof for the given path (see PathGindex) in the view, e.g. a BeaconStateView.
+func ProveView(v view.View, path ...interface{}) (*SingleProof, error) {
+	gindex, _, err := PathGindex(v.Type(), path...)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSingleProof(v.Backing(), gindex)
+}
+
+// ProveViewMulti builds a multi-proof for the given paths (see PathGindex) in the view, e.g. a BeaconStateView.
+func ProveViewMulti(v view.View, paths ...[]interface{}) (*MultiProof, error) {
+	gindices := make([]tree.Gindex64, 0, len(paths))
+	for i, path := range paths {
+		gindex, _, err := PathGindex(v.Type(), path...)
+		if err != nil {
+			return nil, fmt.Errorf("path %d: %w", i, err)
+		}
+		gindices = append(gindices, gindex)
+	}
+	return BuildMultiProof(v.Backing(), gindices...)
+}
+
+// HelperIndices returns the gindices of the nodes needed to verify the given leaves together,
+// excluding the nodes that can be computed from the leaves themselves. Sorted in descending order.
+func HelperIndices(gindices []tree.Gindex64) []tree.Gindex64 {
+	helpers := make(map[tree.Gindex64]struct{})
+	paths := make(map[tree.Gindex64]struct{})
+	for _, g := range gindices {
+		for x := g; x > 1; x >>= 1 {
+			helpers[x^1] = struct{}{}
+			paths[x] = struct{}{}
+		}
+	}
+	out := make([]tree.Gindex64, 0, len(helpers))
+	for g := range helpers {
+		if _, ok := paths[g]; !ok {
+			out = append(out, g)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i] > out[j]
+	})
+	return out
+}
+
+// CalculateMultiMerkleRoot computes the root of the tree from the leaves and helper nodes of a multi-proof.
+func CalculateMultiMerkleRoot(leaves []tree.Root, helpers []tree.Root, gindices []tree.Gindex64) (tree.Root, error) {
+	if len(leaves) != len(gindices) {
+		return tree.Root{}, fmt.Errorf("got %d leaves, but %d gindices", len(leaves), len(gindices))
+	}
+	helperIndices := HelperIndices(gindices)
+	if len(helpers) != len(helperIndices) {
+		return tree.Root{}, fmt.Errorf("got %d helper nodes, but expected %d", len(helpers), len(helperIndices))
+	}
+	objects := make(map[tree.Gindex64]tree.Root, len(leaves)+len(helpers))
+	for i, g := range gindices {
+		if g == 0 {
+			return tree.Root{}, errors.New("invalid zero gindex")
+		}
+		objects[g] = leaves[i]
+	}
+	for i, g := range helperIndices {
+		objects[g] = helpers[i]
+	}
+	keys := make([]tree.Gindex64, 0, len(objects))
+	for g := range objects {
+		keys = append(keys, g)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] > keys[j]
+	})
+	// Parents are always lower than their children, so appending them to the descending keys keeps working bottom-up.
+	for pos := 0; pos < len(keys); pos++ {
+		k := keys[pos]
+		if k <= 1 {
+			continue
+		}
+		left, okLeft := objects[k&^1]
+		right, okRight := objects[k|1]
+		if _, done := objects[k>>1]; okLeft && okRight && !done {
+			objects[k>>1] = hashing.Hash(append(left[:], right[:]...))
+			keys = append(keys, k>>1)
+		}
+	}
+	root, ok := objects[tree.RootGindex]
+	if !ok {
+		return tree.Root{}, errors.New("multi-proof is incomplete, could not compute root")
+	}
+	return root, nil
+}
@@ -0,0 +1,121 @@
+package merkle_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	blsu "github.com/protolambda/bls12-381-util"
+	"github.com/protolambda/ztyp/tree"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/util/merkle"
+)
+
+func testState(t *testing.T) *phase0.BeaconStateView {
+	spec := configs.Minimal
+	validators := make([]phase0.KickstartValidatorData, 64)
+	for i := range validators {
+		var skBytes [32]byte
+		skBytes[31] = byte(i + 1)
+		var sk blsu.SecretKey
+		if err := sk.Deserialize(&skBytes); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := blsu.SkToPk(&sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = phase0.KickstartValidatorData{
+			Pubkey:  pub.Serialize(),
+			Balance: spec.MAX_EFFECTIVE_BALANCE - common.Gwei(i),
+		}
+	}
+	state, _, err := phase0.KickStartState(spec, common.Root{0x01}, 1_000_000, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestProveView(t *testing.T) {
+	state := testState(t)
+	root := state.HashTreeRoot(tree.GetHashFn())
+
+	proof, err := merkle.ProveView(state, "validators", 12, "effective_balance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Verify(root) {
+		t.Fatal("failed to verify validator effective balance proof")
+	}
+	vals, _ := state.Validators()
+	val, _ := vals.Validator(12)
+	eff, _ := val.EffectiveBalance()
+	var expected tree.Root
+	binary.LittleEndian.PutUint64(expected[:8], uint64(eff))
+	if proof.Leaf != expected {
+		t.Fatalf("unexpected leaf %s, expected %s", proof.Leaf, expected)
+	}
+
+	proof.Leaf[0] ^= 1
+	if proof.Verify(root) {
+		t.Fatal("verified proof with modified leaf")
+	}
+
+	for _, path := range [][]interface{}{
+		{"finalized_checkpoint"},
+		{"balances", common.ValidatorIndex(13)},
+		{"validators", merkle.LengthKey},
+		{"fork", "current_version"},
+	} {
+		p, err := merkle.ProveView(state, path...)
+		if err != nil {
+			t.Fatalf("path %v: %v", path, err)
+		}
+		if !p.Verify(root) {
+			t.Fatalf("path %v: failed to verify proof", path)
+		}
+	}
+
+	if _, err := merkle.ProveView(state, "validators", 12, "effective_balance", 0); err == nil {
+		t.Fatal("expected error when navigating into basic type")
+	}
+	if _, err := merkle.ProveView(state, "unknown_field"); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestProveViewMulti(t *testing.T) {
+	state := testState(t)
+	root := state.HashTreeRoot(tree.GetHashFn())
+
+	proof, err := merkle.ProveViewMulti(state,
+		[]interface{}{"validators", 3, "pubkey"},
+		[]interface{}{"validators", 4, "effective_balance"},
+		[]interface{}{"balances", 3},
+		[]interface{}{"finalized_checkpoint", "root"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proof.Verify(root) {
+		t.Fatal("failed to verify multi-proof")
+	}
+	proof.Helpers[0][0] ^= 1
+	if proof.Verify(root) {
+		t.Fatal("verified multi-proof with modified helper")
+	}
+}
+
+func TestConcatGindices(t *testing.T) {
+	g, err := merkle.ConcatGindices(2, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 = 0b10, 3 = 0b11, 5 = 0b101 -> 0b1_0_1_01
+	if g != 0b10101 {
+		t.Fatalf("unexpected gindex %d", g)
+	}
+}
@@ -1,27 +1,19 @@
 package main
 
 import (
-	"encoding/binary"
 	"fmt"
 
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/interop"
 	"github.com/protolambda/ztyp/tree"
 )
 
 func CreateTestValidators(count uint64, balance common.Gwei) []phase0.KickstartValidatorData {
-	out := make([]phase0.KickstartValidatorData, 0, count)
-	for i := uint64(0); i < count; i++ {
-		pubkey := common.BLSPubkey{0xaa}
-		binary.LittleEndian.PutUint64(pubkey[1:], i)
-		withdrawalCred := common.Root{0xbb}
-		binary.LittleEndian.PutUint64(withdrawalCred[1:], i)
-		out = append(out, phase0.KickstartValidatorData{
-			Pubkey:                pubkey,
-			WithdrawalCredentials: withdrawalCred,
-			Balance:               balance,
-		})
+	out, err := interop.KickstartValidators(count, common.ETH1_ADDRESS_WITHDRAWAL_PREFIX, balance)
+	if err != nil {
+		panic(err)
 	}
 	return out
 }
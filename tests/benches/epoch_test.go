@@ -0,0 +1,132 @@
+package benches
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/configs"
+)
+
+var electraSpec = func() *common.Spec {
+	s := *configs.Mainnet
+	s.ALTAIR_FORK_EPOCH = 0
+	s.BELLATRIX_FORK_EPOCH = 0
+	s.CAPELLA_FORK_EPOCH = 0
+	s.DENEB_FORK_EPOCH = 0
+	s.ALPACA_FORK_EPOCH = 0
+	return &s
+}()
+
+var denebSpec = func() *common.Spec {
+	s := *electraSpec
+	s.ALPACA_FORK_EPOCH = common.FAR_FUTURE_EPOCH
+	return &s
+}()
+
+// createEpochTestState creates an Electra state at the last slot of the first epoch after genesis,
+// with participation of most of the validators, ready for the epoch transition.
+func createEpochTestState(b *testing.B) (*electra.BeaconStateView, *common.EpochsContext) {
+	state, epc := CreateTestElectraState(electraSpec, stateValidatorFill, MAX_EFFECTIVE_BALANCE)
+	prepareEpochTestState(b, electraSpec, state, epc)
+	return state, epc
+}
+
+// createDenebEpochTestState is like createEpochTestState, but creates a Deneb state.
+func createDenebEpochTestState(b *testing.B) (*deneb.BeaconStateView, *common.EpochsContext) {
+	state, epc := CreateTestDenebState(denebSpec, stateValidatorFill, MAX_EFFECTIVE_BALANCE)
+	prepareEpochTestState(b, denebSpec, state, epc)
+	return state, epc
+}
+
+func prepareEpochTestState(b *testing.B, spec *common.Spec, state altair.AltairLikeBeaconState, epc *common.EpochsContext) {
+	ctx := context.Background()
+	if err := common.ProcessSlots(ctx, spec, epc, &beacon.StandardUpgradeableBeaconState{BeaconState: state},
+		spec.SLOTS_PER_EPOCH*2-1); err != nil {
+		b.Fatal(err)
+	}
+	prev, err := state.PreviousEpochParticipation()
+	if err != nil {
+		b.Fatal(err)
+	}
+	curr, err := state.CurrentEpochParticipation()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := common.ValidatorIndex(0); i < stateValidatorFill; i++ {
+		flags := altair.TIMELY_SOURCE_FLAG | altair.TIMELY_TARGET_FLAG | altair.TIMELY_HEAD_FLAG
+		if i%8 == 0 {
+			flags = 0
+		}
+		if err := prev.SetFlags(i, flags); err != nil {
+			b.Fatal(err)
+		}
+		if err := curr.SetFlags(i, flags); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkEpoch(b *testing.B, process func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *electra.BeaconStateView) error) {
+	state, epc := createEpochTestState(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s, err := state.CopyState()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := process(ctx, electraSpec, epc, s.(*electra.BeaconStateView)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkElectraEpochStepwise(b *testing.B) {
+	benchmarkEpoch(b, func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *electra.BeaconStateView) error {
+		return state.ProcessEpochStepwise(ctx, spec, epc)
+	})
+}
+
+func BenchmarkElectraEpochSinglePass(b *testing.B) {
+	benchmarkEpoch(b, func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *electra.BeaconStateView) error {
+		return electra.ProcessEpochSinglePass(ctx, spec, epc, state)
+	})
+}
+
+func benchmarkDenebEpoch(b *testing.B, process func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *deneb.BeaconStateView) error) {
+	state, epc := createDenebEpochTestState(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s, err := state.CopyState()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := process(ctx, denebSpec, epc, s.(*deneb.BeaconStateView)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDenebEpochStepwise(b *testing.B) {
+	benchmarkDenebEpoch(b, func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *deneb.BeaconStateView) error {
+		return state.ProcessEpochStepwise(ctx, spec, epc)
+	})
+}
+
+func BenchmarkDenebEpochSinglePass(b *testing.B) {
+	benchmarkDenebEpoch(b, func(ctx context.Context, spec *common.Spec, epc *common.EpochsContext, state *deneb.BeaconStateView) error {
+		return state.ProcessEpoch(ctx, spec, epc)
+	})
+}
@@ -7,6 +7,8 @@ import (
 	kbls "github.com/kilic/bls12-381"
 	blsu "github.com/protolambda/bls12-381-util"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/eth2/configs"
 )
@@ -36,3 +38,19 @@ func CreateTestState(validatorCount uint64, balance common.Gwei) (*phase0.Beacon
 	}
 	return out, epc
 }
+
+func CreateTestElectraState(spec *common.Spec, validatorCount uint64, balance common.Gwei) (*electra.BeaconStateView, *common.EpochsContext) {
+	out, epc, err := electra.KickStartState(spec, common.Root{123}, 1564000000, CreateTestValidators(validatorCount, balance), &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		panic(err)
+	}
+	return out, epc
+}
+
+func CreateTestDenebState(spec *common.Spec, validatorCount uint64, balance common.Gwei) (*deneb.BeaconStateView, *common.EpochsContext) {
+	out, epc, err := deneb.KickStartState(spec, common.Root{123}, 1564000000, CreateTestValidators(validatorCount, balance), &deneb.ExecutionPayloadHeader{})
+	if err != nil {
+		panic(err)
+	}
+	return out, epc
+}
@@ -85,7 +85,7 @@ func (c *FinalityTestCase) Run() error {
 		c.Pre = state.BeaconState
 	}()
 	for _, b := range c.Blocks {
-		if err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
+		if _, err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
 			return err
 		}
 	}
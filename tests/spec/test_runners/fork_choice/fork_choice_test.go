@@ -0,0 +1,369 @@
+package fork_choice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/snappy"
+	"gopkg.in/yaml.v3"
+
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/tests/spec/test_util"
+)
+
+type PayloadStatusStep struct {
+	Status          common.PayloadStatus `yaml:"status"`
+	LatestValidHash *common.Hash32       `yaml:"latest_valid_hash"`
+}
+
+type HeadCheck struct {
+	Slot common.Slot `yaml:"slot"`
+	Root common.Root `yaml:"root"`
+}
+
+type Checks struct {
+	Time                *common.Timestamp  `yaml:"time"`
+	GenesisTime         *common.Timestamp  `yaml:"genesis_time"`
+	Head                *HeadCheck         `yaml:"head"`
+	JustifiedCheckpoint *common.Checkpoint `yaml:"justified_checkpoint"`
+	FinalizedCheckpoint *common.Checkpoint `yaml:"finalized_checkpoint"`
+	ProposerBoostRoot   *common.Root       `yaml:"proposer_boost_root"`
+	// Not supported, the case is skipped if present
+	GetProposerHead                *common.Root `yaml:"get_proposer_head"`
+	ShouldOverrideForkchoiceUpdate *yaml.Node   `yaml:"should_override_forkchoice_update"`
+}
+
+type Step struct {
+	Tick             *common.Timestamp  `yaml:"tick"`
+	Block            string             `yaml:"block"`
+	Blobs            string             `yaml:"blobs"`
+	Proofs           []string           `yaml:"proofs"`
+	Attestation      string             `yaml:"attestation"`
+	AttesterSlashing string             `yaml:"attester_slashing"`
+	PowBlock         string             `yaml:"pow_block"`
+	BlockHash        *common.Hash32     `yaml:"block_hash"`
+	PayloadStatus    *PayloadStatusStep `yaml:"payload_status"`
+	Valid            *bool              `yaml:"valid"`
+	Checks           *Checks            `yaml:"checks"`
+}
+
+func (st *Step) ExpectValid() bool {
+	return st.Valid == nil || *st.Valid
+}
+
+type ForkChoiceTestCase struct {
+	Spec  *common.Spec
+	Fork  test_util.ForkName
+	Store *Store
+	Steps []Step
+}
+
+func (c *ForkChoiceTestCase) loadBlock(t *testing.T, name string, readPart test_util.TestPartReader) *BlockData {
+	valRoot, err := c.Store.blocks[c.Store.anchor.Root].state.GenesisValidatorsRoot()
+	test_util.Check(t, err)
+	var dst interface {
+		common.SpecObj
+		Envelope(spec *common.Spec, digest common.ForkDigest) *common.BeaconBlockEnvelope
+	}
+	switch c.Fork {
+	case "phase0":
+		dst = new(phase0.SignedBeaconBlock)
+	case "altair":
+		dst = new(altair.SignedBeaconBlock)
+	case "bellatrix":
+		dst = new(bellatrix.SignedBeaconBlock)
+	case "capella":
+		dst = new(capella.SignedBeaconBlock)
+	case "deneb":
+		dst = new(deneb.SignedBeaconBlock)
+	case "electra":
+		dst = new(electra.SignedBeaconBlock)
+	default:
+		t.Fatalf("unrecognized fork name: %s", c.Fork)
+	}
+	if !test_util.LoadSpecObj(t, name, dst, readPart) {
+		t.Fatalf("missing block %s", name)
+	}
+	// the envelope computes the block root, the slot is only needed for the digest of the signature.
+	benv := dst.Envelope(c.Spec, common.ForkDigest{})
+	benv = dst.Envelope(c.Spec, common.ComputeForkDigest(c.Spec.ForkVersion(benv.Slot), valRoot))
+	block, err := NewBlockData(c.Spec, benv)
+	test_util.Check(t, err)
+	return block
+}
+
+func (c *ForkChoiceTestCase) Load(t *testing.T, forkName test_util.ForkName, readPart test_util.TestPartReader) {
+	// each case has its own execution engine mock
+	spec := *readPart.Spec()
+	exec := &MockExecEngine{Statuses: make(map[common.Hash32]common.PayloadStatus)}
+	spec.ExecutionEngine = exec
+	c.Spec = &spec
+	c.Fork = forkName
+	anchorState := test_util.LoadState(t, forkName, "anchor_state", readPart)
+	if anchorState == nil {
+		t.Fatalf("failed to load anchor state")
+	}
+	var anchorBlock interface {
+		common.SpecObj
+		Header(spec *common.Spec) *common.BeaconBlockHeader
+	}
+	switch forkName {
+	case "phase0":
+		anchorBlock = new(phase0.BeaconBlock)
+	case "altair":
+		anchorBlock = new(altair.BeaconBlock)
+	case "bellatrix":
+		anchorBlock = new(bellatrix.BeaconBlock)
+	case "capella":
+		anchorBlock = new(capella.BeaconBlock)
+	case "deneb":
+		anchorBlock = new(deneb.BeaconBlock)
+	case "electra":
+		anchorBlock = new(electra.BeaconBlock)
+	default:
+		t.Fatalf("unrecognized fork name: %s", forkName)
+	}
+	if !test_util.LoadSpecObj(t, "anchor_block", anchorBlock, readPart) {
+		t.Fatalf("failed to load anchor block")
+	}
+	store, err := NewStore(c.Spec, exec, anchorState, anchorBlock.Header(c.Spec))
+	test_util.Check(t, err)
+	c.Store = store
+
+	p := readPart.Part("steps.yaml")
+	dec := yaml.NewDecoder(p)
+	test_util.Check(t, dec.Decode(&c.Steps))
+	test_util.Check(t, p.Close())
+}
+
+// blobCount returns the number of blobs in the sidecar data of the block, or -1 if the data is missing.
+func (c *ForkChoiceTestCase) blobCount(t *testing.T, step *Step, readPart test_util.TestPartReader) int {
+	if step.Blobs == "" {
+		return -1
+	}
+	p := readPart.Part(step.Blobs + ".ssz_snappy")
+	if !p.Exists() {
+		return -1
+	}
+	data, err := ioutil.ReadAll(p)
+	test_util.Check(t, err)
+	test_util.Check(t, p.Close())
+	size, err := snappy.DecodedLen(data)
+	test_util.Check(t, err)
+	blobSize := 32 * int(c.Spec.FIELD_ELEMENTS_PER_BLOB)
+	if size%blobSize != 0 {
+		t.Fatalf("blobs %s size %d is not a multiple of the blob size %d", step.Blobs, size, blobSize)
+	}
+	return size / blobSize
+}
+
+func (c *ForkChoiceTestCase) runStep(t *testing.T, i int, step *Step, readPart test_util.TestPartReader) error {
+	s := c.Store
+	switch {
+	case step.Tick != nil:
+		return s.OnTick(*step.Tick)
+	case step.Block != "":
+		block := c.loadBlock(t, step.Block, readPart)
+		if block.BlobCommitments > 0 {
+			// Blob availability only, the KZG proofs of the blobs are not verified.
+			count := c.blobCount(t, step, readPart)
+			if count < 0 {
+				return fmt.Errorf("blob data of block %s is not available", block.Envelope.BlockRoot)
+			}
+			if count != block.BlobCommitments || len(step.Proofs) != block.BlobCommitments {
+				return fmt.Errorf("block %s has %d commitments, but got %d blobs and %d proofs",
+					block.Envelope.BlockRoot, block.BlobCommitments, count, len(step.Proofs))
+			}
+		}
+		return s.OnBlock(block)
+	case step.Attestation != "" && c.Fork == "electra":
+		var att electra.AttestationElectra
+		if !test_util.LoadSpecObj(t, step.Attestation, &att, readPart) {
+			t.Fatalf("missing attestation %s", step.Attestation)
+		}
+		if err := s.OnAttestationElectra(&att, false); err != nil {
+			return err
+		}
+		return s.sync()
+	case step.Attestation != "":
+		var att phase0.Attestation
+		if !test_util.LoadSpecObj(t, step.Attestation, &att, readPart) {
+			t.Fatalf("missing attestation %s", step.Attestation)
+		}
+		if err := s.OnAttestation(&att, false); err != nil {
+			return err
+		}
+		return s.sync()
+	case step.AttesterSlashing != "" && c.Fork == "electra":
+		var slashing electra.AttesterSlashingElectra
+		if !test_util.LoadSpecObj(t, step.AttesterSlashing, &slashing, readPart) {
+			t.Fatalf("missing attester slashing %s", step.AttesterSlashing)
+		}
+		return s.OnAttesterSlashingElectra(&slashing)
+	case step.AttesterSlashing != "":
+		var slashing phase0.AttesterSlashing
+		if !test_util.LoadSpecObj(t, step.AttesterSlashing, &slashing, readPart) {
+			t.Fatalf("missing attester slashing %s", step.AttesterSlashing)
+		}
+		return s.OnAttesterSlashing(&slashing)
+	case step.PowBlock != "":
+		var powBlock PowBlock
+		if !test_util.LoadSSZ(t, step.PowBlock, &powBlock, readPart) {
+			t.Fatalf("missing pow block %s", step.PowBlock)
+		}
+		s.powBlocks[powBlock.BlockHash] = &powBlock
+		return nil
+	case step.BlockHash != nil && step.PayloadStatus != nil:
+		return s.OnPayloadStatus(*step.BlockHash, step.PayloadStatus.Status, step.PayloadStatus.LatestValidHash)
+	case step.Checks != nil:
+		c.check(t, i, step.Checks)
+		return nil
+	default:
+		t.Fatalf("step %d: unrecognized step", i)
+		return nil
+	}
+}
+
+func (c *ForkChoiceTestCase) check(t *testing.T, i int, checks *Checks) {
+	s := c.Store
+	if checks.GetProposerHead != nil || checks.ShouldOverrideForkchoiceUpdate != nil {
+		t.Skipf("step %d: proposer head checks are not supported", i)
+	}
+	if checks.Time != nil && *checks.Time != s.time {
+		t.Errorf("step %d: expected time %d, got %d", i, *checks.Time, s.time)
+	}
+	if checks.GenesisTime != nil && *checks.GenesisTime != s.genesisTime {
+		t.Errorf("step %d: expected genesis time %d, got %d", i, *checks.GenesisTime, s.genesisTime)
+	}
+	if checks.Head != nil {
+		root, slot, err := s.Head()
+		if err != nil {
+			t.Errorf("step %d: failed to get head: %v", i, err)
+		} else if root != checks.Head.Root || slot != checks.Head.Slot {
+			t.Errorf("step %d: expected head %s at slot %d, got %s at slot %d",
+				i, checks.Head.Root, checks.Head.Slot, root, slot)
+		}
+	}
+	if checks.JustifiedCheckpoint != nil && *checks.JustifiedCheckpoint != s.justified {
+		t.Errorf("step %d: expected justified checkpoint %s, got %s", i, checks.JustifiedCheckpoint, s.justified)
+	}
+	if checks.FinalizedCheckpoint != nil && *checks.FinalizedCheckpoint != s.finalized {
+		t.Errorf("step %d: expected finalized checkpoint %s, got %s", i, checks.FinalizedCheckpoint, s.finalized)
+	}
+	if checks.ProposerBoostRoot != nil && *checks.ProposerBoostRoot != s.proposerBoostRoot {
+		t.Errorf("step %d: expected proposer boost root %s, got %s", i, checks.ProposerBoostRoot, s.proposerBoostRoot)
+	}
+}
+
+func (c *ForkChoiceTestCase) Run(t *testing.T, readPart test_util.TestPartReader) {
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		err := c.runStep(t, i, step, readPart)
+		if err != nil && step.ExpectValid() {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if err == nil && !step.ExpectValid() {
+			t.Fatalf("step %d: expected an error, but step was processed", i)
+		}
+	}
+}
+
+// The fork choice tests also run for Electra, which is not part of test_util.AllForks yet.
+var forkChoiceForks = []test_util.ForkName{"phase0", "altair", "bellatrix", "capella", "deneb", "electra"}
+
+func runForkChoiceTest(t *testing.T, handlerName string) {
+	runStepsTest(t, "fork_choice/"+handlerName)
+}
+
+// runStepsTest runs tests in the fork choice test format, also used by the sync tests.
+func runStepsTest(t *testing.T, handlerPath string) {
+	caseRunner := test_util.HandleBLS(func(t *testing.T, forkName test_util.ForkName, readPart test_util.TestPartReader) {
+		c := new(ForkChoiceTestCase)
+		c.Load(t, forkName, readPart)
+		c.Run(t, readPart)
+	})
+	for _, preset := range []*common.Spec{configs.Minimal, configs.Mainnet} {
+		t.Run(preset.PRESET_BASE, func(t *testing.T) {
+			for _, fork := range forkChoiceForks {
+				t.Run(string(fork), func(t *testing.T) {
+					test_util.RunHandler(t, handlerPath, caseRunner, preset, fork)
+				})
+			}
+		})
+	}
+}
+
+func TestGetHead(t *testing.T) {
+	runForkChoiceTest(t, "get_head")
+}
+
+func TestOnBlock(t *testing.T) {
+	runForkChoiceTest(t, "on_block")
+}
+
+func TestExAnte(t *testing.T) {
+	runForkChoiceTest(t, "ex_ante")
+}
+
+func TestReorg(t *testing.T) {
+	runForkChoiceTest(t, "reorg")
+}
+
+func TestWithholding(t *testing.T) {
+	runForkChoiceTest(t, "withholding")
+}
+
+func TestOnMergeBlock(t *testing.T) {
+	runForkChoiceTest(t, "on_merge_block")
+}
+
+func TestSyncOptimistic(t *testing.T) {
+	runStepsTest(t, "sync/optimistic")
+}
+
+func TestPayloadStatusStepDecoding(t *testing.T) {
+	var steps []Step
+	data := `
+- block_hash: '0x0100000000000000000000000000000000000000000000000000000000000000'
+  payload_status: {status: SYNCING, latest_valid_hash: null, validation_error: null}
+- block_hash: '0x0200000000000000000000000000000000000000000000000000000000000000'
+  payload_status: {status: INVALID, latest_valid_hash: '0x0100000000000000000000000000000000000000000000000000000000000000', validation_error: null}
+`
+	test_util.Check(t, yaml.Unmarshal([]byte(data), &steps))
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(steps))
+	}
+	if s := steps[0].PayloadStatus; s == nil || s.Status != common.PayloadSyncing || s.LatestValidHash != nil {
+		t.Fatalf("unexpected first payload status: %v", s)
+	}
+	if s := steps[1].PayloadStatus; s == nil || s.Status != common.PayloadInvalid || s.LatestValidHash == nil || *s.LatestValidHash != (common.Hash32{0x01}) {
+		t.Fatalf("unexpected second payload status: %v", s)
+	}
+	if err := yaml.Unmarshal([]byte("{status: MAYBE}"), new(PayloadStatusStep)); err == nil {
+		t.Fatal("expected error for unknown payload status")
+	}
+}
+
+func TestNewBlockDataElectra(t *testing.T) {
+	block := &electra.SignedBeaconBlock{}
+	block.Message.Body.Attestations = electra.AttestationsElectra{{Data: phase0.AttestationData{Slot: 3}}}
+	block.Message.Body.AttesterSlashings = electra.AttesterSlashingsElectra{{}}
+	block.Message.Body.ExecutionPayload.BlockHash = common.Hash32{0x01}
+	block.Message.Body.BlobKZGCommitments = deneb.KZGCommitments{{}, {}}
+	data, err := NewBlockData(configs.Minimal, block.Envelope(configs.Minimal, common.ForkDigest{}))
+	test_util.Check(t, err)
+	if len(data.AttestationsElectra) != 1 || data.AttestationsElectra[0].Data.Slot != 3 || len(data.AttesterSlashingsElectra) != 1 {
+		t.Fatal("expected the Electra attestations and attester slashings of the block")
+	}
+	if data.Payload == nil || data.Payload.BlockHash != (common.Hash32{0x01}) || data.BlobCommitments != 2 {
+		t.Fatal("expected the payload and blob commitments of the block")
+	}
+}
@@ -0,0 +1,809 @@
+package fork_choice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/protolambda/zrnt/eth2/beacon"
+	"github.com/protolambda/zrnt/eth2/beacon/altair"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
+	"github.com/protolambda/zrnt/eth2/beacon/phase0"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forkchoice/proto"
+	"github.com/protolambda/ztyp/codec"
+	"github.com/protolambda/ztyp/tree"
+	. "github.com/protolambda/ztyp/view"
+)
+
+const INTERVALS_PER_SLOT = 3
+
+type PowBlock struct {
+	BlockHash       common.Hash32
+	ParentHash      common.Hash32
+	TotalDifficulty Uint256View
+}
+
+func (b *PowBlock) Deserialize(dr *codec.DecodingReader) error {
+	return dr.FixedLenContainer(&b.BlockHash, &b.ParentHash, &b.TotalDifficulty)
+}
+
+func (b *PowBlock) FixedLength() uint64 {
+	return 32 + 32 + 32
+}
+
+// lessU256 compares the little-endian 64 bit limbs, from most to least significant.
+func lessU256(a, b Uint256View) bool {
+	x, y := [4]uint64(a), [4]uint64(b)
+	for i := 3; i >= 0; i-- {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return false
+}
+
+// MockExecEngine returns the payload status configured for the block hash, VALID by default.
+type MockExecEngine struct {
+	Statuses map[common.Hash32]common.PayloadStatus
+}
+
+func (m *MockExecEngine) status(blockHash common.Hash32) common.PayloadStatus {
+	if s, ok := m.Statuses[blockHash]; ok {
+		return s
+	}
+	return common.PayloadValid
+}
+
+func (m *MockExecEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (status common.PayloadStatus, err error) {
+	return m.status(executionPayload.BlockHash), nil
+}
+
+func (m *MockExecEngine) DenebIsValidVersionedHashes(ctx context.Context, payload *deneb.ExecutionPayload, versionedHashes []common.Hash32) (bool, error) {
+	return true, nil
+}
+
+func (m *MockExecEngine) DenebIsValidBlockHash(ctx context.Context, payload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (bool, error) {
+	return m.status(payload.BlockHash) != common.PayloadInvalidBlockHash, nil
+}
+
+func (m *MockExecEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (status common.PayloadStatus, err error) {
+	return m.status(executionPayload.BlockHash), nil
+}
+
+func (m *MockExecEngine) CapellaIsValidBlockHash(ctx context.Context, payload *capella.ExecutionPayload) (bool, error) {
+	return m.status(payload.BlockHash) != common.PayloadInvalidBlockHash, nil
+}
+
+func (m *MockExecEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (status common.PayloadStatus, err error) {
+	return m.status(executionPayload.BlockHash), nil
+}
+
+func (m *MockExecEngine) BellatrixIsValidBlockHash(ctx context.Context, payload *bellatrix.ExecutionPayload) (bool, error) {
+	return m.status(payload.BlockHash) != common.PayloadInvalidBlockHash, nil
+}
+
+type blockInfo struct {
+	slot       common.Slot
+	parentRoot common.Root
+	blockHash  common.Hash32
+	state      common.BeaconState
+	epc        *common.EpochsContext
+	// justification and finalization after processing the epoch of the block
+	unrealizedJustified common.Checkpoint
+	unrealizedFinalized common.Checkpoint
+}
+
+// storeVotes wraps the vote store of the forkchoice with the parts of the spec get_weight
+// that the forkchoice does not implement: the proposer boost, and the removal of the weight of equivocating validators.
+type storeVotes struct {
+	forkchoice.VoteStore
+
+	equivocating map[common.ValidatorIndex]struct{}
+	// balances as applied to the weights of the graph
+	balances []common.Gwei
+
+	boost              forkchoice.NodeRef
+	boostWeight        common.Gwei
+	appliedBoost       forkchoice.NodeRef
+	appliedBoostWeight common.Gwei
+
+	changed bool
+}
+
+// setProposerBoost boosts the weight of the given block. A zero weight resets the boost.
+func (v *storeVotes) setProposerBoost(block forkchoice.NodeRef, weight common.Gwei) {
+	if weight == 0 {
+		block = forkchoice.NodeRef{}
+	}
+	if block != v.boost || weight != v.boostWeight {
+		v.boost = block
+		v.boostWeight = weight
+		v.changed = true
+	}
+}
+
+// equivocated recomputes the weights with the next head computation, after validators were marked as equivocating.
+func (v *storeVotes) equivocated() {
+	v.changed = true
+}
+
+func (v *storeVotes) HasChanges() bool {
+	return v.changed || v.VoteStore.HasChanges()
+}
+
+// ComputeDeltas computes the vote deltas from the balances applied previously, instead of the given old balances,
+// and includes the change in proposer boost.
+func (v *storeVotes) ComputeDeltas(indices map[forkchoice.NodeRef]forkchoice.NodeIndex, oldBalances []common.Gwei, newBalances []common.Gwei) []forkchoice.SignedGwei {
+	balances := make([]common.Gwei, len(newBalances))
+	for i, b := range newBalances {
+		if _, ok := v.equivocating[common.ValidatorIndex(i)]; !ok {
+			balances[i] = b
+		}
+	}
+	deltas := v.VoteStore.ComputeDeltas(indices, v.balances, balances)
+	v.balances = balances
+
+	if i, ok := indices[v.appliedBoost]; ok {
+		deltas[i] -= forkchoice.SignedGwei(v.appliedBoostWeight)
+	}
+	v.appliedBoost = forkchoice.NodeRef{}
+	v.appliedBoostWeight = 0
+	if i, ok := indices[v.boost]; ok {
+		deltas[i] += forkchoice.SignedGwei(v.boostWeight)
+		v.appliedBoost = v.boost
+		v.appliedBoostWeight = v.boostWeight
+	}
+	v.changed = false
+	return deltas
+}
+
+type checkpointState struct {
+	state common.BeaconState
+	epc   *common.EpochsContext
+}
+
+// Store wraps the ProtoForkChoice with the remaining parts of the fork choice store of the spec:
+// time, block and checkpoint states, (unrealized) checkpoints, proposer boost and equivocations.
+//
+// The proto-array is never pruned, and keeps the anchor as finalized checkpoint:
+// blocks that do not descend from the finalized checkpoint are rejected by OnBlock,
+// and the head is searched from the justified checkpoint, which descends from the finalized checkpoint.
+// The justified epoch of each node is fixed when it is added, so the time-based pull-up of
+// the voting source of older blocks is only approximated.
+type Store struct {
+	spec  *common.Spec
+	fc    forkchoice.Forkchoice
+	votes *storeVotes
+	exec  *MockExecEngine
+
+	genesisTime common.Timestamp
+	time        common.Timestamp
+
+	anchor    common.Checkpoint
+	justified common.Checkpoint
+	finalized common.Checkpoint
+
+	unrealizedJustified common.Checkpoint
+	unrealizedFinalized common.Checkpoint
+
+	proposerBoostRoot common.Root
+	equivocating      map[common.ValidatorIndex]struct{}
+
+	blocks           map[common.Root]*blockInfo
+	checkpointStates map[common.Checkpoint]*checkpointState
+	powBlocks        map[common.Hash32]*PowBlock
+}
+
+func NewStore(spec *common.Spec, exec *MockExecEngine, anchorState common.BeaconState, anchorBlock *common.BeaconBlockHeader) (*Store, error) {
+	slot, err := anchorState.Slot()
+	if err != nil {
+		return nil, err
+	}
+	if anchorBlock.Slot != slot {
+		return nil, fmt.Errorf("anchor block slot %d does not match anchor state slot %d", anchorBlock.Slot, slot)
+	}
+	stateRoot := anchorState.HashTreeRoot(tree.GetHashFn())
+	if anchorBlock.StateRoot != stateRoot {
+		return nil, fmt.Errorf("anchor block state root %s does not match anchor state %s", anchorBlock.StateRoot, stateRoot)
+	}
+	genesisTime, err := anchorState.GenesisTime()
+	if err != nil {
+		return nil, err
+	}
+	epc, err := common.NewEpochsContext(spec, anchorState)
+	if err != nil {
+		return nil, err
+	}
+	anchorRoot := anchorBlock.HashTreeRoot(tree.GetHashFn())
+	anchor := common.Checkpoint{Epoch: spec.SlotToEpoch(slot), Root: anchorRoot}
+	s := &Store{
+		spec:                spec,
+		exec:                exec,
+		genesisTime:         genesisTime,
+		time:                genesisTime + common.Timestamp(slot)*spec.SECONDS_PER_SLOT,
+		anchor:              anchor,
+		justified:           anchor,
+		finalized:           anchor,
+		unrealizedJustified: anchor,
+		unrealizedFinalized: anchor,
+		equivocating:        make(map[common.ValidatorIndex]struct{}),
+		blocks: map[common.Root]*blockInfo{anchorRoot: {
+			slot:                slot,
+			parentRoot:          anchorBlock.ParentRoot,
+			state:               anchorState,
+			epc:                 epc,
+			unrealizedJustified: anchor,
+			unrealizedFinalized: anchor,
+		}},
+		checkpointStates: map[common.Checkpoint]*checkpointState{anchor: {state: anchorState, epc: epc}},
+		powBlocks:        make(map[common.Hash32]*PowBlock),
+	}
+	if ts, ok := anchorState.(bellatrix.ExecutionTrackingBeaconState); ok {
+		header, err := ts.LatestExecutionPayloadHeader()
+		if err != nil {
+			return nil, err
+		}
+		if s.blocks[anchorRoot].blockHash, err = header.BlockHash(); err != nil {
+			return nil, err
+		}
+	}
+	balances, err := s.justifiedBalances()
+	if err != nil {
+		return nil, err
+	}
+	s.votes = &storeVotes{VoteStore: proto.NewProtoVoteStore(spec), equivocating: s.equivocating}
+	graph := proto.NewProtoArray(anchorBlock.ParentRoot, anchorRoot, slot, anchor.Epoch, anchor.Epoch, nil)
+	s.fc, err = forkchoice.NewForkChoice(spec, anchor, anchor, anchorRoot, slot, graph, s.votes, balances)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) CurrentSlot() common.Slot {
+	return s.spec.TimeToSlot(s.time, s.genesisTime)
+}
+
+func (s *Store) CurrentEpoch() common.Epoch {
+	return s.spec.SlotToEpoch(s.CurrentSlot())
+}
+
+// ancestor returns the root of the block at or before the slot, in the chain of the given block.
+func (s *Store) ancestor(root common.Root, slot common.Slot) (common.Root, error) {
+	for {
+		b, ok := s.blocks[root]
+		if !ok {
+			return common.Root{}, fmt.Errorf("unknown block %s", root)
+		}
+		if b.slot <= slot || root == s.anchor.Root {
+			return root, nil
+		}
+		root = b.parentRoot
+	}
+}
+
+func (s *Store) checkpointBlock(root common.Root, epoch common.Epoch) (common.Root, error) {
+	slot, err := s.spec.EpochStartSlot(epoch)
+	if err != nil {
+		return common.Root{}, err
+	}
+	return s.ancestor(root, slot)
+}
+
+func (s *Store) checkpointState(cp common.Checkpoint) (*checkpointState, error) {
+	if cs, ok := s.checkpointStates[cp]; ok {
+		return cs, nil
+	}
+	b, ok := s.blocks[cp.Root]
+	if !ok {
+		return nil, fmt.Errorf("unknown checkpoint block %s", cp.Root)
+	}
+	state, err := b.state.CopyState()
+	if err != nil {
+		return nil, err
+	}
+	epc := b.epc.Clone()
+	slot, err := s.spec.EpochStartSlot(cp.Epoch)
+	if err != nil {
+		return nil, err
+	}
+	if b.slot < slot {
+		if err := common.ProcessSlots(context.Background(), s.spec, epc,
+			&beacon.StandardUpgradeableBeaconState{BeaconState: state}, slot); err != nil {
+			return nil, err
+		}
+	}
+	cs := &checkpointState{state: state, epc: epc}
+	s.checkpointStates[cp] = cs
+	return cs, nil
+}
+
+// justifiedBalances returns the effective balances of the active unslashed validators of the justified checkpoint state.
+func (s *Store) justifiedBalances() ([]common.Gwei, error) {
+	cs, err := s.checkpointState(s.justified)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := cs.state.Validators()
+	if err != nil {
+		return nil, err
+	}
+	count, err := vals.ValidatorCount()
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]common.Gwei, count)
+	for _, i := range cs.epc.CurrentEpoch.ActiveIndices {
+		v, err := vals.Validator(i)
+		if err != nil {
+			return nil, err
+		}
+		if slashed, err := v.Slashed(); err != nil {
+			return nil, err
+		} else if slashed {
+			continue
+		}
+		if balances[i], err = v.EffectiveBalance(); err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
+
+func (s *Store) proposerScore() (common.Gwei, error) {
+	cs, err := s.checkpointState(s.justified)
+	if err != nil {
+		return 0, err
+	}
+	committeeWeight := cs.epc.TotalActiveStake / common.Gwei(s.spec.SLOTS_PER_EPOCH)
+	return committeeWeight * common.Gwei(s.spec.PROPOSER_SCORE_BOOST) / 100, nil
+}
+
+func (s *Store) updateCheckpoints(justified common.Checkpoint, finalized common.Checkpoint) {
+	if justified.Epoch > s.justified.Epoch {
+		s.justified = justified
+	}
+	if finalized.Epoch > s.finalized.Epoch {
+		s.finalized = finalized
+	}
+}
+
+func (s *Store) updateUnrealizedCheckpoints(justified common.Checkpoint, finalized common.Checkpoint) {
+	if justified.Epoch > s.unrealizedJustified.Epoch {
+		s.unrealizedJustified = justified
+	}
+	if finalized.Epoch > s.unrealizedFinalized.Epoch {
+		s.unrealizedFinalized = finalized
+	}
+}
+
+// sync updates the forkchoice with the justified checkpoint, balances and proposer boost of the store.
+func (s *Store) sync() error {
+	if s.fc.Justified() != s.justified {
+		if err := s.fc.UpdateJustified(context.Background(), s.justified.Root, s.justified, s.anchor, s.justifiedBalances); err != nil {
+			return err
+		}
+	}
+	score, err := s.proposerScore()
+	if err != nil {
+		return err
+	}
+	var boost forkchoice.NodeRef
+	if b, ok := s.blocks[s.proposerBoostRoot]; ok {
+		boost = forkchoice.NodeRef{Root: s.proposerBoostRoot, Slot: b.slot}
+	}
+	s.votes.setProposerBoost(boost, score)
+	return nil
+}
+
+func (s *Store) onTickPerSlot(time common.Timestamp) {
+	previousSlot := s.CurrentSlot()
+	s.time = time
+	currentSlot := s.CurrentSlot()
+	if currentSlot > previousSlot {
+		s.proposerBoostRoot = common.Root{}
+		if currentSlot%s.spec.SLOTS_PER_EPOCH == 0 {
+			s.updateCheckpoints(s.unrealizedJustified, s.unrealizedFinalized)
+		}
+	}
+}
+
+func (s *Store) OnTick(time common.Timestamp) error {
+	tickSlot := s.spec.TimeToSlot(time, s.genesisTime)
+	for s.CurrentSlot() < tickSlot {
+		s.onTickPerSlot(s.genesisTime + common.Timestamp(s.CurrentSlot()+1)*s.spec.SECONDS_PER_SLOT)
+	}
+	s.onTickPerSlot(time)
+	return s.sync()
+}
+
+// BlockData is the fork-specific block content that the store needs.
+type BlockData struct {
+	Envelope          *common.BeaconBlockEnvelope
+	Attestations      []phase0.Attestation
+	AttesterSlashings []phase0.AttesterSlashing
+	// Electra attestations and slashings, spanning multiple committees (EIP-7549)
+	AttestationsElectra      []electra.AttestationElectra
+	AttesterSlashingsElectra []electra.AttesterSlashingElectra
+	// Nil if there is no execution payload
+	Payload *struct {
+		BlockHash  common.Hash32
+		ParentHash common.Hash32
+	}
+	BlobCommitments int
+}
+
+func NewBlockData(spec *common.Spec, benv *common.BeaconBlockEnvelope) (*BlockData, error) {
+	out := &BlockData{Envelope: benv}
+	setPayload := func(blockHash, parentHash common.Hash32) {
+		out.Payload = &struct {
+			BlockHash  common.Hash32
+			ParentHash common.Hash32
+		}{blockHash, parentHash}
+	}
+	switch body := benv.Body.(type) {
+	case *phase0.BeaconBlockBody:
+		out.Attestations, out.AttesterSlashings = body.Attestations, body.AttesterSlashings
+	case *altair.BeaconBlockBody:
+		out.Attestations, out.AttesterSlashings = body.Attestations, body.AttesterSlashings
+	case *bellatrix.BeaconBlockBody:
+		out.Attestations, out.AttesterSlashings = body.Attestations, body.AttesterSlashings
+		setPayload(body.ExecutionPayload.BlockHash, body.ExecutionPayload.ParentHash)
+	case *capella.BeaconBlockBody:
+		out.Attestations, out.AttesterSlashings = body.Attestations, body.AttesterSlashings
+		setPayload(body.ExecutionPayload.BlockHash, body.ExecutionPayload.ParentHash)
+	case *deneb.BeaconBlockBody:
+		out.Attestations, out.AttesterSlashings = body.Attestations, body.AttesterSlashings
+		setPayload(body.ExecutionPayload.BlockHash, body.ExecutionPayload.ParentHash)
+		out.BlobCommitments = len(body.BlobKZGCommitments)
+	case *electra.BeaconBlockBody:
+		out.AttestationsElectra, out.AttesterSlashingsElectra = body.Attestations, body.AttesterSlashings
+		setPayload(body.ExecutionPayload.BlockHash, body.ExecutionPayload.ParentHash)
+		out.BlobCommitments = len(body.BlobKZGCommitments)
+	default:
+		return nil, fmt.Errorf("unrecognized block body type: %T", body)
+	}
+	return out, nil
+}
+
+func (s *Store) validateMergeBlock(block *BlockData) error {
+	if s.spec.TERMINAL_BLOCK_HASH != (common.Hash32{}) {
+		if s.spec.SlotToEpoch(block.Envelope.Slot) < s.spec.TERMINAL_BLOCK_HASH_ACTIVATION_EPOCH {
+			return errors.New("terminal block hash is not activated yet")
+		}
+		if block.Payload.ParentHash != s.spec.TERMINAL_BLOCK_HASH {
+			return errors.New("merge block does not build on terminal block hash")
+		}
+		return nil
+	}
+	powBlock, ok := s.powBlocks[block.Payload.ParentHash]
+	if !ok {
+		return fmt.Errorf("unknown pow block %s", block.Payload.ParentHash)
+	}
+	powParent, ok := s.powBlocks[powBlock.ParentHash]
+	if !ok {
+		return fmt.Errorf("unknown pow parent block %s", powBlock.ParentHash)
+	}
+	if lessU256(powBlock.TotalDifficulty, s.spec.TERMINAL_TOTAL_DIFFICULTY) ||
+		!lessU256(powParent.TotalDifficulty, s.spec.TERMINAL_TOTAL_DIFFICULTY) {
+		return errors.New("merge block does not build on a valid terminal pow block")
+	}
+	return nil
+}
+
+// OnBlock imports the block, if available (blob data is checked by the caller).
+// The attestations and attester slashings of the block are processed too.
+func (s *Store) OnBlock(block *BlockData) error {
+	benv := block.Envelope
+	parent, ok := s.blocks[benv.ParentRoot]
+	if !ok {
+		return fmt.Errorf("unknown parent block %s", benv.ParentRoot)
+	}
+	if _, ok := s.blocks[benv.BlockRoot]; ok {
+		return nil
+	}
+	if benv.Slot > s.CurrentSlot() {
+		return fmt.Errorf("block slot %d is in the future, current slot is %d", benv.Slot, s.CurrentSlot())
+	}
+	finalizedSlot, err := s.spec.EpochStartSlot(s.finalized.Epoch)
+	if err != nil {
+		return err
+	}
+	if benv.Slot <= finalizedSlot {
+		return fmt.Errorf("block slot %d is not after finalized slot %d", benv.Slot, finalizedSlot)
+	}
+	if cpRoot, err := s.checkpointBlock(benv.ParentRoot, s.finalized.Epoch); err != nil {
+		return err
+	} else if cpRoot != s.finalized.Root {
+		return errors.New("block does not descend from the finalized checkpoint")
+	}
+
+	state, err := parent.state.CopyState()
+	if err != nil {
+		return err
+	}
+	epc := parent.epc.Clone()
+	if block.Payload != nil {
+		if es, ok := state.(bellatrix.ExecutionUpgradeBeaconState); ok {
+			completed, err := es.IsTransitionCompleted()
+			if err != nil {
+				return err
+			}
+			if !completed && block.Payload.BlockHash != (common.Hash32{}) {
+				if err := s.validateMergeBlock(block); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	payloadStatus, err := common.StateTransition(context.Background(), s.spec, epc,
+		&beacon.StandardUpgradeableBeaconState{BeaconState: state}, benv, true)
+	if err != nil {
+		return err
+	}
+
+	info := &blockInfo{
+		slot:       benv.Slot,
+		parentRoot: benv.ParentRoot,
+		state:      state,
+		epc:        epc,
+	}
+	if block.Payload != nil {
+		info.blockHash = block.Payload.BlockHash
+	}
+	// blocks without payload are valid, SYNCING and ACCEPTED payloads make the block optimistic
+	execStatus := forkchoice.ExecutionStatusFromPayload(payloadStatus)
+
+	// Compute the pulled-up tip: processing the justification of the epoch of the block.
+	// Empty slots do not change the participation, so processing up to the next epoch gives the same result.
+	pulledUp, err := state.CopyState()
+	if err != nil {
+		return err
+	}
+	nextEpochSlot, err := s.spec.EpochStartSlot(s.spec.SlotToEpoch(benv.Slot) + 1)
+	if err != nil {
+		return err
+	}
+	if err := common.ProcessSlots(context.Background(), s.spec, epc.Clone(),
+		&beacon.StandardUpgradeableBeaconState{BeaconState: pulledUp}, nextEpochSlot); err != nil {
+		return err
+	}
+	if info.unrealizedJustified, err = pulledUp.CurrentJustifiedCheckpoint(); err != nil {
+		return err
+	}
+	if info.unrealizedFinalized, err = pulledUp.FinalizedCheckpoint(); err != nil {
+		return err
+	}
+	s.blocks[benv.BlockRoot] = info
+
+	timeIntoSlot := (s.time - s.genesisTime) % s.spec.SECONDS_PER_SLOT
+	isBeforeAttestingInterval := timeIntoSlot < s.spec.SECONDS_PER_SLOT/INTERVALS_PER_SLOT
+	if s.CurrentSlot() == benv.Slot && isBeforeAttestingInterval && s.proposerBoostRoot == (common.Root{}) {
+		s.proposerBoostRoot = benv.BlockRoot
+	}
+
+	justified, err := state.CurrentJustifiedCheckpoint()
+	if err != nil {
+		return err
+	}
+	finalized, err := state.FinalizedCheckpoint()
+	if err != nil {
+		return err
+	}
+	s.updateCheckpoints(justified, finalized)
+	s.updateUnrealizedCheckpoints(info.unrealizedJustified, info.unrealizedFinalized)
+	// the voting source of blocks from prior epochs is pulled up
+	votingSource := justified
+	if s.spec.SlotToEpoch(benv.Slot) < s.CurrentEpoch() {
+		s.updateCheckpoints(info.unrealizedJustified, info.unrealizedFinalized)
+		votingSource = info.unrealizedJustified
+	}
+
+	if !s.fc.ProcessBlockWithPayload(benv.ParentRoot, benv.BlockRoot, benv.Slot, votingSource.Epoch, s.anchor.Epoch,
+		common.Root(info.blockHash), execStatus) {
+		return fmt.Errorf("forkchoice did not accept block %s", benv.BlockRoot)
+	}
+	if err := s.sync(); err != nil {
+		return err
+	}
+
+	for i := range block.Attestations {
+		if err := s.OnAttestation(&block.Attestations[i], true); err != nil {
+			return fmt.Errorf("block attestation %d: %w", i, err)
+		}
+	}
+	for i := range block.AttesterSlashings {
+		if err := s.OnAttesterSlashing(&block.AttesterSlashings[i]); err != nil {
+			return fmt.Errorf("block attester slashing %d: %w", i, err)
+		}
+	}
+	for i := range block.AttestationsElectra {
+		if err := s.OnAttestationElectra(&block.AttestationsElectra[i], true); err != nil {
+			return fmt.Errorf("block attestation %d: %w", i, err)
+		}
+	}
+	for i := range block.AttesterSlashingsElectra {
+		if err := s.OnAttesterSlashingElectra(&block.AttesterSlashingsElectra[i]); err != nil {
+			return fmt.Errorf("block attester slashing %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) OnAttestation(att *phase0.Attestation, isFromBlock bool) error {
+	data := &att.Data
+	cs, err := s.validateAttestationData(data, isFromBlock)
+	if err != nil {
+		return err
+	}
+	committee, err := cs.epc.GetBeaconCommittee(data.Slot, data.Index)
+	if err != nil {
+		return err
+	}
+	indexed, err := att.ConvertToIndexed(s.spec, committee)
+	if err != nil {
+		return err
+	}
+	if err := phase0.ValidateIndexedAttestation(s.spec, cs.epc, cs.state, indexed); err != nil {
+		return err
+	}
+	s.applyVotes(data, indexed.AttestingIndices)
+	return nil
+}
+
+// OnAttestationElectra is OnAttestation for Electra attestations, which may span multiple committees.
+func (s *Store) OnAttestationElectra(att *electra.AttestationElectra, isFromBlock bool) error {
+	data := &att.Data
+	cs, err := s.validateAttestationData(data, isFromBlock)
+	if err != nil {
+		return err
+	}
+	indexed, err := att.ConvertToIndexed(s.spec, cs.epc)
+	if err != nil {
+		return err
+	}
+	if err := electra.ValidateIndexedAttestation(s.spec, cs.epc, cs.state, indexed); err != nil {
+		return err
+	}
+	s.applyVotes(data, indexed.AttestingIndices)
+	return nil
+}
+
+// validateAttestationData checks the attestation against the store, and returns the state of its target checkpoint.
+func (s *Store) validateAttestationData(data *phase0.AttestationData, isFromBlock bool) (*checkpointState, error) {
+	target := data.Target
+	if !isFromBlock {
+		current := s.CurrentEpoch()
+		previous := current.Previous()
+		if target.Epoch != current && target.Epoch != previous {
+			return nil, fmt.Errorf("attestation target epoch %d is not current or previous epoch", target.Epoch)
+		}
+		if s.CurrentSlot() < data.Slot+1 {
+			return nil, fmt.Errorf("attestation slot %d is too early, current slot is %d", data.Slot, s.CurrentSlot())
+		}
+	}
+	if target.Epoch != s.spec.SlotToEpoch(data.Slot) {
+		return nil, errors.New("attestation target epoch does not match slot")
+	}
+	if _, ok := s.blocks[target.Root]; !ok {
+		return nil, fmt.Errorf("unknown target block %s", target.Root)
+	}
+	b, ok := s.blocks[data.BeaconBlockRoot]
+	if !ok {
+		return nil, fmt.Errorf("unknown attested block %s", data.BeaconBlockRoot)
+	}
+	if b.slot > data.Slot {
+		return nil, errors.New("attested block is after the attestation slot")
+	}
+	if cpRoot, err := s.checkpointBlock(data.BeaconBlockRoot, target.Epoch); err != nil {
+		return nil, err
+	} else if cpRoot != target.Root {
+		return nil, errors.New("attestation target is not the checkpoint block of the attested block")
+	}
+	return s.checkpointState(target)
+}
+
+// applyVotes adds the votes of the attesting validators that are not known to equivocate.
+func (s *Store) applyVotes(data *phase0.AttestationData, attestingIndices []common.ValidatorIndex) {
+	// add a node for the attested slot, the vote applies to the block root (or slot after it).
+	s.fc.ProcessSlot(data.BeaconBlockRoot, data.Slot, s.fc.Justified().Epoch, s.anchor.Epoch)
+	for _, i := range attestingIndices {
+		if _, ok := s.equivocating[i]; ok {
+			continue
+		}
+		s.fc.ProcessAttestation(i, data.BeaconBlockRoot, data.Slot)
+	}
+}
+
+func (s *Store) OnAttesterSlashing(slashing *phase0.AttesterSlashing) error {
+	att1, att2 := &slashing.Attestation1, &slashing.Attestation2
+	if !phase0.IsSlashableAttestationData(&att1.Data, &att2.Data) {
+		return errors.New("attester slashing is not slashable")
+	}
+	b := s.blocks[s.justified.Root]
+	if err := phase0.ValidateIndexedAttestation(s.spec, b.epc, b.state, att1); err != nil {
+		return fmt.Errorf("attestation 1: %w", err)
+	}
+	if err := phase0.ValidateIndexedAttestation(s.spec, b.epc, b.state, att2); err != nil {
+		return fmt.Errorf("attestation 2: %w", err)
+	}
+	return s.markEquivocating(att1.AttestingIndices, att2.AttestingIndices)
+}
+
+func (s *Store) OnAttesterSlashingElectra(slashing *electra.AttesterSlashingElectra) error {
+	att1, att2 := &slashing.Attestation1, &slashing.Attestation2
+	if !electra.IsSlashableAttestationData(&att1.Data, &att2.Data) {
+		return errors.New("attester slashing is not slashable")
+	}
+	b := s.blocks[s.justified.Root]
+	if err := electra.ValidateIndexedAttestation(s.spec, b.epc, b.state, att1); err != nil {
+		return fmt.Errorf("attestation 1: %w", err)
+	}
+	if err := electra.ValidateIndexedAttestation(s.spec, b.epc, b.state, att2); err != nil {
+		return fmt.Errorf("attestation 2: %w", err)
+	}
+	return s.markEquivocating(att1.AttestingIndices, att2.AttestingIndices)
+}
+
+// markEquivocating removes the weight of the validators that attested to both of the slashable attestations.
+func (s *Store) markEquivocating(indices1 []common.ValidatorIndex, indices2 []common.ValidatorIndex) error {
+	indices := make(map[common.ValidatorIndex]struct{}, len(indices1))
+	for _, i := range indices1 {
+		indices[i] = struct{}{}
+	}
+	changed := false
+	for _, i := range indices2 {
+		if _, ok := indices[i]; ok {
+			if _, ok := s.equivocating[i]; !ok {
+				s.equivocating[i] = struct{}{}
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	s.votes.equivocated()
+	return nil
+}
+
+// OnPayloadStatus changes the status returned by the execution engine for the given block hash,
+// and updates the status of an already imported block with the payload.
+func (s *Store) OnPayloadStatus(blockHash common.Hash32, status common.PayloadStatus, latestValidHash *common.Hash32) error {
+	s.exec.Statuses[blockHash] = status
+	for root, b := range s.blocks {
+		if b.blockHash != blockHash {
+			continue
+		}
+		switch {
+		case status.IsInvalid():
+			var lvh *common.Root
+			if latestValidHash != nil {
+				h := common.Root(*latestValidHash)
+				lvh = &h
+			}
+			return s.fc.SetPayloadInvalid(root, lvh)
+		case status == common.PayloadValid:
+			return s.fc.SetPayloadValid(root)
+		}
+	}
+	return nil
+}
+
+// Head returns the head block, searching from the justified checkpoint.
+func (s *Store) Head() (root common.Root, slot common.Slot, err error) {
+	b, ok := s.blocks[s.justified.Root]
+	if !ok {
+		return common.Root{}, 0, fmt.Errorf("unknown justified block %s", s.justified.Root)
+	}
+	head, err := s.fc.FindHead(s.justified.Root, b.slot)
+	if err != nil {
+		return common.Root{}, 0, err
+	}
+	return head.Root, s.blocks[head.Root].slot, nil
+}
@@ -0,0 +1,58 @@
+package fork_choice
+
+import (
+	"testing"
+
+	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/configs"
+	"github.com/protolambda/zrnt/eth2/forkchoice"
+	"github.com/protolambda/zrnt/eth2/forkchoice/proto"
+)
+
+func TestStoreVotes(t *testing.T) {
+	spec := configs.Minimal
+	genesis := common.Checkpoint{Root: common.Root{0x01}, Epoch: 0}
+	a := forkchoice.NodeRef{Root: common.Root{0x0a}, Slot: 1}
+	b := forkchoice.NodeRef{Root: common.Root{0x0b}, Slot: 1}
+	votes := &storeVotes{
+		VoteStore:    proto.NewProtoVoteStore(spec),
+		equivocating: make(map[common.ValidatorIndex]struct{}),
+	}
+	graph := proto.NewProtoArray(common.Root{}, genesis.Root, 0, 0, 0, nil)
+	fc, err := forkchoice.NewForkChoice(spec, genesis, genesis, genesis.Root, 0, graph, votes, []common.Gwei{10, 10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectHead := func(expected forkchoice.NodeRef) {
+		t.Helper()
+		h, err := fc.Head()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h != expected {
+			t.Fatalf("expected head %s, got %s", expected, h)
+		}
+	}
+	fc.ProcessBlock(genesis.Root, a.Root, a.Slot, 0, 0)
+	fc.ProcessBlock(genesis.Root, b.Root, b.Slot, 0, 0)
+	fc.ProcessAttestation(0, a.Root, 1)
+	expectHead(a)
+
+	// the boost outweighs the single vote
+	votes.setProposerBoost(b, 15)
+	expectHead(b)
+
+	// resetting the boost removes the previously applied weight
+	votes.setProposerBoost(forkchoice.NodeRef{}, 0)
+	expectHead(a)
+
+	// an equivocating voter loses the weight of its vote
+	votes.setProposerBoost(b, 5)
+	votes.equivocating[0] = struct{}{}
+	votes.equivocated()
+	expectHead(b)
+
+	// and does not regain it with a new vote
+	fc.ProcessAttestation(0, a.Root, 2)
+	expectHead(b)
+}
@@ -1,13 +1,14 @@
 package sanity
 
 import (
-	"bytes"
 	"fmt"
-	"io/ioutil"
 	"testing"
 
-	"github.com/golang/snappy"
+	"github.com/protolambda/zrnt/eth2/beacon/bellatrix"
+	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/tests/spec/test_util"
 	"github.com/protolambda/ztyp/codec"
@@ -16,11 +17,14 @@ import (
 
 type InitializationTestCase struct {
 	Spec          *common.Spec
-	GenesisState  *phase0.BeaconStateView
-	ExpectedState *phase0.BeaconStateView
+	Fork          test_util.ForkName
+	GenesisState  common.BeaconState
+	ExpectedState common.BeaconState
 	Eth1Timestamp common.Timestamp
 	Eth1BlockHash common.Root
 	Deposits      []common.Deposit
+	// Post-merge forks only, the default header if the test case does not have one.
+	ExecutionPayloadHeader codec.Deserializable
 }
 
 type DepositsCountMeta struct {
@@ -33,27 +37,23 @@ type Eth1InitData struct {
 }
 
 func (c *InitializationTestCase) Load(t *testing.T, forkName test_util.ForkName, readPart test_util.TestPartReader) {
-	if forkName != "phase0" {
+	c.Spec = readPart.Spec()
+	c.Fork = forkName
+	// nil if expecting a failed genesis
+	c.ExpectedState = test_util.LoadState(t, forkName, "state", readPart)
+	switch forkName {
+	case "phase0":
+	case "bellatrix":
+		c.ExecutionPayloadHeader = new(bellatrix.ExecutionPayloadHeader)
+	case "capella":
+		c.ExecutionPayloadHeader = new(capella.ExecutionPayloadHeader)
+	case "deneb", "electra":
+		c.ExecutionPayloadHeader = new(deneb.ExecutionPayloadHeader)
+	default:
 		t.Fatalf("genesis initialization not supported for fork %s", forkName)
 	}
-	c.Spec = readPart.Spec()
-	{
-		p := readPart.Part("state.ssz_snappy")
-		if p.Exists() {
-			data, err := ioutil.ReadAll(p)
-			test_util.Check(t, err)
-			test_util.Check(t, p.Close())
-			uncompressed, err := snappy.Decode(nil, data)
-			test_util.Check(t, err)
-			state, err := phase0.AsBeaconStateView(
-				phase0.BeaconStateType(c.Spec).Deserialize(
-					codec.NewDecodingReader(bytes.NewReader(uncompressed), uint64(len(uncompressed)))))
-			test_util.Check(t, err)
-			c.ExpectedState = state
-		} else {
-			// expecting a failed genesis
-			c.ExpectedState = nil
-		}
+	if c.ExecutionPayloadHeader != nil {
+		test_util.LoadSSZ(t, "execution_payload_header", c.ExecutionPayloadHeader, readPart)
 	}
 	{
 		p := readPart.Part("eth1.yaml")
@@ -81,7 +81,23 @@ func (c *InitializationTestCase) Load(t *testing.T, forkName test_util.ForkName,
 }
 
 func (c *InitializationTestCase) Run() error {
-	res, _, err := phase0.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false)
+	var res common.BeaconState
+	var err error
+	switch h := c.ExecutionPayloadHeader.(type) {
+	case *bellatrix.ExecutionPayloadHeader:
+		res, _, err = bellatrix.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, h, false)
+	case *capella.ExecutionPayloadHeader:
+		res, _, err = capella.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, h, false)
+	case *deneb.ExecutionPayloadHeader:
+		// Electra did not change the execution payload header
+		if c.Fork == "electra" {
+			res, _, err = electra.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, h, false)
+		} else {
+			res, _, err = deneb.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, h, false)
+		}
+	default:
+		res, _, err = phase0.GenesisFromEth1(c.Spec, c.Eth1BlockHash, c.Eth1Timestamp, c.Deposits, false)
+	}
 	if err != nil {
 		return err
 	}
@@ -108,7 +124,7 @@ func (c *InitializationTestCase) Check(t *testing.T) {
 }
 
 func TestInitialization(t *testing.T) {
-	// TODO: support initialization for all forks, not just phase0
-	test_util.RunTransitionTest(t, []test_util.ForkName{"phase0"}, "genesis", "initialization",
+	// altair genesis is not supported, it starts from phase0
+	test_util.RunTransitionTest(t, []test_util.ForkName{"phase0", "bellatrix", "capella", "deneb", "electra"}, "genesis", "initialization",
 		func() test_util.TransitionTest { return new(InitializationTestCase) })
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 
 	"github.com/golang/snappy"
 	"github.com/protolambda/ztyp/codec"
@@ -41,6 +42,8 @@ func runCase(t *testing.T, forkName test_util.ForkName, readPart test_util.TestP
 		genesisState, err = capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(decodingReader))
 	case "deneb":
 		genesisState, err = deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(decodingReader))
+	case "electra":
+		genesisState, err = electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(decodingReader))
 	default:
 		t.Fatalf("unrecognized fork name: %s", forkName)
 	}
@@ -63,9 +66,12 @@ func runCase(t *testing.T, forkName test_util.ForkName, readPart test_util.TestP
 	}
 }
 
+// the genesis validity tests also cover Electra
+var validityForks = append(append([]test_util.ForkName{}, test_util.AllForks...), "electra")
+
 func validity(spec *common.Spec) func(t *testing.T) {
 	return func(t *testing.T) {
-		for _, fork := range test_util.AllForks {
+		for _, fork := range validityForks {
 			t.Run(string(fork), func(t *testing.T) {
 				test_util.RunHandler(t, "genesis/validity", runCase, spec, fork)
 			})
@@ -18,8 +18,11 @@ type MockExecEngine struct {
 	Valid bool `yaml:"execution_valid"`
 }
 
-func (m *MockExecEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (valid bool, err error) {
-	return m.Valid, nil
+func (m *MockExecEngine) DenebNotifyNewPayload(ctx context.Context, executionPayload *deneb.ExecutionPayload, parentBeaconBlockRoot common.Root) (status common.PayloadStatus, err error) {
+	if !m.Valid {
+		return common.PayloadInvalid, nil
+	}
+	return common.PayloadValid, nil
 }
 
 func (m *MockExecEngine) DenebIsValidVersionedHashes(ctx context.Context, payload *deneb.ExecutionPayload, versionedHashes []common.Hash32) (bool, error) {
@@ -30,16 +33,22 @@ func (m *MockExecEngine) DenebIsValidBlockHash(ctx context.Context, payload *den
 	return m.Valid, nil
 }
 
-func (m *MockExecEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (valid bool, err error) {
-	return m.Valid, nil
+func (m *MockExecEngine) CapellaNotifyNewPayload(ctx context.Context, executionPayload *capella.ExecutionPayload) (status common.PayloadStatus, err error) {
+	if !m.Valid {
+		return common.PayloadInvalid, nil
+	}
+	return common.PayloadValid, nil
 }
 
 func (m *MockExecEngine) CapellaIsValidBlockHash(ctx context.Context, payload *capella.ExecutionPayload) (bool, error) {
 	return m.Valid, nil
 }
 
-func (m *MockExecEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (valid bool, err error) {
-	return m.Valid, nil
+func (m *MockExecEngine) BellatrixNotifyNewPayload(ctx context.Context, executionPayload *bellatrix.ExecutionPayload) (status common.PayloadStatus, err error) {
+	if !m.Valid {
+		return common.PayloadInvalid, nil
+	}
+	return common.PayloadValid, nil
 }
 
 func (m *MockExecEngine) BellatrixIsValidBlockHash(ctx context.Context, payload *bellatrix.ExecutionPayload) (bool, error) {
@@ -82,14 +91,17 @@ func (c *ExecutionPayloadTestCase) Load(t *testing.T, forkName test_util.ForkNam
 func (c *ExecutionPayloadTestCase) Run() error {
 	switch s := c.Pre.(type) {
 	case bellatrix.ExecutionTrackingBeaconState:
-		return bellatrix.ProcessExecutionPayload(context.Background(), c.Spec,
+		_, err := bellatrix.ProcessExecutionPayload(context.Background(), c.Spec,
 			s, &c.BlockBody.(*bellatrix.BeaconBlockBody).ExecutionPayload, &c.Execution)
+		return err
 	case capella.ExecutionTrackingBeaconState:
-		return capella.ProcessExecutionPayload(context.Background(), c.Spec,
+		_, err := capella.ProcessExecutionPayload(context.Background(), c.Spec,
 			s, &c.BlockBody.(*capella.BeaconBlockBody).ExecutionPayload, &c.Execution)
+		return err
 	case deneb.ExecutionTrackingBeaconState:
-		return deneb.ProcessExecutionPayload(context.Background(), c.Spec,
+		_, err := deneb.ProcessExecutionPayload(context.Background(), c.Spec,
 			s, c.BlockBody.(*deneb.BeaconBlockBody), &c.Execution)
+		return err
 	default:
 		return fmt.Errorf("unrecognized state type: %T", c.Pre)
 	}
@@ -130,7 +130,7 @@ func (c *TransitionTestCase) Run() error {
 		c.Pre = state.BeaconState
 	}()
 	for _, b := range c.Blocks {
-		if err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
+		if _, err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
 			return err
 		}
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/protolambda/zrnt/eth2/beacon/capella"
 	"github.com/protolambda/zrnt/eth2/beacon/common"
 	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+	"github.com/protolambda/zrnt/eth2/beacon/electra"
 	"github.com/protolambda/zrnt/eth2/beacon/phase0"
 	"github.com/protolambda/zrnt/eth2/configs"
 )
@@ -62,6 +63,8 @@ func LoadState(t *testing.T, fork ForkName, name string, readPart TestPartReader
 			state, err = capella.AsBeaconStateView(capella.BeaconStateType(spec).Deserialize(decodingReader))
 		case "deneb":
 			state, err = deneb.AsBeaconStateView(deneb.BeaconStateType(spec).Deserialize(decodingReader))
+		case "electra":
+			state, err = electra.AsBeaconStateView(electra.BeaconStateType(spec).Deserialize(decodingReader))
 		default:
 			t.Fatalf("unrecognized fork name: %s", fork)
 			return nil
@@ -168,7 +171,7 @@ func (c *BlocksTestCase) Run() error {
 		c.Pre = state.BeaconState
 	}()
 	for _, b := range c.Blocks {
-		if err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
+		if _, err := common.StateTransition(context.Background(), c.Spec, epc, state, b, true); err != nil {
 			return err
 		}
 	}